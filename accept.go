@@ -0,0 +1,88 @@
+package reqtify
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+   Requests which don't set their own Accept header get one built from the
+   unmarshallers they use, so that servers which negotiate content send
+   something we can decode. When there are several, the first one added is
+   preferred, and the others are given decreasing q-values:
+
+	api.New("/thing").JSONInto(&v).XMLInto(&v)
+	// Accept: application/json, application/xml;q=0.9, text/xml;q=0.9
+
+   Unmarshallers which take anything, like TextInto, add a wildcard with the
+   lowest preference, so the server may send whatever it has.
+*/
+
+// a ResponseUnmarshaller may implement MediaTyper to declare the media types
+// it can decode, for the automatic Accept header.
+type MediaTyper interface {
+	MediaTypes() []string
+}
+
+// returns the media types an unmarshaller decodes, or nil if it takes anything.
+func mediaTypes(u ResponseUnmarshaller) ([]string) {
+	switch u := u.(type) {
+	case JSONUnmarshaller, *JSONExtractor:
+		return []string{"application/json"}
+	case JSONAPIUnmarshaller:
+		return []string{JSONAPIContentType}
+	case GraphQLUnmarshaller:
+		return []string{GraphQLResponseContentType, "application/json"}
+	case XMLUnmarshaller:
+		return []string{"application/xml", "text/xml"}
+	case YAMLUnmarshaller:
+		return []string{"application/yaml", "application/x-yaml", "text/yaml"}
+	case MsgpackUnmarshaller:
+		return []string{MsgpackContentType, "application/x-msgpack"}
+	case CBORUnmarshaller:
+		return []string{CBORContentType}
+	case CSVUnmarshaller:
+		return []string{"text/csv"}
+	case MediaTyper:
+		return u.MediaTypes()
+	}
+	return nil
+}
+
+// builds an Accept header from the request's unmarshallers, or returns ""
+// if none of them care.
+func (this *RequestImpl) accept() (string) {
+	unmarshallers := append([]ResponseUnmarshaller(nil), this.Response...)
+	for _, s := range this.StatusResponse {
+		unmarshallers = append(unmarshallers, s.Unmarshaller)
+	}
+
+	var parts []string
+	seen := make(map[string]bool)
+	anything := false
+	q := 10
+	for _, u := range unmarshallers {
+		types := mediaTypes(u)
+		if types == nil {
+			anything = true
+			continue
+		}
+
+		added := false
+		for _, t := range types {
+			if seen[t] { continue }
+			seen[t] = true
+			added = true
+			if q == 10 {
+				parts = append(parts, t)
+			} else {
+				parts = append(parts, t + ";q=0." + strconv.Itoa(q))
+			}
+		}
+		if added && q > 2 { q-- }
+	}
+
+	if len(parts) == 0 { return "" }
+	if anything { parts = append(parts, "*/*;q=0.1") }
+	return strings.Join(parts, ", ")
+}
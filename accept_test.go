@@ -0,0 +1,40 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestAccept(t *testing.T) {
+	var accept string
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		accept = req.Header.Get("Accept")
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("{}"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var v struct{}
+	var text string
+	cases := []struct {
+		req      Request
+		expected string
+	}{
+		{reqt.New("/"), ""},
+		{reqt.New("/").TextInto(&text), ""},
+		{reqt.New("/").JSONInto(&v), "application/json"},
+		{reqt.New("/").JSONInto(&v).ExtractInto("a", &v).ErrorJSONInto(&v), "application/json"},
+		{reqt.New("/").JSONInto(&v).Into(FromXML(&v)), "application/json, application/xml;q=0.9, text/xml;q=0.9"},
+		{reqt.New("/").CBORInto(&v).TextInto(&text), "application/cbor, */*;q=0.1"},
+		{reqt.New("/").JSONInto(&v).Header("Accept", "application/vnd.api+json"), "application/vnd.api+json"},
+	}
+	for i, c := range cases {
+		c.req.Do() // the body won't decode as everything, but that doesn't matter here
+		if accept != c.expected { t.Errorf("Accept Mismatch %d: got %q, expected %q", i, accept, c.expected) }
+	}
+}
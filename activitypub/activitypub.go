@@ -0,0 +1,202 @@
+// Package activitypub provides helpers for talking to ActivityPub servers
+// through reqtify: content negotiation for ActivityStreams documents, and
+// signed fetching and delivery of activities.
+package activitypub
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/httpsig"
+)
+
+// the media type of ActivityStreams documents.
+const ContentType = "application/activity+json"
+
+// the JSON-LD media type for ActivityStreams, which some servers require
+// instead of ContentType.
+const LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+// the Accept header sent when fetching objects.
+const Accept = LDContentType + ", " + ContentType
+
+// the JSON-LD context of ActivityStreams documents.
+const Context = "https://www.w3.org/ns/activitystreams"
+
+var ErrNotActivityStreams error = errors.New("activitypub: response is not an ActivityStreams document")
+
+// an ActivityStreams object. Only commonly used properties are decoded; to
+// access extensions, decode into your own type with Client.Get.
+type Object struct {
+	Context      interface{}     `json:"@context,omitempty"`
+	ID           string          `json:"id,omitempty"`
+	Type         string          `json:"type,omitempty"`
+	Actor        interface{}     `json:"actor,omitempty"`
+	Object       interface{}     `json:"object,omitempty"`
+	Target       interface{}     `json:"target,omitempty"`
+	To           []string        `json:"to,omitempty"`
+	Cc           []string        `json:"cc,omitempty"`
+	AttributedTo interface{}     `json:"attributedTo,omitempty"`
+	InReplyTo    interface{}     `json:"inReplyTo,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	Summary      string          `json:"summary,omitempty"`
+	Name         string          `json:"name,omitempty"`
+	URL          interface{}     `json:"url,omitempty"`
+	Published    string          `json:"published,omitempty"`
+}
+
+// an ActivityStreams actor.
+type Actor struct {
+	Object
+
+	PreferredUsername string    `json:"preferredUsername,omitempty"`
+	Inbox             string    `json:"inbox,omitempty"`
+	Outbox            string    `json:"outbox,omitempty"`
+	Followers         string    `json:"followers,omitempty"`
+	Following         string    `json:"following,omitempty"`
+	Endpoints         Endpoints `json:"endpoints"`
+	PublicKey         PublicKey `json:"publicKey"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	ID           string `json:"id,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	PublicKeyPem string `json:"publicKeyPem,omitempty"`
+}
+
+// returns the inbox to deliver to for this actor, preferring the shared inbox.
+func (this *Actor) DeliveryInbox() (string) {
+	if this.Endpoints.SharedInbox != "" { return this.Endpoints.SharedInbox }
+	return this.Inbox
+}
+
+// a Client fetches and delivers ActivityStreams documents. Objects are
+// addressed by absolute IRIs, so its Reqtifier should have an empty root.
+type Client struct {
+	Reqtifier reqtify.Reqtifier
+}
+
+// creates a Client whose requests are signed as keyID (typically the URL of
+// an actor's publicKey) with the given private key, using draft-cavage
+// signatures as expected by Mastodon and most other fediverse software.
+// opts are passed through to reqtify.New.
+func NewClient(keyID string, key crypto.Signer, agent string, opts ...reqtify.Option) (*Client) {
+	signer := &httpsig.CavageSigner{
+		KeyID: keyID,
+		Keys: httpsig.StaticKeys{keyID: &httpsig.Key{ID: keyID, Private: key}},
+	}
+	opts = append([]reqtify.Option{reqtify.WithMiddleware(signer.Middleware())}, opts...)
+	return &Client{Reqtifier: reqtify.New("", nil, nil, nil, agent, opts...)}
+}
+
+// builds a request for an ActivityStreams object, with the appropriate
+// Accept header. The caller may customize it further before calling Do.
+func (this *Client) NewGet(iri string) (reqtify.Request) {
+	return this.Reqtifier.New(iri).Method(reqtify.GET).Header("Accept", Accept)
+}
+
+// fetches the object at iri and decodes it into into. If the server doesn't
+// respond with a 2xx status, the body isn't decoded, and the error is an
+// *reqtify.UnexpectedStatusError. If it responds with something other than an
+// ActivityStreams document, into is left alone and the error is
+// ErrNotActivityStreams.
+func (this *Client) Get(iri string, into interface{}) (*http.Response, error) {
+	u := &activityStreamsUnmarshaller{into: into, codec: this.jsonCodec()}
+	return this.NewGet(iri).ExpectStatusRange(200, 299).HeaderInto("Content-Type", &u.contentType).Into(u).Do()
+}
+
+// returns the JSON codec the Client's Reqtifier was configured with.
+func (this *Client) jsonCodec() (reqtify.JSONCodec) {
+	if r, ok := this.Reqtifier.(*reqtify.ReqtifierImpl); ok && r.JSONCodec != nil { return r.JSONCodec }
+	return reqtify.DefaultJSONCodec
+}
+
+// decodes a response body as JSON, but only if its content type, captured
+// with HeaderInto before the body is unmarshalled, is ActivityStreams.
+type activityStreamsUnmarshaller struct {
+	into        interface{}
+	codec       reqtify.JSONCodec
+	contentType string
+}
+
+func (this *activityStreamsUnmarshaller) Unmarshal(body []byte) (error) {
+	if !IsActivityStreams(this.contentType) { return ErrNotActivityStreams }
+	return this.codec.Unmarshal(body, this.into)
+}
+
+func (this *activityStreamsUnmarshaller) MediaTypes() ([]string) {
+	return []string{LDContentType, ContentType}
+}
+
+// fetches an object.
+func (this *Client) FetchObject(iri string) (*Object, error) {
+	var o Object
+	resp, err := this.Get(iri, &o)
+	if resp != nil { resp.Body.Close() }
+	if err != nil { return nil, err }
+	return &o, nil
+}
+
+// fetches an actor.
+func (this *Client) FetchActor(iri string) (*Actor, error) {
+	var a Actor
+	resp, err := this.Get(iri, &a)
+	if resp != nil { resp.Body.Close() }
+	if err != nil { return nil, err }
+	return &a, nil
+}
+
+// builds a request which posts an activity to an inbox. The activity is sent
+// as canonical JSON, so its digest and signature can be reproduced from its
+// content.
+func (this *Client) NewPost(inbox string, activity interface{}) (reqtify.Request, error) {
+	body, err := reqtify.MarshalCanonicalJSON(activity)
+	if err != nil { return nil, err }
+	return this.Reqtifier.New(inbox).Method(reqtify.POST).Body(bytes.NewReader(body), LDContentType), nil
+}
+
+// posts an activity to an inbox.
+func (this *Client) Post(inbox string, activity interface{}) (*http.Response, error) {
+	req, err := this.NewPost(inbox, activity)
+	if err != nil { return nil, err }
+	resp, err := req.Do()
+	if err != nil { return resp, err }
+	return resp, checkResponse(resp)
+}
+
+// delivers an activity to an actor, via their shared inbox if they have one.
+func (this *Client) Deliver(to *Actor, activity interface{}) (error) {
+	inbox := to.DeliveryInbox()
+	if inbox == "" { return fmt.Errorf("activitypub: actor %q has no inbox", to.ID) }
+	resp, err := this.Post(inbox, activity)
+	if resp != nil { resp.Body.Close() }
+	return err
+}
+
+// reports whether a content type denotes an ActivityStreams document.
+func IsActivityStreams(contentType string) (bool) {
+	media := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	switch strings.ToLower(media) {
+	case ContentType:
+		return true
+	case "application/ld+json":
+		return strings.Contains(contentType, "activitystreams")
+	}
+	return false
+}
+
+func checkResponse(resp *http.Response) (error) {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+	return nil
+}
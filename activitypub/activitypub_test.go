@@ -0,0 +1,151 @@
+package activitypub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/thewug/reqtify"
+)
+
+func TestClient(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		if r.Method == "GET" {
+			w.Header().Set("Content-Type", ContentType)
+			w.Write([]byte(`{"id": "` + r.URL.String() + `", "type": "Person", "inbox": "/inbox", "endpoints": {"sharedInbox": "/shared"}}`))
+		} else {
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil { t.Fatalf("couldn't generate key: %s", err.Error()) }
+	client := NewClient("https://bot.example/actor#main-key", key, "test")
+
+	actor, err := client.FetchActor(server.URL + "/users/alice")
+	if err != nil { t.Fatalf("Fetch Failure: %s", err.Error()) }
+	if actor.Type != "Person" || actor.DeliveryInbox() != "/shared" {
+		t.Errorf("Actor Mismatch: got %+v", actor)
+	}
+	if got.Header.Get("Accept") != Accept {
+		t.Errorf("Accept Mismatch: got %s, expected %s", got.Header.Get("Accept"), Accept)
+	}
+	if sig := got.Header.Get("Signature"); !strings.Contains(sig, `headers="(request-target) host date"`) {
+		t.Errorf("Signature Mismatch: got %s", sig)
+	}
+
+	actor.Endpoints.SharedInbox = server.URL + "/shared"
+	err = client.Deliver(actor, Object{Context: Context, Type: "Follow", Actor: "https://bot.example/actor", Object: actor.ID})
+	if err != nil { t.Fatalf("Deliver Failure: %s", err.Error()) }
+	if got.URL.Path != "/shared" || got.Header.Get("Content-Type") != LDContentType {
+		t.Errorf("Delivery Mismatch: got %s with content type %s", got.URL.Path, got.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, `"type":"Follow"`) {
+		t.Errorf("Body Mismatch: got %s", body)
+	}
+	if canonical, err := reqtify.CanonicalJSON([]byte(body)); err != nil || string(canonical) != body {
+		t.Errorf("Canonical Mismatch: got %s, expected %s", body, canonical)
+	}
+	if sig := got.Header.Get("Signature"); !strings.Contains(sig, `headers="(request-target) host date digest"`) || got.Header.Get("Digest") == "" {
+		t.Errorf("Signature Mismatch: got %s", sig)
+	}
+}
+
+func TestGetErrorPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte("<html><body>Gone</body></html>"))
+	}))
+	defer server.Close()
+
+	client := &Client{Reqtifier: reqtify.New("", nil, nil, nil, "test")}
+	_, err := client.FetchObject(server.URL + "/notes/1")
+	var statusErr *reqtify.UnexpectedStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusGone {
+		t.Errorf("Status Mismatch: got %v, expected an unexpected 410 status error", err)
+	}
+}
+
+func TestGetNotActivityStreams(t *testing.T) {
+	page := `<html><body>{"id": "not json"}</body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	// answers without a Request on the response, the way mocks do.
+	canned := func(next reqtify.RoundTripFunc) reqtify.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Status: "200 OK",
+				Header: http.Header{"Content-Type": []string{"text/html"}},
+				Body: ioutil.NopCloser(strings.NewReader(`{"id": "https://example.com/partial"}`)),
+			}, nil
+		}
+	}
+
+	for name, client := range map[string]*Client{
+		"Server": &Client{Reqtifier: reqtify.New("", nil, nil, nil, "test")},
+		"Mock": &Client{Reqtifier: reqtify.New("", nil, nil, nil, "test", reqtify.WithMiddleware(canned))},
+	} {
+		o := Object{ID: "untouched"}
+		resp, err := client.Get(server.URL + "/notes/1", &o)
+		if resp != nil { resp.Body.Close() }
+		if err != ErrNotActivityStreams {
+			t.Errorf("%s Error Mismatch: got %v, expected %v", name, err, ErrNotActivityStreams)
+		}
+		if o.ID != "untouched" {
+			t.Errorf("%s Object Mismatch: got %+v, expected it to be left alone", name, o)
+		}
+	}
+}
+
+type closeTracker struct {
+	io.ReadCloser
+	closed *bool
+}
+
+func (this closeTracker) Close() (error) {
+	*this.closed = true
+	return this.ReadCloser.Close()
+}
+
+func TestFetchClosesBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	var closed bool
+	track := func(next reqtify.RoundTripFunc) reqtify.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			resp, err := next(r)
+			if resp != nil { resp.Body = closeTracker{ReadCloser: resp.Body, closed: &closed} }
+			return resp, err
+		}
+	}
+	client := &Client{Reqtifier: reqtify.New("", nil, nil, nil, "test", reqtify.WithMiddleware(track))}
+	for _, fetch := range []func(string) error{
+		func(iri string) error { _, err := client.FetchObject(iri); return err },
+		func(iri string) error { _, err := client.FetchActor(iri); return err },
+	} {
+		closed = false
+		if err := fetch(server.URL + "/users/gone"); err == nil { t.Errorf("Status Mismatch: expected an error") }
+		if !closed { t.Errorf("Close Mismatch: the error response's body was left open") }
+	}
+}
@@ -0,0 +1,52 @@
+package reqtify
+
+import (
+	"net/http"
+)
+
+// the eventual result of a request sent with DoAsync.
+type Future struct {
+	done   chan struct{}
+	result Result
+}
+
+// starts do in a new goroutine, returning a Future for its result. This is
+// how DoAsync is implemented, and is exported for Request implementations
+// outside this package.
+func NewFuture(do func() (*http.Response, error)) (*Future) {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.result.Response, f.result.Err = do()
+	}()
+	return f
+}
+
+// returns a channel which is closed once the request has finished.
+func (this *Future) Done() (<-chan struct{}) {
+	return this.done
+}
+
+// waits for the request to finish, and returns what Do would have.
+func (this *Future) Wait() (*http.Response, error) {
+	<-this.done
+	return this.result.Response, this.result.Err
+}
+
+// returns the result of the request without waiting for it. ok is false
+// if it hasn't finished yet.
+func (this *Future) Result() (result Result, ok bool) {
+	select {
+	case <-this.done:
+		return this.result, true
+	default:
+		return Result{}, false
+	}
+}
+
+// sends the request in the background, like Do does in the foreground.
+// The request, and its unmarshallers' targets, mustn't be touched until
+// it's finished.
+func (this *RequestImpl) DoAsync() (*Future) {
+	return NewFuture(this.Do)
+}
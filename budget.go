@@ -0,0 +1,99 @@
+package reqtify
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+   When an upstream struggles, every client retrying every failed call can
+   multiply its load several times over, and keep it down. A RetryBudget caps
+   retries at a fraction of requests, over a sliding window, so that retries
+   help with occasional failures but can't turn into a retry storm:
+
+	budget := &reqtify.RetryBudget{Ratio: 0.2, MinRetries: 10}
+	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithRetryBudget(budget))
+
+   allows retries for up to 20% of requests, plus 10 more per window for when
+   there's little traffic. Several Reqtifiers can share a budget. A request
+   which would retry when the budget is spent returns its last response or
+   error instead.
+*/
+
+const retryBudgetBuckets = 10
+
+// limits retries to a fraction of requests. The zero value allows none, but
+// MinRetries. Safe for concurrent use.
+type RetryBudget struct {
+	Ratio      float64       // how many retries are allowed per request.
+	MinRetries int           // how many retries are allowed per window regardless.
+	Window     time.Duration // the window requests and retries are counted over. Defaults to 10 seconds.
+
+	lock    sync.Mutex
+	buckets [retryBudgetBuckets]budgetBucket
+}
+
+type budgetBucket struct {
+	index    int64 // which slice of time this bucket is counting.
+	requests int
+	retries  int
+}
+
+// limits retries made by the Reqtifier with budget.
+func WithRetryBudget(budget *RetryBudget) Option {
+	return func(r *ReqtifierImpl) {
+		r.RetryBudget = budget
+	}
+}
+
+func (this *RetryBudget) window() (time.Duration) {
+	if this.Window <= 0 { return 10 * time.Second }
+	return this.Window
+}
+
+func (this *RetryBudget) index(now time.Time) (int64) {
+	width := int64(this.window()) / retryBudgetBuckets
+	if width < 1 { width = 1 }
+	return now.UnixNano() / width
+}
+
+func (this *RetryBudget) bucket(now time.Time) (*budgetBucket) {
+	i := this.index(now)
+	b := &this.buckets[(i % retryBudgetBuckets + retryBudgetBuckets) % retryBudgetBuckets]
+	if b.index != i { *b = budgetBucket{index: i} }
+	return b
+}
+
+// counts a request. Safe to call on nil.
+func (this *RetryBudget) deposit(now time.Time) {
+	if this == nil { return }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.bucket(now).requests++
+}
+
+// reports whether a retry is allowed now, counting it if it is. Safe to call
+// on nil, which allows every retry.
+func (this *RetryBudget) withdraw(now time.Time) (bool) {
+	if this == nil { return true }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	requests, retries := this.totals(now)
+	if float64(retries + 1) > float64(this.MinRetries) + this.Ratio * float64(requests) { return false }
+	this.bucket(now).retries++
+	return true
+}
+
+// returns how many requests and retries there have been in the window
+// ending now.
+func (this *RetryBudget) totals(now time.Time) (requests, retries int) {
+	current := this.index(now)
+	for _, b := range this.buckets {
+		if b.index > current - retryBudgetBuckets && b.index <= current {
+			requests += b.requests
+			retries += b.retries
+		}
+	}
+	return requests, retries
+}
@@ -0,0 +1,55 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	budget := &RetryBudget{Ratio: 0.5}
+	warnings := 0
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithRetryBudget(budget), WithLogger(LoggerFunc(func(level LogLevel, format string, args ...interface{}) {
+		if level == LogWarn { warnings++ }
+	})))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	// every other request may retry once
+	expected := []int{1, 2, 1, 2}
+	for i, e := range expected {
+		calls = 0
+		resp, err := reqt.New("/a").Retry(policy).Do()
+		if err != nil || resp.StatusCode != 503 { t.Errorf("Response Mismatch: got %v, %v", resp, err) }
+		if calls != e { t.Errorf("Calls Mismatch: request %d: got %d, expected %d", i, calls, e) }
+	}
+	if warnings != 4 { t.Errorf("Warnings Mismatch: got %d, expected 4", warnings) }
+
+	// retries stop counting against the budget once they leave the window
+	clock.Advance(5 * time.Second)
+	calls = 0
+	reqt.New("/a").Retry(policy).Do()
+	if calls != 1 { t.Errorf("Window Mismatch: got %d, expected 1", calls) }
+	clock.Advance(6 * time.Second)
+	calls = 0
+	reqt.New("/a").Retry(policy).Do()
+	reqt.New("/a").Retry(policy).Do()
+	if calls != 3 { t.Errorf("Expired Mismatch: got %d, expected 3", calls) }
+
+	// some retries are always allowed
+	reqt.(*ReqtifierImpl).RetryBudget = &RetryBudget{MinRetries: 2}
+	calls = 0
+	reqt.New("/a").Retry(RetryPolicy{MaxAttempts: 5}).Do()
+	if calls != 3 { t.Errorf("Min Retries Mismatch: got %d, expected 3", calls) }
+}
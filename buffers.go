@@ -0,0 +1,78 @@
+package reqtify
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+/*
+   The defaults throughout net/http (and reqtify) are tuned for small API
+   responses. Workloads which mostly move large files, like media downloads,
+   can do considerably better with bigger buffers, at the cost of memory per
+   connection.
+*/
+
+// the buffer size used to stream response bodies with DownloadTo, if not configured.
+const defaultCopyBufferSize = 32 * 1024
+
+// returns an *http.Transport only the Reqtifier uses, which can be configured
+// without affecting anything else, or nil if its client doesn't use one. The
+// first time, the client and its transport (or http.DefaultTransport, if it
+// has none) are copied, so the ones the Reqtifier was made with, which may be
+// shared, are left alone.
+func (this *ReqtifierImpl) transport() (*http.Transport) {
+	client, ok := this.HttpClient.(*http.Client)
+	if !ok { return nil }
+	if this.ownTransport != nil { return this.ownTransport }
+
+	base := client.Transport
+	if base == nil { base = http.DefaultTransport }
+	t, ok := base.(*http.Transport)
+	if !ok { return nil }
+
+	copied := *client
+	copied.Transport = t.Clone()
+	this.HttpClient = &copied
+	this.ownTransport = copied.Transport.(*http.Transport)
+	return this.ownTransport
+}
+
+// sets the sizes of the buffers the transport uses to read from and write to
+// each connection. Zero leaves a size at its default (currently 4KB). This
+// only has an effect if the Reqtifier's client uses an *http.Transport, which
+// is copied rather than modified.
+func WithBufferSizes(read, write int) Option {
+	return func(r *ReqtifierImpl) {
+		if t := r.transport(); t != nil {
+			if read != 0 { t.ReadBufferSize = read }
+			if write != 0 { t.WriteBufferSize = write }
+		}
+	}
+}
+
+// sets the size of the buffer used to stream response bodies with DownloadTo.
+func WithCopyBufferSize(size int) Option {
+	return func(r *ReqtifierImpl) {
+		r.CopyBufferSize = size
+	}
+}
+
+// streams the response body into w as it arrives, rather than reading it into
+// memory, for large downloads. The body returned by Do is then empty, and
+// unmarshallers aren't run.
+func (this *RequestImpl) DownloadTo(w io.Writer) (Request) {
+	this.DownloadWriter = w
+	return this
+}
+
+func (this *ReqtifierImpl) download(req *RequestImpl, resp *http.Response) (error) {
+	size := this.CopyBufferSize
+	if size <= 0 { size = defaultCopyBufferSize }
+
+	_, err := io.CopyBuffer(req.DownloadWriter, resp.Body, make([]byte, size))
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(nil))
+	return err
+}
@@ -0,0 +1,48 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestBufferSizes(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithBufferSizes(1 << 16, 0), WithCopyBufferSize(1 << 20))
+	transport := reqt.(*ReqtifierImpl).transport()
+	if transport.ReadBufferSize != 1 << 16 || transport.WriteBufferSize != 0 { t.Errorf("Transport Mismatch: got %d, %d", transport.ReadBufferSize, transport.WriteBufferSize) }
+	if reqt.(*ReqtifierImpl).CopyBufferSize != 1 << 20 { t.Errorf("Copy Mismatch: got %d", reqt.(*ReqtifierImpl).CopyBufferSize) }
+
+	// the client and transport it was given are copied, not modified
+	for _, client := range []*http.Client{{}, http.DefaultClient, {Transport: http.DefaultTransport}} {
+		before := client.Transport
+		reqt := New("https://this.is.a.test", nil, client, nil, "test", WithBufferSizes(1 << 16, 1 << 16))
+		if client.Transport != before { t.Errorf("Client Mismatch: the caller's client was modified") }
+		if http.DefaultTransport.(*http.Transport).ReadBufferSize != 0 || http.DefaultTransport.(*http.Transport).WriteBufferSize != 0 { t.Errorf("Default Transport Mismatch: http.DefaultTransport was modified") }
+		own := reqt.(*ReqtifierImpl).HttpClient.(*http.Client)
+		if own == client || own.Transport.(*http.Transport).ReadBufferSize != 1 << 16 { t.Errorf("Own Client Mismatch: got %v", own) }
+	}
+
+	shared := &http.Transport{}
+	New("https://this.is.a.test", nil, &http.Client{Transport: shared}, nil, "test", WithBufferSizes(1 << 16, 1 << 16))
+	if shared.ReadBufferSize != 0 { t.Errorf("Shared Transport Mismatch: got %d", shared.ReadBufferSize) }
+}
+
+func TestDownloadTo(t *testing.T) {
+	contents := strings.Repeat("media", 100000)
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(contents))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithCopyBufferSize(1 << 16))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var out bytes.Buffer
+	resp, err := reqt.New("/video.mp4").DownloadTo(&out).Do()
+	if err != nil { t.Fatalf("Download Failure: %s", err.Error()) }
+	if out.String() != contents { t.Errorf("Download Mismatch: got %d bytes", out.Len()) }
+	if rest, _ := ioutil.ReadAll(resp.Body); len(rest) != 0 { t.Errorf("Body Mismatch: got %d bytes left over", len(rest)) }
+}
@@ -0,0 +1,220 @@
+package reqtify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+   Bulk performs the same kind of request for many ids, which is the shape of
+   most cleanup and migration scripts:
+
+	summary := reqtify.Bulk(reqtify.IDs(ids...), func(id string) reqtify.Request {
+		return api.New("/posts/" + id).Method(reqtify.DELETE)
+	}, reqtify.BulkOptions{Concurrency: 4, Attempts: 3})
+
+   Every request still goes through the Reqtifier's rate limiter, so
+   Concurrency only helps when the limiter isn't the bottleneck.
+*/
+
+type BulkOptions struct {
+	Concurrency int           // how many requests may be in flight at once. Defaults to 1.
+	Attempts    int           // how many times each item is tried before giving up. Defaults to 1.
+	Backoff     time.Duration // the delay between attempts at the same item.
+
+	// decides whether an item succeeded. If nil, transport errors and
+	// responses with status 400 or above are failures.
+	Check       func(id string, resp *http.Response, err error) error
+
+	// called after each item finishes. Calls are serialized.
+	Progress    func(BulkProgress)
+
+	// if canceled, no further items are started and pending retries are abandoned.
+	Context     context.Context
+
+	// the clock Backoff and Elapsed follow. Defaults to SystemClock. The
+	// requests themselves follow their Reqtifier's clock.
+	Clock       Clock
+}
+
+type BulkProgress struct {
+	ID        string
+	Err       error // the final error for this item, if it failed.
+	Done      int   // items finished so far, including this one.
+	Succeeded int
+	Failed    int
+}
+
+type BulkFailure struct {
+	ID  string
+	Err error
+}
+
+type BulkSummary struct {
+	Total     int
+	Succeeded int
+	Failures  []BulkFailure // in the order items finished.
+	Elapsed   time.Duration
+}
+
+// reports whether every item succeeded.
+func (this *BulkSummary) OK() (bool) {
+	return len(this.Failures) == 0
+}
+
+// returns a channel which yields the given ids, for use with Bulk.
+func IDs(ids ...string) (<-chan string) {
+	ch := make(chan string, len(ids))
+	for _, id := range ids {
+		ch <- id
+	}
+	close(ch)
+	return ch
+}
+
+func defaultBulkCheck(id string, resp *http.Response, err error) (error) {
+	if err != nil { return err }
+	if resp.StatusCode >= 400 {
+		return &ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+	return nil
+}
+
+// performs a request built by template for each id read from ids, until ids
+// is closed. See BulkOptions.
+func Bulk(ids <-chan string, template func(id string) Request, opts BulkOptions) (BulkSummary) {
+	if opts.Concurrency < 1 { opts.Concurrency = 1 }
+	if opts.Attempts < 1 { opts.Attempts = 1 }
+	if opts.Check == nil { opts.Check = defaultBulkCheck }
+	ctx := opts.Context
+	if ctx == nil { ctx = context.Background() }
+	if opts.Clock == nil { opts.Clock = SystemClock{} }
+
+	start := opts.Clock.Now()
+	var summary BulkSummary
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	finish := func(id string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		summary.Total++
+		if err == nil {
+			summary.Succeeded++
+		} else {
+			summary.Failures = append(summary.Failures, BulkFailure{ID: id, Err: err})
+		}
+		if opts.Progress != nil {
+			opts.Progress(BulkProgress{ID: id, Err: err, Done: summary.Total, Succeeded: summary.Succeeded, Failed: len(summary.Failures)})
+		}
+	}
+
+	work := func() {
+		defer wg.Done()
+		for {
+			var id string
+			var ok bool
+			select {
+			case id, ok = <- ids:
+			case <- ctx.Done():
+				return
+			}
+			if !ok { return }
+
+			var err error
+			for attempt := 1; attempt <= opts.Attempts; attempt++ {
+				if attempt != 1 {
+					if err = clockSleep(ctx, opts.Clock, opts.Backoff); err != nil { break }
+				}
+				resp, e := template(id).Context(ctx).Do()
+				err = opts.Check(id, resp, e)
+				if resp != nil && resp.Body != nil {
+					io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				if err == nil { break }
+			}
+			finish(id, err)
+		}
+	}
+
+	wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go work()
+	}
+	wg.Wait()
+
+	summary.Elapsed = opts.Clock.Now().Sub(start)
+	return summary
+}
+
+// the outcome of one request in a Batch.
+type Result struct {
+	Response *http.Response
+	Err      error
+}
+
+// returned by Batch when any of its requests failed.
+type BatchError struct {
+	Failed []int   // the indexes of the requests which failed, in order.
+	Errs   []error // their errors, in the same order.
+	Total  int
+}
+
+func (this *BatchError) Error() (string) {
+	return fmt.Sprintf("%d of %d requests failed, first: %s", len(this.Failed), this.Total, this.Errs[0].Error())
+}
+
+func (this *BatchError) Unwrap() ([]error) {
+	return this.Errs
+}
+
+// performs requests with up to concurrency of them in flight at once, and
+// returns their results in the same order as requests. If any failed, the
+// error is a *BatchError listing them. Each request's context is replaced
+// with ctx; if it's canceled, requests which haven't started yet fail with
+// its error, and their files are closed as if they'd been sent.
+//
+// Responses are returned as Do returned them, so bodies which weren't
+// consumed by an unmarshaller must be closed by the caller.
+func Batch(ctx context.Context, concurrency int, requests ...Request) ([]Result, error) {
+	if concurrency < 1 { concurrency = 1 }
+	results := make([]Result, len(requests))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := ctx.Err(); err != nil {
+					if c, ok := requests[i].(interface{ CloseFiles() }); ok { c.CloseFiles() }
+					results[i].Err = err
+					continue
+				}
+				results[i].Response, results[i].Err = requests[i].Context(ctx).Do()
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var batchErr *BatchError
+	for i, r := range results {
+		if r.Err == nil { continue }
+		if batchErr == nil { batchErr = &BatchError{Total: len(requests)} }
+		batchErr.Failed = append(batchErr.Failed, i)
+		batchErr.Errs = append(batchErr.Errs, r.Err)
+	}
+	if batchErr != nil { return results, batchErr }
+	return results, nil
+}
@@ -0,0 +1,79 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func TestBulk(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var lock sync.Mutex
+	seen := map[string]int{}
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		seen[req.URL.Path]++
+		status := 200
+		// "/2" fails once, "/3" always fails
+		if req.URL.Path == "/3" || req.URL.Path == "/2" && seen["/2"] == 1 { status = 500 }
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	progress := 0
+	summary := Bulk(IDs("1", "2", "3", "4"), func(id string) Request {
+		return reqt.New("/" + id).Method(DELETE)
+	}, BulkOptions{Concurrency: 2, Attempts: 2, Progress: func(p BulkProgress) { progress = p.Done }})
+
+	if summary.Total != 4 || summary.Succeeded != 3 || progress != 4 { t.Errorf("Summary Mismatch: got %+v, progress %d", summary, progress) }
+	if len(summary.Failures) != 1 || summary.Failures[0].ID != "3" { t.Errorf("Failure Mismatch: got %+v", summary.Failures) }
+	if seen["/3"] != 2 || seen["/1"] != 1 { t.Errorf("Attempt Mismatch: got %v", seen) }
+}
+
+func TestBatch(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/bad" { return nil, errors.New("connection refused") }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`"` + req.URL.Path + `"`))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	names := make([]string, 20)
+	var requests []Request
+	for i := range names {
+		path := "/" + strconv.Itoa(i)
+		if i == 5 || i == 12 { path = "/bad" }
+		requests = append(requests, reqt.New(path).JSONInto(&names[i]))
+	}
+
+	results, err := Batch(context.Background(), 4, requests...)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || fmt.Sprint(batchErr.Failed) != "[5 12]" || batchErr.Total != 20 { t.Fatalf("Error Mismatch: got %v", err) }
+	if err.Error() != "2 of 20 requests failed, first: connection refused" { t.Errorf("Message Mismatch: got %s", err.Error()) }
+	for i, r := range results {
+		failed := i == 5 || i == 12
+		if (r.Err != nil) != failed || (!failed && (r.Response == nil || names[i] != "/" + strconv.Itoa(i))) { t.Errorf("Result Mismatch %d: got %+v, %q", i, r, names[i]) }
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	closed := 0
+	file := &closeRecorder{Reader: strings.NewReader("data"), close: func() { closed++ }}
+	results, err = Batch(ctx, 2, reqt.New("/1"), reqt.New("/2").FileArg("file", "data.txt", file))
+	if !errors.Is(err, context.Canceled) || results[1].Err != context.Canceled { t.Errorf("Cancel Mismatch: got %v", err) }
+	if closed != 1 { t.Errorf("Close Mismatch: got %d closes of an unsent request's file, expected 1", closed) }
+
+	if results, err = Batch(context.Background(), 0); err != nil || len(results) != 0 { t.Errorf("Empty Mismatch: got %v, %v", results, err) }
+}
@@ -0,0 +1,174 @@
+package reqtify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a ResponseCache is a simple in-memory cache of successful GET responses.
+// Entries are keyed by URL and by the values of any request headers named in
+// the response's Vary header, and expire after a fixed TTL regardless of
+// what the server says about caching. It's meant for bots which repeatedly
+// fetch resources that rarely change.
+type ResponseCache struct {
+	TTL     time.Duration
+
+	lock    sync.Mutex
+	entries map[string][]*cacheEntry
+}
+
+type cacheEntry struct {
+	status     int
+	statusText string
+	proto      string
+	header     http.Header
+	body       []byte
+	vary       map[string]string
+	expires    time.Time
+}
+
+// creates a ResponseCache whose entries live for ttl, unless a request
+// overrides it with CacheTTL.
+func NewResponseCache(ttl time.Duration) (*ResponseCache) {
+	return &ResponseCache{TTL: ttl}
+}
+
+// caches responses to GET requests made through a Reqtifier.
+func WithResponseCache(cache *ResponseCache) Option {
+	return WithMiddleware(cache.Middleware())
+}
+
+// overrides the TTL of a Reqtifier's ResponseCache for this request. A TTL of
+// zero bypasses the cache entirely: the response is neither served from nor
+// stored in it.
+func (this *RequestImpl) CacheTTL(ttl time.Duration) (Request) {
+	this.CacheLifetime = &ttl
+	return this
+}
+
+func (this *cacheEntry) matches(r *http.Request) (bool) {
+	for key, value := range this.vary {
+		if r.Header.Get(key) != value { return false }
+	}
+	return true
+}
+
+func (this *cacheEntry) response(r *http.Request) (*http.Response) {
+	return &http.Response{
+		Status: this.statusText,
+		StatusCode: this.status,
+		Proto: this.proto,
+		Header: this.header.Clone(),
+		Body: ioutil.NopCloser(bytes.NewReader(this.body)),
+		ContentLength: int64(len(this.body)),
+		Request: r,
+	}
+}
+
+func (this *ResponseCache) lookup(r *http.Request, now time.Time) (*cacheEntry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	key := r.URL.String()
+	live := this.entries[key][:0]
+	var found *cacheEntry
+	for _, e := range this.entries[key] {
+		if now.After(e.expires) { continue }
+		live = append(live, e)
+		if found == nil && e.matches(r) { found = e }
+	}
+	if len(live) == 0 {
+		delete(this.entries, key)
+	} else {
+		this.entries[key] = live
+	}
+	return found
+}
+
+func (this *ResponseCache) store(r *http.Request, e *cacheEntry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.entries == nil {
+		this.entries = make(map[string][]*cacheEntry)
+	}
+
+	key := r.URL.String()
+	list := this.entries[key][:0]
+	for _, old := range this.entries[key] {
+		if !old.matches(r) { list = append(list, old) }
+	}
+	this.entries[key] = append(list, e)
+}
+
+// returns middleware which serves GET requests from the cache when possible,
+// and stores successful responses to them.
+func (this *ResponseCache) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if r.Method != string(GET) { return next(r) }
+
+			ttl := this.TTL
+			if req, ok := RequestFromContext(r.Context()); ok && req.CacheLifetime != nil {
+				ttl = *req.CacheLifetime
+			}
+			if ttl <= 0 { return next(r) }
+
+			now := requestClock(r).Now()
+			if e := this.lookup(r, now); e != nil {
+				return e.response(r), nil
+			}
+
+			resp, err := next(r)
+			if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 || resp.StatusCode == http.StatusPartialContent {
+				return resp, err
+			}
+
+			e := cacheEntry{
+				status: resp.StatusCode,
+				statusText: resp.Status,
+				proto: resp.Proto,
+				header: resp.Header.Clone(),
+				vary: make(map[string]string),
+				expires: now.Add(ttl),
+			}
+			for _, list := range resp.Header.Values("Vary") {
+				for _, key := range strings.Split(list, ",") {
+					key = strings.TrimSpace(key)
+					if key == "*" { return resp, err }
+					if key != "" { e.vary[key] = r.Header.Get(key) }
+				}
+			}
+
+			e.body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil { return nil, err }
+
+			this.store(r, &e)
+			return e.response(r), nil
+		}
+	}
+}
+
+// removes every cached response for a URL.
+func (this *ResponseCache) Invalidate(url string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.entries, url)
+}
+
+// removes every cached response for the URL a request would fetch.
+func (this *ResponseCache) InvalidateRequest(req Request) {
+	this.Invalidate(req.URL())
+}
+
+// removes every cached response.
+func (this *ResponseCache) Clear() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries = nil
+}
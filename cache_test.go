@@ -0,0 +1,47 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"time"
+	"net/http"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func TestResponseCache(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		header := http.Header{}
+		header.Set("Vary", "Accept-Language")
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(calls)))}, nil
+	})
+
+	cache := NewResponseCache(time.Hour)
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithResponseCache(cache))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	fetch := func(req Request) string {
+		var text string
+		_, err := req.Into(textCapture{into: &text}).Do()
+		if err != nil { t.Fatalf("Do Failure: %s", err.Error()) }
+		return text
+	}
+
+	if got := fetch(reqt.New("/a")); got != "1" { t.Errorf("Cache Mismatch: got %s, expected 1", got) }
+	if got := fetch(reqt.New("/a")); got != "1" { t.Errorf("Cache Mismatch: got %s, expected cached 1", got) }
+	if got := fetch(reqt.New("/a").Header("Accept-Language", "fr")); got != "2" { t.Errorf("Vary Mismatch: got %s, expected 2", got) }
+	if got := fetch(reqt.New("/a").URLArg("x", 1)); got != "3" { t.Errorf("Cache Mismatch: got %s, expected 3", got) }
+	if got := fetch(reqt.New("/a").CacheTTL(0)); got != "4" { t.Errorf("TTL Mismatch: got %s, expected uncached 4", got) }
+	if got := fetch(reqt.New("/a").Method(POST)); got != "5" { t.Errorf("Method Mismatch: got %s, expected uncached 5", got) }
+
+	cache.InvalidateRequest(reqt.New("/a"))
+	if got := fetch(reqt.New("/a")); got != "6" { t.Errorf("Invalidate Mismatch: got %s, expected 6", got) }
+
+	if got := fetch(reqt.New("/b").CacheTTL(time.Nanosecond)); got != "7" { t.Errorf("Cache Mismatch: got %s, expected 7", got) }
+	time.Sleep(time.Millisecond)
+	if got := fetch(reqt.New("/b")); got != "8" { t.Errorf("TTL Mismatch: got %s, expected expired entry to be refetched", got) }
+}
@@ -0,0 +1,57 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+)
+
+// calls f with the outcome of the request once Do has finished, whether it
+// succeeded or not. Hooks run in the order they were added, on the goroutine
+// which called Do (or DoAsync's), before Do returns.
+func (this *RequestImpl) OnComplete(f func(*http.Response, error)) (Request) {
+	this.CompletionHooks = append(this.CompletionHooks, f)
+	return this
+}
+
+// calls f with the error once Do has finished, if it failed.
+func (this *RequestImpl) OnError(f func(error)) (Request) {
+	return this.OnComplete(func(resp *http.Response, err error) {
+		if err != nil { f(err) }
+	})
+}
+
+// queues the request in its Outbox if it failed, and runs its completion
+// hooks, returning the result Do should.
+func (this *RequestImpl) Complete(resp *http.Response, err error) (*http.Response, error) {
+	if this.DurableOutbox != nil {
+		err = this.DurableOutbox.queue(this, resp, err)
+	}
+	for _, f := range this.CompletionHooks {
+		f(resp, err)
+	}
+	return resp, err
+}
+
+// calls f with each informational (1xx) response the server sends before its
+// final one, like 100 Continue, or 103 Early Hints, whose Link headers name
+// resources the final response will need. Hooks run in the order they were
+// added, on the transport's goroutine, while the request is in flight.
+func (this *RequestImpl) On1xx(f func(code int, header textproto.MIMEHeader)) (Request) {
+	this.InformationalHooks = append(this.InformationalHooks, f)
+	return this
+}
+
+// returns ctx with a trace calling the request's 1xx hooks, if it has any.
+func (this *RequestImpl) traceInformational(ctx context.Context) (context.Context) {
+	if len(this.InformationalHooks) == 0 { return ctx }
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			for _, f := range this.InformationalHooks {
+				f(code, header)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package reqtify
+
+import (
+	"testing"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+)
+
+func TestOn1xx(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var codes []int
+	var link string
+	_, err := New(server.URL, nil, nil, nil, "test").New("/").
+		On1xx(func(code int, header textproto.MIMEHeader) {
+			codes = append(codes, code)
+			link = header.Get("Link")
+		}).
+		On1xx(func(code int, header textproto.MIMEHeader) { codes = append(codes, -code) }).
+		Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if len(codes) != 2 || codes[0] != 103 || codes[1] != -103 { t.Errorf("Code Mismatch: got %v, expected [103 -103]", codes) }
+	if link != "</style.css>; rel=preload; as=style" { t.Errorf("Header Mismatch: got %q", link) }
+}
@@ -0,0 +1,170 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+/*
+   Canonical JSON, per RFC 8785 (the JSON Canonicalization Scheme): object
+   keys are sorted, insignificant whitespace is removed, strings use the
+   minimal escaping, and numbers are formatted the way ECMAScript does. Two
+   documents with the same content always canonicalize to the same bytes,
+   which makes them suitable for signing and hashing. CanonicalJSONBody
+   sends a body whose signature and digest can be reproduced from its
+   content, IdempotencyKeyFrom derives an idempotency key from it, and
+   activitypub sends its deliveries canonicalized.
+
+   As with JSON in ECMAScript, numbers are IEEE 754 doubles, so integers
+   beyond 2^53 lose precision.
+*/
+
+// re-encodes a JSON document in canonical form.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil { return nil, err }
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("canonical json: unexpected data after top-level value")
+	}
+
+	var b bytes.Buffer
+	if err := writeCanonical(&b, v); err != nil { return nil, err }
+	return b.Bytes(), nil
+}
+
+// marshals v as canonical JSON.
+func MarshalCanonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil { return nil, err }
+	return CanonicalJSON(data)
+}
+
+// marshals v as canonical JSON and uses it as the request body, so that
+// signatures and digests over it are reproducible.
+func (this *RequestImpl) CanonicalJSONBody(v interface{}) (Request) {
+	data, err := MarshalCanonicalJSON(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	return this.Body(bytes.NewReader(data), "application/json")
+}
+
+func writeCanonical(b *bytes.Buffer, v interface{}) (error) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case string:
+		writeCanonicalString(b, v)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil { return fmt.Errorf("canonical json: %w", err) }
+		s, err := canonicalNumber(f)
+		if err != nil { return err }
+		b.WriteString(s)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i != 0 { b.WriteByte(',') }
+			if err := writeCanonical(b, e); err != nil { return err }
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// keys are ordered by their UTF-16 code units, not their bytes
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i != 0 { b.WriteByte(',') }
+			writeCanonicalString(b, k)
+			b.WriteByte(':')
+			if err := writeCanonical(b, v[k]); err != nil { return err }
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("canonical json: unexpected %T", v)
+	}
+	return nil
+}
+
+func lessUTF16(a, b string) (bool) {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] { return ua[i] < ub[i] }
+	}
+	return len(ua) < len(ub)
+}
+
+func writeCanonicalString(b *bytes.Buffer, s string) {
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':  b.WriteString(`\"`)
+		case '\\': b.WriteString(`\\`)
+		case '\b': b.WriteString(`\b`)
+		case '\f': b.WriteString(`\f`)
+		case '\n': b.WriteString(`\n`)
+		case '\r': b.WriteString(`\r`)
+		case '\t': b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// formats a number as ECMAScript's Number.prototype.toString does.
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) { return "", errors.New("canonical json: number out of range") }
+	if f == 0 { return "0", nil }
+
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+
+	// the shortest digits which round trip, and the decimal exponent
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exponent := e[:strings.IndexByte(e, 'e')], e[strings.IndexByte(e, 'e') + 1:]
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(exponent)
+	k, n := len(digits), exp + 1
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n - k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+
+	esign := "+"
+	if n - 1 < 0 { esign = "-" }
+	eabs := n - 1
+	if eabs < 0 { eabs = -eabs }
+	if k == 1 {
+		return sign + digits + "e" + esign + strconv.Itoa(eabs), nil
+	}
+	return sign + digits[:1] + "." + digits[1:] + "e" + esign + strconv.Itoa(eabs), nil
+}
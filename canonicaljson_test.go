@@ -0,0 +1,34 @@
+package reqtify
+
+import (
+	"testing"
+	"math"
+	"strings"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	// from RFC 8785 section 3.2.2
+	in := `{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'B\u0022\u005c\\\u0022\/",
+		"literals": [null, true, false]
+	}`
+	expected := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	out, err := CanonicalJSON([]byte(in))
+	if err != nil || string(out) != expected { t.Errorf("Canonical Mismatch: got %s, %v\nexpected %s", out, err, expected) }
+
+	// keys sort by UTF-16 code units, so astral characters come before U+FB33
+	out, _ = CanonicalJSON([]byte(`{"\ufb33": 1, "\ud83d\ude00": 2, "a": 3}`))
+	if string(out) != "{\"a\":3,\"\U0001F600\":2,\"\uFB33\":1}" { t.Errorf("Sort Mismatch: got %s", out) }
+
+	for f, s := range map[float64]string{1e21: "1e+21", 1e20: "100000000000000000000", -1.5e-7: "-1.5e-7", 1e-6: "0.000001", math.MaxFloat64: "1.7976931348623157e+308"} {
+		if got, _ := canonicalNumber(f); got != s { t.Errorf("Number Mismatch: got %s, expected %s", got, s) }
+	}
+
+	if _, err := CanonicalJSON([]byte(`{} {}`)); err == nil { t.Errorf("Trailing Mismatch: trailing data accepted") }
+
+	// the first build error is the one kept
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	req := reqt.New("/").Method("BAD METHOD").CanonicalJSONBody(math.NaN()).IdempotencyKeyFrom(math.Inf(1))
+	if _, err := req.Do(); err == nil || !strings.Contains(err.Error(), "invalid method") { t.Errorf("Build Error Mismatch: got %v, expected the invalid method", err) }
+}
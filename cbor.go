@@ -0,0 +1,36 @@
+package reqtify
+
+import (
+	"bytes"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const CBORContentType = "application/cbor"
+
+type CBORUnmarshaller struct {
+	output_value interface{}
+}
+
+func (this CBORUnmarshaller) Unmarshal(body []byte) error {
+	return cbor.Unmarshal(body, this.output_value)
+}
+
+func FromCBOR(output_value interface{}) ResponseUnmarshaller {
+	return CBORUnmarshaller{output_value: output_value}
+}
+
+func (this *RequestImpl) CBORInto(into interface{}) (Request) {
+	this.Response = append(this.Response, FromCBOR(into))
+	return this
+}
+
+// marshals v as CBOR and uses it as the request body.
+func (this *RequestImpl) CBORBody(v interface{}) (Request) {
+	data, err := cbor.Marshal(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	return this.Body(bytes.NewReader(data), CBORContentType)
+}
@@ -0,0 +1,36 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"net/http"
+	"io/ioutil"
+)
+
+func TestCBOR(t *testing.T) {
+	type point struct {
+		X, Y int
+		Label string `cbor:"label"`
+	}
+
+	var http_mock_client test.MockHttpClient
+	var contentType string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		contentType = req.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var out point
+	_, err := reqt.New("/echo").Method(POST).CBORBody(point{X: 1, Y: -2, Label: "origin-ish"}).CBORInto(&out).Do()
+	if err != nil { t.Fatalf("CBOR Failure: %s", err.Error()) }
+	if contentType != CBORContentType { t.Errorf("Content Type Mismatch: got %q", contentType) }
+	if out != (point{X: 1, Y: -2, Label: "origin-ish"}) { t.Errorf("CBOR Mismatch: got %+v", out) }
+
+	_, err = reqt.New("/echo").Method(POST).CBORBody(make(chan int)).Do()
+	if err == nil { t.Errorf("Build Error Mismatch: unencodable body accepted") }
+}
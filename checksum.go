@@ -0,0 +1,184 @@
+package reqtify
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// a checksum which the body of a response is expected to match.
+type Checksum struct {
+	Algorithm string
+	Expected  []byte
+	Source    string // where the expected value came from, for error messages
+}
+
+// returned while reading a response body whose digest does not match.
+type ChecksumError struct {
+	Algorithm string
+	Expected  []byte
+	Actual    []byte
+	Source    string
+}
+
+func (this *ChecksumError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch (from %s): expected %x, got %x", this.Algorithm, this.Source, this.Expected, this.Actual)
+}
+
+// returns a hash for a checksum algorithm name, accepting the spellings used
+// by Digest, Content-Digest, and common convention.
+func newChecksumHash(algo string) (hash.Hash) {
+	switch strings.ToLower(algo) {
+	case "md5":
+		return md5.New()
+	case "sha1", "sha-1", "sha":
+		return sha1.New()
+	case "sha256", "sha-256":
+		return sha256.New()
+	case "sha512", "sha-512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// decodes an expected digest given as either hex or base64.
+func decodeDigest(h hash.Hash, value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if len(value) == 2 * h.Size() {
+		if b, err := hex.DecodeString(value); err == nil { return b, nil }
+	}
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(b) != h.Size() {
+		return nil, fmt.Errorf("invalid digest %q: expected %d bytes as hex or base64", value, h.Size())
+	}
+	return b, nil
+}
+
+// finds the checksums advertised by a response's Content-MD5, Digest (RFC 3230),
+// and Content-Digest (RFC 9530) headers, ignoring algorithms we don't know.
+func headerChecksums(resp *http.Response) ([]Checksum) {
+	var sums []Checksum
+	add := func(algo, value, source string) {
+		h := newChecksumHash(algo)
+		if h == nil { return }
+		if expected, err := decodeDigest(h, value); err == nil {
+			sums = append(sums, Checksum{Algorithm: strings.ToLower(algo), Expected: expected, Source: source})
+		}
+	}
+
+	if v := resp.Header.Get("Content-MD5"); v != "" {
+		add("md5", v, "Content-MD5 header")
+	}
+	for _, list := range resp.Header.Values("Digest") {
+		for _, d := range strings.Split(list, ",") {
+			if kv := strings.SplitN(strings.TrimSpace(d), "=", 2); len(kv) == 2 {
+				add(kv[0], kv[1], "Digest header")
+			}
+		}
+	}
+	for _, list := range resp.Header.Values("Content-Digest") {
+		for _, d := range strings.Split(list, ",") {
+			if kv := strings.SplitN(strings.TrimSpace(d), "=", 2); len(kv) == 2 {
+				add(kv[0], strings.Trim(kv[1], ":"), "Content-Digest header")
+			}
+		}
+	}
+	return sums
+}
+
+// an io.ReadCloser which hashes everything read through it, and returns a
+// ChecksumError instead of io.EOF if the result doesn't match.
+type checksumReader struct {
+	io.ReadCloser
+	sums   []Checksum
+	hashes []hash.Hash
+	err    error
+}
+
+func (this *checksumReader) Read(p []byte) (int, error) {
+	if this.err != nil { return 0, this.err }
+
+	n, err := this.ReadCloser.Read(p)
+	for _, h := range this.hashes {
+		h.Write(p[:n])
+	}
+	if err == io.EOF {
+		for i, h := range this.hashes {
+			if actual := h.Sum(nil); !bytes.Equal(actual, this.sums[i].Expected) {
+				err = &ChecksumError{
+					Algorithm: this.sums[i].Algorithm,
+					Expected: this.sums[i].Expected,
+					Actual: actual,
+					Source: this.sums[i].Source,
+				}
+				break
+			}
+		}
+		this.err = err
+	}
+	return n, err
+}
+
+// wraps the response body so that it is checked against the request's
+// checksums and, if enabled, those advertised in the response headers.
+func verifyResponseChecksums(req *RequestImpl, resp *http.Response, fromHeaders bool) {
+	sums := req.Checksums
+	// digest headers describe the encoded body, which we won't see if the
+	// transport decompressed it for us. They're also meaningless without one.
+	if fromHeaders && !resp.Uncompressed && resp.StatusCode != http.StatusNotModified && resp.StatusCode != http.StatusNoContent && req.Verb != HEAD {
+		sums = append(sums[:len(sums):len(sums)], headerChecksums(resp)...)
+	}
+	if len(sums) == 0 || resp.Body == nil { return }
+
+	c := checksumReader{ReadCloser: resp.Body, sums: sums}
+	for _, s := range sums {
+		c.hashes = append(c.hashes, newChecksumHash(s.Algorithm))
+	}
+	resp.Body = &c
+}
+
+// wraps resp's body so that it's checked against the request's checksums
+// and, if its Reqtifier verifies digests, those in resp's headers. Do calls
+// this for every response; it's exported for Request implementations which
+// embed RequestImpl.
+func (this *RequestImpl) CheckChecksums(resp *http.Response) {
+	verifyResponseChecksums(this, resp, this.ReqClient != nil && this.ReqClient.VerifyDigests)
+}
+
+// verifies that the response body has the given digest, as it is read. If it
+// doesn't, reading the body fails with a *ChecksumError, so Do will fail if
+// the body is being unmarshalled. algo may be md5, sha1, sha256 or sha512, and
+// expected may be hex or base64.
+func (this *RequestImpl) VerifyChecksum(algo, expected string) (Request) {
+	h := newChecksumHash(algo)
+	if h == nil {
+		this.setBuildError(fmt.Errorf("unsupported checksum algorithm %q", algo))
+		return this
+	}
+
+	digest, err := decodeDigest(h, expected)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+
+	this.Checksums = append(this.Checksums, Checksum{Algorithm: strings.ToLower(algo), Expected: digest, Source: "VerifyChecksum"})
+	return this
+}
+
+// verifies response bodies against the digests advertised in their Content-MD5,
+// Digest, and Content-Digest headers, if any are present.
+func WithDigestVerification() Option {
+	return func(r *ReqtifierImpl) {
+		r.VerifyDigests = true
+	}
+}
@@ -0,0 +1,47 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	header := http.Header{}
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello world"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	_, err := reqt.New("/").VerifyChecksum("sha256", "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9").Into(textCapture{into: &text}).Do()
+	if err != nil { t.Errorf("Checksum Failure: %s", err.Error()) }
+
+	_, err = reqt.New("/").VerifyChecksum("md5", "XrY7u+Ae7tCTyyK7j1rNww==").Into(textCapture{into: &text}).Do()
+	if err != nil { t.Errorf("Checksum Failure: %s", err.Error()) }
+
+	_, err = reqt.New("/").VerifyChecksum("sha1", "0000000000000000000000000000000000000000").Into(textCapture{into: &text}).Do()
+	if _, ok := err.(*ChecksumError); !ok { t.Errorf("Checksum Mismatch: got %v, expected a ChecksumError", err) }
+
+	_, err = reqt.New("/").VerifyChecksum("crc9", "00").Do()
+	if err == nil { t.Errorf("Algorithm Mismatch: unknown algorithm accepted") }
+
+	// header digests are only checked when enabled
+	header.Set("Content-MD5", "AAAAAAAAAAAAAAAAAAAAAA==")
+	_, err = reqt.New("/").Into(textCapture{into: &text}).Do()
+	if err != nil { t.Errorf("Checksum Failure: %s", err.Error()) }
+
+	reqt.(*ReqtifierImpl).VerifyDigests = true
+	_, err = reqt.New("/").Into(textCapture{into: &text}).Do()
+	if _, ok := err.(*ChecksumError); !ok { t.Errorf("Checksum Mismatch: got %v, expected a ChecksumError", err) }
+
+	header.Set("Content-MD5", "XrY7u+Ae7tCTyyK7j1rNww==")
+	header.Set("Content-Digest", "sha-256=:uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=:")
+	_, err = reqt.New("/").Into(textCapture{into: &text}).Do()
+	if err != nil { t.Errorf("Checksum Failure: %s", err.Error()) }
+}
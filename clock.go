@@ -0,0 +1,81 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// a Clock tells a Reqtifier the time, and waits for it to pass. Retry
+// backoff, Retry-After deadlines, quiet hours, cache lifetimes, bandwidth
+// limits, Bulk's backoff and stall detection all follow it, so tests can
+// substitute a fake clock (see test.FakeClock) and advance it instead of
+// sleeping.
+//
+// Rate limiters are tickers handed to New and WithRateGroup, so to put them
+// on the same clock, create them with its NewTicker. Timeouts, which are
+// enforced by contexts and the network, always use the real time.
+type Clock interface {
+	Now() time.Time
+	// returns a channel which receives the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+	// returns a ticker which ticks every d.
+	NewTicker(d time.Duration) *time.Ticker
+}
+
+// the real time, as reported by the time package.
+type SystemClock struct{}
+
+func (SystemClock) Now() (time.Time) { return time.Now() }
+func (SystemClock) After(d time.Duration) (<-chan time.Time) { return time.After(d) }
+func (SystemClock) NewTicker(d time.Duration) (*time.Ticker) { return time.NewTicker(d) }
+
+// sets the clock the Reqtifier uses. The default is SystemClock.
+func WithClock(clock Clock) Option {
+	return func(r *ReqtifierImpl) {
+		r.Clock = clock
+	}
+}
+
+func (this *ReqtifierImpl) clock() (Clock) {
+	if this == nil || this.Clock == nil { return SystemClock{} }
+	return this.Clock
+}
+
+// waits for d to pass on clock, or ctx to be done.
+func clockSleep(ctx context.Context, clock Clock, d time.Duration) (error) {
+	if d <= 0 { return ctx.Err() }
+	if _, ok := clock.(SystemClock); ok { return sleepContext(ctx, d) }
+	select {
+	case <- clock.After(d):
+		return nil
+	case <- ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// calls f in its own goroutine once d has passed on clock, unless the
+// returned function is called first to stop it.
+func clockAfterFunc(clock Clock, d time.Duration, f func()) (func()) {
+	if _, ok := clock.(SystemClock); ok {
+		timer := time.AfterFunc(d, f)
+		return func() { timer.Stop() }
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <- clock.After(d):
+			f()
+		case <- stop:
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(stop) }) }
+}
+
+// the clock of the Reqtifier sending r, for middleware.
+func requestClock(r *http.Request) (Clock) {
+	if req, ok := RequestFromContext(r.Context()); ok { return req.ReqClient.clock() }
+	return SystemClock{}
+}
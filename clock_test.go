@@ -0,0 +1,110 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var calls int32
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&calls, 1)
+		header := http.Header{}
+		if req.URL.Path == "/flaky" && n == 1 {
+			header.Set("Retry-After", time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC).Format(http.TimeFormat))
+			return &http.Response{StatusCode: 503, Header: header, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(int(n))))}, nil
+	})
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewResponseCache(time.Minute)
+	reqt := New("https://this.is.a.test", clock.NewTicker(time.Second), nil, nil, "test", WithClock(clock), WithResponseCache(cache))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// the first attempt waits for a tick, then the retry for the Retry-After date, an hour away
+	done := make(chan string)
+	go func() {
+		var text string
+		reqt.New("/flaky").Retry(RetryPolicy{MaxAttempts: 2, Backoff: time.Second}).TextInto(&text).Do()
+		done <- text
+	}()
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	select {
+	case text := <- done: t.Fatalf("Retry Mismatch: got %q before the Retry-After date", text)
+	case <- time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Minute)
+	if text := <- done; text != "2" { t.Errorf("Retry Mismatch: got %q, expected 2", text) }
+
+	// the rate limiter and cache lifetimes follow the clock too
+	fetch := func() string {
+		result := make(chan string)
+		go func() {
+			var text string
+			reqt.New("/cached").TextInto(&text).Do()
+			result <- text
+		}()
+		for {
+			select {
+			case text := <- result:
+				return text
+			case <- time.After(time.Millisecond):
+				clock.Advance(time.Second)
+			}
+		}
+	}
+	if got := fetch(); got != "3" { t.Errorf("Cache Mismatch: got %s, expected 3", got) }
+	if got := fetch(); got != "3" { t.Errorf("Cache Mismatch: got %s, expected cached 3", got) }
+	clock.Advance(time.Hour)
+	if got := fetch(); got != "4" { t.Errorf("TTL Mismatch: got %s, expected expired entry to be refetched", got) }
+}
+
+func TestClockStallAndBulk(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var calls int32
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/stall" {
+			<- req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		status := 200
+		if atomic.AddInt32(&calls, 1) == 1 { status = 500 }
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithStallTimeouts(time.Minute, time.Minute))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// stalls are detected by the clock, not after a real minute
+	done := make(chan error)
+	go func() {
+		_, err := reqt.New("/stall").Do()
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if _, ok := (<- done).(*StallError); !ok { t.Errorf("Stall Mismatch: expected a StallError") }
+
+	// and so is Bulk's backoff between attempts
+	bulkClock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	summary := make(chan BulkSummary)
+	go func() {
+		summary <- Bulk(IDs("1"), func(id string) Request {
+			return reqt.New("/" + id)
+		}, BulkOptions{Attempts: 2, Backoff: time.Hour, Clock: bulkClock})
+	}()
+	bulkClock.BlockUntil(1)
+	bulkClock.Advance(time.Hour)
+	if s := <- summary; !s.OK() || s.Elapsed != time.Hour { t.Errorf("Bulk Mismatch: got %+v, expected success after exactly an hour", s) }
+}
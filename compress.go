@@ -0,0 +1,28 @@
+package reqtify
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// compresses the request body with gzip, and sets Content-Encoding
+// accordingly, for APIs which accept or require compressed uploads. The body
+// is compressed as it is sent, so the request has no Content-Length.
+func (this *RequestImpl) CompressBody() (Request) {
+	this.CompressRequest = true
+	return this
+}
+
+// returns a reader producing body compressed with gzip.
+func gzipReader(body io.Reader) (io.ReadCloser) {
+	pr, pw := io.Pipe()
+	go func() {
+		w := gzip.NewWriter(pw)
+		_, err := io.Copy(w, body)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
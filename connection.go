@@ -0,0 +1,24 @@
+package reqtify
+
+// closes the connection after every request instead of keeping it alive for
+// reuse, for servers which misbehave with persistent connections.
+func WithoutKeepAlives() Option {
+	return func(r *ReqtifierImpl) {
+		r.DisableKeepAlives = true
+	}
+}
+
+// sends this Accept-Encoding header with every request which doesn't set one
+// itself. Responses in encodings reqtify understands are still decompressed,
+// unless decompression is disabled. See WithoutDecompression.
+func WithAcceptEncoding(encoding string) Option {
+	return func(r *ReqtifierImpl) {
+		r.AcceptEncoding = encoding
+	}
+}
+
+// closes the connection after this request instead of keeping it alive.
+func (this *RequestImpl) Close() (Request) {
+	this.CloseConnection = true
+	return this
+}
@@ -0,0 +1,59 @@
+package reqtify
+
+import (
+	"testing"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+)
+
+// starts a server which echoes the Accept-Encoding header, and counts the
+// connections made to it.
+func connectionServer() (*httptest.Server, *int32) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept-Encoding")))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew { atomic.AddInt32(&conns, 1) }
+	}
+	server.Start()
+	return server, &conns
+}
+
+func TestConnectionOptions(t *testing.T) {
+	send := func(req Request) {
+		resp, err := req.Do()
+		if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// connections are kept alive by default
+	server, conns := connectionServer()
+	reqt := New(server.URL, nil, nil, nil, "test")
+	for i := 0; i < 3; i++ { send(reqt.New("/")) }
+	if n := atomic.LoadInt32(conns); n != 1 { t.Errorf("Keep-Alive Mismatch: got %d connections, expected 1", n) }
+
+	// unless a request asks to close its connection
+	send(reqt.New("/").Close())
+	send(reqt.New("/"))
+	if n := atomic.LoadInt32(conns); n != 2 { t.Errorf("Close Mismatch: got %d connections, expected 2", n) }
+	server.Close()
+
+	// or the Reqtifier turns them off
+	server, conns = connectionServer()
+	reqt = New(server.URL, nil, nil, nil, "test", WithoutKeepAlives())
+	for i := 0; i < 3; i++ { send(reqt.New("/")) }
+	if n := atomic.LoadInt32(conns); n != 3 { t.Errorf("WithoutKeepAlives Mismatch: got %d connections, expected 3", n) }
+
+	// WithAcceptEncoding sets a default which requests can override
+	reqt = New(server.URL, nil, nil, nil, "test", WithAcceptEncoding("identity"))
+	var text string
+	if _, err := reqt.New("/").TextInto(&text).Do(); err != nil || text != "identity" { t.Errorf("Accept-Encoding Mismatch: got %q, %v", text, err) }
+	if _, err := reqt.New("/").AcceptEncoding("gzip").TextInto(&text).Do(); err != nil || text != "gzip" { t.Errorf("Accept-Encoding Override Mismatch: got %q, %v", text, err) }
+	server.Close()
+}
@@ -0,0 +1,178 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// controls how CSV responses are parsed.
+type CSVOptions struct {
+	Comma            rune // the field delimiter. Defaults to ','.
+	Comment          rune // lines beginning with this are ignored, if not 0.
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+
+	// when decoding into [][]string, drops the first row.
+	SkipHeader       bool
+
+	// when decoding into structs, the first row is data rather than column
+	// names, and columns are matched to fields in the order they're declared.
+	NoHeader         bool
+}
+
+type CSVUnmarshaller struct {
+	output_value interface{}
+	options      CSVOptions
+}
+
+var ErrCSVTarget = errors.New("CSV can only be decoded into *[][]string or a pointer to a slice of structs")
+
+// decodes CSV into either a *[][]string, or a pointer to a slice of structs,
+// whose fields are matched to columns by the header row. A field's column
+// name is taken from its `csv:"name"` tag, or else its name, ignoring case.
+// Fields tagged `csv:"-"` are skipped. Fields may be strings, numbers, bools,
+// pointers to those (nil for empty cells), or implement encoding.TextUnmarshaler.
+func (this CSVUnmarshaller) Unmarshal(body []byte) error {
+	r := csv.NewReader(bytes.NewReader(body))
+	if this.options.Comma != 0 { r.Comma = this.options.Comma }
+	r.Comment = this.options.Comment
+	r.LazyQuotes = this.options.LazyQuotes
+	r.TrimLeadingSpace = this.options.TrimLeadingSpace
+	r.FieldsPerRecord = -1
+
+	if rows, ok := this.output_value.(*[][]string); ok {
+		records, err := r.ReadAll()
+		if err != nil { return err }
+		if this.options.SkipHeader && len(records) != 0 {
+			records = records[1:]
+		}
+		*rows = records
+		return nil
+	}
+
+	v := reflect.ValueOf(this.output_value)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return ErrCSVTarget
+	}
+	slice := v.Elem()
+	elem := slice.Type().Elem()
+
+	columns := csvFields(elem)
+	if !this.options.NoHeader {
+		header, err := r.Read()
+		if err == io.EOF {
+			slice.Set(reflect.MakeSlice(slice.Type(), 0, 0))
+			return nil
+		} else if err != nil {
+			return err
+		}
+		byName := make(map[string]int)
+		for _, i := range columns {
+			byName[strings.ToLower(csvName(elem.Field(i)))] = i
+		}
+		columns = make([]int, len(header))
+		for c, name := range header {
+			i, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok { i = -1 }
+			columns[c] = i
+		}
+	}
+
+	out := reflect.MakeSlice(slice.Type(), 0, 0)
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF { break }
+		if err != nil { return err }
+
+		item := reflect.New(elem).Elem()
+		for c, value := range record {
+			if c >= len(columns) || columns[c] < 0 { continue }
+			if err := setCSVField(item.Field(columns[c]), value); err != nil {
+				return fmt.Errorf("csv: record %d, column %d: %w", line, c + 1, err)
+			}
+		}
+		out = reflect.Append(out, item)
+	}
+	slice.Set(out)
+	return nil
+}
+
+func FromCSV(output_value interface{}, options CSVOptions) ResponseUnmarshaller {
+	return CSVUnmarshaller{output_value: output_value, options: options}
+}
+
+// returns the indexes of the fields of t which can hold columns.
+func csvFields(t reflect.Type) ([]int) {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("csv") == "-" { continue }
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+func csvName(f reflect.StructField) (string) {
+	if name := strings.Split(f.Tag.Get("csv"), ",")[0]; name != "" { return name }
+	return f.Name
+}
+
+func setCSVField(f reflect.Value, value string) (error) {
+	if f.Kind() == reflect.Ptr {
+		if value == "" { return nil }
+		f.Set(reflect.New(f.Type().Elem()))
+		f = f.Elem()
+	}
+	// strings are kept as they are, since spaces in CSV are significant, but
+	// spaces around numbers, booleans and times aren't.
+	if t, ok := f.Addr().Interface().(*time.Time); ok {
+		return t.UnmarshalText([]byte(strings.TrimSpace(value)))
+	}
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	if f.Kind() == reflect.String {
+		f.SetString(value)
+		return nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" { return nil }
+
+	switch f.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil { return err }
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil { return err }
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, f.Type().Bits())
+		if err != nil { return err }
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, f.Type().Bits())
+		if err != nil { return err }
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+// decodes the response as CSV with the default options. See FromCSV for
+// other options.
+func (this *RequestImpl) CSVInto(into interface{}) (Request) {
+	this.Response = append(this.Response, FromCSV(into, CSVOptions{}))
+	return this
+}
@@ -0,0 +1,85 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"errors"
+	"net/http"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+)
+
+type csvRow struct {
+	ID      int      `csv:"id"`
+	Name    string
+	Score   *float64 `csv:"score"`
+	Ignored string   `csv:"-"`
+}
+
+func TestCSVInto(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("id,name,score,extra\n1,alice,2.5,x\n2,\"bob, jr\",,y\n"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var rows [][]string
+	var structs []csvRow
+	_, err := reqt.New("/export").CSVInto(&rows).CSVInto(&structs).Do()
+	if err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if len(rows) != 3 || rows[2][1] != "bob, jr" { t.Errorf("Rows Mismatch: got %q", rows) }
+	if len(structs) != 2 || structs[0].ID != 1 || structs[0].Name != "alice" || *structs[0].Score != 2.5 || structs[1].Score != nil || structs[1].Name != "bob, jr" {
+		t.Errorf("Struct Mismatch: got %+v", structs)
+	}
+
+	err = FromCSV(&rows, CSVOptions{Comma: ';', SkipHeader: true}).Unmarshal([]byte("a;b\n1;2\n"))
+	if err != nil || !reflect.DeepEqual(rows, [][]string{{"1", "2"}}) { t.Errorf("Options Mismatch: got %q, %v", rows, err) }
+
+	err = FromCSV(&structs, CSVOptions{NoHeader: true}).Unmarshal([]byte("7,carol,1\n"))
+	if err != nil || len(structs) != 1 || structs[0].ID != 7 || structs[0].Name != "carol" { t.Errorf("Positional Mismatch: got %+v, %v", structs, err) }
+
+	if err = FromCSV(&structs, CSVOptions{}).Unmarshal([]byte("id\nseven\n")); err == nil { t.Errorf("Error Mismatch: bad integer accepted") }
+	if err = FromCSV(&struct{}{}, CSVOptions{}).Unmarshal([]byte("")); err != ErrCSVTarget { t.Errorf("Target Mismatch: got %v", err) }
+}
+
+func TestCSVSpaces(t *testing.T) {
+	type row struct {
+		ID   int       `csv:"id"`
+		Name string    `csv:"name"`
+		Ok   bool      `csv:"ok"`
+		At   time.Time `csv:"at"`
+	}
+	var rows []row
+	var raw [][]string
+	body := []byte("id,name,ok,at\n 1 , padded ,true , 2020-01-02T03:04:05Z \n")
+	if err := FromCSV(&rows, CSVOptions{}).Unmarshal(body); err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if err := FromCSV(&raw, CSVOptions{SkipHeader: true}).Unmarshal(body); err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if len(rows) != 1 || rows[0].ID != 1 || !rows[0].Ok || rows[0].At.Year() != 2020 { t.Errorf("Parse Mismatch: got %+v", rows) }
+	if rows[0].Name != " padded " || raw[0][1] != rows[0].Name { t.Errorf("String Mismatch: got %q and %q, expected \" padded \"", rows[0].Name, raw[0][1]) }
+}
+
+// a string type which normalizes itself, and rejects values it doesn't know.
+type csvStatus string
+
+func (this *csvStatus) UnmarshalText(text []byte) (error) {
+	switch s := strings.ToLower(strings.TrimSpace(string(text))); s {
+	case "open", "closed":
+		*this = csvStatus(s)
+		return nil
+	}
+	return errors.New("unknown status " + string(text))
+}
+
+func TestCSVTextUnmarshalerString(t *testing.T) {
+	var rows []struct {
+		Status csvStatus `csv:"status"`
+	}
+	if err := FromCSV(&rows, CSVOptions{}).Unmarshal([]byte("status\n OPEN \n")); err != nil || len(rows) != 1 || rows[0].Status != "open" {
+		t.Errorf("Unmarshal Mismatch: got %+v, %v", rows, err)
+	}
+	if err := FromCSV(&rows, CSVOptions{}).Unmarshal([]byte("status\npending\n")); err == nil { t.Errorf("Error Mismatch: invalid status accepted") }
+}
@@ -0,0 +1,247 @@
+package reqtify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+   Dumps write out requests and responses as they go over the wire, in the
+   style of net/http/httputil, with every header the transport will see:
+
+	>>> request 1, attempt 1
+	POST /posts HTTP/1.1
+	Host: api.example.com
+	Content-Type: application/json
+
+	{"title":"hello"}
+	<<< response 1
+	HTTP/1.1 201 Created
+	Content-Type: application/json
+
+	{"id":7}
+
+   Unlike DebugPrint, bodies are copied as they stream past, rather than
+   being read ahead of time, so dumping doesn't change what's sent. A request
+   is written once its response arrives, and its response once its body has
+   been read or closed. A server can answer before it's read the whole
+   request body, in which case the dump says it's partial. Secrets are masked
+   by the Reqtifier's Redactor.
+*/
+
+// controls what a Dumper writes.
+type DumpOptions struct {
+	Bodies      bool  // include request and response bodies.
+	MaxBodySize int64 // bodies longer than this are truncated. Zero means no limit.
+}
+
+// a Dumper writes dumps of the requests made through a Reqtifier and their
+// responses. It can be turned on and off while in use.
+type Dumper struct {
+	Options DumpOptions
+
+	lock     sync.Mutex
+	w        io.Writer
+	disabled int32
+	count    int64
+}
+
+func NewDumper(w io.Writer, options DumpOptions) (*Dumper) {
+	return &Dumper{w: w, Options: options}
+}
+
+// writes dumps of the Reqtifier's traffic to w. The Dumper is kept in the
+// Reqtifier's Dumper field, to turn dumping on and off.
+func WithDumpWriter(w io.Writer, options DumpOptions) Option {
+	return WithDumper(NewDumper(w, options))
+}
+
+// writes dumps of the Reqtifier's traffic with dumper.
+func WithDumper(dumper *Dumper) Option {
+	return func(r *ReqtifierImpl) {
+		r.Dumper = dumper
+		r.Use(dumper.Middleware())
+	}
+}
+
+// turns dumping on or off, for requests sent afterwards.
+func (this *Dumper) SetEnabled(enabled bool) {
+	var disabled int32
+	if !enabled { disabled = 1 }
+	atomic.StoreInt32(&this.disabled, disabled)
+}
+
+func (this *Dumper) Enabled() (bool) {
+	return atomic.LoadInt32(&this.disabled) == 0
+}
+
+// returns middleware which dumps each round trip.
+func (this *Dumper) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if !this.Enabled() { return next(r) }
+
+			id := atomic.AddInt64(&this.count, 1)
+			redactor := requestRedactor(r)
+			var reqBody *dumpCapture
+			if this.Options.Bodies && r.Body != nil && r.Body != http.NoBody {
+				reqBody = this.capture(redactor)
+				r.Body = &dumpBody{ReadCloser: r.Body, capture: reqBody}
+			}
+
+			// the transport adds these, so put them in the dump as it will
+			header := r.Header.Clone()
+			if header.Get("Host") == "" {
+				host := r.Host
+				if host == "" { host = r.URL.Host }
+				header.Set("Host", host)
+			}
+			if r.ContentLength > 0 && header.Get("Content-Length") == "" {
+				header.Set("Content-Length", fmt.Sprint(r.ContentLength))
+			}
+
+			resp, err := next(r)
+
+			var b bytes.Buffer
+			fmt.Fprintf(&b, ">>> request %d, attempt %d\n", id, AttemptFromContext(r.Context()))
+			fmt.Fprintf(&b, "%s %s HTTP/%d.%d\n", r.Method, redactor.URL(r.URL.RequestURI()), r.ProtoMajor, r.ProtoMinor)
+			writeDumpHeaders(&b, redactor.HTTPHeader(header))
+			if reqBody != nil { reqBody.writeTo(&b, header.Get("Content-Type")) }
+			if err != nil {
+				fmt.Fprintf(&b, "<<< response %d\nerror: %s\n", id, err.Error())
+				this.write(b.Bytes())
+				return resp, err
+			}
+
+			fmt.Fprintf(&b, "<<< response %d\n", id)
+			proto, status := resp.Proto, resp.Status
+			if proto == "" { proto = "HTTP/1.1" }
+			if status == "" { status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)) }
+			fmt.Fprintf(&b, "%s %s\n", proto, status)
+			writeDumpHeaders(&b, redactor.HTTPHeader(resp.Header))
+			if !this.Options.Bodies || resp.Body == nil {
+				this.write(b.Bytes())
+				return resp, err
+			}
+			resp.Body = &dumpBody{ReadCloser: resp.Body, capture: this.capture(redactor), done: func(c *dumpCapture) {
+				c.writeTo(&b, resp.Header.Get("Content-Type"))
+				this.write(b.Bytes())
+			}}
+			return resp, err
+		}
+	}
+}
+
+func (this *Dumper) write(dump []byte) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.w != nil { this.w.Write(dump) }
+}
+
+func (this *Dumper) capture(redactor *Redactor) (*dumpCapture) {
+	limit := this.Options.MaxBodySize
+	// secrets can only be found in a body which was captured whole
+	if len(redactor.Params) != 0 { limit = 0 }
+	return &dumpCapture{limit: limit, truncate: this.Options.MaxBodySize, redactor: redactor}
+}
+
+func writeDumpHeaders(b *bytes.Buffer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(b, "%s: %s\n", k, v)
+		}
+	}
+}
+
+// the part of a body kept for a dump. Request bodies are written by the
+// transport, which may still be doing so when the dump is, so it's locked.
+type dumpCapture struct {
+	limit    int64 // how much to keep, or zero for all of it.
+	truncate int64 // how much to write, or zero for all of it.
+	redactor *Redactor
+
+	lock     sync.Mutex
+	buffer   bytes.Buffer
+	size     int64
+	err      error
+	finished bool // the body has been read to the end, or closed.
+}
+
+func (this *dumpCapture) write(p []byte, err error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if err != nil && err != io.EOF { this.err = err }
+	this.size += int64(len(p))
+	if this.limit > 0 {
+		if room := this.limit - int64(this.buffer.Len()); room < int64(len(p)) {
+			if room < 0 { room = 0 }
+			p = p[:room]
+		}
+	}
+	this.buffer.Write(p)
+}
+
+func (this *dumpCapture) finish() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.finished = true
+}
+
+func (this *dumpCapture) writeTo(b *bytes.Buffer, contentType string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	body := this.redactor.Body(contentType, this.buffer.Bytes())
+	// a redacted body was captured whole, so its size is what's left of it
+	size := this.size
+	if len(this.redactor.Params) != 0 { size = int64(len(body)) }
+	if this.truncate > 0 && int64(len(body)) > this.truncate {
+		body = body[:this.truncate]
+	}
+	truncated := size - int64(len(body))
+	b.WriteString("\n")
+	b.Write(body)
+	if len(body) != 0 && !strings.HasSuffix(string(body), "\n") { b.WriteString("\n") }
+	if truncated > 0 { fmt.Fprintf(b, "[%d more bytes]\n", truncated) }
+	if this.err != nil { fmt.Fprintf(b, "[error reading body: %s]\n", this.err.Error()) }
+	if !this.finished && this.err == nil { b.WriteString("[partial: the body was still being sent]\n") }
+}
+
+// copies a body into a dumpCapture as it's read, calling done once when
+// it's been read or closed.
+type dumpBody struct {
+	io.ReadCloser
+	capture *dumpCapture
+	done    func(*dumpCapture)
+	once    sync.Once
+}
+
+func (this *dumpBody) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+	this.capture.write(p[:n], err)
+	if err != nil { this.finish() }
+	return n, err
+}
+
+func (this *dumpBody) Close() (error) {
+	err := this.ReadCloser.Close()
+	this.finish()
+	return err
+}
+
+func (this *dumpBody) finish() {
+	this.once.Do(func() {
+		this.capture.finish()
+		if this.done != nil { this.done(this.capture) }
+	})
+}
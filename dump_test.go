@@ -0,0 +1,85 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+func TestDumpWriter(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var sent string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			body, _ := ioutil.ReadAll(req.Body)
+			sent = string(body)
+		}
+		header := make(http.Header)
+		header.Set("Content-Type", "text/plain")
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello world"))}, nil
+	})
+
+	var b bytes.Buffer
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test",
+		WithDumpWriter(&b, DumpOptions{Bodies: true, MaxBodySize: 5}),
+		WithRedactor(&Redactor{Params: []string{"api_key"}}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	if _, err := reqt.New("/a").Method(POST).URLArg("api_key", "secret").FormArg("name", "value").BasicAuthentication("u", "p").TextInto(&text).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+
+	dump := b.String()
+	if sent != "name=value" { t.Errorf("Sent Mismatch: got %q, expected %q", sent, "name=value") }
+	if text != "hello world" { t.Errorf("Text Mismatch: got %q, expected %q", text, "hello world") }
+	if strings.Contains(dump, "secret") || strings.Contains(dump, "Basic ") { t.Errorf("Secret Mismatch: got %q", dump) }
+	for _, expected := range []string{
+		">>> request 1, attempt 1\nPOST /a?api_key=%5Bredacted%5D HTTP/1.1\n",
+		"Host: this.is.a.test\n",
+		"\nname=\n[5 more bytes]\n<<< response 1\nHTTP/1.1 200 OK\nContent-Type: text/plain\n\nhello\n[6 more bytes]\n",
+	} {
+		if !strings.Contains(dump, expected) { t.Errorf("Dump Mismatch: got %q, expected it to contain %q", dump, expected) }
+	}
+
+	b.Reset()
+	reqt.(*ReqtifierImpl).Dumper.SetEnabled(false)
+	if _, err := reqt.New("/a").Method(POST).FormArg("name", "value").TextInto(&text).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if b.Len() != 0 { t.Errorf("Disabled Mismatch: got %q", b.String()) }
+
+	reqt.(*ReqtifierImpl).Dumper.SetEnabled(true)
+	reqt.(*ReqtifierImpl).Dumper.Options = DumpOptions{}
+	if _, err := reqt.New("/a").TextInto(&text).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if !strings.Contains(b.String(), ">>> request 2, attempt 1\nGET /a HTTP/1.1\n") || strings.Contains(b.String(), "hello") { t.Errorf("Headers Only Mismatch: got %q", b.String()) }
+}
+
+func TestDumpEarlyResponse(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	sent := make(chan struct{})
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		// answer before the body's been sent, as a server rejecting it might
+		go func() {
+			io.Copy(ioutil.Discard, req.Body)
+			req.Body.Close()
+			close(sent)
+		}()
+		return &http.Response{StatusCode: 413, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	var b bytes.Buffer
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithDumpWriter(&b, DumpOptions{Bodies: true}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	body := io.MultiReader(strings.NewReader(strings.Repeat("x", 1 << 20)))
+	resp, _ := reqt.New("/upload").Method(POST).Body(body, "text/plain").Do()
+	<-sent
+	resp.Body.Close()
+
+	dump := b.String()
+	if !strings.Contains(dump, "<<< response 1\nHTTP/1.1 413") { t.Errorf("Dump Mismatch: got %q", dump) }
+	if request := dump[:strings.Index(dump, "<<<")]; strings.Count(request, "x") != 1 << 20 && !strings.Contains(request, "[partial") {
+		t.Errorf("Partial Mismatch: a partial body wasn't labeled: got %d bytes", strings.Count(request, "x"))
+	}
+}
@@ -0,0 +1,193 @@
+package reqtify
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+/*
+   net/http only decompresses responses transparently when it picked the
+   Accept-Encoding header itself, and only for gzip. reqtify additionally
+   decodes any content coding it knows about, so that choosing an
+   Accept-Encoding doesn't mean giving up decompression, unless the request
+   asks for the raw bytes with RawEncoding.
+
+   That includes brotli and zstd, which net/http doesn't understand at all.
+   Many CDNs serve brotli by default to clients which advertise it:
+
+	reqtify.WithAcceptEncoding("br, zstd, gzip")
+*/
+
+// a ContentDecoder wraps a reader producing content in some encoding with one
+// producing the decoded content.
+type ContentDecoder func(io.Reader) (io.ReadCloser, error)
+
+// the content codings reqtify can decode, by name.
+var contentDecoders = map[string]ContentDecoder{
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": decodeDeflate,
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil { return nil, err }
+		return zstdReader{d}, nil
+	},
+}
+
+// a zstd.Decoder's Close doesn't return an error, so it isn't an io.Closer.
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (this zstdReader) Close() (error) {
+	this.Decoder.Close()
+	return nil
+}
+
+// "deflate" is supposed to mean zlib wrapped deflate, but some servers send
+// raw deflate streams, so sniff for the zlib header.
+func decodeDeflate(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0] & 0x0f == 8 && (uint16(header[0]) << 8 | uint16(header[1])) % 31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// delivers response bodies exactly as the server encoded them, for every
+// request made through a Reqtifier. See RequestImpl.RawEncoding.
+func WithoutDecompression() Option {
+	return func(r *ReqtifierImpl) {
+		r.DisableDecompression = true
+	}
+}
+
+// sets the Accept-Encoding header for this request. Responses in encodings
+// reqtify understands are still decompressed, unless RawEncoding is used.
+func (this *RequestImpl) AcceptEncoding(encoding string) (Request) {
+	return this.Header("Accept-Encoding", encoding)
+}
+
+// delivers the response body exactly as the server encoded it, for example to
+// store compressed bytes without recompressing them. If no Accept-Encoding is
+// set, gzip is requested.
+func (this *RequestImpl) RawEncoding() (Request) {
+	this.NoDecompression = true
+	return this
+}
+
+// reports whether a response body was decompressed, either by net/http or by
+// reqtify, in which case the Content-Encoding and Content-Length headers
+// have been removed.
+func WasDecompressed(resp *http.Response) (bool) {
+	return resp.Uncompressed
+}
+
+// prevents net/http from decompressing a response on our behalf, when it
+// would have otherwise, by choosing the Accept-Encoding header ourselves.
+func (this *ReqtifierImpl) prepareEncoding(req *RequestImpl, r *http.Request) {
+	if this.DisableDecompression || req.NoDecompression {
+		if r.Header.Get("Accept-Encoding") == "" {
+			r.Header.Set("Accept-Encoding", "gzip")
+		}
+	}
+}
+
+// decodes the response body, if it has a content coding we understand and
+// decompression hasn't been disabled.
+func (this *ReqtifierImpl) decodeResponse(req *RequestImpl, resp *http.Response) (error) {
+	if this.DisableDecompression || req.NoDecompression || !hasContent(req, resp) { return nil }
+
+	codings, ok := contentCodings(resp.Header.Get("Content-Encoding"))
+	if !ok || len(codings) == 0 { return nil } // leave unknown codings alone, the caller may know what to do
+
+	decoded, err := decodeContent(resp.Body, codings)
+	if err != nil {
+		resp.Body.Close()
+		return err
+	}
+
+	resp.Body = decoded
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// parses a Content-Encoding header into the codings applied to the content,
+// and whether reqtify knows how to decode all of them.
+func contentCodings(header string) ([]string, bool) {
+	var codings []string
+	for _, c := range strings.Split(header, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" && c != "identity" {
+			if contentDecoders[c] == nil { return nil, false }
+			codings = append(codings, c)
+		}
+	}
+	return codings, true
+}
+
+// wraps body with readers undoing each of codings. Closing the result closes
+// body as well, but body is left open if an error is returned.
+func decodeContent(body io.ReadCloser, codings []string) (io.ReadCloser, error) {
+	// codings are listed in the order they were applied, so undo them backwards
+	var decoded io.Reader = body
+	var closers []io.Closer
+	for i := len(codings) - 1; i >= 0; i-- {
+		d, err := contentDecoders[codings[i]](decoded)
+		if err != nil {
+			for _, c := range closers { c.Close() }
+			return nil, err
+		}
+		closers = append(closers, d)
+		decoded = d
+	}
+	return &decodedBody{Reader: decoded, closers: append(closers, body)}, nil
+}
+
+// reports whether a response can carry a body at all. Responses to HEAD and
+// 1xx, 204 and 304 responses are empty, but keep the Content-Encoding (and,
+// for HEAD, the Content-Length) the full response would have had.
+func hasContent(req *RequestImpl, resp *http.Response) (bool) {
+	if req.Verb == HEAD || resp.Body == nil || resp.Body == http.NoBody {
+		return false
+	}
+	// a zero ContentLength is also what hand-built responses have when they
+	// don't say, so only trust it if the server actually sent one.
+	if resp.ContentLength == 0 && resp.Header.Get("Content-Length") != "" {
+		return false
+	}
+	code := resp.StatusCode
+	return !(code >= 100 && code < 200) && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (this *decodedBody) Close() (error) {
+	var err error
+	for _, c := range this.closers {
+		if e := c.Close(); e != nil && err == nil { err = e }
+	}
+	return err
+}
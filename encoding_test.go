@@ -0,0 +1,127 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"io/ioutil"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompression(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte("hello, world"))
+	w.Close()
+
+	var http_mock_client test.MockHttpClient
+	var accept string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		accept = req.Header.Get("Accept-Encoding")
+		return &http.Response{
+			StatusCode: 200,
+			Header: http.Header{"Content-Encoding": []string{"gzip"}},
+			Body: ioutil.NopCloser(bytes.NewReader(compressed.Bytes())),
+		}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	resp, err := reqt.New("/").AcceptEncoding("gzip, br").Do()
+	if err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "hello, world" || !WasDecompressed(resp) || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("Decode Mismatch: got %q, %v", body, resp.Header)
+	}
+	if accept != "gzip, br" { t.Errorf("Accept Mismatch: got %q", accept) }
+
+	resp, _ = reqt.New("/").RawEncoding().Do()
+	body, _ = ioutil.ReadAll(resp.Body)
+	if !bytes.Equal(body, compressed.Bytes()) || WasDecompressed(resp) { t.Errorf("Raw Mismatch: got %q", body) }
+	if accept != "gzip" { t.Errorf("Accept Mismatch: got %q, expected gzip", accept) }
+}
+
+func TestDecompressionEmptyBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Length", "42")
+	}))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test", WithAcceptEncoding("gzip"))
+
+	resp, err := reqt.New("/").Method(HEAD).Do()
+	if err != nil { t.Fatalf("HEAD Failure: %s", err.Error()) }
+	if resp.Header.Get("Content-Encoding") != "gzip" || resp.Header.Get("Content-Length") != "42" || WasDecompressed(resp) {
+		t.Errorf("HEAD Header Mismatch: got %v", resp.Header)
+	}
+
+	resp, err = reqt.New("/").Header("If-None-Match", `"v1"`).Do()
+	if err != nil { t.Fatalf("304 Failure: %s", err.Error()) }
+	if resp.StatusCode != http.StatusNotModified || WasDecompressed(resp) {
+		t.Errorf("304 Mismatch: got %d, %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestBrotliAndZstd(t *testing.T) {
+	var br, zs bytes.Buffer
+	bw := brotli.NewWriter(&br)
+	bw.Write([]byte("brotli body"))
+	bw.Close()
+	zw, _ := zstd.NewWriter(&zs)
+	zw.Write([]byte("zstd body"))
+	zw.Close()
+
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		body, encoding := br.Bytes(), "br"
+		if req.URL.Path == "/zstd" { body, encoding = zs.Bytes(), "zstd" }
+		return &http.Response{
+			StatusCode: 200,
+			Header: http.Header{"Content-Encoding": []string{encoding}},
+			Body: ioutil.NopCloser(bytes.NewReader(body)),
+		}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithAcceptEncoding("br, zstd"))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	for path, expected := range map[string]string{"/br": "brotli body", "/zstd": "zstd body"} {
+		resp, err := reqt.New(path).Do()
+		if err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != expected { t.Errorf("Decode Mismatch: got %q, expected %q", body, expected) }
+	}
+}
+
+func TestCompressBody(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var body, encoding string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		encoding = req.Header.Get("Content-Encoding")
+		r, err := gzip.NewReader(req.Body)
+		if err != nil { return nil, err }
+		b, _ := ioutil.ReadAll(r)
+		body = string(b)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	_, err := reqt.New("/ingest").Method(POST).Body(bytes.NewReader([]byte("line one\nline two\n")), "text/plain").CompressBody().Do()
+	if err != nil { t.Fatalf("Compress Failure: %s", err.Error()) }
+	if encoding != "gzip" || body != "line one\nline two\n" { t.Errorf("Compress Mismatch: got %q, %q", encoding, body) }
+}
@@ -0,0 +1,125 @@
+package reqtify
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// an ETagStore remembers the ETag and Last-Modified validators of GET
+// responses, along with their bodies. Later requests for the same URL are
+// made conditional with If-None-Match and If-Modified-Since, and if the server
+// answers 304 Not Modified, the stored response is returned in its place, so
+// callers always see a complete 200 response.
+//
+// Requests which already carry their own conditional headers are passed
+// through untouched.
+type ETagStore struct {
+	lock    sync.Mutex
+	entries map[string]*etagEntry
+}
+
+type etagEntry struct {
+	cacheEntry
+	etag         string
+	lastModified string
+}
+
+func NewETagStore() (*ETagStore) {
+	return &ETagStore{}
+}
+
+// makes GET requests through a Reqtifier conditional using an ETagStore.
+func WithETagStore(store *ETagStore) Option {
+	return WithMiddleware(store.Middleware())
+}
+
+func (this *ETagStore) get(url string) (*etagEntry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.entries[url]
+}
+
+func (this *ETagStore) set(url string, e *etagEntry) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.entries == nil {
+		this.entries = make(map[string]*etagEntry)
+	}
+	this.entries[url] = e
+}
+
+// returns middleware which adds conditional headers to GET requests and
+// serves stored bodies in response to 304 Not Modified.
+func (this *ETagStore) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if r.Method != string(GET) || r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+				return next(r)
+			}
+
+			key := r.URL.String()
+			stored := this.get(key)
+			if stored != nil {
+				if stored.etag != "" {
+					r.Header.Set("If-None-Match", stored.etag)
+				}
+				if stored.lastModified != "" {
+					r.Header.Set("If-Modified-Since", stored.lastModified)
+				}
+			}
+
+			resp, err := next(r)
+			if err != nil { return resp, err }
+
+			if resp.StatusCode == http.StatusNotModified && stored != nil {
+				resp.Body.Close()
+
+				// a 304 carries updated metadata for the stored response
+				updated := *stored
+				updated.header = stored.header.Clone()
+				for k, v := range resp.Header {
+					updated.header[k] = v
+				}
+				this.set(key, &updated)
+				return updated.response(r), nil
+			}
+
+			if resp.StatusCode != http.StatusOK { return resp, err }
+
+			e := etagEntry{
+				etag: resp.Header.Get("ETag"),
+				lastModified: resp.Header.Get("Last-Modified"),
+			}
+			if e.etag == "" && e.lastModified == "" {
+				this.Forget(key)
+				return resp, err
+			}
+
+			e.status = resp.StatusCode
+			e.statusText = resp.Status
+			e.proto = resp.Proto
+			e.header = resp.Header.Clone()
+			e.body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil { return nil, err }
+
+			this.set(key, &e)
+			return e.response(r), nil
+		}
+	}
+}
+
+// forgets the validators and body stored for a URL.
+func (this *ETagStore) Forget(url string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.entries, url)
+}
+
+// forgets everything.
+func (this *ETagStore) Clear() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries = nil
+}
@@ -0,0 +1,41 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func TestETagStore(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			return &http.Response{StatusCode: 304, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		header := http.Header{}
+		header.Set("ETag", `"v1"`)
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader("body" + strconv.Itoa(calls)))}, nil
+	})
+
+	store := NewETagStore()
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithETagStore(store))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	for i := 0; i < 2; i++ {
+		var text string
+		resp, err := reqt.New("/a").Into(textCapture{into: &text}).Do()
+		if err != nil { t.Fatalf("Do Failure: %s", err.Error()) }
+		if resp.StatusCode != 200 || text != "body1" { t.Errorf("ETag Mismatch: got %d %s, expected 200 body1", resp.StatusCode, text) }
+	}
+	if calls != 2 { t.Errorf("Call Mismatch: got %d, expected 2", calls) }
+
+	store.Forget("https://this.is.a.test/a")
+	var text string
+	reqt.New("/a").Into(textCapture{into: &text}).Do()
+	if text != "body3" { t.Errorf("Forget Mismatch: got %s, expected body3", text) }
+}
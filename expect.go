@@ -0,0 +1,83 @@
+package reqtify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// how much of an unexpected response's body is kept for debugging.
+const statusExcerptLength = 512
+
+// an inclusive range of status codes.
+type StatusRange struct {
+	Low, High int
+}
+
+func (this StatusRange) String() (string) {
+	if this.Low == this.High { return strconv.Itoa(this.Low) }
+	return strconv.Itoa(this.Low) + "-" + strconv.Itoa(this.High)
+}
+
+// returned by Do when the response status isn't one the request expected.
+// See ExpectStatus.
+type UnexpectedStatusError struct {
+	StatusCode int
+	StatusText string
+	Expected   []StatusRange
+	Excerpt    []byte // the beginning of the response body.
+}
+
+func (this *UnexpectedStatusError) Error() (string) {
+	expected := make([]string, len(this.Expected))
+	for i, r := range this.Expected {
+		expected[i] = r.String()
+	}
+	msg := fmt.Sprintf("unexpected status %s (expected %s)", this.StatusText, strings.Join(expected, ", "))
+	if len(this.Excerpt) != 0 { msg += ": " + strconv.Quote(string(this.Excerpt)) }
+	return msg
+}
+
+// lets errors.As find a *ResponseError, like the one Bulk reports.
+func (this *UnexpectedStatusError) Unwrap() (error) {
+	return &ResponseError{StatusCode: this.StatusCode, StatusText: this.StatusText}
+}
+
+// makes Do return an *UnexpectedStatusError if the response status isn't one
+// of codes. In that case the response body is neither downloaded nor
+// unmarshalled, though it can still be read from the returned response.
+func (this *RequestImpl) ExpectStatus(codes ...int) (Request) {
+	for _, c := range codes {
+		this.ExpectedStatus = append(this.ExpectedStatus, StatusRange{Low: c, High: c})
+	}
+	return this
+}
+
+// like ExpectStatus, but for every status from min to max, inclusive.
+func (this *RequestImpl) ExpectStatusRange(min, max int) (Request) {
+	this.ExpectedStatus = append(this.ExpectedStatus, StatusRange{Low: min, High: max})
+	return this
+}
+
+// returns an *UnexpectedStatusError if the response's status isn't expected.
+func (this *RequestImpl) CheckStatus(resp *http.Response) (error) {
+	if len(this.ExpectedStatus) == 0 { return nil }
+	for _, r := range this.ExpectedStatus {
+		if resp.StatusCode >= r.Low && resp.StatusCode <= r.High { return nil }
+	}
+
+	err := &UnexpectedStatusError{StatusCode: resp.StatusCode, StatusText: resp.Status, Expected: this.ExpectedStatus}
+	if err.StatusText == "" { err.StatusText = strconv.Itoa(resp.StatusCode) }
+	if resp.Body != nil {
+		// put back what we read, so the caller can still see the whole body
+		err.Excerpt, _ = io.ReadAll(io.LimitReader(resp.Body, statusExcerptLength))
+		resp.Body = &struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(err.Excerpt), resp.Body), resp.Body}
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+   Extraction decodes parts of a JSON response into separate targets, by path:
+
+	var items []Item
+	var cursor string
+	api.New("/items").ExtractInto("data.items", &items).ExtractInto("meta.cursor", &cursor).Do()
+
+   A path is a list of object keys and array indexes separated by dots, like
+   "data.items.0.id". The empty path is the whole document. Each object or
+   array along the way is only parsed once, however many targets are
+   extracted from it. Targets whose paths don't exist in the response are
+   left alone, like struct fields missing from a JSON object.
+*/
+
+type extractTarget struct {
+	path string
+	into interface{}
+}
+
+// a ResponseUnmarshaller which decodes parts of a JSON document into
+// several targets.
+type JSONExtractor struct {
+	targets []extractTarget
+	Codec   JSONCodec // decodes each target. Defaults to DefaultJSONCodec.
+}
+
+func NewJSONExtractor() (*JSONExtractor) {
+	return &JSONExtractor{}
+}
+
+// decodes the value at path into into.
+func (this *JSONExtractor) Add(path string, into interface{}) (*JSONExtractor) {
+	this.targets = append(this.targets, extractTarget{path: path, into: into})
+	return this
+}
+
+func (this *JSONExtractor) Unmarshal(body []byte) error {
+	values := jsonValues{"": body}
+	for _, target := range this.targets {
+		raw, err := values.lookup(target.path)
+		if err != nil { return err }
+		if raw == nil { continue }
+		codec := this.Codec
+		if codec == nil { codec = DefaultJSONCodec }
+		if err := codec.Unmarshal(raw, target.into); err != nil {
+			return fmt.Errorf("extracting %q: %w", target.path, err)
+		}
+	}
+	return nil
+}
+
+// decodes the value at path in a JSON document into into. It reports
+// whether the path was found.
+func ExtractJSON(body []byte, path string, into interface{}) (bool, error) {
+	raw, err := jsonValues{"": body}.lookup(path)
+	if err != nil || raw == nil { return false, err }
+	return true, json.Unmarshal(raw, into)
+}
+
+// the values in a JSON document found so far, by path.
+type jsonValues map[string]json.RawMessage
+
+// returns the value at path, or nil if there isn't one.
+func (this jsonValues) lookup(path string) (json.RawMessage, error) {
+	if raw, ok := this[path]; ok { return raw, nil }
+
+	parent := ""
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		parent = path[:i]
+	}
+	raw, err := this.lookup(parent)
+	if err != nil || raw == nil { return nil, err }
+
+	// parse the parent once, and remember all of its children
+	prefix := parent + "."
+	if parent == "" { prefix = "" }
+	switch trimmed := bytes.TrimLeft(raw, " \t\r\n"); {
+	case len(trimmed) != 0 && trimmed[0] == '{':
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil { return nil, err }
+		for k, v := range object {
+			this[prefix + k] = v
+		}
+	case len(trimmed) != 0 && trimmed[0] == '[':
+		var array []json.RawMessage
+		if err := json.Unmarshal(raw, &array); err != nil { return nil, err }
+		for i, v := range array {
+			this[prefix + strconv.Itoa(i)] = v
+		}
+	}
+
+	// so missing children aren't looked for again
+	if _, ok := this[path]; !ok {
+		this[path] = nil
+	}
+	return this[path], nil
+}
+
+// decodes the value at path in the JSON response into into. See JSONExtractor.
+func (this *RequestImpl) ExtractInto(path string, into interface{}) (Request) {
+	for _, u := range this.Response {
+		if extractor, ok := u.(*JSONExtractor); ok {
+			extractor.Add(path, into)
+			return this
+		}
+	}
+	extractor := NewJSONExtractor().Add(path, into)
+	extractor.Codec = this.jsonCodec()
+	this.Response = append(this.Response, extractor)
+	return this
+}
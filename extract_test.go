@@ -0,0 +1,40 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestExtractInto(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		body := `{"data": {"items": [{"id": 1}, {"id": 2}]}, "meta": {"cursor": "abc", "total": 2}}`
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var items []struct{ ID int `json:"id"` }
+	var cursor string
+	var second, missing int
+	missing = -1
+	_, err := reqt.New("/items").
+		ExtractInto("data.items", &items).
+		ExtractInto("meta.cursor", &cursor).
+		ExtractInto("data.items.1.id", &second).
+		ExtractInto("meta.next.page", &missing).Do()
+	if err != nil { t.Fatalf("Extract Failure: %s", err.Error()) }
+	if len(items) != 2 || items[1].ID != 2 || cursor != "abc" || second != 2 || missing != -1 {
+		t.Errorf("Extract Mismatch: got %v, %q, %d, %d", items, cursor, second, missing)
+	}
+
+	_, err = reqt.New("/items").ExtractInto("meta.cursor", &second).Do()
+	if err == nil { t.Errorf("Type Mismatch: string extracted into int") }
+
+	found, err := ExtractJSON([]byte(`[{"a": [true]}]`), "0.a.0", new(bool))
+	if !found || err != nil { t.Errorf("ExtractJSON Mismatch: got %v, %v", found, err) }
+}
@@ -0,0 +1,383 @@
+// Package feed fetches RSS, Atom and JSON Feed documents through reqtify and
+// decodes them into a single Feed type, and polls them politely: with
+// conditional GETs, honoring the feed's own refresh hints, and backing off
+// from servers which are failing or asking us to slow down.
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+// the formats a Feed can be decoded from.
+const (
+	RSS      = "rss"
+	Atom     = "atom"
+	JSONFeed = "json"
+)
+
+// the Accept header sent when fetching feeds.
+const Accept = "application/feed+json, application/atom+xml, application/rss+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8"
+
+var ErrUnknownFormat error = errors.New("feed: document is not RSS, Atom or JSON Feed")
+
+// a feed, in whichever format it was published.
+type Feed struct {
+	Format      string
+	Title       string
+	Link        string // the site the feed belongs to.
+	Description string
+	Updated     time.Time
+
+	// how long the publisher asks clients to wait between polls, from RSS's
+	// <ttl>, or zero.
+	TTL         time.Duration
+	Items       []Item
+}
+
+type Item struct {
+	ID          string // the guid or id, or the link if there is none.
+	Title       string
+	Link        string
+	Summary     string
+	Content     string
+	Author      string
+	Published   time.Time
+	Updated     time.Time
+	Enclosures  []Enclosure
+}
+
+// a file attached to an item, such as a podcast episode.
+type Enclosure struct {
+	URL         string
+	Type        string
+	Length      int64
+}
+
+// decodes a response body as a feed. Use it with Request.Into.
+type FeedUnmarshaller struct {
+	output_value *Feed
+}
+
+func Into(f *Feed) (reqtify.ResponseUnmarshaller) {
+	return FeedUnmarshaller{output_value: f}
+}
+
+func (this FeedUnmarshaller) Unmarshal(body []byte) (error) {
+	f, err := Parse(body)
+	if err != nil { return err }
+	*this.output_value = *f
+	return nil
+}
+
+// decodes an RSS (0.9x, 1.0 or 2.0), Atom or JSON Feed document.
+func Parse(body []byte) (*Feed, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) != 0 && trimmed[0] == '{' {
+		return parseJSON(trimmed)
+	}
+
+	var doc xmlDocument
+	err := reqtify.FromXMLWith(&doc, reqtify.XMLOptions{Lenient: true, IgnoreNamespaces: true}).Unmarshal(body)
+	if err != nil { return nil, err }
+
+	switch doc.XMLName.Local {
+	case "rss":
+		return doc.Channel.feed(doc.Channel.Items), nil
+	case "RDF":
+		// RSS 1.0 puts the items alongside the channel rather than in it
+		return doc.Channel.feed(doc.Items), nil
+	case "feed":
+		return doc.atom(), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// the union of the root elements of RSS and Atom documents, after namespaces
+// have been stripped.
+type xmlDocument struct {
+	XMLName   xml.Name
+	Channel   rssChannel  `xml:"channel"`
+	Items     []rssItem   `xml:"item"`
+
+	Title     xmlText     `xml:"title"`
+	Subtitle  xmlText     `xml:"subtitle"`
+	Links     []xmlLink   `xml:"link"`
+	Updated   string      `xml:"updated"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Links         []xmlLink `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	PubDate       string    `xml:"pubDate"`
+	Date          string    `xml:"date"`
+	TTL           string    `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	About       string    `xml:"about,attr"`
+	GUID        string    `xml:"guid"`
+	Title       string    `xml:"title"`
+	Links       []xmlLink `xml:"link"`
+	Description string    `xml:"description"`
+	Encoded     string    `xml:"encoded"`
+	Author      string    `xml:"author"`
+	Creator     string    `xml:"creator"`
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"date"`
+	Enclosures  []struct {
+		URL     string `xml:"url,attr"`
+		Type    string `xml:"type,attr"`
+		Length  string `xml:"length,attr"`
+	} `xml:"enclosure"`
+}
+
+type atomEntry struct {
+	ID          string    `xml:"id"`
+	Title       xmlText   `xml:"title"`
+	Links       []xmlLink `xml:"link"`
+	Summary     xmlText   `xml:"summary"`
+	Content     xmlText   `xml:"content"`
+	Authors     []struct {
+		Name    string `xml:"name"`
+	} `xml:"author"`
+	Published   string    `xml:"published"`
+	Issued      string    `xml:"issued"`
+	Updated     string    `xml:"updated"`
+}
+
+// an RSS <link>, whose text is the link, or an Atom <link>, whose href is.
+// Once namespaces are stripped, RSS feeds often contain both.
+type xmlLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+	Text   string `xml:",chardata"`
+}
+
+// picks the link to the page itself from a list of links.
+func alternate(links []xmlLink) (string) {
+	for _, l := range links {
+		if text := strings.TrimSpace(l.Text); text != "" { return text }
+	}
+	for _, l := range links {
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") { return l.Href }
+	}
+	return ""
+}
+
+// an Atom text construct. XHTML content is kept as markup rather than
+// flattened into its text.
+type xmlText struct {
+	Type   string
+	Text   string
+}
+
+func (this *xmlText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (error) {
+	for _, a := range start.Attr {
+		if a.Name.Local == "type" { this.Type = a.Value }
+	}
+
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	xhtml := this.Type == "xhtml"
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err != nil { return err }
+		switch tok := t.(type) {
+		case xml.StartElement:
+			depth++
+			// the outer <div> of xhtml content is a wrapper, not content
+			if xhtml && depth > 1 { e.EncodeToken(tok) }
+		case xml.EndElement:
+			if depth == 0 {
+				e.Flush()
+				this.Text = strings.TrimSpace(buf.String())
+				return nil
+			}
+			if xhtml && depth > 1 { e.EncodeToken(tok) }
+			depth--
+		case xml.CharData:
+			if xhtml {
+				e.EncodeToken(tok)
+			} else {
+				buf.Write(tok)
+			}
+		}
+	}
+}
+
+func (this *rssChannel) feed(items []rssItem) (*Feed) {
+	f := Feed{
+		Format: RSS,
+		Title: strings.TrimSpace(this.Title),
+		Link: alternate(this.Links),
+		Description: strings.TrimSpace(this.Description),
+		Updated: parseTime(this.LastBuildDate, this.PubDate, this.Date),
+	}
+	if minutes, err := strconv.Atoi(strings.TrimSpace(this.TTL)); err == nil && minutes > 0 {
+		f.TTL = time.Duration(minutes) * time.Minute
+	}
+
+	for _, i := range items {
+		item := Item{
+			ID: strings.TrimSpace(i.GUID),
+			Title: strings.TrimSpace(i.Title),
+			Link: alternate(i.Links),
+			Summary: strings.TrimSpace(i.Description),
+			Content: strings.TrimSpace(i.Encoded),
+			Author: strings.TrimSpace(i.Creator),
+			Published: parseTime(i.PubDate, i.Date),
+		}
+		if item.Author == "" { item.Author = strings.TrimSpace(i.Author) }
+		if item.ID == "" { item.ID = i.About }
+		if item.ID == "" { item.ID = item.Link }
+		for _, e := range i.Enclosures {
+			length, _ := strconv.ParseInt(e.Length, 10, 64)
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: e.URL, Type: e.Type, Length: length})
+		}
+		f.Items = append(f.Items, item)
+	}
+	return &f
+}
+
+func (this *xmlDocument) atom() (*Feed) {
+	f := Feed{
+		Format: Atom,
+		Title: this.Title.Text,
+		Link: alternate(this.Links),
+		Description: this.Subtitle.Text,
+		Updated: parseTime(this.Updated),
+	}
+
+	for _, e := range this.Entries {
+		item := Item{
+			ID: strings.TrimSpace(e.ID),
+			Title: e.Title.Text,
+			Link: alternate(e.Links),
+			Summary: e.Summary.Text,
+			Content: e.Content.Text,
+			Published: parseTime(e.Published, e.Issued),
+			Updated: parseTime(e.Updated),
+		}
+		if len(e.Authors) != 0 { item.Author = strings.TrimSpace(e.Authors[0].Name) }
+		if item.ID == "" { item.ID = item.Link }
+		if item.Published.IsZero() { item.Published = item.Updated }
+		for _, l := range e.Links {
+			if l.Rel != "enclosure" { continue }
+			length, _ := strconv.ParseInt(l.Length, 10, 64)
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: l.Href, Type: l.Type, Length: length})
+		}
+		f.Items = append(f.Items, item)
+	}
+	return &f
+}
+
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Description string `json:"description"`
+	Items       []struct {
+		ID            json.RawMessage `json:"id"`
+		URL           string          `json:"url"`
+		Title         string          `json:"title"`
+		ContentHTML   string          `json:"content_html"`
+		ContentText   string          `json:"content_text"`
+		Summary       string          `json:"summary"`
+		DatePublished string          `json:"date_published"`
+		DateModified  string          `json:"date_modified"`
+		Author        *jsonAuthor     `json:"author"`
+		Authors       []jsonAuthor    `json:"authors"`
+		Attachments   []struct {
+			URL         string `json:"url"`
+			MimeType    string `json:"mime_type"`
+			SizeInBytes int64  `json:"size_in_bytes"`
+		} `json:"attachments"`
+	} `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+func parseJSON(body []byte) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(body, &doc); err != nil { return nil, err }
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") { return nil, ErrUnknownFormat }
+
+	f := Feed{
+		Format: JSONFeed,
+		Title: doc.Title,
+		Link: doc.HomePageURL,
+		Description: doc.Description,
+	}
+	for _, i := range doc.Items {
+		item := Item{
+			Title: i.Title,
+			Link: i.URL,
+			Summary: i.Summary,
+			Content: i.ContentHTML,
+			Published: parseTime(i.DatePublished),
+			Updated: parseTime(i.DateModified),
+		}
+
+		// ids should be strings, but version 1 feeds sometimes use numbers
+		var id string
+		if json.Unmarshal(i.ID, &id) != nil { id = string(i.ID) }
+		item.ID = id
+		if item.ID == "" { item.ID = item.Link }
+
+		if item.Content == "" { item.Content = i.ContentText }
+		if len(i.Authors) != 0 {
+			item.Author = i.Authors[0].Name
+		} else if i.Author != nil {
+			item.Author = i.Author.Name
+		}
+		for _, a := range i.Attachments {
+			item.Enclosures = append(item.Enclosures, Enclosure{URL: a.URL, Type: a.MimeType, Length: a.SizeInBytes})
+		}
+		f.Items = append(f.Items, item)
+	}
+	return &f, nil
+}
+
+// the date formats found in feeds in the wild, most common first.
+var timeFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, _2 Jan 2006 15:04:05 -0700",
+	"Mon, _2 Jan 2006 15:04:05 MST",
+	"_2 Jan 2006 15:04:05 -0700",
+	"_2 Jan 2006 15:04:05 MST",
+	"Mon, _2 Jan 06 15:04:05 -0700",
+	"Mon, _2 Jan 2006 15:04 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parses the first of values which is a recognizable date.
+func parseTime(values ...string) (time.Time) {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" { continue }
+		for _, format := range timeFormats {
+			if t, err := time.Parse(format, v); err == nil { return t }
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,182 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Example&nbsp;News</title>
+	<link>https://example.com/</link>
+	<atom:link href="https://example.com/feed.xml" rel="self"/>
+	<description>All the news</description>
+	<ttl>60</ttl>
+	<item>
+		<title>Second</title>
+		<link>https://example.com/2</link>
+		<guid isPermaLink="false">post-2</guid>
+		<dc:creator>alice</dc:creator>
+		<pubDate>Tue, 02 Jan 2024 10:00:00 +0000</pubDate>
+		<content:encoded><![CDATA[<p>two</p>]]></content:encoded>
+		<enclosure url="https://example.com/2.mp3" type="audio/mpeg" length="1234"/>
+	</item>
+	<item>
+		<title>First</title>
+		<link>https://example.com/1</link>
+		<pubDate>Mon, 1 Jan 2024 10:00:00 GMT</pubDate>
+	</item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Example Blog</title>
+	<link href="https://example.com/blog/feed" rel="self"/>
+	<link href="https://example.com/blog/"/>
+	<updated>2024-01-02T10:00:00Z</updated>
+	<entry>
+		<id>tag:example.com,2024:1</id>
+		<title type="html">A &amp;lt;b&amp;gt;post&amp;lt;/b&amp;gt;</title>
+		<link href="https://example.com/blog/1"/>
+		<updated>2024-01-02T10:00:00Z</updated>
+		<author><name>bob</name></author>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Hello <b>world</b></p></div></content>
+	</entry>
+</feed>`
+
+const jsonFeedDoc = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example JSON",
+	"home_page_url": "https://example.com/",
+	"items": [
+		{"id": "1", "url": "https://example.com/j1", "content_text": "hi", "date_published": "2024-01-03T10:00:00Z", "authors": [{"name": "carol"}]},
+		{"id": 2, "url": "https://example.com/j2", "content_html": "<p>hey</p>", "attachments": [{"url": "https://example.com/j2.png", "mime_type": "image/png", "size_in_bytes": 99}]}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(rssFeed))
+	if err != nil { t.Fatalf("RSS Failure: %s", err.Error()) }
+	if f.Format != RSS || f.Title != "Example News" || f.Link != "https://example.com/" || f.TTL != time.Hour || len(f.Items) != 2 {
+		t.Errorf("RSS Mismatch: got %+v", f)
+	}
+	if i := f.Items[0]; i.ID != "post-2" || i.Author != "alice" || i.Content != "<p>two</p>" || i.Published.Day() != 2 || len(i.Enclosures) != 1 || i.Enclosures[0].Length != 1234 {
+		t.Errorf("RSS Item Mismatch: got %+v", i)
+	}
+	if i := f.Items[1]; i.ID != "https://example.com/1" || i.Published.IsZero() {
+		t.Errorf("RSS Item Mismatch: got %+v", i)
+	}
+
+	f, err = Parse([]byte(atomFeed))
+	if err != nil { t.Fatalf("Atom Failure: %s", err.Error()) }
+	if f.Format != Atom || f.Title != "Example Blog" || f.Link != "https://example.com/blog/" || len(f.Items) != 1 {
+		t.Errorf("Atom Mismatch: got %+v", f)
+	}
+	if i := f.Items[0]; i.Title != "A &lt;b&gt;post&lt;/b&gt;" || i.Author != "bob" || i.Content != "<p>Hello <b>world</b></p>" || i.Published.IsZero() {
+		t.Errorf("Atom Item Mismatch: got %+v", i)
+	}
+
+	f, err = Parse([]byte(jsonFeedDoc))
+	if err != nil { t.Fatalf("JSON Failure: %s", err.Error()) }
+	if f.Format != JSONFeed || f.Title != "Example JSON" || len(f.Items) != 2 {
+		t.Errorf("JSON Mismatch: got %+v", f)
+	}
+	if i := f.Items[0]; i.ID != "1" || i.Content != "hi" || i.Author != "carol" || i.Published.Day() != 3 {
+		t.Errorf("JSON Item Mismatch: got %+v", i)
+	}
+	if i := f.Items[1]; i.ID != "2" || i.Content != "<p>hey</p>" || len(i.Enclosures) != 1 || i.Enclosures[0].Type != "image/png" {
+		t.Errorf("JSON Item Mismatch: got %+v", i)
+	}
+
+	if _, err = Parse([]byte(`<html><body/></html>`)); err != ErrUnknownFormat { t.Errorf("Format Mismatch: got %v", err) }
+	if _, err = Parse([]byte(`{"title": "not a feed"}`)); err != ErrUnknownFormat { t.Errorf("Format Mismatch: got %v", err) }
+}
+
+func TestSubscription(t *testing.T) {
+	var lock sync.Mutex
+	body := atomFeed
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "7200")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		etag := `"` + time.Duration(len(body)).String() + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	r := reqtify.New("", nil, nil, nil, "test")
+	ctx := context.Background()
+	sub := Subscription{URL: server.URL + "/feed", Interval: time.Minute}
+
+	f, items, err := sub.Poll(ctx, r)
+	if err != nil || f == nil || len(items) != 0 || sub.ETag == "" { t.Fatalf("Prime Mismatch: got %+v, %+v, %v", f, items, err) }
+	if wait := time.Until(sub.Next); wait < 59 * time.Second || wait > time.Minute { t.Errorf("Schedule Mismatch: next poll in %s", wait) }
+
+	f, items, err = sub.Poll(ctx, r)
+	if err != nil || f != nil || items != nil || conditional != 1 { t.Errorf("Conditional Mismatch: got %+v, %+v, %v after %d", f, items, err, conditional) }
+
+	lock.Lock()
+	body = `<feed><entry><id>new</id></entry><entry><id>tag:example.com,2024:1</id></entry></feed>`
+	lock.Unlock()
+	f, items, err = sub.Poll(ctx, r)
+	if err != nil || f == nil || len(items) != 1 || items[0].ID != "new" { t.Errorf("New Item Mismatch: got %+v, %v", items, err) }
+
+	busy := Subscription{URL: server.URL + "/busy", Interval: time.Minute}
+	_, _, err = busy.Poll(ctx, r)
+	if err == nil || busy.Failures != 1 { t.Errorf("Failure Mismatch: got %v, %d failures", err, busy.Failures) }
+	if wait := time.Until(busy.Next); wait < time.Hour + 59 * time.Minute { t.Errorf("Retry-After Mismatch: next poll in %s", wait) }
+
+	backfill := Subscription{URL: server.URL + "/feed", Backfill: true}
+	_, items, _ = backfill.Poll(ctx, r)
+	if len(items) != 2 { t.Errorf("Backfill Mismatch: got %+v", items) }
+}
+
+func TestPoller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss><channel><item><guid>` + time.Now().Format(time.RFC3339Nano) + `</guid></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	found := make(chan Item, 10)
+	p := NewPoller(reqtify.New("", nil, nil, nil, "test"), func(sub *Subscription, f *Feed, items []Item) {
+		for _, i := range items { found <- i }
+	})
+	p.Errors = func(sub *Subscription, err error) {
+		if sub.URL != "http://0.0.0.0:1/" { t.Errorf("Poll Failure: %s", err.Error()) }
+	}
+	p.Add(&Subscription{URL: "http://0.0.0.0:1/", Interval: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	// added while running, and polled again once its interval passes
+	p.Add(&Subscription{URL: server.URL, Interval: 20 * time.Millisecond})
+	select {
+	case <- found:
+	case <- time.After(5 * time.Second):
+		t.Errorf("Poll Mismatch: no new items reported")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled { t.Errorf("Run Mismatch: got %v", err) }
+}
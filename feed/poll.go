@@ -0,0 +1,240 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+// the defaults for Subscription's polling intervals.
+const (
+	DefaultInterval = 15 * time.Minute
+	DefaultMaxInterval = 24 * time.Hour
+)
+
+// a Subscription tracks the state needed to poll one feed politely: its
+// validators, for conditional GETs, the items already seen, so that only new
+// ones are reported, and when it should next be polled.
+type Subscription struct {
+	URL          string
+
+	// the time between polls. The feed's TTL and the server's Retry-After
+	// header lengthen it, but never shorten it. Defaults to DefaultInterval.
+	Interval     time.Duration
+
+	// the longest time between polls when backing off after failures.
+	// Defaults to DefaultMaxInterval.
+	MaxInterval  time.Duration
+
+	// report the items already in the feed the first time it's polled,
+	// rather than only the ones which appear afterwards.
+	Backfill     bool
+
+	ETag         string
+	LastModified string
+	Next         time.Time // when the feed should next be polled.
+	Failures     int       // the number of consecutive failed polls.
+
+	ttl          time.Duration
+	seen         map[string]bool
+	primed       bool
+}
+
+func (this *Subscription) interval() (time.Duration) {
+	if this.Interval <= 0 { return DefaultInterval }
+	return this.Interval
+}
+
+func (this *Subscription) maxInterval() (time.Duration) {
+	if this.MaxInterval <= 0 { return DefaultMaxInterval }
+	return this.MaxInterval
+}
+
+// fetches the feed, if it has changed since the last poll, and returns it
+// along with the items which weren't in it before. If it hasn't changed, the
+// feed is nil. Next is updated either way.
+func (this *Subscription) Poll(ctx context.Context, r reqtify.Reqtifier) (*Feed, []Item, error) {
+	req := r.New(this.URL).Method(reqtify.GET).Header("Accept", Accept).Context(ctx)
+	if this.ETag != "" { req.Header("If-None-Match", this.ETag) }
+	if this.LastModified != "" { req.Header("If-Modified-Since", this.LastModified) }
+
+	var f Feed
+	resp, err := req.IntoForStatus("2xx", Into(&f)).Do()
+	if resp != nil { resp.Body.Close() }
+	if err == nil && resp.StatusCode != http.StatusNotModified && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		err = &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+	if err != nil {
+		this.Failures++
+		this.schedule(resp)
+		return nil, nil, fmt.Errorf("feed: polling %s: %w", this.URL, err)
+	}
+
+	this.Failures = 0
+	if resp.StatusCode == http.StatusNotModified {
+		this.schedule(resp)
+		return nil, nil, nil
+	}
+
+	this.ETag = resp.Header.Get("ETag")
+	this.LastModified = resp.Header.Get("Last-Modified")
+	this.ttl = f.TTL
+	this.schedule(resp)
+	return &f, this.unseen(&f), nil
+}
+
+// returns the items in a feed which haven't been seen before, and forgets
+// the ones which are no longer in it.
+func (this *Subscription) unseen(f *Feed) ([]Item) {
+	var items []Item
+	seen := make(map[string]bool, len(f.Items))
+	for _, item := range f.Items {
+		seen[item.ID] = true
+		if !this.seen[item.ID] && (this.primed || this.Backfill) {
+			items = append(items, item)
+		}
+	}
+	this.seen = seen
+	this.primed = true
+	return items
+}
+
+// decides when to poll next, from the configured interval, the feed's own
+// TTL, the server's Retry-After, and the number of recent failures.
+func (this *Subscription) schedule(resp *http.Response) {
+	wait := this.interval()
+	if this.ttl > wait { wait = this.ttl }
+
+	if this.Failures > 0 {
+		for i := 1; i < this.Failures && wait < this.maxInterval(); i++ {
+			wait *= 2
+		}
+		if wait > this.maxInterval() { wait = this.maxInterval() }
+	}
+
+	// a server which explicitly asks us to wait gets its way, even past MaxInterval
+	if resp != nil {
+		if after := retryAfter(resp.Header.Get("Retry-After")); after > wait { wait = after }
+	}
+
+	this.Next = time.Now().Add(wait)
+}
+
+func retryAfter(value string) (time.Duration) {
+	if value == "" { return 0 }
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// a Poller polls a set of Subscriptions, each on its own schedule, and
+// reports new items to Handler.
+type Poller struct {
+	Reqtifier reqtify.Reqtifier
+
+	// called with the new items in a feed, if there are any.
+	Handler   func(sub *Subscription, f *Feed, items []Item)
+
+	// called when a poll fails. Optional.
+	Errors    func(sub *Subscription, err error)
+
+	lock      sync.Mutex
+	subs      []*Subscription
+	wake      chan struct{}
+}
+
+func NewPoller(r reqtify.Reqtifier, handler func(sub *Subscription, f *Feed, items []Item)) (*Poller) {
+	return &Poller{Reqtifier: r, Handler: handler}
+}
+
+func (this *Poller) wakeup() (chan struct{}) {
+	if this.wake == nil { this.wake = make(chan struct{}, 1) }
+	return this.wake
+}
+
+// adds a subscription. It's polled as soon as its Next time arrives, which
+// is immediately for a new Subscription.
+func (this *Poller) Add(sub *Subscription) {
+	this.lock.Lock()
+	this.subs = append(this.subs, sub)
+	wake := this.wakeup()
+	this.lock.Unlock()
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// removes the subscription for a URL.
+func (this *Poller) Remove(url string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for i, sub := range this.subs {
+		if sub.URL == url {
+			this.subs = append(this.subs[:i], this.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// returns the subscription which should be polled next, if any.
+func (this *Poller) due() (*Subscription, chan struct{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	var next *Subscription
+	for _, sub := range this.subs {
+		if next == nil || sub.Next.Before(next.Next) { next = sub }
+	}
+	return next, this.wakeup()
+}
+
+// polls subscriptions, one at a time, as they come due, until ctx is
+// canceled.
+func (this *Poller) Run(ctx context.Context) (error) {
+	for {
+		sub, wake := this.due()
+
+		if sub != nil && !time.Now().Before(sub.Next) {
+			this.poll(ctx, sub)
+			if ctx.Err() != nil { return ctx.Err() }
+			continue
+		}
+
+		// wait until the next one is due, or another is added
+		var timer <-chan time.Time
+		var t *time.Timer
+		if sub != nil {
+			t = time.NewTimer(time.Until(sub.Next))
+			timer = t.C
+		}
+
+		select {
+		case <- ctx.Done():
+		case <- wake:
+		case <- timer:
+		}
+		if t != nil { t.Stop() }
+		if ctx.Err() != nil { return ctx.Err() }
+	}
+}
+
+func (this *Poller) poll(ctx context.Context, sub *Subscription) {
+	f, items, err := sub.Poll(ctx, this.Reqtifier)
+	if err != nil {
+		if this.Errors != nil { this.Errors(sub, err) }
+		return
+	}
+	if len(items) != 0 && this.Handler != nil {
+		this.Handler(sub, f, items)
+	}
+}
@@ -0,0 +1,78 @@
+package reqtify
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// adds the file at path to the multipart form. It isn't opened until the
+// request body is sent, and it's closed once it's been read, or the request
+// is done, whichever is first. The filename sent is the last element of path,
+// and the content type is guessed from its extension, or sniffed from its
+// contents. If every part of the form has a known length, the request is
+// sent with a Content-Length.
+func (this *RequestImpl) FileArgPath(key, path string) (Request) {
+	file := FormFile{Name: filepath.Base(path), Path: path, ContentType: mime.TypeByExtension(filepath.Ext(path))}
+	this.FormFiles[key] = append(this.FormFiles[key], file)
+	return this
+}
+
+// returns file, with Data reading lazily from the file at its Path, which
+// is closed when the request is done, if it hasn't been already.
+func (this *RequestImpl) openLazily(file FormFile) (FormFile) {
+	lazy := &lazyFile{path: file.Path}
+	this.pathFiles = append(this.pathFiles, lazy)
+	file.Data = lazy
+	if info, err := os.Stat(file.Path); err == nil && info.Mode().IsRegular() {
+		file.size, file.sized = info.Size(), true
+	}
+	return file
+}
+
+// a file which is opened when it's first read, and closed exactly once: when
+// it's been read to the end, reading it fails, or Close is called.
+type lazyFile struct {
+	lock   sync.Mutex
+	path   string
+	file   *os.File
+	closed bool
+	err    error // what reads return once it's closed.
+}
+
+func (this *lazyFile) Read(p []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.closed { return 0, this.err }
+	if this.file == nil {
+		f, err := os.Open(this.path)
+		if err != nil {
+			this.close(err)
+			return 0, err
+		}
+		this.file = f
+	}
+
+	n, err := this.file.Read(p)
+	if err != nil { this.close(err) }
+	return n, err
+}
+
+func (this *lazyFile) Close() (error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed { return nil }
+	return this.close(os.ErrClosed)
+}
+
+// closes the file, if it was opened, so that reads return err from now on.
+func (this *lazyFile) close(err error) (error) {
+	this.closed = true
+	this.err = err
+	if this.file == nil { return nil }
+	closeErr := this.file.Close()
+	this.file = nil
+	return closeErr
+}
@@ -0,0 +1,75 @@
+package reqtify
+
+import (
+	"testing"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+)
+
+func TestFileArgPath(t *testing.T) {
+	type part struct {
+		name, filename, contentType, data string
+	}
+	var parts []part
+	var length int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		length = r.ContentLength
+		parts = nil
+		reader, err := r.MultipartReader()
+		if err != nil { w.WriteHeader(400); return }
+		for p, err := reader.NextPart(); err == nil; p, err = reader.NextPart() {
+			data, _ := ioutil.ReadAll(p)
+			parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(data)})
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	png := filepath.Join(dir, "photo.png")
+	notes := filepath.Join(dir, "notes")
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	// the file is only opened when the request is sent
+	req := reqt.New("/upload").Method(POST).FormArg("title", "cat").FileArgPath("photo", png)
+	if err := ioutil.WriteFile(png, []byte("\x89PNG\r\n\x1a\nmeow"), 0600); err != nil { t.Fatal(err) }
+	if _, err := req.Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if length <= 0 { t.Errorf("Content-Length Mismatch: got %d", length) }
+	if len(parts) != 2 || parts[1] != (part{"photo", "photo.png", "image/png", "\x89PNG\r\n\x1a\nmeow"}) || parts[0].data != "cat" { t.Errorf("Parts Mismatch: got %q", parts) }
+	if files := req.(*RequestImpl).pathFiles; len(files) != 0 { t.Errorf("Close Mismatch: %d files left open", len(files)) }
+
+	// without an extension, the type is sniffed, and the length isn't known ahead of time
+	if err := ioutil.WriteFile(notes, []byte("just text"), 0600); err != nil { t.Fatal(err) }
+	if _, err := reqt.New("/upload").Method(POST).FileArgPath("notes", notes).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if length != -1 { t.Errorf("Content-Length Mismatch: got %d, expected -1", length) }
+	if len(parts) != 1 || parts[0] != (part{"notes", "notes", "text/plain; charset=utf-8", "just text"}) { t.Errorf("Sniffed Mismatch: got %q", parts) }
+
+	// missing files fail the request
+	_, err := reqt.New("/upload").Method(POST).FileArgPath("missing", filepath.Join(dir, "missing.txt")).Do()
+	if !errors.Is(err, os.ErrNotExist) { t.Errorf("Missing Mismatch: got %v", err) }
+}
+
+func TestLazyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := ioutil.WriteFile(path, []byte("data"), 0600); err != nil { t.Fatal(err) }
+
+	// closed once read to the end
+	f := &lazyFile{path: path}
+	if f.file != nil { t.Errorf("Lazy Mismatch: opened before being read") }
+	data, err := ioutil.ReadAll(f)
+	if err != nil || string(data) != "data" { t.Errorf("Read Mismatch: got %q, %v", data, err) }
+	if !f.closed || f.file != nil { t.Errorf("EOF Mismatch: not closed at EOF") }
+	if n, err := f.Read(make([]byte, 1)); n != 0 || err != io.EOF { t.Errorf("After EOF Mismatch: got %d, %v", n, err) }
+	if err := f.Close(); err != nil { t.Errorf("Close Mismatch: got %v", err) }
+
+	// or when it's closed
+	f = &lazyFile{path: path}
+	f.Read(make([]byte, 1))
+	if err := f.Close(); err != nil { t.Errorf("Close Mismatch: got %v", err) }
+	if err := f.Close(); err != nil { t.Errorf("Second Close Mismatch: got %v", err) }
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) { t.Errorf("After Close Mismatch: got %v", err) }
+}
@@ -0,0 +1,109 @@
+package reqtify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// the media type of GraphQL responses, from the GraphQL over HTTP spec.
+const GraphQLResponseContentType = "application/graphql-response+json"
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// makes this a GraphQL request: a POST whose body is the query and its
+// variables. Unmarshallers then decode the response's data, rather than the
+// whole response, and any errors in it are returned as GraphQLErrors.
+//
+//	var resp struct{ User struct{ Name string } }
+//	api.New("/graphql").GraphQL(`query($id: ID!) { user(id: $id) { name } }`).Variable("id", 42).JSONInto(&resp).Do()
+func (this *RequestImpl) GraphQL(query string) (Request) {
+	this.Verb = POST
+	this.GraphQLQuery = query
+	this.Header("Accept", GraphQLResponseContentType + ", application/json;q=0.9")
+	return this.graphQLBody()
+}
+
+// sets a variable for a GraphQL query.
+func (this *RequestImpl) Variable(name string, value interface{}) (Request) {
+	if this.GraphQLVariables == nil { this.GraphQLVariables = make(map[string]interface{}) }
+	this.GraphQLVariables[name] = value
+	return this.graphQLBody()
+}
+
+func (this *RequestImpl) graphQLBody() (Request) {
+	return this.JSONBody(graphQLRequest{Query: this.GraphQLQuery, Variables: this.GraphQLVariables})
+}
+
+// an error reported by a GraphQL server.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (this GraphQLError) Error() (string) {
+	if len(this.Path) == 0 { return this.Message }
+	var path []string
+	for _, p := range this.Path {
+		path = append(path, fmt.Sprint(p))
+	}
+	return strings.Join(path, ".") + ": " + this.Message
+}
+
+// the errors in a GraphQL response. Data which was returned alongside them
+// is still decoded.
+type GraphQLErrors []GraphQLError
+
+func (this GraphQLErrors) Error() (string) {
+	if len(this) == 1 { return "graphql: " + this[0].Error() }
+	return fmt.Sprintf("graphql: %s (and %d more errors)", this[0].Error(), len(this) - 1)
+}
+
+func (this GraphQLErrors) Unwrap() ([]error) {
+	errs := make([]error, len(this))
+	for i, e := range this {
+		errs[i] = e
+	}
+	return errs
+}
+
+// unwraps the {data, errors} envelope of a GraphQL response, and passes the
+// data to the request's unmarshallers.
+type GraphQLUnmarshaller struct {
+	Unmarshallers []ResponseUnmarshaller
+	StatusCode    int
+	codec         JSONCodec
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+func (this GraphQLUnmarshaller) Unmarshal(body []byte) (error) {
+	var resp graphQLResponse
+	if err := this.codec.Unmarshal(body, &resp); err != nil {
+		// not a GraphQL response at all, probably from a proxy in front of it
+		if this.StatusCode >= 400 { return &ResponseError{StatusCode: this.StatusCode} }
+		return err
+	}
+
+	if len(resp.Data) != 0 && string(resp.Data) != "null" {
+		for _, u := range this.Unmarshallers {
+			if err := u.Unmarshal(resp.Data); err != nil { return err }
+		}
+	}
+	if len(resp.Errors) != 0 { return resp.Errors }
+	if this.StatusCode >= 400 { return &ResponseError{StatusCode: this.StatusCode} }
+	return nil
+}
@@ -0,0 +1,52 @@
+package reqtify
+
+import (
+	"testing"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestGraphQL(t *testing.T) {
+	var got struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	var accept, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept, contentType = r.Header.Get("Accept"), r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&got)
+		switch got.Variables["id"] {
+		case float64(42):
+			fmt.Fprint(w, `{"data": {"user": {"name": "alice"}}}`)
+		case float64(43):
+			fmt.Fprint(w, `{"data": {"user": {"name": "bob", "email": null}}, "errors": [{"message": "forbidden", "path": ["user", "email"], "locations": [{"line": 1, "column": 20}]}, {"message": "again"}]}`)
+		default:
+			w.WriteHeader(502)
+			fmt.Fprint(w, `<html>bad gateway</html>`)
+		}
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	const query = `query($id: ID!) { user(id: $id) { name email } }`
+	var resp struct{ User struct{ Name string } }
+	_, err := reqt.New("/graphql").GraphQL(query).Variable("id", 42).JSONInto(&resp).Do()
+	if err != nil || resp.User.Name != "alice" { t.Errorf("Data Mismatch: got %+v, %v", resp, err) }
+	if got.Query != query || len(got.Variables) != 1 || contentType != "application/json" || accept != GraphQLResponseContentType + ", application/json;q=0.9" {
+		t.Errorf("Request Mismatch: got %+v, %q, %q", got, contentType, accept)
+	}
+
+	_, err = reqt.New("/graphql").GraphQL(query).Variable("id", 43).JSONInto(&resp).Do()
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 2 || gqlErrs[0].Locations[0].Column != 20 || resp.User.Name != "bob" { t.Fatalf("Errors Mismatch: got %v, %+v", err, resp) }
+	if err.Error() != "graphql: user.email: forbidden (and 1 more errors)" { t.Errorf("Message Mismatch: got %s", err.Error()) }
+	var one GraphQLError
+	if !errors.As(err, &one) || one.Message != "forbidden" { t.Errorf("Unwrap Mismatch: got %+v", one) }
+
+	_, err = reqt.New("/graphql").GraphQL(query).Variable("id", 0).Do()
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 502 { t.Errorf("Status Mismatch: got %v", err) }
+}
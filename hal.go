@@ -0,0 +1,215 @@
+package reqtify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+/*
+   HAL (application/hal+json) responses carry links to related resources in
+   "_links", and may embed them in "_embedded". Embedding HAL in a struct
+   decoded with HALInto lets it follow them, with the same headers and
+   settings as the request it came from:
+
+	type Orders struct {
+		reqtify.HAL
+		Total int `json:"total"`
+	}
+
+	var orders Orders
+	api.New("/orders").HALInto(&orders).Do()
+	next := orders.Follow("next")
+*/
+
+const HALContentType = "application/hal+json"
+
+var ErrNoLink error = errors.New("no such link")
+var ErrHALNotBound error = errors.New("hal: value wasn't decoded by a request, so has nothing to follow links from")
+
+// a link from a HAL resource.
+type HALLink struct {
+	Href        string `json:"href"`
+	Templated   bool   `json:"templated,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Deprecation string `json:"deprecation,omitempty"`
+}
+
+// a HAL resource's links by relation. Relations with a single link are
+// decoded as a list of one.
+type HALLinks map[string][]HALLink
+
+func (this *HALLinks) UnmarshalJSON(data []byte) (error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil { return err }
+	*this = make(HALLinks, len(raw))
+	for rel, value := range raw {
+		var links []HALLink
+		if err := json.Unmarshal(value, &links); err != nil {
+			var link HALLink
+			if err := json.Unmarshal(value, &link); err != nil { return fmt.Errorf("hal: link %s: %w", rel, err) }
+			links = []HALLink{link}
+		}
+		(*this)[rel] = links
+	}
+	return nil
+}
+
+// the links and embedded resources of a HAL resource. Embed it in the
+// structs which HAL responses are decoded into.
+type HAL struct {
+	Links    HALLinks                   `json:"_links,omitempty"`
+	Embedded map[string]json.RawMessage `json:"_embedded,omitempty"`
+
+	origin Request
+}
+
+func (this *HAL) setHALOrigin(origin Request) {
+	this.origin = origin
+}
+
+// returns the first link with the given relation.
+func (this *HAL) Link(rel string) (HALLink, bool) {
+	if links := this.Links[rel]; len(links) != 0 { return links[0], true }
+	return HALLink{}, false
+}
+
+// returns a request for the link with the given relation, made from the
+// request this resource was decoded by, as Request.Follow does. If there's
+// no such link, the request fails with ErrNoLink, and if this resource wasn't
+// decoded with HALInto or FromHAL (by JSONInto, say), with ErrHALNotBound.
+func (this *HAL) Follow(rel string) (Request) {
+	return this.FollowTemplate(rel, nil)
+}
+
+// like Follow, but expands a templated link (RFC 6570) with vars first.
+func (this *HAL) FollowTemplate(rel string, vars map[string]interface{}) (Request) {
+	if this.origin == nil { return failedRequest(ErrHALNotBound) }
+	link, ok := this.Link(rel)
+	if !ok {
+		err := fmt.Errorf("%w: %q", ErrNoLink, rel)
+		req := this.origin.Clone()
+		if s, ok := req.(interface{ setBuildError(error) }); ok {
+			s.setBuildError(err)
+			return req
+		}
+		return failedRequest(err)
+	}
+	href := link.Href
+	if link.Templated { href = ExpandURITemplate(href, vars) }
+	return this.origin.Follow(href)
+}
+
+// decodes the embedded resource with the given relation into into.
+func (this *HAL) EmbeddedInto(rel string, into interface{}) (error) {
+	raw, ok := this.Embedded[rel]
+	if !ok { return fmt.Errorf("hal: nothing embedded as %q", rel) }
+	return json.Unmarshal(raw, into)
+}
+
+// decodes a HAL response as JSON, and lets any HAL inside it follow links
+// from origin.
+type HALUnmarshaller struct {
+	output_value interface{}
+	origin       Request
+	codec        JSONCodec
+}
+
+func (this HALUnmarshaller) Unmarshal(body []byte) error {
+	var err error
+	if this.codec != nil {
+		err = this.codec.Unmarshal(body, this.output_value)
+	} else {
+		err = json.Unmarshal(body, this.output_value)
+	}
+	if h, ok := this.output_value.(interface{ setHALOrigin(Request) }); ok && this.origin != nil {
+		h.setHALOrigin(this.origin)
+	}
+	return err
+}
+
+func (this HALUnmarshaller) MediaTypes() ([]string) {
+	return []string{HALContentType, "application/json"}
+}
+
+// returns an unmarshaller for HAL responses, whose links are followed from
+// origin.
+func FromHAL(output_value interface{}, origin Request) ResponseUnmarshaller {
+	return HALUnmarshaller{output_value: output_value, origin: origin}
+}
+
+func (this *RequestImpl) HALInto(into interface{}) (Request) {
+	this.Response = append(this.Response, HALUnmarshaller{output_value: into, origin: this, codec: this.jsonCodec()})
+	return this
+}
+
+// makes HAL values decoded by this request follow links from origin. Only
+// needed by Request implementations which wrap a RequestImpl.
+func (this *RequestImpl) BindHAL(origin Request) {
+	for i, u := range this.Response {
+		if h, ok := u.(HALUnmarshaller); ok {
+			h.origin = origin
+			this.Response[i] = h
+		}
+	}
+}
+
+func (this *RequestImpl) hasHAL() (bool) {
+	for _, u := range this.Response {
+		if _, ok := u.(HALUnmarshaller); ok { return true }
+	}
+	return false
+}
+
+// expands a URI template (RFC 6570), supporting simple {var} expansion and
+// the +, /, ? and & operators, which cover the templates HAL links use.
+// Missing variables expand to nothing.
+func ExpandURITemplate(template string, vars map[string]interface{}) (string) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 { break }
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 { break }
+		b.WriteString(template[:start])
+		expr := template[start + 1:start + end]
+		template = template[start + end + 1:]
+
+		op := byte(0)
+		if expr != "" && strings.IndexByte("+/?&", expr[0]) >= 0 {
+			op, expr = expr[0], expr[1:]
+		}
+		first := true
+		for _, name := range strings.Split(expr, ",") {
+			value, ok := vars[name]
+			if !ok || value == nil { continue }
+			s := fmt.Sprint(value)
+			if op == '+' {
+				s = (&url.URL{Path: s}).EscapedPath()
+			} else {
+				s = url.QueryEscape(s)
+				s = strings.ReplaceAll(s, "+", "%20")
+			}
+
+			switch {
+			case op == '?' || op == '&':
+				sep := "&"
+				if first && op == '?' { sep = "?" }
+				b.WriteString(sep + url.QueryEscape(name) + "=" + s)
+			case op == '/':
+				b.WriteString("/" + s)
+			case !first:
+				b.WriteString("," + s)
+			default:
+				b.WriteString(s)
+			}
+			first = false
+		}
+	}
+	b.WriteString(template)
+	return b.String()
+}
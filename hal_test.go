@@ -0,0 +1,79 @@
+package reqtify
+
+import (
+	"testing"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+type halOrders struct {
+	HAL
+	Total int `json:"total"`
+}
+
+func TestHAL(t *testing.T) {
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", HALContentType)
+		switch r.URL.RequestURI() {
+		case "/orders":
+			fmt.Fprint(w, `{"_links": {"self": {"href": "/orders"}, "next": {"href": "/orders?page=2"}, "find": {"href": "/orders{/id}{?fields,expand}", "templated": true}, "item": [{"href": "/orders/1"}, {"href": "/orders/2"}]},
+				"_embedded": {"orders": [{"total": 30}]}, "total": 2}`)
+		case "/orders?page=2":
+			fmt.Fprint(w, `{"_links": {"prev": {"href": "orders"}}, "total": 1}`)
+		default:
+			fmt.Fprintf(w, `{"total": 0, "_links": {"self": {"href": %q}}}`, r.URL.RequestURI())
+		}
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	var orders halOrders
+	if _, err := reqt.New("/orders").Header("Authorization", "token").HALInto(&orders).Do(); err != nil || orders.Total != 2 { t.Fatalf("Decode Mismatch: got %+v, %v", orders, err) }
+	if len(orders.Links["item"]) != 2 || orders.Links["next"][0].Href != "/orders?page=2" { t.Errorf("Links Mismatch: got %+v", orders.Links) }
+	var embedded []halOrders
+	if err := orders.EmbeddedInto("orders", &embedded); err != nil || len(embedded) != 1 || embedded[0].Total != 30 { t.Errorf("Embedded Mismatch: got %+v, %v", embedded, err) }
+
+	var page2 halOrders
+	if _, err := orders.Follow("next").HALInto(&page2).Do(); err != nil || page2.Total != 1 || auth[1] != "token" { t.Fatalf("Follow Mismatch: got %+v, %v, %q", page2, err, auth) }
+	// relative to the page it came from, not the first one
+	var prev halOrders
+	if req := page2.Follow("prev"); req.URL() != server.URL + "/orders" { t.Errorf("Relative Mismatch: got %s", req.URL()) }
+	if _, err := page2.Follow("prev").HALInto(&prev).Do(); err != nil || prev.Total != 2 { t.Errorf("Prev Mismatch: got %+v, %v", prev, err) }
+
+	var found halOrders
+	if _, err := orders.FollowTemplate("find", map[string]interface{}{"id": 7, "expand": "a b"}).HALInto(&found).Do(); err != nil || found.Links["self"][0].Href != "/orders/7?expand=a+b" {
+		t.Errorf("Template Mismatch: got %+v, %v", found.Links, err)
+	}
+
+	if _, err := orders.Follow("missing").Do(); !errors.Is(err, ErrNoLink) { t.Errorf("Missing Mismatch: got %v", err) }
+
+	// values which weren't decoded with HALInto have nothing to follow from
+	var plain halOrders
+	if _, err := reqt.New("/orders").JSONInto(&plain).Do(); err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if _, err := plain.Follow("next").Header("Authorization", "token").HALInto(&page2).Do(); !errors.Is(err, ErrHALNotBound) { t.Errorf("Unbound Mismatch: got %v", err) }
+	if _, err := (&HAL{}).Follow("next").Do(); !errors.Is(err, ErrHALNotBound) { t.Errorf("Zero Mismatch: got %v", err) }
+
+	// origins which don't embed RequestImpl fail the same way
+	var wrapped halOrders
+	FromHAL(&wrapped, wrappedRequest{reqt.New("/orders")}).Unmarshal([]byte(`{"total": 1}`))
+	if _, err := wrapped.Follow("missing").Do(); !errors.Is(err, ErrNoLink) { t.Errorf("Wrapped Missing Mismatch: got %v", err) }
+}
+
+func TestExpandURITemplate(t *testing.T) {
+	vars := map[string]interface{}{"id": 42, "q": "a&b", "path": "x/y", "n": 3}
+	for template, expected := range map[string]string{
+		"/items/{id}":            "/items/42",
+		"/search{?q,n,missing}":  "/search?q=a%26b&n=3",
+		"/list?x=1{&n}":          "/list?x=1&n=3",
+		"/files{/path}":          "/files/x%2Fy",
+		"/raw/{+path}":           "/raw/x/y",
+		"/{id,n}":                "/42,3",
+		"/unclosed{id":           "/unclosed{id",
+	} {
+		if got := ExpandURITemplate(template, vars); got != expected { t.Errorf("Expand Mismatch: %s gave %s, expected %s", template, got, expected) }
+	}
+}
@@ -0,0 +1,408 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// a HARRecorder captures the requests made through a Reqtifier and their
+// responses, and writes them out as an HTTP Archive, which browser devtools
+// and many other tools can open:
+//
+//	har := reqtify.NewHARRecorder()
+//	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithHARRecorder(har))
+//	...
+//	har.WriteFile("session.har")
+//
+// Credentials in headers are redacted unless KeepSecrets is set, so that
+// archives can be shared in bug reports.
+type HARRecorder struct {
+	// bodies longer than this are truncated. Defaults to 1MB; negative
+	// means bodies aren't recorded at all.
+	MaxBodySize  int64
+
+	// redact no headers, rather than those in RedactHeaders.
+	KeepSecrets  bool
+
+	// the headers redacted, in addition to DefaultRedactedHeaders.
+	RedactHeaders []string
+
+	lock    sync.Mutex
+	entries []*harEntry
+}
+
+// the headers a HARRecorder redacts by default.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+func NewHARRecorder() (*HARRecorder) {
+	return &HARRecorder{}
+}
+
+// records the traffic of a Reqtifier with a HARRecorder.
+func WithHARRecorder(recorder *HARRecorder) Option {
+	return WithMiddleware(recorder.Middleware())
+}
+
+type harLog struct {
+	Log struct {
+		Version string      `json:"version"`
+		Creator harCreator  `json:"creator"`
+		Entries []*harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+
+	body            *harBody
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size        int64  `json:"size"`
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func (this *HARRecorder) maxBodySize() (int64) {
+	if this.MaxBodySize == 0 { return 1 << 20 }
+	return this.MaxBodySize
+}
+
+func (this *HARRecorder) headers(h http.Header) ([]harNameValue) {
+	list := []harNameValue{}
+	for k, values := range h {
+		redact := false
+		if !this.KeepSecrets {
+			for _, secret := range append(DefaultRedactedHeaders, this.RedactHeaders...) {
+				if strings.EqualFold(k, secret) { redact = true }
+			}
+		}
+		for _, v := range values {
+			if redact { v = "[redacted]" }
+			list = append(list, harNameValue{Name: k, Value: v})
+		}
+	}
+	return list
+}
+
+// returns text, and "base64" as its encoding if it isn't valid UTF-8.
+func harText(body []byte) (string, string) {
+	if utf8.Valid(body) { return string(body), "" }
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func millis(d time.Duration) (float64) {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// returns middleware which records each round trip. Response bodies are
+// recorded as they're read, so those which haven't been read yet appear
+// incomplete. Bodies in a content coding reqtify understands are recorded
+// decoded, as HAR requires, even for requests which asked for RawEncoding.
+func (this *HARRecorder) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			e := &harEntry{StartedDateTime: start}
+			e.Request = harRequest{
+				Method: r.Method,
+				URL: r.URL.String(),
+				HTTPVersion: r.Proto,
+				Cookies: []harNameValue{},
+				Headers: this.headers(r.Header),
+				QueryString: []harNameValue{},
+				HeadersSize: -1,
+				BodySize: r.ContentLength,
+			}
+			if e.Request.HTTPVersion == "" { e.Request.HTTPVersion = "HTTP/1.1" }
+			for k, values := range r.URL.Query() {
+				for _, v := range values {
+					e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: k, Value: v})
+				}
+			}
+
+			if r.Body != nil && r.Body != http.NoBody && this.maxBodySize() >= 0 {
+				e.Request.PostData = &harPostData{MimeType: r.Header.Get("Content-Type")}
+				r.Body = &harRequestBody{ReadCloser: r.Body, recorder: this, entry: e}
+			}
+
+			this.lock.Lock()
+			this.entries = append(this.entries, e)
+			this.lock.Unlock()
+
+			resp, err := next(r)
+			headers := time.Now()
+
+			this.lock.Lock()
+			defer this.lock.Unlock()
+			e.Timings.Wait = millis(headers.Sub(start))
+			e.Time = e.Timings.Wait
+			if err != nil {
+				e.Comment = err.Error()
+				return resp, err
+			}
+
+			e.Response = harResponse{
+				Status: resp.StatusCode,
+				StatusText: strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))),
+				HTTPVersion: resp.Proto,
+				Cookies: []harNameValue{},
+				Headers: this.headers(resp.Header),
+				Content: harContent{Size: -1, MimeType: resp.Header.Get("Content-Type")},
+				RedirectURL: resp.Header.Get("Location"),
+				HeadersSize: -1,
+				BodySize: -1,
+			}
+			if resp.Body != nil {
+				e.body = &harBody{ReadCloser: resp.Body, recorder: this, entry: e, start: start, headers: headers}
+				if codings, ok := contentCodings(resp.Header.Get("Content-Encoding")); ok && len(codings) != 0 && r.Method != string(HEAD) {
+					e.body.codings = codings
+				}
+				resp.Body = e.body
+			}
+			return resp, err
+		}
+	}
+}
+
+// records a request body as it's sent, so that uploads needn't be held in
+// memory, or read through anything but their own WriteTo.
+type harRequestBody struct {
+	io.ReadCloser
+	recorder *HARRecorder
+	entry    *harEntry
+	buffer   bytes.Buffer
+	size     int64
+}
+
+func (this *harRequestBody) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+	this.Write(p[:n])
+	return n, err
+}
+
+// copies the body to w with its own WriteTo, if it has one, recording what's
+// written on the way.
+func (this *harRequestBody) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(io.MultiWriter(this, w), this.ReadCloser)
+}
+
+// records p as sent.
+func (this *harRequestBody) Write(p []byte) (int, error) {
+	this.recorder.lock.Lock()
+	defer this.recorder.lock.Unlock()
+	this.size += int64(len(p))
+	if room := this.recorder.maxBodySize() - int64(this.buffer.Len()); room > 0 {
+		if int64(len(p)) < room { room = int64(len(p)) }
+		this.buffer.Write(p[:room])
+	}
+	this.entry.Request.BodySize = this.size
+	this.entry.Request.PostData.Text, this.entry.Request.PostData.Encoding = harText(this.buffer.Bytes())
+	return len(p), nil
+}
+
+// records a response body as it's read.
+type harBody struct {
+	io.ReadCloser
+	recorder *HARRecorder
+	entry    *harEntry
+	start    time.Time
+	headers  time.Time
+	buffer   bytes.Buffer
+	size     int64 // as read from the wire
+	content  int64 // after decoding
+
+	// encoded bodies are fed through a decoder as they're read, which
+	// delivers the decoded content to the buffer.
+	codings  []string
+	encoded  *io.PipeWriter
+	decoding chan struct{}
+	finish   sync.Once
+}
+
+func (this *harBody) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+
+	if this.codings != nil {
+		if n != 0 {
+			this.decoder().Write(p[:n]) // fails only once the decoder has given up
+		}
+		if err != nil { this.finishDecoding() }
+	}
+
+	this.recorder.lock.Lock()
+	defer this.recorder.lock.Unlock()
+	this.size += int64(n)
+	if this.codings == nil { this.capture(p[:n]) }
+	this.update()
+	return n, err
+}
+
+func (this *harBody) Close() (error) {
+	err := this.ReadCloser.Close()
+	if this.codings != nil { this.finishDecoding() }
+	return err
+}
+
+// starts the decoder, if it isn't running yet, and returns its input.
+func (this *harBody) decoder() (*io.PipeWriter) {
+	if this.encoded != nil { return this.encoded }
+
+	r, w := io.Pipe()
+	this.encoded, this.decoding = w, make(chan struct{})
+	go func() {
+		defer close(this.decoding)
+		decoded, err := decodeContent(r, this.codings)
+		if err == nil {
+			io.Copy(harContentWriter{this}, decoded)
+			decoded.Close()
+		}
+		r.CloseWithError(io.ErrClosedPipe)
+	}()
+	return w
+}
+
+// waits for the decoder to deliver everything it was given.
+func (this *harBody) finishDecoding() {
+	this.finish.Do(func() {
+		if this.encoded == nil { return }
+		this.encoded.Close()
+		<-this.decoding
+	})
+}
+
+// receives the output of a harBody's decoder.
+type harContentWriter struct {
+	body *harBody
+}
+
+func (this harContentWriter) Write(p []byte) (int, error) {
+	this.body.recorder.lock.Lock()
+	defer this.body.recorder.lock.Unlock()
+	this.body.capture(p)
+	this.body.update()
+	return len(p), nil
+}
+
+// adds decoded content to the buffer, as much as fits.
+func (this *harBody) capture(p []byte) {
+	this.content += int64(len(p))
+	if room := this.recorder.maxBodySize() - int64(this.buffer.Len()); room > 0 {
+		if int64(len(p)) < room { room = int64(len(p)) }
+		this.buffer.Write(p[:room])
+	}
+}
+
+// copies what has been read so far into the entry.
+func (this *harBody) update() {
+	now := time.Now()
+	e := this.entry
+	e.Response.BodySize = this.size
+	e.Response.Content.Size = this.content
+	if this.codings != nil { e.Response.Content.Compression = this.content - this.size }
+	e.Response.Content.Text, e.Response.Content.Encoding = harText(this.buffer.Bytes())
+	e.Timings.Receive = millis(now.Sub(this.headers))
+	e.Time = millis(now.Sub(this.start))
+}
+
+// writes the recorded traffic to w as a HAR document.
+func (this *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	var doc harLog
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = harCreator{Name: "reqtify", Version: "1"}
+
+	this.lock.Lock()
+	doc.Log.Entries = append([]*harEntry{}, this.entries...)
+	data, err := json.MarshalIndent(doc, "", "  ")
+	this.lock.Unlock()
+	if err != nil { return 0, err }
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// writes the recorded traffic to a file, replacing it if it exists.
+func (this *HARRecorder) WriteFile(path string) (error) {
+	f, err := os.Create(path)
+	if err != nil { return err }
+	_, err = this.WriteTo(f)
+	if closeErr := f.Close(); err == nil { err = closeErr }
+	return err
+}
+
+// forgets the traffic recorded so far.
+func (this *HARRecorder) Reset() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries = nil
+}
+
+// returns the number of round trips recorded.
+func (this *HARRecorder) Len() (int) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return len(this.entries)
+}
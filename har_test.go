@@ -0,0 +1,145 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := test.NewServer(test.Header("Set-Cookie", "session=secret"))
+	defer server.Close()
+
+	har := NewHARRecorder()
+	reqt := New(server.URL, nil, nil, nil, "test", WithHARRecorder(har))
+	var echo test.EchoResponse
+	if _, err := reqt.New("/post").Method(POST).URLArg("q", "1").FormArg("a", "b").Header("Authorization", "Bearer x").JSONInto(&echo).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if _, err := reqt.New("/binary").Body(bytes.NewReader([]byte{0xff, 0xfe}), "application/octet-stream").Method(PUT).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if echo.Body != "a=b" || har.Len() != 2 { t.Fatalf("Passthrough Mismatch: got %+v, %d entries", echo, har.Len()) }
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err := har.WriteFile(path); err != nil { t.Fatalf("Write Failure: %s", err.Error()) }
+	data, _ := ioutil.ReadFile(path)
+	var doc struct {
+		Log struct {
+			Version string
+			Entries []struct {
+				Request struct {
+					Method      string
+					URL         string
+					Headers     []harNameValue
+					QueryString []harNameValue
+					PostData    *harPostData
+				}
+				Response struct {
+					Status     int
+					StatusText string
+					Headers    []harNameValue
+					Content    harContent
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil || doc.Log.Version != "1.2" || len(doc.Log.Entries) != 2 { t.Fatalf("HAR Mismatch: %v, %s", err, data) }
+
+	first := doc.Log.Entries[0]
+	if first.Request.Method != "POST" || first.Request.URL != server.URL + "/post?q=1" || first.Request.PostData.Text != "a=b" || first.Request.QueryString[0] != (harNameValue{"q", "1"}) {
+		t.Errorf("Request Mismatch: got %+v", first.Request)
+	}
+	if first.Response.Status != 200 || first.Response.StatusText != "OK" || first.Response.Content.MimeType != "application/json" || first.Response.Content.Size == 0 {
+		t.Errorf("Response Mismatch: got %+v", first.Response)
+	}
+	var recorded test.EchoResponse
+	if err := json.Unmarshal([]byte(first.Response.Content.Text), &recorded); err != nil || recorded.Body != "a=b" { t.Errorf("Content Mismatch: got %q", first.Response.Content.Text) }
+
+	for _, h := range append(first.Request.Headers, first.Response.Headers...) {
+		if (http.CanonicalHeaderKey(h.Name) == "Authorization" || h.Name == "Set-Cookie") && h.Value != "[redacted]" { t.Errorf("Redact Mismatch: %s is %q", h.Name, h.Value) }
+	}
+	if post := doc.Log.Entries[1].Request.PostData; post.Encoding != "base64" || post.Text != "//4=" { t.Errorf("Binary Mismatch: got %+v", post) }
+
+	har.Reset()
+	if har.Len() != 0 { t.Errorf("Reset Mismatch") }
+}
+
+func TestHARRecorderDecodesContent(t *testing.T) {
+	body := `{"a":"` + strings.Repeat("x", 100) + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(body))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	har := NewHARRecorder()
+	reqt := New(server.URL, nil, nil, nil, "test", WithHARRecorder(har), WithAcceptEncoding("gzip"))
+	var decoded map[string]string
+	if _, err := reqt.New("/").JSONInto(&decoded).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	resp, err := reqt.New("/").RawEncoding().Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var buffer bytes.Buffer
+	har.WriteTo(&buffer)
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					BodySize int64
+					Content  harContent
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &doc); err != nil || len(doc.Log.Entries) != 2 { t.Fatalf("HAR Mismatch: %v, %s", err, buffer.Bytes()) }
+	for i, e := range doc.Log.Entries {
+		content := e.Response.Content
+		if content.Text != body || content.Encoding != "" || content.Size != int64(len(body)) {
+			t.Errorf("%d: Content Mismatch: got %+v", i, content)
+		}
+		if e.Response.BodySize <= 0 || e.Response.BodySize >= content.Size || content.Compression != content.Size - e.Response.BodySize {
+			t.Errorf("%d: Compression Mismatch: got body size %d, %+v", i, e.Response.BodySize, content)
+		}
+	}
+}
+
+func TestHARRecorderStreamsRequestBody(t *testing.T) {
+	server := test.NewServer()
+	defer server.Close()
+
+	har := NewHARRecorder()
+	har.MaxBodySize = 4
+	reqt := New(server.URL, nil, nil, nil, "test", WithHARRecorder(har))
+	var echo test.EchoResponse
+	body := strings.Repeat("streamed body ", 1000)
+	if _, err := reqt.New("/post").Method(POST).Body(io.MultiReader(strings.NewReader(body)), "text/plain").JSONInto(&echo).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if echo.Body != body { t.Errorf("Passthrough Mismatch: got %d bytes, expected %d", len(echo.Body), len(body)) }
+
+	var buffer bytes.Buffer
+	har.WriteTo(&buffer)
+	var doc struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					BodySize int64
+					PostData *harPostData
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &doc); err != nil || len(doc.Log.Entries) != 1 { t.Fatalf("HAR Mismatch: %v, %s", err, buffer.Bytes()) }
+	request := doc.Log.Entries[0].Request
+	if request.BodySize != int64(len(body)) || request.PostData == nil || request.PostData.Text != "stre" || request.PostData.MimeType != "text/plain" {
+		t.Errorf("Request Mismatch: got body size %d, %+v", request.BodySize, request.PostData)
+	}
+}
@@ -0,0 +1,36 @@
+package reqtify
+
+import (
+	"net/http"
+)
+
+// a response header, or all of them, to be captured. See HeaderInto.
+type HeaderCapture struct {
+	Key  string
+	Into *string       // receives the first value of Key, if set.
+	All  *http.Header  // receives a copy of every header, if set.
+	Trailer bool       // captures trailers instead, once the body has been read. See TrailerInto.
+}
+
+// stores the first value of the response header key in into, or "" if the
+// response doesn't have it. Useful for pagination cursors, rate limit
+// information, and request IDs.
+func (this *RequestImpl) HeaderInto(key string, into *string) (Request) {
+	this.HeaderCaptures = append(this.HeaderCaptures, HeaderCapture{Key: key, Into: into})
+	return this
+}
+
+// stores a copy of the response headers in into.
+func (this *RequestImpl) HeadersInto(into *http.Header) (Request) {
+	this.HeaderCaptures = append(this.HeaderCaptures, HeaderCapture{All: into})
+	return this
+}
+
+// fills in the headers this request asked for from a response.
+func (this *RequestImpl) CaptureHeaders(resp *http.Response) {
+	for _, c := range this.HeaderCaptures {
+		if c.Trailer { continue }
+		if c.Into != nil { *c.Into = resp.Header.Get(c.Key) }
+		if c.All != nil { *c.All = resp.Header.Clone() }
+	}
+}
@@ -0,0 +1,118 @@
+// Package htmldoc decodes HTML responses, for scraping pages which have no
+// API. It's kept apart from reqtify so that programs which don't parse HTML
+// don't build golang.org/x/net/html and cascadia:
+//
+//	var titles []string
+//	api.New("/blog").Into(htmldoc.SelectInto("article h2 > a", &titles)).Do()
+package htmldoc
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/thewug/reqtify"
+	"golang.org/x/net/html"
+)
+
+var mediaTypes = []string{"text/html", "application/xhtml+xml"}
+
+// decodes a response as an HTML document.
+type Unmarshaller struct {
+	into *html.Node
+}
+
+// parses the body as an HTML document, into the node passed to Into, which
+// becomes the document node.
+func (this Unmarshaller) Unmarshal(body []byte) (error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	*this.into = *doc
+	for c := this.into.FirstChild; c != nil; c = c.NextSibling {
+		c.Parent = this.into
+	}
+	return nil
+}
+
+func (this Unmarshaller) MediaTypes() ([]string) { return mediaTypes }
+func (this Unmarshaller) DecodesText() (bool) { return true }
+
+// returns an unmarshaller which parses an HTML response into into.
+func Into(into *html.Node) (reqtify.ResponseUnmarshaller) {
+	return Unmarshaller{into: into}
+}
+
+// stores the text of the elements in an HTML response matching a selector.
+type SelectorUnmarshaller struct {
+	selector cascadia.Sel
+	err      error
+	into     *[]string
+}
+
+// parses the body as an HTML document, and stores the text content of each
+// element matching the selector.
+func (this SelectorUnmarshaller) Unmarshal(body []byte) (error) {
+	if this.err != nil {
+		return this.err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	*this.into = (*this.into)[:0]
+	for _, n := range cascadia.QueryAll(doc, this.selector) {
+		*this.into = append(*this.into, Text(n))
+	}
+	return nil
+}
+
+func (this SelectorUnmarshaller) MediaTypes() ([]string) { return mediaTypes }
+func (this SelectorUnmarshaller) DecodesText() (bool) { return true }
+
+// returns an unmarshaller which extracts the text of every element matching
+// a CSS selector, like "article h2 > a". If the selector is invalid,
+// decoding fails with its error.
+func SelectInto(selector string, into *[]string) (reqtify.ResponseUnmarshaller) {
+	sel, err := cascadia.Parse(selector)
+	return SelectorUnmarshaller{selector: sel, err: err, into: into}
+}
+
+// returns the elements under root matching a CSS selector.
+func Select(root *html.Node, selector string) ([]*html.Node, error) {
+	sel, err := cascadia.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	return cascadia.QueryAll(root, sel), nil
+}
+
+// returns the text content of a node, with surrounding whitespace trimmed.
+func Text(n *html.Node) (string) {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// returns the value of an attribute of a node, if it has one.
+func Attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
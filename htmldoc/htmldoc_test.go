@@ -0,0 +1,49 @@
+package htmldoc
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/test"
+
+	"testing"
+	"errors"
+	"net/http"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func TestHTMLDoc(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var accept string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		accept = req.Header.Get("Accept")
+		page := `<html><body><ul><li><a href="/a">First</a></li><li><a href="/b"> Second <b>post</b></a></li></ul></body></html>`
+		if req.URL.Path == "/invalid" { page = "<p>caf\xe9</p>" }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(page))}, nil
+	})
+
+	reqt := reqtify.New("https://this.is.a.test", nil, nil, nil, "test", reqtify.WithUTF8Policy(reqtify.UTF8Reject))
+	reqt.(*reqtify.ReqtifierImpl).HttpClient = &http_mock_client
+
+	var doc html.Node
+	var titles []string
+	_, err := reqt.New("/").Into(Into(&doc)).Into(SelectInto("li > a", &titles)).Do()
+	if err != nil { t.Fatalf("Parse Failure: %s", err.Error()) }
+	if expected := []string{"First", "Second post"}; !reflect.DeepEqual(titles, expected) { t.Errorf("Selector Mismatch: got %q, expected %q", titles, expected) }
+	if accept != "text/html, application/xhtml+xml" { t.Errorf("Accept Mismatch: got %q", accept) }
+
+	links, err := Select(&doc, "a[href]")
+	if err != nil || len(links) != 2 { t.Fatalf("Select Mismatch: got %d links, %v", len(links), err) }
+	if href, _ := Attr(links[1], "href"); href != "/b" { t.Errorf("Attribute Mismatch: got %q", href) }
+	if doc.FirstChild.Parent != &doc { t.Errorf("Parent Mismatch: document children not reparented") }
+
+	_, err = reqt.New("/").Into(SelectInto("li >", &titles)).Do()
+	if err == nil { t.Errorf("Selector Mismatch: invalid selector accepted") }
+
+	// HTML is text, so the UTF-8 policy applies to it
+	_, err = reqt.New("/invalid").Into(Into(&doc)).Do()
+	var utf8Err *reqtify.InvalidUTF8Error
+	if !errors.As(err, &utf8Err) { t.Errorf("UTF-8 Mismatch: got %v", err) }
+}
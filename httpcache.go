@@ -0,0 +1,360 @@
+package reqtify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+   An HTTP cache which follows the caching rules of RFC 7234: responses are
+   stored and reused according to their Cache-Control, Expires, Age, and Vary
+   headers, stale responses are revalidated with conditional requests, and
+   requests' own Cache-Control directives are honored.
+
+   Unlike ResponseCache, which caches everything for a fixed time, this cache
+   only reuses responses the server says may be reused.
+*/
+
+// a response as stored in an HTTPCache.
+type CachedResponse struct {
+	StatusCode     int
+	Status         string
+	Proto          string
+	Header         http.Header
+	Body           []byte
+
+	// the values of the request headers named by the response's Vary header,
+	// when the response was stored.
+	VaryHeaders    http.Header
+	RequestTime    time.Time
+	ResponseTime   time.Time
+}
+
+// a CacheStorage stores responses for an HTTPCache. Implementations must be
+// safe for concurrent use. Responses passed to Set won't be modified
+// afterwards, and responses returned by Get won't be modified by the cache.
+type CacheStorage interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// a CacheStorage which keeps responses in memory, without limit.
+type MemoryCacheStorage struct {
+	lock    sync.RWMutex
+	entries map[string]*CachedResponse
+}
+
+func NewMemoryCacheStorage() (*MemoryCacheStorage) {
+	return &MemoryCacheStorage{entries: make(map[string]*CachedResponse)}
+}
+
+func (this *MemoryCacheStorage) Get(key string) (*CachedResponse, bool) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	resp, ok := this.entries[key]
+	return resp, ok
+}
+
+func (this *MemoryCacheStorage) Set(key string, resp *CachedResponse) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.entries[key] = resp
+}
+
+func (this *MemoryCacheStorage) Delete(key string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.entries, key)
+}
+
+type HTTPCache struct {
+	Storage CacheStorage
+
+	// if set, the cache behaves as a shared cache: responses marked private
+	// aren't stored, s-maxage is honored, and responses to requests with an
+	// Authorization header are only stored if explicitly allowed.
+	Shared  bool
+}
+
+// creates a private HTTPCache. If storage is nil, responses are kept in memory.
+func NewHTTPCache(storage CacheStorage) (*HTTPCache) {
+	if storage == nil { storage = NewMemoryCacheStorage() }
+	return &HTTPCache{Storage: storage}
+}
+
+// caches responses to requests made through a Reqtifier, following RFC 7234.
+func WithHTTPCache(cache *HTTPCache) Option {
+	return WithMiddleware(cache.Middleware())
+}
+
+// the directives of a Cache-Control header. Directives without a value map to "".
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) (cacheControl) {
+	cc := cacheControl{}
+	for _, list := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(list, ",") {
+			kv := strings.SplitN(strings.TrimSpace(d), "=", 2)
+			if kv[0] == "" { continue }
+			key := strings.ToLower(kv[0])
+			if len(kv) == 2 {
+				cc[key] = strings.Trim(kv[1], `"`)
+			} else {
+				cc[key] = ""
+			}
+		}
+	}
+	return cc
+}
+
+func (this cacheControl) has(key string) (bool) {
+	_, ok := this[key]
+	return ok
+}
+
+// returns the value of a delta-seconds directive.
+func (this cacheControl) seconds(key string) (time.Duration, bool) {
+	v, ok := this[key]
+	if !ok { return 0, false }
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 { return 0, false }
+	return time.Duration(n) * time.Second, true
+}
+
+func cacheKey(r *http.Request) (string) {
+	return r.Method + " " + r.URL.String()
+}
+
+// status codes which may be cached without explicit freshness information.
+var heuristicallyCacheable = map[int]bool{200: true, 203: true, 204: true, 300: true, 301: true, 404: true, 405: true, 410: true, 414: true, 501: true}
+
+// returns how long a stored response is fresh for, per RFC 7234 section 4.2.1.
+func (this *HTTPCache) freshnessLifetime(c *CachedResponse) (time.Duration) {
+	cc := parseCacheControl(c.Header)
+	if this.Shared {
+		if d, ok := cc.seconds("s-maxage"); ok { return d }
+	}
+	if d, ok := cc.seconds("max-age"); ok { return d }
+
+	date, err := http.ParseTime(c.Header.Get("Date"))
+	if err != nil { date = c.ResponseTime }
+	if expires := c.Header.Get("Expires"); expires != "" {
+		at, err := http.ParseTime(expires)
+		if err != nil { return 0 } // invalid dates, like "0", mean already expired
+		return at.Sub(date)
+	}
+
+	if lm, err := http.ParseTime(c.Header.Get("Last-Modified")); err == nil && heuristicallyCacheable[c.StatusCode] {
+		return date.Sub(lm) / 10
+	}
+	return 0
+}
+
+// returns the age of a stored response, per RFC 7234 section 4.2.3.
+func currentAge(c *CachedResponse, now time.Time) (time.Duration) {
+	var apparent time.Duration
+	if date, err := http.ParseTime(c.Header.Get("Date")); err == nil && c.ResponseTime.After(date) {
+		apparent = c.ResponseTime.Sub(date)
+	}
+	corrected := c.ResponseTime.Sub(c.RequestTime)
+	if age, err := strconv.ParseInt(c.Header.Get("Age"), 10, 64); err == nil {
+		corrected += time.Duration(age) * time.Second
+	}
+	if apparent > corrected {
+		corrected = apparent
+	}
+	return corrected + now.Sub(c.ResponseTime)
+}
+
+// reports whether a response may be stored, per RFC 7234 section 3.
+func (this *HTTPCache) storable(r *http.Request, resp *http.Response, reqCC cacheControl) (bool) {
+	if r.Method != string(GET) || reqCC.has("no-store") { return false }
+	// a partial response would be stored as if it were the whole thing
+	if resp.StatusCode == http.StatusPartialContent { return false }
+
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || (this.Shared && cc.has("private")) { return false }
+	if this.Shared && r.Header.Get("Authorization") != "" && !cc.has("public") && !cc.has("s-maxage") && !cc.has("must-revalidate") {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" { return false }
+
+	_, expires := resp.Header["Expires"]
+	return expires || cc.has("max-age") || (this.Shared && cc.has("s-maxage")) || cc.has("public") || cc.has("private") ||
+		heuristicallyCacheable[resp.StatusCode] && resp.Header.Get("Last-Modified") != ""
+}
+
+func varyHeaders(r *http.Request, header http.Header) (http.Header) {
+	vary := http.Header{}
+	for _, list := range header.Values("Vary") {
+		for _, key := range strings.Split(list, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				vary[http.CanonicalHeaderKey(key)] = r.Header.Values(key)
+			}
+		}
+	}
+	return vary
+}
+
+func (this *CachedResponse) varyMatches(r *http.Request) (bool) {
+	for key, values := range this.VaryHeaders {
+		if strings.Join(values, ",") != strings.Join(r.Header.Values(key), ",") { return false }
+	}
+	return true
+}
+
+func (this *CachedResponse) response(r *http.Request, now time.Time) (*http.Response) {
+	header := this.Header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(currentAge(this, now) / time.Second), 10))
+	return &http.Response{
+		Status: this.Status,
+		StatusCode: this.StatusCode,
+		Proto: this.Proto,
+		Header: header,
+		Body: ioutil.NopCloser(bytes.NewReader(this.Body)),
+		ContentLength: int64(len(this.Body)),
+		Request: r,
+	}
+}
+
+// returns middleware implementing the cache.
+func (this *HTTPCache) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if r.Method != string(GET) && r.Method != string(HEAD) {
+				if HttpVerb(r.Method).safe() { return next(r) }
+				return this.invalidating(next, r)
+			}
+			// stored responses are whole representations, which can't answer
+			// for a range of one, and ranges aren't worth storing
+			if r.Header.Get("Range") != "" { return next(r) }
+
+			reqCC := parseCacheControl(r.Header)
+			if len(reqCC) == 0 && r.Header.Get("Pragma") == "no-cache" {
+				reqCC["no-cache"] = ""
+			}
+
+			clock := requestClock(r)
+			now := clock.Now()
+			stored, ok := this.Storage.Get(cacheKey(r))
+			if ok && !stored.varyMatches(r) {
+				stored, ok = nil, false
+			}
+
+			if ok && this.fresh(stored, reqCC, now) {
+				return stored.response(r, now), nil
+			}
+			if reqCC.has("only-if-cached") {
+				return &http.Response{
+					Status: "504 Gateway Timeout",
+					StatusCode: http.StatusGatewayTimeout,
+					Header: http.Header{},
+					Body: ioutil.NopCloser(bytes.NewReader(nil)),
+					Request: r,
+				}, nil
+			}
+
+			// a stale response can still be reused if the server confirms it's unchanged
+			conditional := false
+			if ok && r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "" {
+				if etag := stored.Header.Get("ETag"); etag != "" {
+					r.Header.Set("If-None-Match", etag)
+					conditional = true
+				}
+				if lm := stored.Header.Get("Last-Modified"); lm != "" {
+					r.Header.Set("If-Modified-Since", lm)
+					conditional = true
+				}
+			}
+
+			requestTime := clock.Now()
+			resp, err := next(r)
+			if err != nil { return resp, err }
+			responseTime := clock.Now()
+
+			if conditional && resp.StatusCode == http.StatusNotModified {
+				resp.Body.Close()
+				updated := *stored
+				updated.Header = stored.Header.Clone()
+				for k, v := range resp.Header {
+					updated.Header[k] = v
+				}
+				updated.RequestTime = requestTime
+				updated.ResponseTime = responseTime
+				this.Storage.Set(cacheKey(r), &updated)
+				return updated.response(r, responseTime), nil
+			}
+
+			if r.Method != string(GET) || !this.storable(r, resp, reqCC) {
+				return resp, nil
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil { return nil, err }
+
+			c := CachedResponse{
+				StatusCode: resp.StatusCode,
+				Status: resp.Status,
+				Proto: resp.Proto,
+				Header: resp.Header.Clone(),
+				Body: body,
+				VaryHeaders: varyHeaders(r, resp.Header),
+				RequestTime: requestTime,
+				ResponseTime: responseTime,
+			}
+			this.Storage.Set(cacheKey(r), &c)
+
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		}
+	}
+}
+
+// reports whether a stored response can be used without revalidation.
+func (this *HTTPCache) fresh(c *CachedResponse, reqCC cacheControl, now time.Time) (bool) {
+	cc := parseCacheControl(c.Header)
+	if reqCC.has("no-cache") || cc.has("no-cache") { return false }
+
+	lifetime := this.freshnessLifetime(c)
+	if d, ok := reqCC.seconds("max-age"); ok && d < lifetime {
+		lifetime = d
+	}
+	age := currentAge(c, now)
+	if d, ok := reqCC.seconds("min-fresh"); ok {
+		age += d
+	}
+	if age < lifetime { return true }
+
+	// stale responses may be used if the client says so, unless the server forbids it
+	if cc.has("must-revalidate") || (this.Shared && cc.has("proxy-revalidate")) { return false }
+	if stale, ok := reqCC["max-stale"]; ok {
+		if stale == "" { return true }
+		d, valid := reqCC.seconds("max-stale")
+		return valid && age - lifetime < d
+	}
+	return false
+}
+
+// sends a request with an unsafe method, and invalidates the stored responses
+// it may have changed, per RFC 7234 section 4.4.
+func (this *HTTPCache) invalidating(next RoundTripFunc, r *http.Request) (*http.Response, error) {
+	resp, err := next(r)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 399 { return resp, err }
+
+	this.Storage.Delete(string(GET) + " " + r.URL.String())
+	for _, h := range []string{"Location", "Content-Location"} {
+		if loc := resp.Header.Get(h); loc != "" {
+			if u, err := r.URL.Parse(loc); err == nil && u.Host == r.URL.Host {
+				this.Storage.Delete(string(GET) + " " + u.String())
+			}
+		}
+	}
+	return resp, err
+}
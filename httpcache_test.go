@@ -0,0 +1,92 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+func TestHTTPCache(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		header := http.Header{}
+		switch req.URL.Path {
+		case "/fresh":
+			header.Set("Cache-Control", "max-age=60")
+			header.Set("Vary", "Accept")
+		case "/nostore":
+			header.Set("Cache-Control", "no-store, max-age=60")
+		case "/stale":
+			header.Set("Cache-Control", "max-age=0")
+			header.Set("ETag", `"x"`)
+			if req.Header.Get("If-None-Match") == `"x"` {
+				return &http.Response{StatusCode: 304, Header: header, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+			}
+		}
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader("body" + strconv.Itoa(calls)))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithHTTPCache(NewHTTPCache(nil)))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	fetch := func(req Request) (string, *http.Response) {
+		var text string
+		resp, err := req.Into(textCapture{into: &text}).Do()
+		if err != nil { t.Fatalf("Do Failure: %s", err.Error()) }
+		return text, resp
+	}
+
+	fetch(reqt.New("/fresh"))
+	if text, resp := fetch(reqt.New("/fresh")); text != "body1" || resp.Header.Get("Age") == "" {
+		t.Errorf("Fresh Mismatch: got %s (age %s), expected cached body1", text, resp.Header.Get("Age"))
+	}
+	if text, _ := fetch(reqt.New("/fresh").Header("Accept", "text/plain")); text != "body2" { t.Errorf("Vary Mismatch: got %s, expected body2", text) }
+	if text, _ := fetch(reqt.New("/fresh").Header("Accept", "text/plain").Header("Cache-Control", "no-cache")); text != "body3" { t.Errorf("No-Cache Mismatch: got %s, expected body3", text) }
+
+	fetch(reqt.New("/nostore"))
+	if text, _ := fetch(reqt.New("/nostore")); text != "body5" { t.Errorf("No-Store Mismatch: got %s, expected body5", text) }
+
+	fetch(reqt.New("/stale"))
+	if text, resp := fetch(reqt.New("/stale")); text != "body6" || resp.StatusCode != 200 || calls != 7 {
+		t.Errorf("Revalidate Mismatch: got %d %s after %d calls, expected 200 body6 after 7", resp.StatusCode, text, calls)
+	}
+
+	reqt.New("/fresh").Method(POST).Do()
+	if text, _ := fetch(reqt.New("/fresh").Header("Accept", "text/plain")); text != "body9" { t.Errorf("Invalidate Mismatch: got %s, expected body9", text) }
+}
+
+func TestHTTPCacheRange(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		header := http.Header{"Cache-Control": {"max-age=60"}}
+		if req.Header.Get("Range") == "bytes=0-0" {
+			header.Set("Content-Range", "bytes 0-0/5")
+			return &http.Response{StatusCode: 206, Header: header, Body: ioutil.NopCloser(strings.NewReader("b"))}, nil
+		}
+		return &http.Response{StatusCode: 200, Header: header, Body: ioutil.NopCloser(strings.NewReader("body" + strconv.Itoa(calls)))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithHTTPCache(NewHTTPCache(nil)))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	fetch := func(req Request) (string, int) {
+		var text string
+		resp, err := req.Into(textCapture{into: &text}).Do()
+		if err != nil { t.Fatalf("Do Failure: %s", err.Error()) }
+		return text, resp.StatusCode
+	}
+
+	if text, status := fetch(reqt.New("/file").Header("Range", "bytes=0-0")); text != "b" || status != 206 { t.Errorf("Range Mismatch: got %d %s", status, text) }
+	if text, status := fetch(reqt.New("/file")); text != "body2" || status != 200 { t.Errorf("Partial Stored: got %d %s, expected 200 body2", status, text) }
+	if text, status := fetch(reqt.New("/file").Header("Range", "bytes=0-0")); text != "b" || status != 206 || calls != 3 {
+		t.Errorf("Range Lookup Mismatch: got %d %s after %d calls, expected 206 b after 3", status, text, calls)
+	}
+	if text, _ := fetch(reqt.New("/file")); text != "body2" { t.Errorf("Fresh Mismatch: got %s, expected cached body2", text) }
+}
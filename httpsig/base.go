@@ -0,0 +1,213 @@
+package httpsig
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// derived component identifiers defined by RFC 9421 section 2.2.
+const (
+	Method        = "@method"
+	TargetURI     = "@target-uri"
+	Authority     = "@authority"
+	Scheme        = "@scheme"
+	RequestTarget = "@request-target"
+	Path          = "@path"
+	Query         = "@query"
+	QueryParam    = "@query-param"
+	Status        = "@status"
+)
+
+// a message is the view of an HTTP request or response needed to compute
+// component values. For responses, req is the request that produced it, if
+// known, so that components marked with the "req" parameter can be resolved.
+type message struct {
+	req    *http.Request
+	status int
+	header http.Header
+	isResp bool
+}
+
+func requestMessage(r *http.Request) (message) {
+	return message{req: r, header: r.Header}
+}
+
+func responseMessage(resp *http.Response) (message) {
+	return message{req: resp.Request, status: resp.StatusCode, header: resp.Header, isResp: true}
+}
+
+// parses a component name as written by a user, like "content-type" or
+// `"@query-param";name="id"`, into a component identifier.
+func parseComponent(s string) (paramString, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return paramString{value: strings.ToLower(s)}, nil
+	}
+
+	p := sfParser{input: s}
+	name, err := p.parseString()
+	if err != nil { return paramString{}, err }
+	ps, err := p.parseParams()
+	if err != nil { return paramString{}, err }
+	if !p.eof() { return paramString{}, ErrMalformedField }
+	return paramString{value: name, params: ps}, nil
+}
+
+// resolves the value of a single covered component against a message.
+func (this message) componentValue(c paramString) (string, error) {
+	if _, ok := c.params.get("req"); ok {
+		if !this.isResp || this.req == nil {
+			return "", fmt.Errorf("httpsig: component %s refers to a request which is not available", c.serialize())
+		}
+		inner := paramString{value: c.value}
+		for _, k := range c.params.keys {
+			if k != "req" { inner.params.set(k, c.params.values[k]) }
+		}
+		return requestMessage(this.req).componentValue(inner)
+	}
+
+	if !strings.HasPrefix(c.value, "@") {
+		return this.fieldValue(c.value)
+	}
+
+	if c.value == Status {
+		if !this.isResp {
+			return "", fmt.Errorf("httpsig: %s is only valid for responses", Status)
+		}
+		return strconv.Itoa(this.status), nil
+	}
+
+	if this.req == nil || this.isResp {
+		return "", fmt.Errorf("httpsig: %s is only valid for requests", c.value)
+	}
+
+	u := this.req.URL
+	switch c.value {
+	case Method:
+		return strings.ToUpper(this.req.Method), nil
+	case TargetURI:
+		return targetURI(this.req), nil
+	case Authority:
+		return authority(this.req), nil
+	case Scheme:
+		return strings.ToLower(scheme(this.req)), nil
+	case RequestTarget:
+		return u.RequestURI(), nil
+	case Path:
+		if u.EscapedPath() == "" { return "/", nil }
+		return u.EscapedPath(), nil
+	case Query:
+		return "?" + u.RawQuery, nil
+	case QueryParam:
+		name := c.params.str("name")
+		values, ok := u.Query()[name]
+		if !ok {
+			return "", fmt.Errorf("httpsig: query parameter %q not present", name)
+		}
+		if len(values) != 1 {
+			return "", fmt.Errorf("httpsig: query parameter %q appears more than once", name)
+		}
+		return percentEncode(values[0]), nil
+	}
+	return "", fmt.Errorf("httpsig: unknown derived component %s", c.value)
+}
+
+// returns the canonical value of a header field: all instances trimmed and
+// joined with a comma and a space.
+func (this message) fieldValue(name string) (string, error) {
+	values := append([]string(nil), this.header.Values(name)...)
+	if len(values) == 0 && !this.isResp && this.req != nil {
+		// net/http keeps some headers out of the header map on outgoing requests
+		switch name {
+		case "host":
+			values = []string{authority(this.req)}
+		case "content-length":
+			if this.req.ContentLength > 0 || this.req.Body == nil || this.req.Body == http.NoBody {
+				values = []string{strconv.FormatInt(this.req.ContentLength, 10)}
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("httpsig: header field %q not present", name)
+	}
+
+	for i := range values {
+		values[i] = strings.TrimSpace(values[i])
+	}
+	return strings.Join(values, ", "), nil
+}
+
+func scheme(r *http.Request) (string) {
+	if r.URL.Scheme != "" { return r.URL.Scheme }
+	if r.TLS != nil { return "https" }
+	return "http"
+}
+
+func authority(r *http.Request) (string) {
+	host := r.Host
+	if host == "" { host = r.URL.Host }
+	host = strings.ToLower(host)
+
+	// default ports are omitted from the authority
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		s := scheme(r)
+		if s == "http" && port == "80" || s == "https" && port == "443" {
+			host = h
+		}
+	}
+	return host
+}
+
+func targetURI(r *http.Request) (string) {
+	u := url.URL{
+		Scheme: scheme(r),
+		Host: authority(r),
+		Path: r.URL.Path,
+		RawPath: r.URL.RawPath,
+		RawQuery: r.URL.RawQuery,
+	}
+	return u.String()
+}
+
+// percent-encodes everything except the unreserved characters.
+func percentEncode(s string) (string) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// builds the signature base for a message over the given covered components,
+// returning it along with the serialized signature parameters.
+func signatureBase(m message, components []paramString, sigParams params) (string, string, error) {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, c := range components {
+		id := c.serialize()
+		if seen[id] {
+			return "", "", fmt.Errorf("httpsig: component %s is covered more than once", id)
+		}
+		seen[id] = true
+
+		value, err := m.componentValue(c)
+		if err != nil { return "", "", err }
+		b.WriteString(id)
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+
+	input := serializeInnerList(components, sigParams)
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(input)
+	return b.String(), input, nil
+}
@@ -0,0 +1,126 @@
+package httpsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+/*
+   Most of the fediverse (Mastodon, Pleroma, Misskey, etc) predates RFC 9421
+   and still expects signatures in the format described by
+   draft-cavage-http-signatures-12, so a signer for that format is provided
+   here as well. New integrations should prefer Signer.
+*/
+
+// the headers covered by a CavageSigner when none are specified. This is the
+// set Mastodon requires for signed POSTs.
+var DefaultCavageHeaders []string = []string{"(request-target)", "host", "date", "digest"}
+
+type CavageSigner struct {
+	KeyID   string   // the id of the key to sign with, usually the actor's key URL.
+	Keys    KeyProvider
+	Headers []string // the covered headers. Headers not present on a request are skipped.
+	Now     func() time.Time
+}
+
+// signs an outgoing request, adding a Signature header. A Date header is added
+// if there isn't one, and a Digest header is computed for requests with a body
+// if "digest" is covered. Headers which are absent, like Digest on a GET, are
+// left out of the signature.
+func (this *CavageSigner) SignRequest(r *http.Request) (error) {
+	key, err := this.Keys.Key(this.KeyID)
+	if err != nil { return err }
+
+	headers := this.Headers
+	if headers == nil { headers = DefaultCavageHeaders }
+
+	now := time.Now()
+	if this.Now != nil { now = this.Now() }
+	if r.Header.Get("Date") == "" {
+		r.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	}
+
+	var lines, covered []string
+	for _, h := range headers {
+		h = strings.ToLower(h)
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = authority(r)
+		case "digest":
+			if r.Header.Get("Digest") == "" {
+				if err := addLegacyDigest(r); err != nil { return err }
+			}
+			fallthrough
+		default:
+			values := r.Header.Values(h)
+			if len(values) == 0 { continue }
+			value = strings.Join(values, ", ")
+		}
+		lines = append(lines, h + ": " + strings.TrimSpace(value))
+		covered = append(covered, h)
+	}
+
+	var alg Algorithm
+	var name string
+	pub := key.Public
+	if key.Private != nil { pub = key.Private.Public() }
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		alg, name = RSAv15SHA256, "rsa-sha256"
+	case ed25519.PublicKey:
+		alg, name = Ed25519, "hs2019"
+	default:
+		return fmt.Errorf("httpsig: key %q can't be used for cavage signatures", this.KeyID)
+	}
+
+	sig, err := key.sign(alg, []byte(strings.Join(lines, "\n")))
+	if err != nil { return err }
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId=%s,algorithm="%s",headers="%s",signature="%s"`,
+		quoteString(this.KeyID), name, strings.Join(covered, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// returns middleware which signs every request passing through it.
+func (this *CavageSigner) Middleware() (reqtify.Middleware) {
+	return func(next reqtify.RoundTripFunc) reqtify.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if err := this.SignRequest(r); err != nil { return nil, err }
+			return next(r)
+		}
+	}
+}
+
+// computes an RFC 3230 Digest header, as used alongside cavage signatures.
+// the body is read into memory and replaced with a rewindable copy.
+func addLegacyDigest(r *http.Request) (error) {
+	if r.Body == nil || r.Body == http.NoBody { return nil }
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil { return err }
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	digest := sha256.Sum256(body)
+	r.Header.Set("Digest", "SHA-256=" + base64.StdEncoding.EncodeToString(digest[:]))
+	return nil
+}
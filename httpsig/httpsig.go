@@ -0,0 +1,284 @@
+// Package httpsig implements HTTP Message Signatures (RFC 9421) for reqtify.
+//
+// A Signer signs outgoing requests, and is typically installed on a
+// Reqtifier as middleware:
+//
+//	signer := &httpsig.Signer{KeyID: "my-key", Keys: keys, Components: httpsig.DefaultComponents}
+//	api := reqtify.New(root, nil, nil, nil, agent, reqtify.WithMiddleware(signer.Middleware()))
+//
+// A Verifier checks signatures on incoming requests or on responses.
+package httpsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+// the components covered by a Signer when none are specified.
+var DefaultComponents []string = []string{Method, TargetURI, "content-type", "content-digest"}
+
+var ErrNoSignature error = errors.New("httpsig: message is not signed")
+var ErrExpired error = errors.New("httpsig: signature has expired")
+var ErrDigestMismatch error = errors.New("httpsig: content digest does not match body")
+
+type Signer struct {
+	Label      string        // the signature label, "sig1" if empty.
+	KeyID      string        // the id of the key to sign with, looked up in Keys.
+	Keys       KeyProvider
+	Components []string      // the covered components. Header fields not present on a message are skipped.
+	Tag        string        // an application specific tag, omitted if empty.
+	Expires    time.Duration // the signature lifetime, unlimited if zero.
+	Nonce      func() string // generates a nonce for each signature, omitted if nil.
+	Now        func() time.Time
+}
+
+func (this *Signer) now() (time.Time) {
+	if this.Now != nil { return this.Now() }
+	return time.Now()
+}
+
+// signs an outgoing request, adding the Signature-Input and Signature headers.
+// If "content-digest" is a covered component and the request has a body but no
+// Content-Digest header, one is computed, which requires buffering the body.
+func (this *Signer) SignRequest(r *http.Request) (error) {
+	key, err := this.Keys.Key(this.KeyID)
+	if err != nil { return err }
+	alg, err := key.algorithm()
+	if err != nil { return err }
+
+	names := this.Components
+	if names == nil { names = DefaultComponents }
+
+	m := requestMessage(r)
+	var components []paramString
+	for _, name := range names {
+		c, err := parseComponent(name)
+		if err != nil { return err }
+
+		if c.value == "content-digest" && r.Header.Get("Content-Digest") == "" {
+			if err := addContentDigest(r); err != nil { return err }
+		}
+		// header fields which aren't present are silently left out, so a single
+		// component list can be used for requests both with and without bodies.
+		if !strings.HasPrefix(c.value, "@") {
+			if _, err := m.fieldValue(c.value); err != nil { continue }
+		}
+		components = append(components, c)
+	}
+
+	var p params
+	created := this.now()
+	p.set("created", created.Unix())
+	if this.Expires != 0 {
+		p.set("expires", created.Add(this.Expires).Unix())
+	}
+	if this.Nonce != nil {
+		p.set("nonce", this.Nonce())
+	}
+	p.set("keyid", key.ID)
+	if key.ID == "" { p.set("keyid", this.KeyID) }
+	p.set("alg", string(alg))
+	if this.Tag != "" {
+		p.set("tag", this.Tag)
+	}
+
+	base, input, err := signatureBase(m, components, p)
+	if err != nil { return err }
+	sig, err := key.sign(alg, []byte(base))
+	if err != nil { return err }
+
+	label := this.Label
+	if label == "" { label = "sig1" }
+	r.Header.Add("Signature-Input", label + "=" + input)
+	r.Header.Add("Signature", label + "=:" + base64.StdEncoding.EncodeToString(sig) + ":")
+	return nil
+}
+
+// returns middleware which signs every request passing through it.
+func (this *Signer) Middleware() (reqtify.Middleware) {
+	return func(next reqtify.RoundTripFunc) reqtify.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if err := this.SignRequest(r); err != nil { return nil, err }
+			return next(r)
+		}
+	}
+}
+
+// computes an RFC 9530 Content-Digest header for a request body. The body is
+// read into memory and replaced with a rewindable copy.
+func addContentDigest(r *http.Request) (error) {
+	if r.Body == nil || r.Body == http.NoBody { return nil }
+
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil { return err }
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	digest := sha256.Sum256(body)
+	r.Header.Set("Content-Digest", "sha-256=:" + base64.StdEncoding.EncodeToString(digest[:]) + ":")
+	return nil
+}
+
+type Verifier struct {
+	Keys     KeyProvider
+	Label    string        // if set, only the signature with this label is checked.
+	Required []string      // components which a signature must cover to be accepted.
+	MaxAge   time.Duration // if set, signatures created longer ago than this are rejected.
+	Tag      string        // if set, signatures must carry this tag.
+	Now      func() time.Time
+}
+
+func (this *Verifier) now() (time.Time) {
+	if this.Now != nil { return this.Now() }
+	return time.Now()
+}
+
+// verifies the signature on an incoming request. If the signature covers
+// Content-Digest, the body is read and checked against it, and replaced with
+// a copy so it can still be consumed afterwards.
+func (this *Verifier) VerifyRequest(r *http.Request) (error) {
+	return this.verify(requestMessage(r), &r.Body)
+}
+
+// verifies the signature on a response. resp.Request is used to resolve any
+// components that refer to the request which produced the response.
+func (this *Verifier) VerifyResponse(resp *http.Response) (error) {
+	return this.verify(responseMessage(resp), &resp.Body)
+}
+
+// returns middleware which verifies the signature on every response passing
+// through it, failing the request if it's missing or invalid.
+func (this *Verifier) Middleware() (reqtify.Middleware) {
+	return func(next reqtify.RoundTripFunc) reqtify.RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			resp, err := next(r)
+			if err != nil { return resp, err }
+			if resp.Request == nil { resp.Request = r }
+			if err := this.VerifyResponse(resp); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+			return resp, nil
+		}
+	}
+}
+
+func (this *Verifier) verify(m message, body *io.ReadCloser) (error) {
+	inputs, err := parseDictionary(strings.Join(m.header.Values("Signature-Input"), ", "))
+	if err != nil { return err }
+	sigs, err := parseDictionary(strings.Join(m.header.Values("Signature"), ", "))
+	if err != nil { return err }
+
+	checked := false
+	for label, input := range inputs {
+		if this.Label != "" && label != this.Label { continue }
+		sig, ok := sigs[label]
+		if !ok || !input.isList || sig.isList { continue }
+
+		if err := this.verifyOne(m, input, sig.bytes, body); err != nil {
+			return fmt.Errorf("signature %s: %w", label, err)
+		}
+		checked = true
+	}
+
+	if !checked { return ErrNoSignature }
+	return nil
+}
+
+func (this *Verifier) verifyOne(m message, input member, sig []byte, body *io.ReadCloser) (error) {
+	covered := make(map[string]bool)
+	for _, c := range input.list {
+		covered[c.serialize()] = true
+	}
+	for _, name := range this.Required {
+		c, err := parseComponent(name)
+		if err != nil { return err }
+		if !covered[c.serialize()] {
+			return fmt.Errorf("httpsig: required component %s is not covered", c.serialize())
+		}
+	}
+
+	if this.Tag != "" && input.params.str("tag") != this.Tag {
+		return fmt.Errorf("httpsig: signature tag %q not accepted", input.params.str("tag"))
+	}
+
+	now := this.now()
+	if expires, ok := input.params.get("expires"); ok {
+		if e, ok := expires.(int64); ok && now.Unix() > e { return ErrExpired }
+	}
+	if this.MaxAge != 0 {
+		created, ok := input.params.get("created")
+		c, isInt := created.(int64)
+		if !ok || !isInt || now.Sub(time.Unix(c, 0)) > this.MaxAge { return ErrExpired }
+	}
+
+	key, err := this.Keys.Key(input.params.str("keyid"))
+	if err != nil { return err }
+	alg := Algorithm(input.params.str("alg"))
+	if alg == "" {
+		alg, err = key.algorithm()
+		if err != nil { return err }
+	} else if key.Algorithm != "" && key.Algorithm != alg {
+		return fmt.Errorf("httpsig: key %q can't be used with %s", key.ID, alg)
+	}
+
+	base, _, err := signatureBase(m, input.list, input.params)
+	if err != nil { return err }
+	if err := key.verify(alg, []byte(base), sig); err != nil { return err }
+
+	if covered[`"content-digest"`] {
+		return verifyContentDigest(m.header.Get("Content-Digest"), body)
+	}
+	return nil
+}
+
+// checks a body against a Content-Digest header. Every recognized digest
+// must match, and at least one must be recognized.
+func verifyContentDigest(header string, body *io.ReadCloser) (error) {
+	digests, err := parseDictionary(header)
+	if err != nil { return err }
+
+	var data []byte
+	if *body != nil && *body != http.NoBody {
+		data, err = ioutil.ReadAll(*body)
+		(*body).Close()
+		*body = ioutil.NopCloser(bytes.NewReader(data))
+		if err != nil { return err }
+	}
+
+	recognized := false
+	for alg, digest := range digests {
+		var sum []byte
+		switch alg {
+		case "sha-256":
+			s := sha256.Sum256(data)
+			sum = s[:]
+		case "sha-512":
+			s := sha512.Sum512(data)
+			sum = s[:]
+		default:
+			continue
+		}
+		recognized = true
+		if !bytes.Equal(sum, digest.bytes) { return ErrDigestMismatch }
+	}
+
+	if !recognized { return fmt.Errorf("%w: no supported digest algorithm", ErrDigestMismatch) }
+	return nil
+}
@@ -0,0 +1,166 @@
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+type Algorithm string
+
+// signature algorithms registered by RFC 9421 section 6.2.2.
+const (
+	RSAPSSSHA512    Algorithm = "rsa-pss-sha512"
+	RSAv15SHA256    Algorithm = "rsa-v1_5-sha256"
+	HMACSHA256      Algorithm = "hmac-sha256"
+	ECDSAP256SHA256 Algorithm = "ecdsa-p256-sha256"
+	ECDSAP384SHA384 Algorithm = "ecdsa-p384-sha384"
+	Ed25519         Algorithm = "ed25519"
+)
+
+var ErrUnknownKey error = errors.New("httpsig: unknown key")
+var ErrBadSignature error = errors.New("httpsig: signature verification failed")
+
+// a Key is the key material associated with a key id. Signing requires
+// Private (or Secret, for HMAC); verification requires Public (or Secret).
+// Algorithm may be left empty when signing, in which case it is inferred
+// from the key type.
+type Key struct {
+	ID        string
+	Algorithm Algorithm
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	Secret    []byte
+}
+
+// a KeyProvider looks up keys by key id. It is consulted when signing, to
+// find the key to sign with, and when verifying, to find the key named by
+// the keyid parameter of a signature.
+type KeyProvider interface {
+	Key(keyID string) (*Key, error)
+}
+
+// a KeyProvider backed by a fixed set of keys, indexed by key id.
+type StaticKeys map[string]*Key
+
+func (this StaticKeys) Key(keyID string) (*Key, error) {
+	if key, ok := this[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+}
+
+// returns the algorithm to use with this key, inferring it from the key
+// type if one wasn't specified.
+func (this *Key) algorithm() (Algorithm, error) {
+	if this.Algorithm != "" { return this.Algorithm, nil }
+	if this.Secret != nil { return HMACSHA256, nil }
+
+	pub := this.Public
+	if this.Private != nil { pub = this.Private.Public() }
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return RSAPSSSHA512, nil
+	case ed25519.PublicKey:
+		return Ed25519, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return ECDSAP256SHA256, nil
+		case elliptic.P384():
+			return ECDSAP384SHA384, nil
+		}
+	}
+	return "", fmt.Errorf("httpsig: can't infer algorithm for key %q", this.ID)
+}
+
+func (this *Key) sign(alg Algorithm, base []byte) ([]byte, error) {
+	switch alg {
+	case HMACSHA256:
+		if this.Secret == nil { break }
+		mac := hmac.New(sha256.New, this.Secret)
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	case Ed25519:
+		if this.Private == nil { break }
+		return this.Private.Sign(rand.Reader, base, crypto.Hash(0))
+	case RSAPSSSHA512:
+		if this.Private == nil { break }
+		digest := sha512.Sum512(base)
+		return this.Private.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	case RSAv15SHA256:
+		if this.Private == nil { break }
+		digest := sha256.Sum256(base)
+		return this.Private.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		key, ok := this.Private.(*ecdsa.PrivateKey)
+		if !ok { break }
+		digest, size := ecdsaDigest(alg, base)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil { return nil, err }
+		// RFC 9421 uses the fixed width r || s encoding, not ASN.1
+		sig := make([]byte, 2 * size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	default:
+		return nil, fmt.Errorf("httpsig: unsupported algorithm %q", alg)
+	}
+	return nil, fmt.Errorf("httpsig: key %q can't be used to sign with %s", this.ID, alg)
+}
+
+func (this *Key) verify(alg Algorithm, base, sig []byte) (error) {
+	pub := this.Public
+	if pub == nil && this.Private != nil { pub = this.Private.Public() }
+
+	ok := false
+	switch alg {
+	case HMACSHA256:
+		if this.Secret == nil { return fmt.Errorf("httpsig: key %q has no secret", this.ID) }
+		mac := hmac.New(sha256.New, this.Secret)
+		mac.Write(base)
+		ok = hmac.Equal(mac.Sum(nil), sig)
+	case Ed25519:
+		key, isKey := pub.(ed25519.PublicKey)
+		ok = isKey && ed25519.Verify(key, base, sig)
+	case RSAPSSSHA512:
+		key, isKey := pub.(*rsa.PublicKey)
+		digest := sha512.Sum512(base)
+		ok = isKey && rsa.VerifyPSS(key, crypto.SHA512, digest[:], sig, &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}) == nil
+	case RSAv15SHA256:
+		key, isKey := pub.(*rsa.PublicKey)
+		digest := sha256.Sum256(base)
+		ok = isKey && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case ECDSAP256SHA256, ECDSAP384SHA384:
+		key, isKey := pub.(*ecdsa.PublicKey)
+		digest, size := ecdsaDigest(alg, base)
+		if isKey && len(sig) == 2 * size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(key, digest, r, s)
+		}
+	default:
+		return fmt.Errorf("httpsig: unsupported algorithm %q", alg)
+	}
+
+	if !ok { return ErrBadSignature }
+	return nil
+}
+
+func ecdsaDigest(alg Algorithm, base []byte) ([]byte, int) {
+	if alg == ECDSAP384SHA384 {
+		digest := sha512.Sum384(base)
+		return digest[:], 48
+	}
+	digest := sha256.Sum256(base)
+	return digest[:], 32
+}
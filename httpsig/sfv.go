@@ -0,0 +1,286 @@
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+   A minimal implementation of the parts of RFC 8941 (Structured Field Values)
+   needed by HTTP Message Signatures: dictionaries whose members are either
+   inner lists of strings or byte sequences, each carrying parameters whose
+   values are strings, tokens, integers or booleans.
+*/
+
+var ErrMalformedField error = errors.New("httpsig: malformed structured field")
+
+// an ordered set of parameters attached to an item or inner list.
+type params struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (this *params) set(key string, value interface{}) {
+	if this.values == nil {
+		this.values = make(map[string]interface{})
+	}
+	if _, ok := this.values[key]; !ok {
+		this.keys = append(this.keys, key)
+	}
+	this.values[key] = value
+}
+
+func (this *params) get(key string) (interface{}, bool) {
+	v, ok := this.values[key]
+	return v, ok
+}
+
+func (this *params) str(key string) (string) {
+	v, _ := this.values[key].(string)
+	return v
+}
+
+func (this *params) serialize() (string) {
+	var b strings.Builder
+	for _, k := range this.keys {
+		b.WriteByte(';')
+		b.WriteString(k)
+		switch v := this.values[k].(type) {
+		case bool:
+			if !v { b.WriteString("=?0") }
+		case int64:
+			b.WriteByte('=')
+			b.WriteString(strconv.FormatInt(v, 10))
+		case string:
+			b.WriteByte('=')
+			b.WriteString(quoteString(v))
+		case token:
+			b.WriteByte('=')
+			b.WriteString(string(v))
+		}
+	}
+	return b.String()
+}
+
+// a bare token value, serialized without quotes.
+type token string
+
+// a string item with parameters, used for component identifiers.
+type paramString struct {
+	value  string
+	params params
+}
+
+func (this paramString) serialize() (string) {
+	return quoteString(this.value) + this.params.serialize()
+}
+
+// a member of a dictionary. exactly one of list or bytes is meaningful.
+type member struct {
+	list    []paramString
+	isList  bool
+	bytes   []byte
+	params  params
+}
+
+func serializeInnerList(list []paramString, p params) (string) {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, item := range list {
+		if i != 0 { b.WriteByte(' ') }
+		b.WriteString(item.serialize())
+	}
+	b.WriteByte(')')
+	b.WriteString(p.serialize())
+	return b.String()
+}
+
+func quoteString(s string) (string) {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+type sfParser struct {
+	input string
+	pos   int
+}
+
+func (this *sfParser) eof() (bool) {
+	return this.pos >= len(this.input)
+}
+
+func (this *sfParser) peek() (byte) {
+	if this.eof() { return 0 }
+	return this.input[this.pos]
+}
+
+func (this *sfParser) skipSP() {
+	for !this.eof() && (this.peek() == ' ' || this.peek() == '\t') {
+		this.pos++
+	}
+}
+
+func isKeyChar(c byte) (bool) {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+func isTokenChar(c byte) (bool) {
+	return c > ' ' && c < 0x7f && !strings.ContainsRune(`"(),;<=>?@[\]{}`, rune(c))
+}
+
+func (this *sfParser) parseKey() (string, error) {
+	start := this.pos
+	if c := this.peek(); !(c >= 'a' && c <= 'z' || c == '*') {
+		return "", ErrMalformedField
+	}
+	for !this.eof() && isKeyChar(this.peek()) {
+		this.pos++
+	}
+	return this.input[start:this.pos], nil
+}
+
+func (this *sfParser) parseString() (string, error) {
+	if this.peek() != '"' { return "", ErrMalformedField }
+	this.pos++
+	var b strings.Builder
+	for !this.eof() {
+		c := this.input[this.pos]
+		this.pos++
+		switch c {
+		case '\\':
+			if this.eof() { return "", ErrMalformedField }
+			c = this.input[this.pos]
+			this.pos++
+			if c != '"' && c != '\\' { return "", ErrMalformedField }
+			b.WriteByte(c)
+		case '"':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", ErrMalformedField
+}
+
+func (this *sfParser) parseBareItem() (interface{}, error) {
+	c := this.peek()
+	switch {
+	case c == '"':
+		return this.parseString()
+	case c == '-' || c >= '0' && c <= '9':
+		start := this.pos
+		this.pos++
+		for !this.eof() && this.peek() >= '0' && this.peek() <= '9' {
+			this.pos++
+		}
+		return strconv.ParseInt(this.input[start:this.pos], 10, 64)
+	case c == '?':
+		this.pos++
+		switch this.peek() {
+		case '0':
+			this.pos++
+			return false, nil
+		case '1':
+			this.pos++
+			return true, nil
+		}
+		return nil, ErrMalformedField
+	case c == ':':
+		this.pos++
+		end := strings.IndexByte(this.input[this.pos:], ':')
+		if end < 0 { return nil, ErrMalformedField }
+		data, err := base64.StdEncoding.DecodeString(this.input[this.pos:this.pos+end])
+		this.pos += end + 1
+		return data, err
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '*':
+		start := this.pos
+		for !this.eof() && isTokenChar(this.peek()) {
+			this.pos++
+		}
+		return token(this.input[start:this.pos]), nil
+	}
+	return nil, ErrMalformedField
+}
+
+func (this *sfParser) parseParams() (params, error) {
+	var p params
+	for this.peek() == ';' {
+		this.pos++
+		this.skipSP()
+		key, err := this.parseKey()
+		if err != nil { return p, err }
+		var value interface{} = true
+		if this.peek() == '=' {
+			this.pos++
+			value, err = this.parseBareItem()
+			if err != nil { return p, err }
+		}
+		if t, ok := value.(token); ok {
+			value = string(t)
+		}
+		p.set(key, value)
+	}
+	return p, nil
+}
+
+func (this *sfParser) parseMember() (member, error) {
+	var m member
+	if this.peek() == '(' {
+		this.pos++
+		m.isList = true
+		for {
+			this.skipSP()
+			if this.peek() == ')' {
+				this.pos++
+				break
+			}
+			s, err := this.parseString()
+			if err != nil { return m, err }
+			p, err := this.parseParams()
+			if err != nil { return m, err }
+			m.list = append(m.list, paramString{value: s, params: p})
+			if c := this.peek(); c != ' ' && c != ')' {
+				return m, ErrMalformedField
+			}
+		}
+	} else {
+		item, err := this.parseBareItem()
+		if err != nil { return m, err }
+		data, ok := item.([]byte)
+		if !ok { return m, ErrMalformedField }
+		m.bytes = data
+	}
+
+	var err error
+	m.params, err = this.parseParams()
+	return m, err
+}
+
+// parses a dictionary structured field. Only the member types used by
+// Signature-Input and Signature are understood; anything else is an error.
+func parseDictionary(field string) (map[string]member, error) {
+	result := make(map[string]member)
+	p := sfParser{input: field}
+	p.skipSP()
+	for !p.eof() {
+		key, err := p.parseKey()
+		if err != nil { return nil, err }
+		if p.peek() != '=' {
+			return nil, fmt.Errorf("%w: dictionary member %q has no value", ErrMalformedField, key)
+		}
+		p.pos++
+		m, err := p.parseMember()
+		if err != nil { return nil, err }
+		result[key] = m
+
+		p.skipSP()
+		if p.eof() { break }
+		if p.peek() != ',' { return nil, ErrMalformedField }
+		p.pos++
+		p.skipSP()
+		if p.eof() { return nil, ErrMalformedField }
+	}
+	return result, nil
+}
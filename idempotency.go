@@ -0,0 +1,38 @@
+package reqtify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// the header APIs like Stripe's read idempotency keys from.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// sets the request's idempotency key, so that the API it's sent to can tell
+// a retry from a new request, and only act on it once. This makes it safe
+// to retry, and to queue in an Outbox, even if it's a POST.
+func (this *RequestImpl) IdempotencyKey(key string) (Request) {
+	return this.Header(IdempotencyKeyHeader, key)
+}
+
+// sets the request's idempotency key to a hash of v's canonical JSON, so that
+// the same content always gets the same key, even from another process, and
+// whether it's a struct or a map. See CanonicalJSON.
+func (this *RequestImpl) IdempotencyKeyFrom(v interface{}) (Request) {
+	data, err := MarshalCanonicalJSON(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	sum := sha256.Sum256(data)
+	return this.IdempotencyKey(hex.EncodeToString(sum[:]))
+}
+
+// gives a request which might be retried an idempotency key, if its policy
+// asks for one and it isn't idempotent already. Every attempt sends the
+// same key.
+func (this *ReqtifierImpl) autoIdempotencyKey(req *RequestImpl, policy *RetryPolicy) {
+	if policy == nil || !policy.IdempotencyKeys || policy.attempts() <= 1 { return }
+	if idempotent(req.Verb, req.Headers) { return }
+	req.IdempotencyKey(this.NewID())
+}
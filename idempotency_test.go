@@ -0,0 +1,49 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var keys []string
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		if calls % 2 == 1 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithIDGenerator(IDGeneratorFunc(func() string { return "generated" })))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	policy := RetryPolicy{MaxAttempts: 2, IdempotencyKeys: true}
+
+	// generated keys are the same for every attempt
+	reqt.New("/charges").Method(POST).Retry(policy).Do()
+	if len(keys) != 2 || keys[0] != "generated" || keys[1] != "generated" { t.Errorf("Generated Mismatch: got %q", keys) }
+
+	// explicit keys are kept
+	keys = nil
+	reqt.New("/charges").Method(POST).IdempotencyKey("mine").Retry(policy).Do()
+	if len(keys) != 2 || keys[0] != "mine" || keys[1] != "mine" { t.Errorf("Explicit Mismatch: got %q", keys) }
+
+	// derived keys depend only on the content
+	keys = nil
+	reqt.New("/charges").Method(POST).IdempotencyKeyFrom(map[string]interface{}{"amount": 100, "currency": "usd"}).Retry(policy).Do()
+	reqt.New("/charges").Method(POST).IdempotencyKeyFrom(struct{Currency string `json:"currency"`; Amount float64 `json:"amount"`}{"usd", 100.0}).Retry(policy).Do()
+	if len(keys) != 4 || len(keys[0]) != 64 || keys[1] != keys[0] || keys[2] != keys[0] || keys[3] != keys[0] { t.Errorf("Derived Mismatch: got %q", keys) }
+
+	// idempotent methods, and requests which won't be retried, don't get one
+	keys = nil
+	reqt.New("/charges").Retry(policy).Do()
+	reqt.New("/charges").Method(POST).Retry(RetryPolicy{MaxAttempts: 1, IdempotencyKeys: true}).Do()
+	reqt.New("/charges").Method(POST).Retry(RetryPolicy{MaxAttempts: 2}).Do()
+	for _, key := range keys {
+		if key != "" { t.Errorf("Unneeded Mismatch: got %q", keys) }
+	}
+}
@@ -0,0 +1,163 @@
+package reqtify
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// an IDGenerator produces unique identifiers, for correlation headers,
+// idempotency keys, JSON-RPC ids, and anything else reqtify needs to label.
+// Implementations must be safe for concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// adapts an ordinary function to an IDGenerator.
+type IDGeneratorFunc func() string
+
+func (this IDGeneratorFunc) NewID() string {
+	return this()
+}
+
+// generates random (version 4) UUIDs, like "0b4f0c9e-3a1d-4c2f-9a8e-5d6b7c8d9e0f",
+// from Random, or crypto/rand if it's nil.
+type UUIDv4 struct{
+	Random *Random
+}
+
+func (this UUIDv4) NewID() string {
+	var u [16]byte
+	randomBytes(this.Random, u[:])
+	return formatUUID(u, 4)
+}
+
+// generates time ordered (version 7) UUIDs, which sort by creation time and
+// index well in databases. Their random bits come from Random, or crypto/rand
+// if it's nil, and their time from Clock, or the system clock if it's nil.
+type UUIDv7 struct{
+	Random *Random
+	Clock  Clock
+}
+
+func (this UUIDv7) NewID() string {
+	var u [16]byte
+	randomBytes(this.Random, u[6:])
+	putMillis48(u[:], clockNow(this.Clock))
+	return formatUUID(u, 7)
+}
+
+// fills b from random. Identifiers mustn't repeat, so if there's no
+// randomness to be had, it panics rather than returning a predictable one.
+func randomBytes(random *Random, b []byte) {
+	if _, err := random.Read(b); err != nil { panic("reqtify: can't generate a random identifier: " + err.Error()) }
+}
+
+func clockNow(clock Clock) (time.Time) {
+	if clock == nil { return time.Now() }
+	return clock.Now()
+}
+
+func putMillis48(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixNano() / int64(time.Millisecond)))
+	copy(b[:6], ms[2:])
+}
+
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6] & 0x0f | version << 4
+	u[8] = u[8] & 0x3f | 0x80
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// generates ULIDs, 26 character time ordered identifiers in Crockford's base32,
+// like "01ARZ3NDEKTSV4RRFFQ69G5FAV", from Random and Clock as UUIDv7 does.
+type ULID struct{
+	Random *Random
+	Clock  Clock
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (this ULID) NewID() string {
+	var u [16]byte
+	randomBytes(this.Random, u[6:])
+	putMillis48(u[:], clockNow(this.Clock))
+
+	// 128 bits as 26 base32 digits, the first of which only holds 3 bits
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo & 31]
+		lo = lo >> 5 | hi << 59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// generates snowflake ids: 64 bit integers made of a millisecond timestamp,
+// a node number, and a sequence number, which sort by creation time and are
+// unique across up to 1024 nodes, so long as each has its own Node.
+type Snowflake struct {
+	Node  int64     // the node number, from 0 to 1023.
+	Epoch time.Time // the zero point of the timestamp. Defaults to the Unix epoch.
+
+	lock     sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// creates a Snowflake generator for the given node.
+func NewSnowflake(node int64) (*Snowflake) {
+	return &Snowflake{Node: node}
+}
+
+func (this *Snowflake) NewID() string {
+	return strconv.FormatInt(this.Next(), 10)
+}
+
+// returns the next id as an integer.
+func (this *Snowflake) Next() (int64) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	now := this.millis()
+	if now < this.last { now = this.last } // don't go backwards if the clock does
+	if now == this.last {
+		this.sequence = (this.sequence + 1) & 0xfff
+		if this.sequence == 0 {
+			// out of sequence numbers for this millisecond, wait for the next one
+			for now <= this.last {
+				time.Sleep(100 * time.Microsecond)
+				now = this.millis()
+			}
+		}
+	} else {
+		this.sequence = 0
+	}
+	this.last = now
+	return (now & (1 << 41 - 1)) << 22 | (this.Node & 0x3ff) << 12 | this.sequence
+}
+
+func (this *Snowflake) millis() (int64) {
+	if this.Epoch.IsZero() { return time.Now().UnixNano() / int64(time.Millisecond) }
+	return int64(time.Since(this.Epoch) / time.Millisecond)
+}
+
+// sets the generator used for identifiers reqtify creates. The default
+// generates random UUIDs, from the Reqtifier's Random if it has one.
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(r *ReqtifierImpl) {
+		r.IDGenerator = gen
+	}
+}
+
+// returns a new identifier from the Reqtifier's IDGenerator.
+func (this *ReqtifierImpl) NewID() (string) {
+	if this.IDGenerator == nil { return UUIDv4{Random: this.Random}.NewID() }
+	return this.IDGenerator.NewID()
+}
@@ -0,0 +1,46 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+func TestIDGenerators(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for version, gen := range map[string]IDGenerator{"4": UUIDv4{}, "7": UUIDv7{}} {
+		id := gen.NewID()
+		if m := uuid.FindStringSubmatch(id); m == nil || m[1] != version {
+			t.Errorf("UUID Mismatch: got %q, expected version %s", id, version)
+		}
+	}
+
+	ulid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	if id := (ULID{}).NewID(); !ulid.MatchString(id) { t.Errorf("ULID Mismatch: got %q", id) }
+
+	gen := NewSnowflake(5)
+	var last int64
+	for i := 0; i < 5000; i++ {
+		id, err := strconv.ParseInt(gen.NewID(), 10, 64)
+		if err != nil || id <= last { t.Fatalf("Snowflake Mismatch: got %d after %d", id, last) }
+		if id >> 12 & 0x3ff != 5 { t.Fatalf("Node Mismatch: got %d", id >> 12 & 0x3ff) }
+		last = id
+	}
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithIDGenerator(IDGeneratorFunc(func() string { return "fixed" })))
+	if id := reqt.(*ReqtifierImpl).NewID(); id != "fixed" { t.Errorf("Generator Mismatch: got %q", id) }
+}
+
+func TestIDGeneratorsDeterministic(t *testing.T) {
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	for name, gen := range map[string]func() IDGenerator{
+		"UUIDv7": func() IDGenerator { return UUIDv7{Random: NewRandom(1), Clock: clock} },
+		"ULID": func() IDGenerator { return ULID{Random: NewRandom(1), Clock: clock} },
+	} {
+		if a, b := gen().NewID(), gen().NewID(); a != b { t.Errorf("%s Mismatch: got %q and %q from the same seed and time", name, a, b) }
+	}
+	if id := (ULID{Random: NewRandom(1), Clock: clock}).NewID(); id[:10] != "01DXF6DT00" { t.Errorf("ULID Time Mismatch: got %q", id) }
+	if id := (UUIDv7{Random: NewRandom(1), Clock: clock}).NewID(); id[:13] != "016f5e66-e800" { t.Errorf("UUIDv7 Time Mismatch: got %q", id) }
+}
@@ -0,0 +1,85 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+func TestIntegrationRetry(t *testing.T) {
+	server := test.NewServer(test.FailFirst(2, http.StatusServiceUnavailable))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test")
+	var echo test.EchoResponse
+	resp, err := reqt.New("/retry").Method(POST).Arg("a", "b").Retry(RetryPolicy{MaxAttempts: 3}).RetryNonIdempotent().JSONInto(&echo).Do()
+	if err != nil || resp.StatusCode != 200 { t.Fatalf("Retry Failure: %v", err) }
+	if server.Count() != 3 || echo.Method != "POST" || echo.Path != "/retry" || echo.Body != "a=b" { t.Errorf("Retry Mismatch: %d requests, got %+v", server.Count(), echo) }
+
+	dropped := test.NewServer(test.Drop(1, 0))
+	defer dropped.Close()
+	_, err = New(dropped.URL, nil, nil, nil, "test").New("/").Retry(RetryPolicy{MaxAttempts: 2}).Do()
+	if err == nil || dropped.Count() != 2 { t.Errorf("Drop Mismatch: got %v after %d requests", err, dropped.Count()) }
+}
+
+func TestIntegrationTimeouts(t *testing.T) {
+	slow := test.NewServer(test.Delay(time.Second))
+	defer slow.Close()
+
+	_, err := New(slow.URL, nil, nil, nil, "test").New("/").Timeout(50 * time.Millisecond).Do()
+	if !errors.Is(err, context.DeadlineExceeded) { t.Errorf("Timeout Mismatch: got %v", err) }
+
+	trickle := test.NewServer(test.Trickle(2, 20 * time.Millisecond), test.Respond(200, "text/plain", []byte("0123456789")))
+	defer trickle.Close()
+	reqt := New(trickle.URL, nil, nil, nil, "test")
+
+	var text string
+	_, err = reqt.New("/").IdleTimeout(time.Second).TextInto(&text).Do()
+	if err != nil || text != "0123456789" { t.Errorf("Stream Mismatch: got %q, %v", text, err) }
+
+	resp, err := reqt.New("/").IdleTimeout(5 * time.Millisecond).Do()
+	if err == nil {
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	var stall *StallError
+	if !errors.As(err, &stall) { t.Errorf("Stall Mismatch: got %v", err) }
+}
+
+func TestDoAsync(t *testing.T) {
+	server := test.NewServer(test.Delay(50 * time.Millisecond))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	var a, b test.EchoResponse
+	fa := reqt.New("/a").JSONInto(&a).DoAsync()
+	fb := reqt.New("/b").JSONInto(&b).DoAsync()
+	if _, ok := fa.Result(); ok { t.Errorf("Result Mismatch: finished early") }
+
+	select {
+	case <-fb.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Done Mismatch: never finished")
+	}
+	if r, ok := fb.Result(); !ok || r.Err != nil || r.Response.StatusCode != 200 || b.Path != "/b" { t.Errorf("Result Mismatch: got %+v, %+v", r, b) }
+	if resp, err := fa.Wait(); err != nil || resp.StatusCode != 200 || a.Path != "/a" { t.Errorf("Wait Mismatch: got %v, %+v", err, a) }
+}
+
+func TestIntegrationFaults(t *testing.T) {
+	truncated := test.NewServer(test.Truncate(5), test.Respond(200, "text/plain", []byte("0123456789")))
+	defer truncated.Close()
+	var text string
+	_, err := New(truncated.URL, nil, nil, nil, "test").New("/").TextInto(&text).Do()
+	if !errors.Is(err, io.ErrUnexpectedEOF) { t.Errorf("Truncate Mismatch: got %q, %v", text, err) }
+
+	reset := test.NewServer(test.Reset(1, 0))
+	defer reset.Close()
+	_, err = New(reset.URL, nil, nil, nil, "test").New("/").Do()
+	if !errors.Is(err, syscall.ECONNRESET) { t.Errorf("Reset Mismatch: got %v", err) }
+}
@@ -0,0 +1,49 @@
+// Package exchange pairs the replies given to a mock's examiner with the
+// requests waiting for them. It's shared by the test and mock packages, so
+// both match replies to requests the same way.
+package exchange
+
+import (
+	"sync"
+)
+
+// how many requests and replies an examiner's channels hold before blocking.
+const Buffer = 64
+
+// pairs the replies an examiner is given with the requests waiting for them,
+// oldest first. While any request is waiting, a single goroutine reads the
+// replies and hands each to the oldest. The zero value is ready to use.
+type Queue[T any] struct {
+	lock       sync.Mutex
+	pending    []chan T
+	delivering bool
+}
+
+// queues a reply channel for a request, and sends it with send while the
+// queue is locked, so the order requests are read in matches the queue.
+func (this *Queue[T]) Push(send func(), replies <-chan T) (chan T) {
+	mine := make(chan T, 1)
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.pending = append(this.pending, mine)
+	send()
+	if !this.delivering {
+		this.delivering = true
+		go this.deliver(replies)
+	}
+	return mine
+}
+
+func (this *Queue[T]) deliver(replies <-chan T) {
+	for reply := range replies {
+		this.lock.Lock()
+		this.pending[0] <- reply
+		this.pending = this.pending[1:]
+		if len(this.pending) == 0 {
+			this.delivering = false
+			this.lock.Unlock()
+			return
+		}
+		this.lock.Unlock()
+	}
+}
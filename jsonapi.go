@@ -0,0 +1,341 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+   JSON:API (https://jsonapi.org) documents wrap each resource in a type, an
+   id, attributes and relationships, with related resources in a separate
+   "included" list. JSONAPIInto and JSONAPIBody translate between them and
+   ordinary structs, tagged to say which field is which:
+
+	type Article struct {
+		ID     string  `jsonapi:"primary,articles"`
+		Title  string  `jsonapi:"attr,title"`
+		Tags []string  `jsonapi:"attr,tags,omitempty"`
+		Author *Person `jsonapi:"relation,author"`
+	}
+
+   Decoding fills in related resources from "included" where they're present,
+   and only their ids where not. Encoding writes relationships as resource
+   identifiers only, without an "included" list.
+*/
+
+const JSONAPIContentType = "application/vnd.api+json"
+
+// an error object from a JSON:API document.
+type JSONAPIError struct {
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	Source struct {
+		Pointer   string `json:"pointer,omitempty"`
+		Parameter string `json:"parameter,omitempty"`
+	} `json:"source"`
+}
+
+func (this JSONAPIError) Error() (string) {
+	msg := this.Title
+	if this.Detail != "" { msg = this.Detail }
+	if msg == "" { msg = this.Code }
+	if this.Source.Pointer != "" { msg = this.Source.Pointer + ": " + msg }
+	return msg
+}
+
+// the errors in a JSON:API document.
+type JSONAPIErrors []JSONAPIError
+
+func (this JSONAPIErrors) Error() (string) {
+	if len(this) == 1 { return "jsonapi: " + this[0].Error() }
+	return fmt.Sprintf("jsonapi: %s (and %d more errors)", this[0].Error(), len(this) - 1)
+}
+
+func (this JSONAPIErrors) Unwrap() ([]error) {
+	errs := make([]error, len(this))
+	for i, e := range this {
+		errs[i] = e
+	}
+	return errs
+}
+
+type jsonAPIDocument struct {
+	Data     json.RawMessage     `json:"data,omitempty"`
+	Included []*jsonAPIResource  `json:"included,omitempty"`
+	Errors   JSONAPIErrors       `json:"errors,omitempty"`
+}
+
+type jsonAPIResource struct {
+	Type          string                            `json:"type"`
+	ID            string                            `json:"id,omitempty"`
+	Attributes    map[string]json.RawMessage        `json:"attributes,omitempty"`
+	Relationships map[string]jsonAPIRelationship    `json:"relationships,omitempty"`
+}
+
+type jsonAPIRelationship struct {
+	Data json.RawMessage `json:"data"`
+}
+
+type jsonAPIField struct {
+	index     int
+	kind      string // primary, attr or relation
+	name      string // the resource type, for primary fields
+	omitEmpty bool
+}
+
+func jsonAPIFields(t reflect.Type) ([]jsonAPIField, error) {
+	var fields []jsonAPIField
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("jsonapi")
+		if !ok { continue }
+		parts := strings.Split(tag, ",")
+		f := jsonAPIField{index: i, kind: parts[0]}
+		if len(parts) < 2 || (f.kind != "primary" && f.kind != "attr" && f.kind != "relation") {
+			return nil, fmt.Errorf("jsonapi: bad tag %q on %s.%s", tag, t.Name(), t.Field(i).Name)
+		}
+		f.name = parts[1]
+		f.omitEmpty = len(parts) > 2 && parts[2] == "omitempty"
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// the struct type a value holds, through pointers.
+func jsonAPIStruct(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
+
+// marshals v, a struct or a slice of them, as a JSON:API document.
+func MarshalJSONAPI(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() != reflect.Struct {
+		rv = rv.Elem()
+	}
+
+	var data interface{}
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		list := make([]*jsonAPIResource, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			r, err := encodeJSONAPIResource(rv.Index(i), true)
+			if err != nil { return nil, err }
+			list = append(list, r)
+		}
+		data = list
+	} else {
+		r, err := encodeJSONAPIResource(rv, true)
+		if err != nil { return nil, err }
+		data = r
+	}
+	return json.Marshal(map[string]interface{}{"data": data})
+}
+
+// encodes a resource, or only its identifier unless full is set.
+func encodeJSONAPIResource(v reflect.Value, full bool) (*jsonAPIResource, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() { return nil, nil }
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct { return nil, fmt.Errorf("jsonapi: can't encode %s as a resource", v.Type()) }
+	fields, err := jsonAPIFields(v.Type())
+	if err != nil { return nil, err }
+
+	r := &jsonAPIResource{}
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		switch {
+		case f.kind == "primary":
+			r.Type = f.name
+			if !fv.IsZero() { r.ID = fmt.Sprint(fv.Interface()) }
+		case !full || (f.omitEmpty && fv.IsZero()):
+		case f.kind == "attr":
+			raw, err := json.Marshal(fv.Interface())
+			if err != nil { return nil, err }
+			if r.Attributes == nil { r.Attributes = make(map[string]json.RawMessage) }
+			r.Attributes[f.name] = raw
+		case f.kind == "relation":
+			var linkage interface{}
+			if fv.Kind() == reflect.Slice {
+				list := make([]*jsonAPIResource, 0, fv.Len())
+				for i := 0; i < fv.Len(); i++ {
+					id, err := encodeJSONAPIResource(fv.Index(i), false)
+					if err != nil { return nil, err }
+					if id != nil { list = append(list, id) }
+				}
+				linkage = list
+			} else {
+				id, err := encodeJSONAPIResource(fv, false)
+				if err != nil { return nil, err }
+				if id != nil { linkage = id }
+			}
+			raw, _ := json.Marshal(linkage)
+			if r.Relationships == nil { r.Relationships = make(map[string]jsonAPIRelationship) }
+			r.Relationships[f.name] = jsonAPIRelationship{Data: raw}
+		}
+	}
+	if r.Type == "" { return nil, fmt.Errorf("jsonapi: %s has no primary field", v.Type()) }
+	return r, nil
+}
+
+// decodes a JSON:API document into v, a pointer to a struct or a slice of
+// them. If the document has errors, they're returned as JSONAPIErrors.
+func UnmarshalJSONAPI(body []byte, v interface{}) (error) {
+	var doc jsonAPIDocument
+	if err := json.Unmarshal(body, &doc); err != nil { return err }
+	if len(doc.Errors) != 0 { return doc.Errors }
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() { return errors.New("jsonapi: can't decode into a non-pointer") }
+	d := jsonAPIDecoder{included: make(map[string]*jsonAPIResource), decoded: make(map[string]reflect.Value)}
+	for _, r := range doc.Included {
+		d.included[r.Type + "/" + r.ID] = r
+	}
+	if len(doc.Data) == 0 || string(doc.Data) == "null" { return nil }
+
+	target := rv.Elem()
+	if target.Kind() == reflect.Slice {
+		var list []*jsonAPIResource
+		if err := json.Unmarshal(doc.Data, &list); err != nil { return err }
+		slice := reflect.MakeSlice(target.Type(), len(list), len(list))
+		for i, r := range list {
+			if err := d.decode(r, slice.Index(i)); err != nil { return err }
+		}
+		target.Set(slice)
+		return nil
+	}
+
+	var r jsonAPIResource
+	if err := json.Unmarshal(doc.Data, &r); err != nil { return err }
+	return d.decode(&r, target)
+}
+
+type jsonAPIDecoder struct {
+	included map[string]*jsonAPIResource
+	decoded  map[string]reflect.Value // pointers to included resources already decoded, so cycles end.
+	depth    int
+}
+
+// how deeply included resources are followed, for relationships held by
+// value, which can't be shared to end cycles.
+const jsonAPIMaxDepth = 32
+
+// decodes r into v, which is a struct or a pointer to one.
+func (this *jsonAPIDecoder) decode(r *jsonAPIResource, v reflect.Value) (error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() { v.Set(reflect.New(v.Type().Elem())) }
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct { return fmt.Errorf("jsonapi: can't decode a resource into %s", v.Type()) }
+	fields, err := jsonAPIFields(v.Type())
+	if err != nil { return err }
+
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		switch f.kind {
+		case "primary":
+			if f.name != r.Type { return fmt.Errorf("jsonapi: got a %q resource, expected %q", r.Type, f.name) }
+			if err := setJSONAPIID(fv, r.ID); err != nil { return err }
+		case "attr":
+			raw, ok := r.Attributes[f.name]
+			if !ok { continue }
+			if err := json.Unmarshal(raw, fv.Addr().Interface()); err != nil { return fmt.Errorf("jsonapi: attribute %s: %w", f.name, err) }
+		case "relation":
+			rel, ok := r.Relationships[f.name]
+			if !ok || len(rel.Data) == 0 || string(rel.Data) == "null" { continue }
+			if err := this.decodeRelation(rel.Data, fv); err != nil { return fmt.Errorf("jsonapi: relationship %s: %w", f.name, err) }
+		}
+	}
+	return nil
+}
+
+func (this *jsonAPIDecoder) decodeRelation(data json.RawMessage, v reflect.Value) (error) {
+	if v.Kind() == reflect.Slice {
+		var ids []*jsonAPIResource
+		if err := json.Unmarshal(data, &ids); err != nil { return err }
+		slice := reflect.MakeSlice(v.Type(), len(ids), len(ids))
+		for i, id := range ids {
+			if err := this.decodeLinked(id, slice.Index(i)); err != nil { return err }
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	var id jsonAPIResource
+	if err := json.Unmarshal(data, &id); err != nil { return err }
+	return this.decodeLinked(&id, v)
+}
+
+// decodes a related resource from the included ones, or only its
+// identifier if it isn't included.
+func (this *jsonAPIDecoder) decodeLinked(id *jsonAPIResource, v reflect.Value) (error) {
+	key := id.Type + "/" + id.ID
+	full, ok := this.included[key]
+	if !ok || this.depth >= jsonAPIMaxDepth { return this.decode(id, v) }
+	this.depth++
+	defer func() { this.depth-- }()
+
+	if v.Kind() == reflect.Ptr {
+		if p, ok := this.decoded[key]; ok && p.Type() == v.Type() {
+			v.Set(p)
+			return nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		this.decoded[key] = v
+	}
+	return this.decode(full, v)
+}
+
+func setJSONAPIID(v reflect.Value, id string) (error) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(id)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil { return fmt.Errorf("jsonapi: bad id %q: %w", id, err) }
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil { return fmt.Errorf("jsonapi: bad id %q: %w", id, err) }
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("jsonapi: can't store an id in %s", v.Type())
+	}
+	return nil
+}
+
+type JSONAPIUnmarshaller struct {
+	output_value interface{}
+}
+
+func (this JSONAPIUnmarshaller) Unmarshal(body []byte) error {
+	return UnmarshalJSONAPI(body, this.output_value)
+}
+
+func FromJSONAPI(output_value interface{}) ResponseUnmarshaller {
+	return JSONAPIUnmarshaller{output_value: output_value}
+}
+
+func (this *RequestImpl) JSONAPIInto(into interface{}) (Request) {
+	this.Response = append(this.Response, FromJSONAPI(into))
+	return this
+}
+
+// marshals v as a JSON:API document and uses it as the request body.
+func (this *RequestImpl) JSONAPIBody(v interface{}) (Request) {
+	data, err := MarshalJSONAPI(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	return this.Body(bytes.NewReader(data), JSONAPIContentType)
+}
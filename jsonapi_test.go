@@ -0,0 +1,84 @@
+package reqtify
+
+import (
+	"testing"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+type apiPerson struct {
+	ID       int           `jsonapi:"primary,people"`
+	Name     string        `jsonapi:"attr,name"`
+	Articles []*apiArticle `jsonapi:"relation,articles"`
+}
+
+type apiArticle struct {
+	ID       string     `jsonapi:"primary,articles"`
+	Title    string     `jsonapi:"attr,title"`
+	Tags     []string   `jsonapi:"attr,tags,omitempty"`
+	Author   *apiPerson `jsonapi:"relation,author"`
+	Editor   *apiPerson `jsonapi:"relation,editor"`
+}
+
+const jsonAPIDoc = `{
+	"data": [{
+		"type": "articles", "id": "1",
+		"attributes": {"title": "First", "tags": ["a", "b"]},
+		"relationships": {"author": {"data": {"type": "people", "id": "9"}}, "editor": {"data": {"type": "people", "id": "7"}}}
+	}, {
+		"type": "articles", "id": "2",
+		"attributes": {"title": "Second"},
+		"relationships": {"author": {"data": {"type": "people", "id": "9"}}, "editor": {"data": null}}
+	}],
+	"included": [{
+		"type": "people", "id": "9",
+		"attributes": {"name": "Dan"},
+		"relationships": {"articles": {"data": [{"type": "articles", "id": "1"}, {"type": "articles", "id": "2"}]}}
+	}]
+}`
+
+func TestJSONAPIDecode(t *testing.T) {
+	var articles []apiArticle
+	if err := UnmarshalJSONAPI([]byte(jsonAPIDoc), &articles); err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if len(articles) != 2 || articles[0].Title != "First" || len(articles[0].Tags) != 2 || articles[1].Editor != nil { t.Fatalf("Decode Mismatch: got %+v", articles) }
+	author := articles[0].Author
+	if author == nil || author.ID != 9 || author.Name != "Dan" || articles[1].Author != author { t.Errorf("Included Mismatch: got %+v", author) }
+	if len(author.Articles) != 2 || author.Articles[1].ID != "2" { t.Errorf("Cycle Mismatch: got %+v", author.Articles) }
+	if editor := articles[0].Editor; editor == nil || editor.ID != 7 || editor.Name != "" { t.Errorf("Identifier Mismatch: got %+v", editor) }
+
+	var person apiPerson
+	if err := UnmarshalJSONAPI([]byte(`{"data": {"type": "articles", "id": "1"}}`), &person); err == nil { t.Errorf("Type Mismatch: accepted") }
+
+	err := UnmarshalJSONAPI([]byte(`{"errors": [{"status": "422", "title": "Invalid", "detail": "title is required", "source": {"pointer": "/data/attributes/title"}}]}`), &person)
+	var apiErrs JSONAPIErrors
+	if !errors.As(err, &apiErrs) || apiErrs[0].Status != "422" || err.Error() != "jsonapi: /data/attributes/title: title is required" { t.Errorf("Errors Mismatch: got %v", err) }
+}
+
+func TestJSONAPIRequest(t *testing.T) {
+	var body map[string]interface{}
+	var contentType, accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType, accept = r.Header.Get("Content-Type"), r.Header.Get("Accept")
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", JSONAPIContentType)
+		w.Write(data)
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	sent := &apiArticle{Title: "New", Author: &apiPerson{ID: 9, Name: "ignored"}}
+	var echoed apiArticle
+	_, err := reqt.New("/articles").Method(POST).JSONAPIBody(sent).JSONAPIInto(&echoed).Do()
+	if err != nil || contentType != JSONAPIContentType || accept != JSONAPIContentType { t.Fatalf("Request Mismatch: got %q, %q, %v", contentType, accept, err) }
+
+	data := body["data"].(map[string]interface{})
+	if _, ok := data["id"]; ok || data["type"] != "articles" { t.Errorf("Identity Mismatch: got %v", data) }
+	if attrs := data["attributes"].(map[string]interface{}); attrs["title"] != "New" || attrs["tags"] != nil { t.Errorf("Attributes Mismatch: got %v", attrs) }
+	author, _ := json.Marshal(data["relationships"].(map[string]interface{})["author"])
+	if string(author) != `{"data":{"id":"9","type":"people"}}` { t.Errorf("Relationship Mismatch: got %s", author) }
+	if echoed.Title != "New" || echoed.Author.ID != 9 || echoed.Author.Name != "" || echoed.Editor != nil { t.Errorf("Echo Mismatch: got %+v", echoed) }
+}
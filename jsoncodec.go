@@ -0,0 +1,56 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// a JSONCodec encodes and decodes JSON for a Reqtifier. Implement it to swap
+// in a faster library, like go-json or jsoniter, or to customize encoding of
+// particular types, without changing any call sites.
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// a JSONCodec using encoding/json, with its options exposed.
+type StdJSONCodec struct {
+	EscapeHTML            bool   // escape <, >, and & in strings, as json.Marshal does.
+	Indent                string // indent encoded JSON with this, for debugging, if not empty.
+	DisallowUnknownFields bool   // fail decoding objects with fields the target has no place for.
+	UseNumber             bool   // decode numbers into interface{} as json.Number rather than float64.
+}
+
+// behaves exactly like json.Marshal and json.Unmarshal.
+var DefaultJSONCodec JSONCodec = StdJSONCodec{EscapeHTML: true}
+
+func (this StdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(this.EscapeHTML)
+	e.SetIndent("", this.Indent)
+	if err := e.Encode(v); err != nil { return nil, err }
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func (this StdJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	if !this.DisallowUnknownFields && !this.UseNumber {
+		return json.Unmarshal(data, v)
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	if this.DisallowUnknownFields { d.DisallowUnknownFields() }
+	if this.UseNumber { d.UseNumber() }
+	return d.Decode(v)
+}
+
+// sets the codec used for JSONBody, JSONInto, and ExtractInto.
+func WithJSONCodec(codec JSONCodec) Option {
+	return func(r *ReqtifierImpl) {
+		r.JSONCodec = codec
+	}
+}
+
+func (this *RequestImpl) jsonCodec() (JSONCodec) {
+	if this.ReqClient == nil || this.ReqClient.JSONCodec == nil { return DefaultJSONCodec }
+	return this.ReqClient.JSONCodec
+}
@@ -0,0 +1,48 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"io/ioutil"
+)
+
+type countingCodec struct {
+	StdJSONCodec
+	calls *int
+}
+
+func (this countingCodec) Unmarshal(data []byte, v interface{}) error {
+	*this.calls++
+	return this.StdJSONCodec.Unmarshal(data, v)
+}
+
+func TestJSONCodec(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var sent []byte
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil { sent, _ = ioutil.ReadAll(req.Body) }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(`{"a": 1, "b": 2}`)))}, nil
+	})
+
+	calls := 0
+	codec := countingCodec{StdJSONCodec: StdJSONCodec{DisallowUnknownFields: true, UseNumber: true}, calls: &calls}
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithJSONCodec(codec))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var loose map[string]interface{}
+	var a int
+	_, err := reqt.New("/").Method(POST).JSONBody(map[string]string{"q": "<b>"}).JSONInto(&loose).ExtractInto("a", &a).Do()
+	if err != nil { t.Fatalf("Codec Failure: %s", err.Error()) }
+	if string(sent) != `{"q":"<b>"}` { t.Errorf("Encode Mismatch: got %s", sent) }
+	if _, ok := loose["a"].(json.Number); !ok || a != 1 || calls != 2 { t.Errorf("Decode Mismatch: got %T, %d, %d calls", loose["a"], a, calls) }
+
+	var strict struct{ A int `json:"a"` }
+	_, err = reqt.New("/").JSONInto(&strict).Do()
+	if err == nil { t.Errorf("Strict Mismatch: unknown field accepted") }
+
+	indented, _ := StdJSONCodec{Indent: "  "}.Marshal(map[string]int{"x": 1})
+	if string(indented) != "{\n  \"x\": 1\n}" { t.Errorf("Indent Mismatch: got %q", indented) }
+}
@@ -0,0 +1,186 @@
+// Package jsonrpc calls JSON-RPC 2.0 services over HTTP through a reqtify
+// Reqtifier, so that its rate limiting, retries and so on apply to them.
+//
+//	client := &jsonrpc.Client{Reqtifier: api, Path: "/rpc"}
+//	var sum int
+//	err := client.Call(ctx, "add", []int{1, 2}, &sum)
+//
+//	var rpcErr *jsonrpc.Error
+//	if errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc.MethodNotFound { ... }
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/thewug/reqtify"
+)
+
+// the error codes defined by the JSON-RPC 2.0 specification. Codes from
+// -32000 to -32099 are reserved for server errors.
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+var ErrNoResponse error = errors.New("jsonrpc: no response to call")
+
+// an error object returned by a JSON-RPC server.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (this *Error) Error() (string) {
+	return fmt.Sprintf("jsonrpc: %s (%d)", this.Message, this.Code)
+}
+
+// decodes the error's data into v.
+func (this *Error) DataInto(v interface{}) (error) {
+	return json.Unmarshal(this.Data, v)
+}
+
+// a call in a batch. After the batch is sent, Err holds its error, and its
+// result has been decoded into Result, unless Result is nil.
+type Call struct {
+	Method string
+	Params interface{}
+	Result interface{}
+	Notify bool // send it as a notification, with no id or response.
+	Err    error
+
+	id string
+}
+
+type request struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      *string     `json:"id,omitempty"`
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+type Client struct {
+	Reqtifier reqtify.Reqtifier
+	Path      string
+
+	// applied to each HTTP request before it's sent, to add authentication
+	// and so on, if set.
+	Prepare   func(req reqtify.Request) reqtify.Request
+
+	counter   uint64
+}
+
+// returns an id for a call, from the Reqtifier's IDGenerator if it has one.
+func (this *Client) newID() (string) {
+	if gen, ok := this.Reqtifier.(interface{ NewID() string }); ok { return gen.NewID() }
+	return strconv.FormatUint(atomic.AddUint64(&this.counter, 1), 10)
+}
+
+func (this *Call) request() (request) {
+	r := request{Version: "2.0", Method: this.Method, Params: this.Params}
+	if !this.Notify { r.ID = &this.id }
+	return r
+}
+
+// calls method with params, which should be an array or object, or nil,
+// and decodes the result into result, unless it's nil.
+func (this *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) (error) {
+	call := &Call{Method: method, Params: params, Result: result}
+	if err := this.Batch(ctx, call); err != nil { return err }
+	return call.Err
+}
+
+// sends a notification, which the server doesn't answer.
+func (this *Client) Notify(ctx context.Context, method string, params interface{}) (error) {
+	return this.Batch(ctx, &Call{Method: method, Params: params, Notify: true})
+}
+
+// sends calls in a single HTTP request, and matches the responses to them
+// by id. The error is only for the HTTP request as a whole; each call's
+// own error is in its Err.
+func (this *Client) Batch(ctx context.Context, calls ...*Call) (error) {
+	if len(calls) == 0 { return nil }
+
+	var requests []request
+	for _, c := range calls {
+		c.id, c.Err = this.newID(), nil
+		requests = append(requests, c.request())
+	}
+	var payload interface{} = requests
+	if len(calls) == 1 {
+		// batches of one are sent as a plain call, which every server understands
+		payload = requests[0]
+	}
+
+	req := this.Reqtifier.New(this.Path).Method(reqtify.POST).Context(ctx).Header("Accept", "application/json").JSONBody(payload)
+	if this.Prepare != nil { req = this.Prepare(req) }
+	resp, err := req.Do()
+	if err != nil { return err }
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil { return err }
+
+	body = bytes.TrimSpace(body)
+	var responses []response
+	if len(body) != 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &responses)
+	} else if len(body) != 0 {
+		var single response
+		err = json.Unmarshal(body, &single)
+		responses = []response{single}
+	}
+	if err != nil {
+		if resp.StatusCode >= 400 { return &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status} }
+		return err
+	}
+
+	byID := make(map[string]response)
+	var unmatched *Error
+	for _, r := range responses {
+		var id string
+		if json.Unmarshal(r.ID, &id) != nil {
+			// numeric ids are echoed back as numbers by some servers
+			id = string(r.ID)
+		}
+		if id == "" || id == "null" {
+			// an error with no id applies to the whole batch, like a parse error
+			if r.Error != nil { unmatched = r.Error }
+			continue
+		}
+		byID[id] = r
+	}
+
+	for _, c := range calls {
+		if c.Notify { continue }
+		r, ok := byID[c.id]
+		switch {
+		case ok && r.Error != nil:
+			c.Err = r.Error
+		case ok && c.Result != nil:
+			c.Err = json.Unmarshal(r.Result, c.Result)
+		case !ok && unmatched != nil:
+			c.Err = unmatched
+		case !ok && resp.StatusCode >= 400:
+			c.Err = &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+		case !ok:
+			c.Err = ErrNoResponse
+		}
+	}
+	return nil
+}
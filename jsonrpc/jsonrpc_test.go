@@ -0,0 +1,76 @@
+package jsonrpc
+
+import (
+	"testing"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/thewug/reqtify"
+)
+
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  []int           `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func handle(r rpcRequest) (map[string]interface{}) {
+	if r.ID == nil { return nil }
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": r.ID}
+	switch r.Method {
+	case "add":
+		sum := 0
+		for _, p := range r.Params { sum += p }
+		resp["result"] = sum
+	default:
+		resp["error"] = map[string]interface{}{"code": MethodNotFound, "message": "Method not found", "data": map[string]string{"method": r.Method}}
+	}
+	return resp
+}
+
+func TestClient(t *testing.T) {
+	var notified []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var raw json.RawMessage
+		json.NewDecoder(r.Body).Decode(&raw)
+		var batch []rpcRequest
+		if json.Unmarshal(raw, &batch) != nil {
+			var single rpcRequest
+			if json.Unmarshal(raw, &single) != nil || single.Version != "2.0" {
+				json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": nil, "error": map[string]interface{}{"code": ParseError, "message": "Parse error"}})
+				return
+			}
+			if single.ID == nil { notified = append(notified, single.Method) }
+			if resp := handle(single); resp != nil { json.NewEncoder(w).Encode(resp) }
+			return
+		}
+		var responses []map[string]interface{}
+		// answered in reverse, to check they're matched by id
+		for i := len(batch) - 1; i >= 0; i-- {
+			if resp := handle(batch[i]); resp != nil { responses = append(responses, resp) }
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	client := &Client{Reqtifier: reqtify.New(server.URL, nil, nil, nil, "test"), Path: "/rpc"}
+	var sum int
+	if err := client.Call(context.Background(), "add", []int{1, 2, 3}, &sum); err != nil || sum != 6 { t.Errorf("Call Mismatch: got %d, %v", sum, err) }
+
+	err := client.Call(context.Background(), "missing", nil, nil)
+	var rpcErr *Error
+	var data map[string]string
+	if !errors.As(err, &rpcErr) || rpcErr.Code != MethodNotFound || rpcErr.DataInto(&data) != nil || data["method"] != "missing" { t.Errorf("Error Mismatch: got %v", err) }
+
+	if err := client.Notify(context.Background(), "ping", nil); err != nil || len(notified) != 1 || notified[0] != "ping" { t.Errorf("Notify Mismatch: got %v, %v", notified, err) }
+
+	var a, b int
+	calls := []*Call{{Method: "add", Params: []int{1, 1}, Result: &a}, {Method: "nope"}, {Method: "log", Notify: true}, {Method: "add", Params: []int{2, 2}, Result: &b}}
+	if err := client.Batch(context.Background(), calls...); err != nil { t.Fatalf("Batch Failure: %s", err.Error()) }
+	if a != 2 || b != 4 || calls[0].Err != nil || calls[3].Err != nil || calls[2].Err != nil { t.Errorf("Batch Mismatch: got %d, %d, %v", a, b, calls) }
+	if !errors.As(calls[1].Err, &rpcErr) || rpcErr.Code != MethodNotFound { t.Errorf("Batch Error Mismatch: got %v", calls[1].Err) }
+}
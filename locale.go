@@ -0,0 +1,90 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+)
+
+/*
+   Some APIs localize their responses, or shift times into the caller's
+   timezone. Rather than threading a locale through every call site, attach
+   it to the context once, for example in an HTTP handler:
+
+	ctx = reqtify.ContextWithLocale(ctx, "de-DE")
+	ctx = reqtify.ContextWithTimezone(ctx, "Europe/Berlin")
+
+   and every request made with that context (see RequestImpl.Context) carries
+   it, in the headers or parameters named by the Reqtifier's LocaleMapping.
+*/
+
+type localeKey struct{}
+type timezoneKey struct{}
+
+// returns a copy of ctx carrying a locale, like "en-US".
+func ContextWithLocale(ctx context.Context, locale string) (context.Context) {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// returns a copy of ctx carrying a timezone, like "America/New_York".
+func ContextWithTimezone(ctx context.Context, timezone string) (context.Context) {
+	return context.WithValue(ctx, timezoneKey{}, timezone)
+}
+
+// returns the locale attached to ctx, if any.
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	locale, ok := ctx.Value(localeKey{}).(string)
+	return locale, ok && locale != ""
+}
+
+// returns the timezone attached to ctx, if any.
+func TimezoneFromContext(ctx context.Context) (string, bool) {
+	timezone, ok := ctx.Value(timezoneKey{}).(string)
+	return timezone, ok && timezone != ""
+}
+
+// describes how an API expects to be told the caller's locale and timezone.
+// Each may be sent as a header, a query parameter, both, or neither. Values
+// the request already sets itself aren't overridden.
+type LocaleMapping struct {
+	LocaleHeader   string
+	LocaleParam    string
+	TimezoneHeader string
+	TimezoneParam  string
+}
+
+// sends the locale as Accept-Language, and the timezone as Time-Zone.
+var DefaultLocaleMapping = LocaleMapping{LocaleHeader: "Accept-Language", TimezoneHeader: "Time-Zone"}
+
+// propagates the locale and timezone from each request's context, as
+// described by mapping.
+func WithLocalePropagation(mapping LocaleMapping) Option {
+	return func(r *ReqtifierImpl) {
+		r.LocaleMapping = &mapping
+	}
+}
+
+func (this *ReqtifierImpl) applyLocale(ctx context.Context, r *http.Request) {
+	if this.LocaleMapping == nil { return }
+
+	query := r.URL.Query()
+	changed := false
+	set := func(value, header, param string) {
+		if header != "" && r.Header.Get(header) == "" {
+			r.Header.Set(header, value)
+		}
+		if param != "" && query.Get(param) == "" {
+			query.Set(param, value)
+			changed = true
+		}
+	}
+
+	if locale, ok := LocaleFromContext(ctx); ok {
+		set(locale, this.LocaleMapping.LocaleHeader, this.LocaleMapping.LocaleParam)
+	}
+	if timezone, ok := TimezoneFromContext(ctx); ok {
+		set(timezone, this.LocaleMapping.TimezoneHeader, this.LocaleMapping.TimezoneParam)
+	}
+	if changed {
+		r.URL.RawQuery = query.Encode()
+	}
+}
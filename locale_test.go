@@ -0,0 +1,34 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestLocalePropagation(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var last *http.Request
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		last = req
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithLocalePropagation(LocaleMapping{LocaleHeader: "Accept-Language", TimezoneParam: "tz"}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	ctx := ContextWithTimezone(ContextWithLocale(context.Background(), "de-DE"), "Europe/Berlin")
+	reqt.New("/events").URLArg("page", 2).Context(ctx).Do()
+	if last.Header.Get("Accept-Language") != "de-DE" { t.Errorf("Locale Mismatch: got %q", last.Header.Get("Accept-Language")) }
+	if q := last.URL.Query(); q.Get("tz") != "Europe/Berlin" || q.Get("page") != "2" { t.Errorf("Timezone Mismatch: got %s", last.URL) }
+
+	// explicit values win
+	reqt.New("/events").URLArg("tz", "UTC").Header("Accept-Language", "fr").Context(ctx).Do()
+	if last.Header.Get("Accept-Language") != "fr" || last.URL.Query().Get("tz") != "UTC" { t.Errorf("Override Mismatch: got %s %v", last.URL, last.Header) }
+
+	reqt.New("/events").Do()
+	if last.Header.Get("Accept-Language") != "" || last.URL.RawQuery != "" { t.Errorf("Empty Mismatch: got %s %v", last.URL, last.Header) }
+}
@@ -0,0 +1,83 @@
+package reqtify
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// the severity of a log message.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota // details of what reqtify is doing, like retries.
+	LogInfo                  // output asked for explicitly, like DebugPrint.
+	LogWarn                  // things which are probably mistakes.
+	LogError                 // failures nobody else will report.
+)
+
+func (this LogLevel) String() (string) {
+	switch this {
+	case LogDebug: return "DEBUG"
+	case LogInfo:  return "INFO"
+	case LogWarn:  return "WARN"
+	case LogError: return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(this))
+}
+
+// a Logger receives a Reqtifier's diagnostic output, so that it can be sent
+// wherever the rest of an application's logs go. Implementations must be
+// safe for concurrent use.
+type Logger interface {
+	Logf(level LogLevel, format string, args ...interface{})
+}
+
+// adapts an ordinary function to a Logger.
+type LoggerFunc func(level LogLevel, format string, args ...interface{})
+
+func (this LoggerFunc) Logf(level LogLevel, format string, args ...interface{}) {
+	this(level, format, args...)
+}
+
+// logs messages at or above Level to a log.Logger, or the log package's
+// standard logger if Logger is nil.
+type StdLogger struct {
+	Logger *log.Logger
+	Level  LogLevel
+}
+
+func (this StdLogger) Logf(level LogLevel, format string, args ...interface{}) {
+	if level < this.Level { return }
+	if this.Logger == nil {
+		log.Printf(format, args...)
+		return
+	}
+	this.Logger.Printf(format, args...)
+}
+
+// what a Reqtifier logs with if it isn't given a Logger: messages at
+// LogInfo and above go to the log package's standard logger.
+var DefaultLogger Logger = StdLogger{Level: LogInfo}
+
+// sets where the Reqtifier's diagnostic output goes. The default is
+// DefaultLogger.
+func WithLogger(logger Logger) Option {
+	return func(r *ReqtifierImpl) {
+		r.Logger = logger
+	}
+}
+
+func (this *ReqtifierImpl) logf(level LogLevel, format string, args ...interface{}) {
+	if this == nil || this.Logger == nil {
+		DefaultLogger.Logf(level, format, args...)
+		return
+	}
+	this.Logger.Logf(level, format, args...)
+}
+
+// describes how an attempt ended, for logging.
+func attemptOutcome(resp *http.Response, err error) (string) {
+	if err != nil { return err.Error() }
+	return resp.Status
+}
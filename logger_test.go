@@ -0,0 +1,43 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+func TestLogger(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 { return &http.Response{StatusCode: 503, Status: "503 Service Unavailable", Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	var lock sync.Mutex
+	var messages []string
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithLogger(LoggerFunc(func(level LogLevel, format string, args ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		messages = append(messages, level.String() + " " + fmt.Sprintf(format, args...))
+	})))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/a").Retry(RetryPolicy{MaxAttempts: 2}).DebugPrint().Do()
+	if len(messages) != 2 { t.Fatalf("Messages Mismatch: got %q", messages) }
+	if !strings.HasPrefix(messages[0], "INFO Request URL: https://this.is.a.test/a") { t.Errorf("DebugPrint Mismatch: got %q", messages[0]) }
+	if messages[1] != "DEBUG reqtify: GET https://this.is.a.test/a: attempt 1 of 2 failed (503 Service Unavailable), retrying in 0s" { t.Errorf("Retry Mismatch: got %q", messages[1]) }
+
+	var b bytes.Buffer
+	std := StdLogger{Logger: log.New(&b, "", 0), Level: LogWarn}
+	std.Logf(LogInfo, "hidden")
+	std.Logf(LogError, "shown %d", 1)
+	if b.String() != "shown 1\n" { t.Errorf("StdLogger Mismatch: got %q", b.String()) }
+}
@@ -0,0 +1,198 @@
+// Package match describes HTTP requests, for routing requests in mocks and
+// for asserting things about the requests code under test made:
+//
+//	m.HandleMatching(mock.Matching(match.Method(reqtify.POST), match.PathPrefix("/api")), respond)
+//
+//	req := <- examiner.Requests
+//	match.Assert(t, req, match.FormArg("tags", "cat"), match.JSONBody(expected))
+//
+// Matchers which look at the body leave it in place, so it can still be read
+// afterwards.
+package match
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/thewug/reqtify"
+)
+
+// a Matcher checks one thing about a request, returning nil if it holds, or
+// an error saying how the request differs.
+type Matcher func(req *http.Request) error
+
+// checks req against each of matchers, returning the first mismatch.
+func Request(req *http.Request, matchers ...Matcher) (error) {
+	for _, m := range matchers {
+		if err := m(req); err != nil { return err }
+	}
+	return nil
+}
+
+// the parts of *testing.T which Assert uses.
+type TestReporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// fails t for each of matchers req doesn't satisfy, and reports whether it
+// satisfied them all.
+func Assert(t TestReporter, req *http.Request, matchers ...Matcher) (bool) {
+	t.Helper()
+	ok := true
+	for _, m := range matchers {
+		if err := m(req); err != nil {
+			t.Errorf("%s %s: %s", req.Method, req.URL, err.Error())
+			ok = false
+		}
+	}
+	return ok
+}
+
+// matches requests which satisfy all of matchers.
+func All(matchers ...Matcher) (Matcher) {
+	return func(req *http.Request) error {
+		return Request(req, matchers...)
+	}
+}
+
+// matches requests which satisfy any of matchers.
+func Any(matchers ...Matcher) (Matcher) {
+	return func(req *http.Request) error {
+		var errs []string
+		for _, m := range matchers {
+			err := m(req)
+			if err == nil { return nil }
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("none of: %s", strings.Join(errs, "; "))
+	}
+}
+
+// matches requests which don't satisfy m.
+func Not(m Matcher) (Matcher) {
+	return func(req *http.Request) error {
+		if m(req) == nil { return errors.New("matched, but shouldn't have") }
+		return nil
+	}
+}
+
+// matches requests with the method v.
+func Method(v reqtify.HttpVerb) (Matcher) {
+	return func(req *http.Request) error {
+		if !strings.EqualFold(req.Method, string(v)) { return fmt.Errorf("method is %s, expected %s", req.Method, v) }
+		return nil
+	}
+}
+
+// matches requests for exactly path.
+func Path(path string) (Matcher) {
+	return func(req *http.Request) error {
+		if req.URL.Path != path { return fmt.Errorf("path is %s, expected %s", req.URL.Path, path) }
+		return nil
+	}
+}
+
+// matches requests whose path is prefix, or is below it. The prefix "/api"
+// matches "/api" and "/api/users", but not "/apiary".
+func PathPrefix(prefix string) (Matcher) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(req *http.Request) error {
+		path := req.URL.Path
+		if path != prefix && !strings.HasPrefix(path, prefix + "/") { return fmt.Errorf("path is %s, expected it under %s", path, prefix) }
+		return nil
+	}
+}
+
+// matches requests with the header key, set to value among its values.
+func Header(key, value string) (Matcher) {
+	return func(req *http.Request) error {
+		return hasValue("header " + key, req.Header.Values(key), value)
+	}
+}
+
+// matches requests with the URL parameter key, set to value among its values.
+func URLArg(key, value string) (Matcher) {
+	return func(req *http.Request) error {
+		return hasValue("URL argument " + key, req.URL.Query()[key], value)
+	}
+}
+
+// matches requests whose form body, url encoded or multipart, has the
+// parameter key, set to value among its values. Files are ignored.
+func FormArg(key, value string) (Matcher) {
+	return func(req *http.Request) error {
+		form, err := formValues(req)
+		if err != nil { return err }
+		return hasValue("form argument " + key, form[key], value)
+	}
+}
+
+// matches requests whose body is JSON equal to v marshalled as JSON,
+// ignoring formatting and the order of object keys.
+func JSONBody(v interface{}) (Matcher) {
+	expected, err := json.Marshal(v)
+	return func(req *http.Request) error {
+		if err != nil { return err }
+		body, err := Body(req)
+		if err != nil { return err }
+		var got, want interface{}
+		if err := json.Unmarshal(body, &got); err != nil { return fmt.Errorf("body is not JSON: %s", err.Error()) }
+		json.Unmarshal(expected, &want)
+		if !reflect.DeepEqual(got, want) { return fmt.Errorf("body is %s, expected %s", body, expected) }
+		return nil
+	}
+}
+
+// returns req's body, putting a copy back in its place so that it can be
+// read again.
+func Body(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody { return nil, nil }
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			defer body.Close()
+			return ioutil.ReadAll(body)
+		}
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, err
+}
+
+func formValues(req *http.Request) (url.Values, error) {
+	body, err := Body(req)
+	if err != nil { return nil, err }
+	mediatype, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediatype {
+	case "application/x-www-form-urlencoded":
+		return url.ParseQuery(string(body))
+	case "multipart/form-data":
+		form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(int64(len(body)) + 1)
+		if err != nil { return nil, err }
+		defer form.RemoveAll()
+		return url.Values(form.Value), nil
+	}
+	return nil, fmt.Errorf("body is %q, expected a form", mediatype)
+}
+
+func hasValue(what string, values []string, value string) (error) {
+	for _, v := range values {
+		if v == value { return nil }
+	}
+	if len(values) == 0 { return fmt.Errorf("%s is missing, expected %q", what, value) }
+	return fmt.Errorf("%s is %q, expected %q", what, values, value)
+}
@@ -0,0 +1,48 @@
+package match
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/test"
+
+	"testing"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type recorder struct {
+	errors []string
+}
+
+func (this *recorder) Helper() {}
+func (this *recorder) Errorf(format string, args ...interface{}) { this.errors = append(this.errors, format) }
+
+func TestMatchers(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	examiner := http_mock_client.Examine()
+	reqt := &reqtify.ReqtifierImpl{Root: "https://this.is.a.test", HttpClient: &http_mock_client, AgentName: "test"}
+
+	go reqt.New("/api/posts").Method(reqtify.POST).URLArg("page", 2).FormArg("tags", "dog").FormArg("tags", "cat").Do()
+	req := <- examiner.Requests
+	examiner.Responses <- test.ResponseAndError{Response: &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}}
+	if !Assert(t, req, Method(reqtify.POST), PathPrefix("/api"), Path("/api/posts"), URLArg("page", "2"), FormArg("tags", "cat"), Header("User-Agent", "test")) { return }
+
+	var r recorder
+	if Assert(&r, req, Method(reqtify.GET), PathPrefix("/ap"), FormArg("tags", "bird"), Not(FormArg("tags", "cat"))) || len(r.errors) != 4 { t.Errorf("Assert Mismatch: got %d errors, expected 4", len(r.errors)) }
+	if err := Request(req, Any(Method(reqtify.GET), Header("X-Missing", "1"))); err == nil || !strings.Contains(err.Error(), "none of") { t.Errorf("Any Mismatch: got %v", err) }
+	if err := Request(req, All(Method(reqtify.POST), Any(Method(reqtify.GET), FormArg("tags", "dog")))); err != nil { t.Errorf("All Mismatch: got %v", err) }
+	if body, _ := ioutil.ReadAll(req.Body); string(body) != "tags=dog&tags=cat" { t.Errorf("Body Mismatch: got %q", body) }
+
+	go reqt.New("/upload").Method(reqtify.POST).FormArg("tags", "cat").FileArg("file", "a.txt", bytes.NewReader([]byte("hi"))).Do()
+	req = <- examiner.Requests
+	examiner.Responses <- test.ResponseAndError{Response: &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}}
+	if err := Request(req, FormArg("tags", "cat"), Not(FormArg("file", "hi"))); err != nil { t.Errorf("Multipart Mismatch: got %v", err) }
+
+	go reqt.New("/json").Method(reqtify.PUT).JSONBody(map[string]interface{}{"b": []int{1, 2}, "a": "x"}).Do()
+	req = <- examiner.Requests
+	examiner.Responses <- test.ResponseAndError{Response: &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}}
+	if err := Request(req, JSONBody(struct{ A string `json:"a"`; B []int `json:"b"` }{"x", []int{1, 2}})); err != nil { t.Errorf("JSONBody Mismatch: got %v", err) }
+	if err := Request(req, JSONBody(map[string]string{"a": "x"})); err == nil { t.Errorf("JSONBody Mismatch: got a match for a different body") }
+	if err := Request(req, FormArg("a", "x")); err == nil { t.Errorf("FormArg Mismatch: got a match for a JSON body") }
+}
@@ -0,0 +1,260 @@
+package reqtify
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+   Metrics count what a Reqtifier sends, so operators can watch API usage and
+   how close a bot runs to its limits:
+
+	reqtify_requests_total               counter, by method, host and status
+	reqtify_request_duration_seconds     histogram, by method and host
+	reqtify_requests_in_flight           gauge
+	reqtify_rate_limiter_wait_seconds    histogram, by rate group, or "default"
+
+   Each attempt at a request counts separately, and its duration runs until
+   its response headers arrive. Attempts which fail without a response have
+   the status "error". Several Reqtifiers can share one Metrics.
+
+   A Metrics is an http.Handler serving the Prometheus text format, so it can
+   be scraped directly:
+
+	metrics := reqtify.NewMetrics()
+	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithMetrics(metrics))
+	http.Handle("/metrics", metrics)
+
+   To register it with a prometheus.Registry instead, use the Collector in
+   github.com/thewug/reqtify/metrics/prometheus. That's a module of its own,
+   so only programs which import it depend on the Prometheus client.
+*/
+
+// upper bounds, in seconds, of the histogram buckets used if Metrics.Buckets
+// isn't set. The same as the Prometheus client's defaults.
+var DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collects metrics about requests. Use NewMetrics to create one.
+type Metrics struct {
+	Buckets []float64 // histogram bucket upper bounds, in seconds.
+
+	lock      sync.Mutex
+	requests  map[[3]string]uint64
+	durations map[[2]string]*histogram
+	waits     map[string]*histogram
+	inFlight  int64
+}
+
+func NewMetrics() (*Metrics) {
+	return &Metrics{
+		requests: make(map[[3]string]uint64),
+		durations: make(map[[2]string]*histogram),
+		waits: make(map[string]*histogram),
+	}
+}
+
+// records metrics about the Reqtifier's requests in metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(r *ReqtifierImpl) {
+		r.Metrics = metrics
+		r.Use(metrics.Middleware())
+	}
+}
+
+// returns middleware which counts and times each attempt at a request.
+// WithMetrics installs it, and also records rate limiter waits.
+func (this *Metrics) Middleware() (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			clock := requestClock(r)
+			start := clock.Now()
+			atomic.AddInt64(&this.inFlight, 1)
+			resp, err := next(r)
+			atomic.AddInt64(&this.inFlight, -1)
+
+			status := "error"
+			if err == nil { status = strconv.Itoa(resp.StatusCode) }
+			this.observeRequest(r.Method, r.URL.Host, status, clock.Now().Sub(start))
+			return resp, err
+		}
+	}
+}
+
+func (this *Metrics) observeRequest(method, host, status string, d time.Duration) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.requests[[3]string{method, host, status}]++
+	key := [2]string{method, host}
+	if this.durations[key] == nil { this.durations[key] = newHistogram(this.buckets()) }
+	this.durations[key].observe(d.Seconds())
+}
+
+// records time spent waiting for a rate limiter. Safe to call on nil.
+func (this *Metrics) observeWait(group string, d time.Duration) {
+	if this == nil { return }
+	if group == "" { group = "default" }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.waits[group] == nil { this.waits[group] = newHistogram(this.buckets()) }
+	this.waits[group].observe(d.Seconds())
+}
+
+func (this *Metrics) buckets() ([]float64) {
+	if len(this.Buckets) == 0 { return DefaultMetricsBuckets }
+	return this.Buckets
+}
+
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative.
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) (*histogram) {
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (this *histogram) observe(v float64) {
+	this.count++
+	this.sum += v
+	if i := sort.SearchFloat64s(this.bounds, v); i < len(this.bounds) { this.counts[i]++ }
+}
+
+// returns the histogram as Prometheus reports it.
+func (this *histogram) export(labels map[string]string) (MetricValue) {
+	value := MetricValue{Labels: labels, Histogram: &Histogram{Count: this.count, Sum: this.sum, Buckets: make(map[float64]uint64, len(this.bounds))}}
+	cumulative := uint64(0)
+	for i, bound := range this.bounds {
+		cumulative += this.counts[i]
+		value.Histogram.Buckets[bound] = cumulative
+	}
+	return value
+}
+
+// a snapshot of one metric and all of its values.
+type MetricFamily struct {
+	Name   string
+	Help   string
+	Type   string // "counter", "gauge", or "histogram".
+	Values []MetricValue
+}
+
+// one value of a metric, for one combination of labels.
+type MetricValue struct {
+	Labels    map[string]string
+	Value     float64    // for counters and gauges.
+	Histogram *Histogram // for histograms.
+}
+
+type Histogram struct {
+	Count   uint64
+	Sum     float64
+	Buckets map[float64]uint64 // cumulative counts, by upper bound.
+}
+
+// returns a snapshot of every metric, sorted by name and then by labels.
+func (this *Metrics) Families() ([]MetricFamily) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	requests := MetricFamily{Name: "reqtify_requests_total", Help: "Attempts at requests sent, by method, host, and status.", Type: "counter"}
+	for key, count := range this.requests {
+		requests.Values = append(requests.Values, MetricValue{Labels: map[string]string{"method": key[0], "host": key[1], "status": key[2]}, Value: float64(count)})
+	}
+	durations := MetricFamily{Name: "reqtify_request_duration_seconds", Help: "Time until response headers arrive, by method and host.", Type: "histogram"}
+	for key, h := range this.durations {
+		durations.Values = append(durations.Values, h.export(map[string]string{"method": key[0], "host": key[1]}))
+	}
+	inFlight := MetricFamily{Name: "reqtify_requests_in_flight", Help: "Attempts at requests waiting for response headers.", Type: "gauge"}
+	inFlight.Values = []MetricValue{{Labels: map[string]string{}, Value: float64(atomic.LoadInt64(&this.inFlight))}}
+	waits := MetricFamily{Name: "reqtify_rate_limiter_wait_seconds", Help: "Time spent waiting for rate limiters, by rate group.", Type: "histogram"}
+	for group, h := range this.waits {
+		waits.Values = append(waits.Values, h.export(map[string]string{"group": group}))
+	}
+
+	families := []MetricFamily{requests, durations, inFlight, waits}
+	for _, family := range families {
+		sort.Slice(family.Values, func(i, j int) bool {
+			return labelString(family.Values[i].Labels) < labelString(family.Values[j].Labels)
+		})
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// writes every metric in the Prometheus text exposition format.
+func (this *Metrics) WriteTo(w io.Writer) (int64, error) {
+	counter := &countingWriter{w: w}
+	b := bufio.NewWriter(counter)
+	for _, family := range this.Families() {
+		fmt.Fprintf(b, "# HELP %s %s\n", family.Name, family.Help)
+		fmt.Fprintf(b, "# TYPE %s %s\n", family.Name, family.Type)
+		for _, value := range family.Values {
+			if value.Histogram == nil {
+				fmt.Fprintf(b, "%s%s %s\n", family.Name, labelString(value.Labels), formatFloat(value.Value))
+				continue
+			}
+			bounds := make([]float64, 0, len(value.Histogram.Buckets))
+			for bound := range value.Histogram.Buckets {
+				bounds = append(bounds, bound)
+			}
+			sort.Float64s(bounds)
+			for _, bound := range bounds {
+				fmt.Fprintf(b, "%s_bucket%s %d\n", family.Name, labelString(value.Labels, "le", formatFloat(bound)), value.Histogram.Buckets[bound])
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", family.Name, labelString(value.Labels, "le", "+Inf"), value.Histogram.Count)
+			fmt.Fprintf(b, "%s_sum%s %s\n", family.Name, labelString(value.Labels), formatFloat(value.Histogram.Sum))
+			fmt.Fprintf(b, "%s_count%s %d\n", family.Name, labelString(value.Labels), value.Histogram.Count)
+		}
+	}
+	err := b.Flush()
+	return counter.n, err
+}
+
+// serves every metric in the Prometheus text exposition format.
+func (this *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	this.WriteTo(w)
+}
+
+// formats labels as {a="1",b="2"}, sorted, with extra name/value pairs last.
+func labelString(labels map[string]string, extra ...string) (string) {
+	if len(labels) == 0 && len(extra) == 0 { return "" }
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	var pairs []string
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, k + `="` + escape.Replace(labels[k]) + `"`)
+	}
+	for i := 0; i + 1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i] + `="` + escape.Replace(extra[i + 1]) + `"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) (string) {
+	if math.IsInf(v, 1) { return "+Inf" }
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (this *countingWriter) Write(p []byte) (int, error) {
+	n, err := this.w.Write(p)
+	this.n += int64(n)
+	return n, err
+}
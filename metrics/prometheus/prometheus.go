@@ -0,0 +1,71 @@
+// Package prometheus exposes a reqtify.Metrics as a prometheus.Collector, so
+// it can be registered with a prometheus.Registry alongside a program's other
+// metrics:
+//
+//	metrics := reqtify.NewMetrics()
+//	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithMetrics(metrics))
+//	prometheus.MustRegister(reqtifyprom.NewCollector(metrics))
+package prometheus
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thewug/reqtify"
+)
+
+// the labels of each family reqtify.Metrics reports, in the order they're
+// given to prometheus.
+var familyLabels = map[string][]string{
+	"reqtify_requests_total": {"method", "host", "status"},
+	"reqtify_request_duration_seconds": {"method", "host"},
+	"reqtify_requests_in_flight": {},
+	"reqtify_rate_limiter_wait_seconds": {"group"},
+}
+
+// a prometheus.Collector reporting the metrics in a reqtify.Metrics.
+type Collector struct {
+	metrics *reqtify.Metrics
+	descs   map[string]*prometheus.Desc
+}
+
+func NewCollector(metrics *reqtify.Metrics) (*Collector) {
+	c := &Collector{metrics: metrics, descs: make(map[string]*prometheus.Desc, len(familyLabels))}
+	for _, family := range metrics.Families() {
+		c.descs[family.Name] = prometheus.NewDesc(family.Name, family.Help, familyLabels[family.Name], nil)
+	}
+	return c
+}
+
+func (this *Collector) Describe(ch chan<- *prometheus.Desc) {
+	names := make([]string, 0, len(this.descs))
+	for name := range this.descs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ch <- this.descs[name]
+	}
+}
+
+func (this *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, family := range this.metrics.Families() {
+		desc, ok := this.descs[family.Name]
+		if !ok { continue }
+		for _, value := range family.Values {
+			labels := make([]string, len(familyLabels[family.Name]))
+			for i, name := range familyLabels[family.Name] {
+				labels[i] = value.Labels[name]
+			}
+
+			switch {
+			case value.Histogram != nil:
+				ch <- prometheus.MustNewConstHistogram(desc, value.Histogram.Count, value.Histogram.Sum, value.Histogram.Buckets, labels...)
+			case family.Type == "counter":
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value.Value, labels...)
+			default:
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value.Value, labels...)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/thewug/reqtify"
+)
+
+func TestCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	metrics := reqtify.NewMetrics()
+	api := reqtify.New(server.URL, nil, nil, nil, "test", reqtify.WithMetrics(metrics))
+	for i := 0; i < 3; i++ {
+		if _, err := api.New("/").Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(NewCollector(metrics)); err != nil { t.Fatalf("Register Failure: %s", err.Error()) }
+	families, err := registry.Gather()
+	if err != nil { t.Fatalf("Gather Failure: %s", err.Error()) }
+
+	found := map[string]bool{}
+	for _, family := range families {
+		found[family.GetName()] = true
+		switch family.GetName() {
+		case "reqtify_requests_total":
+			m := family.GetMetric()
+			if len(m) != 1 || m[0].GetCounter().GetValue() != 3 { t.Errorf("Requests Mismatch: got %v", m) }
+			labels := map[string]string{}
+			for _, l := range m[0].GetLabel() { labels[l.GetName()] = l.GetValue() }
+			if labels["method"] != "GET" || labels["status"] != "200" { t.Errorf("Label Mismatch: got %v", labels) }
+		case "reqtify_request_duration_seconds":
+			if h := family.GetMetric()[0].GetHistogram(); h.GetSampleCount() != 3 || len(h.GetBucket()) != len(reqtify.DefaultMetricsBuckets) { t.Errorf("Histogram Mismatch: got %v", h) }
+		case "reqtify_requests_in_flight":
+			if g := family.GetMetric()[0].GetGauge().GetValue(); g != 0 { t.Errorf("In Flight Mismatch: got %v", g) }
+		}
+	}
+	for _, name := range []string{"reqtify_requests_total", "reqtify_request_duration_seconds", "reqtify_requests_in_flight"} {
+		if !found[name] { t.Errorf("Family Mismatch: %s missing", name) }
+	}
+}
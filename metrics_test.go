@@ -0,0 +1,62 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 { return nil, errors.New("connection refused") }
+		if calls == 2 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	metrics := NewMetrics()
+	metrics.Buckets = []float64{1, 0.1}
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	reqt := New("https://this.is.a.test", ticker, nil, nil, "test", WithMetrics(metrics))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	if _, err := reqt.New("/a").Retry(RetryPolicy{MaxAttempts: 3}).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	reqt.New("/a").Method(POST).Do()
+
+	families := metrics.Families()
+	if len(families) != 4 { t.Fatalf("Families Mismatch: got %d, expected 4", len(families)) }
+	requests := families[3]
+	if requests.Name != "reqtify_requests_total" || len(requests.Values) != 4 { t.Fatalf("Requests Mismatch: got %+v", requests) }
+	if v := requests.Values[0]; v.Labels["method"] != "GET" || v.Labels["host"] != "this.is.a.test" || v.Labels["status"] != "200" || v.Value != 1 { t.Errorf("Requests Mismatch: got %+v", v) }
+	if v := requests.Values[2]; v.Labels["status"] != "error" || v.Value != 1 { t.Errorf("Error Mismatch: got %+v", v) }
+	if v := requests.Values[3]; v.Labels["method"] != "POST" || v.Labels["status"] != "200" { t.Errorf("Post Mismatch: got %+v", v) }
+
+	durations := families[1]
+	if durations.Name != "reqtify_request_duration_seconds" || len(durations.Values) != 2 { t.Fatalf("Durations Mismatch: got %+v", durations) }
+	if h := durations.Values[0].Histogram; h.Count != 3 || h.Buckets[1] != 3 || len(h.Buckets) != 2 { t.Errorf("Histogram Mismatch: got %+v", h) }
+	if waits := families[0]; waits.Values[0].Labels["group"] != "default" || waits.Values[0].Histogram.Count != 4 { t.Errorf("Wait Mismatch: got %+v", waits.Values[0]) }
+	if inFlight := families[2]; inFlight.Name != "reqtify_requests_in_flight" || inFlight.Values[0].Value != 0 { t.Errorf("In Flight Mismatch: got %+v", inFlight) }
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	text := recorder.Body.String()
+	for _, expected := range []string{
+		"# TYPE reqtify_requests_total counter\n",
+		"reqtify_requests_total{host=\"this.is.a.test\",method=\"GET\",status=\"503\"} 1\n",
+		"reqtify_request_duration_seconds_bucket{host=\"this.is.a.test\",method=\"GET\",le=\"0.1\"} 3\n",
+		"reqtify_request_duration_seconds_bucket{host=\"this.is.a.test\",method=\"GET\",le=\"+Inf\"} 3\n",
+		"reqtify_request_duration_seconds_count{host=\"this.is.a.test\",method=\"GET\"} 3\n",
+		"reqtify_requests_in_flight 0\n",
+		"reqtify_rate_limiter_wait_seconds_count{group=\"default\"} 4\n",
+	} {
+		if !strings.Contains(text, expected) { t.Errorf("Text Mismatch: expected %q in:\n%s", expected, text) }
+	}
+}
@@ -0,0 +1,101 @@
+package reqtify
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+/*
+   Large file uploads normally stream through a small buffer, reading every
+   byte from the page cache into userspace first. Mapping the file into memory
+   instead lets it be written straight out of the page cache, and lets the OS
+   decide how much of a multi gigabyte file to keep resident.
+
+   A mapped file's part implements io.WriterTo all the way up to the request
+   body, so anything which copies the body with io.Copy, like a custom
+   HttpClient, gets the mapping in a single Write. net/http's own transport
+   copies bodies of known length through its own buffer, but still straight
+   out of the mapping, without read calls on the file.
+
+   Where mapping isn't possible (pipes, special files, platforms without mmap),
+   files are read in large chunks instead.
+*/
+
+// the size of the chunks read from files which can't be mapped.
+const mappedFileChunkSize = 1 << 20
+
+// a reader over a memory mapped file. It implements io.WriterTo, so io.Copy
+// writes the mapping directly rather than copying it through a buffer.
+type mappedFile struct {
+	data   []byte
+	offset int
+	file  *os.File
+}
+
+func (this *mappedFile) Read(p []byte) (int, error) {
+	if this.offset >= len(this.data) { return 0, io.EOF }
+	n := copy(p, this.data[this.offset:])
+	this.offset += n
+	return n, nil
+}
+
+func (this *mappedFile) Len() (int) {
+	return len(this.data) - this.offset
+}
+
+func (this *mappedFile) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(this.data[this.offset:])
+	this.offset += n
+	return int64(n), err
+}
+
+func (this *mappedFile) Close() (error) {
+	err := munmap(this.data)
+	this.data = nil
+	if e := this.file.Close(); err == nil { err = e }
+	return err
+}
+
+type chunkedFile struct {
+	*bufio.Reader
+	file *os.File
+}
+
+func (this *chunkedFile) Close() (error) {
+	return this.file.Close()
+}
+
+// returns a reader over the rest of f, memory mapped if possible, or read in
+// large chunks if not. Closing the reader closes f.
+func OpenMapped(f *os.File) (io.ReadCloser, error) {
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() > 0 {
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err == nil && offset < info.Size() {
+			if data, err := mmap(f, info.Size()); err == nil {
+				return &mappedFile{data: data, offset: int(offset), file: f}, nil
+			}
+		}
+	}
+	return &chunkedFile{Reader: bufio.NewReaderSize(f, mappedFileChunkSize), file: f}, nil
+}
+
+// like FileArg, but sends the file through a memory mapping where possible,
+// for very large uploads. The file is closed once the request is done. A
+// mapped file's content type is sniffed from the mapping and its length is
+// known, so the request keeps its Content-Length.
+func (this *RequestImpl) MappedFileArg(key, filename string, f *os.File) (Request) {
+	data, err := OpenMapped(f)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	m, ok := data.(*mappedFile)
+	if !ok { return this.FileArg(key, filename, data) }
+
+	start := m.data[m.offset:]
+	if len(start) > 512 { start = start[:512] }
+	file := FormFile{Name: filename, Data: m, ContentType: detectContentType(filename, start), size: int64(m.Len()), sized: true}
+	this.FormFiles[key] = append(this.FormFiles[key], file)
+	return this
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package reqtify
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoMmap = errors.New("memory mapping is not supported on this platform")
+
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+func munmap(data []byte) (error) {
+	return nil
+}
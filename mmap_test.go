@@ -0,0 +1,83 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"io"
+	"os"
+	"net/http"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+func TestMappedFileArg(t *testing.T) {
+	contents := strings.Repeat("0123456789", 100000)
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil { t.Fatal(err) }
+
+	var http_mock_client test.MockHttpClient
+	var body []byte
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		body, _ = ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	f, _ := os.Open(path)
+	f.Seek(10, 0)
+	_, err := reqt.New("/upload").Method(POST).MappedFileArg("file", "big.bin", f).Do()
+	if err != nil { t.Fatalf("Upload Failure: %s", err.Error()) }
+	if !bytes.Contains(body, []byte("\r\n\r\n" + contents[10:] + "\r\n")) { t.Errorf("Body Mismatch: got %d bytes", len(body)) }
+	if _, err := f.Stat(); err == nil { t.Errorf("Close Mismatch: file still open") }
+
+	// pipes can't be mapped, so are read in chunks
+	r, w, _ := os.Pipe()
+	go func() { w.Write([]byte("piped")); w.Close() }()
+	_, err = reqt.New("/upload").Method(POST).MappedFileArg("file", "pipe", r).Do()
+	if err != nil || !bytes.Contains(body, []byte("\r\n\r\npiped\r\n")) { t.Errorf("Pipe Mismatch: got %q, %v", body, err) }
+}
+
+// records the size of each write, to see whether the mapping was written in
+// one go.
+type writeRecorder struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (this *writeRecorder) Write(p []byte) (int, error) {
+	this.writes = append(this.writes, len(p))
+	return this.Buffer.Write(p)
+}
+
+func TestMappedFileArgWriteTo(t *testing.T) {
+	contents := strings.Repeat("0123456789", 100000)
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil { t.Fatal(err) }
+
+	var http_mock_client test.MockHttpClient
+	var body writeRecorder
+	var length int64
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		length = req.ContentLength
+		io.Copy(&body, req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	f, _ := os.Open(path)
+	if _, err := reqt.New("/upload").Method(POST).MappedFileArg("file", "big.txt", f).Do(); err != nil { t.Fatalf("Upload Failure: %s", err.Error()) }
+	if length <= 0 || length != int64(body.Len()) { t.Errorf("Length Mismatch: got Content-Length %d for %d bytes", length, body.Len()) }
+	if !bytes.Contains(body.Bytes(), []byte("Content-Type: text/plain; charset=utf-8\r\n\r\n" + contents + "\r\n")) { t.Errorf("Body Mismatch: got %d bytes", body.Len()) }
+
+	written := false
+	for _, n := range body.writes {
+		if n == len(contents) { written = true }
+	}
+	if !written { t.Errorf("WriteTo Mismatch: the mapping wasn't written in one go, got writes of %v", body.writes) }
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package reqtify
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmap(f *os.File, size int64) ([]byte, error) {
+	if int64(int(size)) != size { return nil, syscall.EFBIG } // too big to map on 32 bit platforms
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil { return nil, err }
+	return data, nil
+}
+
+func munmap(data []byte) (error) {
+	if data == nil { return nil }
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,23 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"net/http"
+)
+
+func TestAccessors(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	var got reqtify.Request
+	m.AnalyzeWith(func(req *RequestMock) (*http.Response, error) {
+		got = req
+		return JSONResponse(200, `{}`), nil
+	})
+
+	m.New("/posts").Method(reqtify.POST).Header("X-Api-Key", "secret").Arg("tags", "cat").Arg("tags", "dog").URLArg("page", 2).FormArg("title", "hello").Cookie(&http.Cookie{Name: "session", Value: "abc"}).Do()
+	if got.GetVerb() != reqtify.POST || got.GetPath() != "/posts" { t.Errorf("Verb Mismatch: got %s %s", got.GetVerb(), got.GetPath()) }
+	if got.GetHeader("X-Api-Key") != "secret" || got.GetHeader("x-api-key") != "secret" || got.GetHeader("X-Other") != "" { t.Errorf("Header Mismatch: got %q", got.GetHeader("x-api-key")) }
+	if got.GetArg("tags") != "cat" || got.GetURLArg("page") != "2" || got.GetFormArg("title") != "hello" || got.GetFormArg("tags") != "" { t.Errorf("Arg Mismatch: got %q, %q, %q", got.GetArg("tags"), got.GetURLArg("page"), got.GetFormArg("title")) }
+	if cookies := got.GetCookies(); len(cookies) != 1 || cookies[0].Value != "abc" { t.Errorf("Cookies Mismatch: got %v", cookies) }
+}
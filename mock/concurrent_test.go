@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/test"
+
+	"testing"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+func TestConcurrentExamine(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	examiner := m.Examine()
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.New(fmt.Sprintf("/%d", i)).TextInto(&results[i]).Do()
+		}(i)
+	}
+	// answer each request with its own path, so misrouted responses show up.
+	for range results {
+		req := <- examiner.Requests
+		examiner.Responses <- ResponseAndError{Response: response(200, "text/plain", []byte(req.URLPath))}
+	}
+	wg.Wait()
+	for i, r := range results {
+		if r != fmt.Sprintf("/%d", i) { t.Errorf("Response Mismatch: got %q for /%d", r, i) }
+	}
+}
+
+func TestConcurrentRoutes(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Expect("GET", "/once").Times(5)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); m.New("/once").Do() }()
+		go func(i int) { defer wg.Done(); m.Respond("GET", fmt.Sprintf("/r/%d", i), 200, nil) }(i)
+	}
+	wg.Wait()
+	if n := len(m.Unexpected()); n != 15 { t.Errorf("Unexpected Mismatch: got %d, expected 15", n) }
+}
+
+func TestConcurrentHttpExamine(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	examiner := http_mock_client.Examine()
+
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http_mock_client.Get(fmt.Sprintf("https://this.is.a.test/%d", i))
+			if err != nil { return }
+			body, _ := ioutil.ReadAll(resp.Body)
+			results[i] = string(body)
+		}(i)
+	}
+	for range results {
+		req := <- examiner.Requests
+		examiner.Responses <- test.ResponseAndError{Response: &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader([]byte(req.URL.Path)))}}
+	}
+	wg.Wait()
+	for i, r := range results {
+		if !strings.HasSuffix(r, fmt.Sprintf("/%d", i)) || len(r) != len(fmt.Sprintf("/%d", i)) { t.Errorf("Response Mismatch: got %q for /%d", r, i) }
+	}
+}
@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+)
+
+/*
+   A differential harness, for checking that code behaves the same against
+   ReqtifierMock as it does against a real Reqtifier. The two execute requests
+   along different paths (ReqtifierImpl.Do and RequestMock.Do), so a test
+   which passes against the mock doesn't necessarily prove anything about the
+   real thing. Differential runs the same client code against both, serving
+   both from the same http.Handler, and reports any difference in the requests
+   the handler saw or in what the client code returned:
+
+	result := mock.Differential(handler, func(r reqtify.Reqtifier) (interface{}, error) {
+		var out Post
+		_, err := r.New("/posts/1").JSONInto(&out).Do()
+		return out, err
+	})
+	for _, d := range result.Diff() {
+		t.Error(d)
+	}
+*/
+
+// headers which a real transport adds on its own, and which are therefore
+// not compared.
+var TransportHeaders = []string{"User-Agent", "Accept-Encoding", "Content-Length", "Transfer-Encoding", "Connection"}
+
+// what a handler saw of a single request.
+type Observation struct {
+	Method string
+	URL    string // the request URI, like "/posts?page=2".
+	Header http.Header
+	Body   []byte // multipart boundaries are replaced with "BOUNDARY".
+}
+
+// the outcome of running client code against one Reqtifier.
+type Run struct {
+	Requests []Observation
+	Value    interface{}
+	Err      error
+}
+
+type DifferentialResult struct {
+	Real Run
+	Mock Run
+}
+
+// lists the differences between the real and mock runs, if any.
+func (this DifferentialResult) Diff() ([]string) {
+	var diffs []string
+	if len(this.Real.Requests) != len(this.Mock.Requests) {
+		diffs = append(diffs, fmt.Sprintf("request count: real %d, mock %d", len(this.Real.Requests), len(this.Mock.Requests)))
+	}
+	for i := 0; i < len(this.Real.Requests) && i < len(this.Mock.Requests); i++ {
+		real, mock := this.Real.Requests[i], this.Mock.Requests[i]
+		if real.Method != mock.Method || real.URL != mock.URL {
+			diffs = append(diffs, fmt.Sprintf("request %d: real %s %s, mock %s %s", i, real.Method, real.URL, mock.Method, mock.URL))
+		}
+		if !reflect.DeepEqual(real.Header, mock.Header) {
+			diffs = append(diffs, fmt.Sprintf("request %d headers: real %v, mock %v", i, real.Header, mock.Header))
+		}
+		if !bytes.Equal(real.Body, mock.Body) {
+			diffs = append(diffs, fmt.Sprintf("request %d body: real %q, mock %q", i, real.Body, mock.Body))
+		}
+	}
+	if !reflect.DeepEqual(this.Real.Value, this.Mock.Value) {
+		diffs = append(diffs, fmt.Sprintf("result: real %#v, mock %#v", this.Real.Value, this.Mock.Value))
+	}
+	if fmt.Sprint(this.Real.Err) != fmt.Sprint(this.Mock.Err) {
+		diffs = append(diffs, fmt.Sprintf("error: real %v, mock %v", this.Real.Err, this.Mock.Err))
+	}
+	return diffs
+}
+
+// records the requests passing through to a handler.
+type recorder struct {
+	handler  http.Handler
+	lock     sync.Mutex
+	requests []Observation
+}
+
+func (this *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = ioutil.ReadAll(r.Body)
+	}
+	header := r.Header.Clone()
+	for _, h := range TransportHeaders {
+		header.Del(h)
+	}
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil && params["boundary"] != "" {
+		boundary := params["boundary"]
+		body = bytes.ReplaceAll(body, []byte(boundary), []byte("BOUNDARY"))
+		header.Set("Content-Type", string(bytes.ReplaceAll([]byte(header.Get("Content-Type")), []byte(boundary), []byte("BOUNDARY"))))
+	}
+
+	this.lock.Lock()
+	this.requests = append(this.requests, Observation{Method: r.Method, URL: r.URL.RequestURI(), Header: header, Body: body})
+	this.lock.Unlock()
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	this.handler.ServeHTTP(w, r)
+}
+
+// runs client against a real Reqtifier backed by an httptest server, and
+// against a ReqtifierMock, both serving requests with handler.
+func Differential(handler http.Handler, client func(reqtify.Reqtifier) (interface{}, error)) (DifferentialResult) {
+	var result DifferentialResult
+
+	real := &recorder{handler: handler}
+	server := httptest.NewServer(real)
+	defer server.Close()
+	result.Real.Value, result.Real.Err = client(reqtify.New(server.URL, nil, nil, nil, ""))
+	result.Real.Requests = real.requests
+
+	mocked := &recorder{handler: handler}
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: server.URL}}
+	m.AnalyzeWith(func(req *RequestMock) (*http.Response, error) {
+		r, err := req.HTTPRequest(context.Background())
+		if err != nil { return nil, err }
+		w := httptest.NewRecorder()
+		mocked.ServeHTTP(w, r)
+		return w.Result(), nil
+	})
+	result.Mock.Value, result.Mock.Err = client(m)
+	result.Mock.Requests = mocked.requests
+
+	return result
+}
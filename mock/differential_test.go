@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"net/http"
+	"strings"
+	"io/ioutil"
+)
+
+type post struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func TestDifferential(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "title": "hello"}`))
+	})
+
+	clients := map[string]func(reqtify.Reqtifier) (interface{}, error){
+		"get": func(r reqtify.Reqtifier) (interface{}, error) {
+			var out post
+			_, err := r.New("/posts/1").Arg("fields", "title").URLArg("page", 2).Header("X-Test", "yes").JSONInto(&out).Do()
+			return out, err
+		},
+		"form": func(r reqtify.Reqtifier) (interface{}, error) {
+			resp, err := r.New("/posts").Method(reqtify.POST).Arg("title", "hello").FormArg("id", 1).BasicAuthentication("user", "pass").Do()
+			if err != nil { return nil, err }
+			return resp.StatusCode, nil
+		},
+		"multipart": func(r reqtify.Reqtifier) (interface{}, error) {
+			resp, err := r.New("/upload").Method(reqtify.PUT).FormArg("name", "f").FileArg("file", "f.txt", ioutil.NopCloser(strings.NewReader("contents"))).Cookie(&http.Cookie{Name: "session", Value: "abc"}).Do()
+			if err != nil { return nil, err }
+			return resp.StatusCode, nil
+		},
+		"json": func(r reqtify.Reqtifier) (interface{}, error) {
+			var out post
+			_, err := r.New("/posts").Method(reqtify.POST).URLArg("dry_run", true).JSONBody(post{ID: 2, Title: "new"}).JSONInto(&out).Do()
+			return out, err
+		},
+		"status": func(r reqtify.Reqtifier) (interface{}, error) {
+			resp, err := r.New("/missing").Do()
+			if err != nil { return nil, err }
+			return resp.StatusCode, nil
+		},
+	}
+
+	for name, client := range clients {
+		for _, d := range Differential(handler, client).Diff() {
+			t.Errorf("%s: %s", name, d)
+		}
+	}
+}
+
+func TestDifferentialDetectsDivergence(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	result := Differential(handler, func(r reqtify.Reqtifier) (interface{}, error) {
+		_, mocked := r.(*ReqtifierMock)
+		req := r.New("/")
+		if mocked { req.URLArg("extra", 1) }
+		_, err := req.Do()
+		return mocked, err
+	})
+	if diffs := result.Diff(); len(diffs) != 2 { t.Errorf("Diff Mismatch: got %q, expected URL and result differences", diffs) }
+}
@@ -0,0 +1,157 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// an Expectation is a route which expects to be used a certain number of
+// times, checked by Verify:
+//
+//	m.Expect("GET", "/users/1").Times(2).Return(mock.JSONResponse(200, `{"name": "alice"}`), nil)
+//	... code under test ...
+//	m.Verify(t)
+//
+// Once an expectation has been used up, later ones for the same request
+// answer it, so a sequence of responses can be given in order.
+type Expectation struct {
+	method, path string
+
+	lock  sync.Mutex
+	times int // how many calls are expected, or -1 for any number.
+	calls int
+	reply canned
+}
+
+// adds an expectation for one call to method and path (see MatchRequest),
+// answered with an empty 200 response unless Return says otherwise.
+func (this *ReqtifierMock) Expect(method, path string) (*Expectation) {
+	e := &Expectation{method: method, path: path, times: 1}
+	match := MatchRequest(method, path)
+	this.addRoute(&route{
+		match: func(req *RequestMock) bool { return match(req) && e.claim() },
+		respond: e.respond,
+	})
+	this.lock.Lock()
+	this.expectations = append(this.expectations, e)
+	this.lock.Unlock()
+	return e
+}
+
+// sets how many calls are expected.
+func (this *Expectation) Times(n int) (*Expectation) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.times = n
+	return this
+}
+
+// allows any number of calls, including none.
+func (this *Expectation) AnyTimes() (*Expectation) {
+	return this.Times(-1)
+}
+
+// sets what each call returns. The response's body is read now, and each
+// call gets its own copy of it.
+func (this *Expectation) Return(resp *http.Response, err error) (*Expectation) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.reply = newCanned(resp, err)
+	return this
+}
+
+// counts a call if the expectation isn't used up yet. Calls are counted as
+// they are matched, so concurrent requests can't overdraw it.
+func (this *Expectation) claim() (bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.times >= 0 && this.calls >= this.times { return false }
+	this.calls++
+	return true
+}
+
+func (this *Expectation) respond(req *RequestMock) (*http.Response, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.reply.response()
+}
+
+func (this *Expectation) String() (string) {
+	times := fmt.Sprintf("%d times", this.times)
+	if this.times < 0 { times = "any number of times" }
+	return fmt.Sprintf("%s %s, expected %s", this.method, this.path, times)
+}
+
+// the parts of *testing.T which Verify uses.
+type TestReporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// reports whether every expectation was met. If not, it fails t, listing
+// the expectations which weren't, and any requests which no route matched,
+// including those made after an expectation was used up.
+func (this *ReqtifierMock) Verify(t TestReporter) (bool) {
+	t.Helper()
+	ok := true
+	this.lock.Lock()
+	expectations := this.expectations
+	this.lock.Unlock()
+	for _, e := range expectations {
+		e.lock.Lock()
+		if e.times >= 0 && e.calls != e.times {
+			t.Errorf("unmet expectation: %s, got %d", e, e.calls)
+			ok = false
+		}
+		e.lock.Unlock()
+	}
+	for _, u := range this.Unexpected() {
+		t.Errorf("unexpected request: %s", u)
+		ok = false
+	}
+	return ok
+}
+
+// returns the requests no route matched, as "METHOD URL".
+func (this *ReqtifierMock) Unexpected() ([]string) {
+	this.lock.Lock()
+	routes := this.routes
+	this.lock.Unlock()
+	if routes == nil { return nil }
+	routes.lock.Lock()
+	defer routes.lock.Unlock()
+	return append([]string(nil), routes.unmatched...)
+}
+
+// like Verify, but also fails t for canned responses which were never given:
+// Respond and RespondWithFile routes no request used, responses left over in
+// a Sequence, and exchanges ReplayHAR never replayed. Handlers registered
+// with Handle or HandleMatching aren't checked.
+func (this *ReqtifierMock) AssertExhausted(t TestReporter) (bool) {
+	t.Helper()
+	ok := this.Verify(t)
+	this.lock.Lock()
+	routes := this.routes
+	this.lock.Unlock()
+	if routes == nil { return ok }
+	for _, u := range routes.unused() {
+		t.Errorf("unused response: %s", u)
+		ok = false
+	}
+	return ok
+}
+
+// the parts of *testing.T which Cleanup uses.
+type CleanupReporter interface {
+	TestReporter
+	Cleanup(func())
+}
+
+// calls AssertExhausted on t once the test and its subtests finish.
+//
+//	m := &mock.ReqtifierMock{FakeReqtifier: reqt}
+//	m.Cleanup(t)
+func (this *ReqtifierMock) Cleanup(t CleanupReporter) {
+	t.Cleanup(func() { this.AssertExhausted(t) })
+}
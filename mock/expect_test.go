@@ -0,0 +1,67 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type reporter struct {
+	errors []string
+}
+
+func (this *reporter) Helper() {}
+
+func (this *reporter) Errorf(format string, args ...interface{}) {
+	this.errors = append(this.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExpect(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Expect("GET", "/posts/1").Times(2).Return(JSONResponse(200, `{"id": 1, "title": "first"}`), nil)
+	m.Expect("GET", "/posts/1").Return(JSONResponse(200, `{"id": 1, "title": "changed"}`), nil)
+	m.Expect("DELETE", "/posts/1").Return(nil, errors.New("offline"))
+	m.Expect("GET", "/health").AnyTimes()
+
+	var titles []string
+	for i := 0; i < 3; i++ {
+		var p post
+		if _, err := m.New("/posts/1").JSONInto(&p).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+		titles = append(titles, p.Title)
+	}
+	if fmt.Sprint(titles) != "[first first changed]" { t.Errorf("Sequence Mismatch: got %v", titles) }
+	if _, err := m.New("/posts/1").Method(reqtify.DELETE).Do(); err == nil || err.Error() != "offline" { t.Errorf("Error Mismatch: got %v", err) }
+	if resp, err := m.New("/health").Do(); err != nil || resp.StatusCode != 200 { t.Errorf("Default Mismatch: got %v", err) }
+
+	var r reporter
+	if !m.Verify(&r) || len(r.errors) != 0 { t.Errorf("Verify Mismatch: got %v", r.errors) }
+
+	// one call too many, and one expectation never met
+	m.Expect("POST", "/posts")
+	if _, err := m.New("/posts/1").Do(); err != ErrNoHandler { t.Errorf("Exceeded Mismatch: got %v", err) }
+	if m.Verify(&r) || len(r.errors) != 2 { t.Errorf("Verify Mismatch: got %v", r.errors) }
+	if r.errors[0] != "unmet expectation: POST /posts, expected 1 times, got 0" || r.errors[1] != "unexpected request: GET https://this.is.a.test/posts/1" { t.Errorf("Message Mismatch: got %q", r.errors) }
+}
+
+func TestAssertExhausted(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Respond("GET", "/used", 200, nil)
+	m.Respond("GET", "/unused", 200, nil)
+	m.Sequence("GET", "/flaky", ResponseAndError{Response: JSONResponse(503, `{}`)}, ResponseAndError{Response: JSONResponse(200, `{}`)})
+	m.Handle("GET", "/handler", func(req *RequestMock, params Params) (*http.Response, error) { return JSONResponse(200, `{}`), nil })
+
+	m.New("/used").Do()
+	m.New("/flaky").Do()
+	var r reporter
+	if m.AssertExhausted(&r) || len(r.errors) != 2 { t.Errorf("AssertExhausted Mismatch: got %q", r.errors) }
+	if len(r.errors) == 2 && (r.errors[0] != "unused response: GET /unused" || r.errors[1] != "unused response: GET /flaky, 1 of 2 responses") { t.Errorf("Message Mismatch: got %q", r.errors) }
+
+	t.Run("cleanup", func(t *testing.T) {
+		m.Cleanup(t)
+		m.New("/unused").Do()
+		m.New("/flaky").Do()
+	})
+}
@@ -0,0 +1,129 @@
+package mock
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+/*
+   Faults simulate an unreliable network for the requests a Matcher selects,
+   whichever route answers them, so that retries, timeouts and the like can
+   be tested deterministically:
+
+	m.Inject(mock.MatchRequest("GET", "/slow/{id}"), mock.Latency(50 * time.Millisecond), mock.TruncateBody(10))
+	m.Inject(mock.MatchRequest("", "/down"), mock.ConnectionReset())
+*/
+
+// a Fault wraps the handling of a request.
+type Fault func(next ReqtifyAnalyzer) ReqtifyAnalyzer
+
+type injection struct {
+	match  Matcher
+	faults []Fault
+}
+
+// applies faults to every request match accepts. Faults listed first are
+// outermost, and injections made first are applied first.
+func (this *ReqtifierMock) Inject(match Matcher, faults ...Fault) {
+	routes := this.routeTable()
+	routes.lock.Lock()
+	routes.injections = append(routes.injections, injection{match: match, faults: faults})
+	routes.lock.Unlock()
+}
+
+// wraps respond in the faults which apply to req.
+func (this *routes) inject(req *RequestMock, respond ReqtifyAnalyzer) (ReqtifyAnalyzer) {
+	this.lock.Lock()
+	injections := this.injections
+	this.lock.Unlock()
+
+	for i := len(injections) - 1; i >= 0; i-- {
+		if !injections[i].match(req) { continue }
+		for j := len(injections[i].faults) - 1; j >= 0; j-- {
+			respond = injections[i].faults[j](respond)
+		}
+	}
+	return respond
+}
+
+func requestContext(req *RequestMock) (context.Context) {
+	if req.RequestContext == nil { return context.Background() }
+	return req.RequestContext
+}
+
+// delays each request by d, or until its context is done.
+func Latency(d time.Duration) (Fault) {
+	return func(next ReqtifyAnalyzer) ReqtifyAnalyzer {
+		return func(req *RequestMock) (*http.Response, error) {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <- t.C:
+				return next(req)
+			case <- requestContext(req).Done():
+				return nil, requestContext(req).Err()
+			}
+		}
+	}
+}
+
+// fails each request as though the server reset the connection.
+func ConnectionReset() (Fault) {
+	return func(next ReqtifyAnalyzer) ReqtifyAnalyzer {
+		return func(req *RequestMock) (*http.Response, error) {
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+		}
+	}
+}
+
+// makes each request hang until its context is done. Requests without a
+// deadline fail at once with a timeout error, rather than hanging forever.
+func Timeout() (Fault) {
+	return func(next ReqtifyAnalyzer) ReqtifyAnalyzer {
+		return func(req *RequestMock) (*http.Response, error) {
+			ctx := requestContext(req)
+			if _, ok := ctx.Deadline(); !ok && ctx.Done() == nil { return nil, timeoutError{} }
+			<- ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() (string) { return "i/o timeout" }
+func (timeoutError) Timeout() (bool) { return true }
+func (timeoutError) Temporary() (bool) { return true }
+
+// cuts each response body off after n bytes, with io.ErrUnexpectedEOF, as
+// though the connection dropped partway through it.
+func TruncateBody(n int64) (Fault) {
+	return func(next ReqtifyAnalyzer) ReqtifyAnalyzer {
+		return func(req *RequestMock) (*http.Response, error) {
+			resp, err := next(req)
+			if resp != nil && resp.Body != nil {
+				resp.Body = &truncatedBody{ReadCloser: resp.Body, left: n}
+			}
+			return resp, err
+		}
+	}
+}
+
+type truncatedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (this *truncatedBody) Read(p []byte) (int, error) {
+	if this.left <= 0 { return 0, io.ErrUnexpectedEOF }
+	if int64(len(p)) > this.left { p = p[:this.left] }
+	n, err := this.ReadCloser.Read(p)
+	this.left -= int64(n)
+	return n, err
+}
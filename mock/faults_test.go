@@ -0,0 +1,37 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+func TestFaults(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Respond("GET", "/posts/{id}", 200, []byte(`{"id": 1, "title": "hello"}`))
+	m.Inject(MatchRequest("GET", "/posts/1"), Latency(30 * time.Millisecond), TruncateBody(10))
+	m.Inject(MatchRequest("", "/down"), ConnectionReset())
+	m.Inject(MatchRequest("", "/hang"), Timeout())
+
+	var p post
+	start := time.Now()
+	_, err := m.New("/posts/1").JSONInto(&p).Do()
+	if !errors.Is(err, io.ErrUnexpectedEOF) || time.Since(start) < 30 * time.Millisecond { t.Errorf("Latency Mismatch: got %v after %s", err, time.Since(start)) }
+	if _, err = m.New("/posts/2").JSONInto(&p).Do(); err != nil || p.Title != "hello" { t.Errorf("Unaffected Mismatch: got %v", err) }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Millisecond)
+	defer cancel()
+	if _, err = m.New("/posts/1").Context(ctx).Do(); !errors.Is(err, context.DeadlineExceeded) { t.Errorf("Cancel Mismatch: got %v", err) }
+
+	// faults apply even to requests no route answers
+	if _, err = m.New("/down").Do(); !errors.Is(err, syscall.ECONNRESET) { t.Errorf("Reset Mismatch: got %v", err) }
+	if _, err = m.New("/hang").Context(ctx).Do(); !errors.Is(err, context.DeadlineExceeded) { t.Errorf("Timeout Mismatch: got %v", err) }
+	var netErr net.Error
+	if _, err = m.New("/hang").Do(); !errors.As(err, &netErr) || !netErr.Timeout() { t.Errorf("Timeout Mismatch: got %v", err) }
+}
@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+// answers requests matching method and path (see MatchRequest) with status
+// and body, with a Content-Type guessed from the body.
+func (this *ReqtifierMock) Respond(method, path string, status int, body []byte) {
+	this.addFixture(method, path, func(req *RequestMock) (*http.Response, error) {
+		return response(status, http.DetectContentType(body), body), nil
+	})
+}
+
+// answers requests matching method and path (see MatchRequest) with status,
+// and the contents of filename, like a fixture in testdata. The file is read
+// for each request, so a missing one fails the request rather than the
+// registration. Its Content-Type is guessed from its extension.
+//
+//	m.RespondWithFile("GET", "/users/1", "testdata/user.json", 200)
+func (this *ReqtifierMock) RespondWithFile(method, path, filename string, status int) {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	this.addFixture(method, path, func(req *RequestMock) (*http.Response, error) {
+		body, err := ioutil.ReadFile(filename)
+		if err != nil { return nil, err }
+		if contentType == "" { return response(status, http.DetectContentType(body), body), nil }
+		return response(status, contentType, body), nil
+	})
+}
+
+// adds a route answering method and path with respond, which AssertExhausted
+// reports if no request ever uses it.
+func (this *ReqtifierMock) addFixture(method, path string, respond ReqtifyAnalyzer) {
+	var used int32
+	this.addRoute(&route{
+		match: MatchRequest(method, path),
+		respond: func(req *RequestMock) (*http.Response, error) {
+			atomic.StoreInt32(&used, 1)
+			return respond(req)
+		},
+		unused: func() []string {
+			if atomic.LoadInt32(&used) != 0 { return nil }
+			return []string{method + " " + path}
+		},
+	})
+}
+
+// returns a response with status, and a JSON body.
+func JSONResponse(status int, body string) (*http.Response) {
+	return response(status, "application/json", []byte(body))
+}
+
+func response(status int, contentType string, body []byte) (*http.Response) {
+	header := http.Header{}
+	if contentType != "" { header.Set("Content-Type", contentType) }
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		StatusCode: status,
+		Status: strconv.Itoa(status) + " " + http.StatusText(status),
+		Header: header,
+		ContentLength: int64(len(body)),
+		Body: ioutil.NopCloser(bytes.NewReader(body)),
+	}
+}
@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"os"
+)
+
+func TestRespondWithFile(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.RespondWithFile("GET", "/posts/1", "testdata/post.json", 200)
+	m.RespondWithFile("GET", "/posts/2", "testdata/missing.json", 200)
+	m.Respond("POST", "/posts", 201, []byte("created"))
+
+	var p post
+	resp, err := m.New("/posts/1").JSONInto(&p).Do()
+	if err != nil || p.Title != "from a fixture" || resp.Header.Get("Content-Type") != "application/json" { t.Errorf("Fixture Mismatch: got %+v, %v", p, err) }
+	if _, err = m.New("/posts/2").Do(); !os.IsNotExist(err) { t.Errorf("Missing Mismatch: got %v", err) }
+
+	var text string
+	resp, err = m.New("/posts").Method(reqtify.POST).TextInto(&text).Do()
+	if err != nil || resp.StatusCode != 201 || resp.Status != "201 Created" || text != "created" { t.Errorf("Respond Mismatch: got %v, %q", err, text) }
+	if _, err = m.New("/posts/1").Method(reqtify.PUT).Do(); err != ErrNoHandler { t.Errorf("Unmatched Mismatch: got %v", err) }
+}
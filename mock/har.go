@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// controls how recorded requests are matched to new ones by ReplayHAR.
+type HAROptions struct {
+	// also require request bodies to match, for APIs which take their
+	// arguments in POST bodies.
+	MatchBody bool
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		PostData *harContent `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Status     int    `json:"status"`
+		StatusText string `json:"statusText"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content harContent `json:"content"`
+	} `json:"response"`
+}
+
+type harContent struct {
+	Text     string `json:"text"`
+	Encoding string `json:"encoding"`
+}
+
+func (this *harContent) bytes() ([]byte) {
+	if this == nil { return nil }
+	if this.Encoding == "base64" {
+		data, _ := base64.StdEncoding.DecodeString(this.Text)
+		return data
+	}
+	return []byte(this.Text)
+}
+
+// a recorded request and its response, and whether it's been replayed.
+type harExchange struct {
+	method string
+	url    *url.URL
+	body   []byte
+	entry  harEntry
+	used   bool
+}
+
+type harReplay struct {
+	options   HAROptions
+	lock      sync.Mutex
+	exchanges []*harExchange
+}
+
+// answers requests with the responses recorded in a HAR file, like one
+// written by reqtify.HARRecorder or a browser. Requests are matched by
+// method, path and query, ignoring the host, and optionally by body. When
+// a request was recorded more than once, its responses are replayed in
+// order, and the last one is repeated once they run out.
+func (this *ReqtifierMock) ReplayHAR(path string, options HAROptions) (error) {
+	f, err := os.Open(path)
+	if err != nil { return err }
+	defer f.Close()
+	return this.ReplayHARFrom(f, options)
+}
+
+// like ReplayHAR, but reads the archive from r.
+func (this *ReqtifierMock) ReplayHARFrom(r io.Reader, options HAROptions) (error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil { return err }
+
+	replay := &harReplay{options: options}
+	for _, e := range har.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil { return err }
+		replay.exchanges = append(replay.exchanges, &harExchange{method: e.Request.Method, url: u, body: e.Request.PostData.bytes(), entry: e})
+	}
+
+	this.addRoute(&route{match: replay.match, respond: replay.respond, unused: replay.unused})
+	return nil
+}
+
+func (this *harReplay) unused() ([]string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	var unused []string
+	for _, x := range this.exchanges {
+		if !x.used { unused = append(unused, x.method + " " + x.url.String()) }
+	}
+	return unused
+}
+
+// finds the exchange to answer req with, marking it used if replay is set.
+func (this *harReplay) find(req *RequestMock, replay bool) (*harExchange) {
+	got, err := url.Parse(req.URL())
+	if err != nil { return nil }
+	var body []byte
+	if this.options.MatchBody {
+		if reader, _ := req.GetBody(); reader != nil { body, _ = ioutil.ReadAll(reader) }
+		if req.RawBody != nil {
+			// it's been consumed, so replace it for anything which reads it later
+			req.RawBody = bytes.NewReader(body)
+		}
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	var last *harExchange
+	for _, x := range this.exchanges {
+		if !strings.EqualFold(x.method, string(req.Verb)) || x.url.Path != got.Path || !sameQuery(got, x.url) || (x.url.RawQuery == "" && got.RawQuery != "") { continue }
+		if this.options.MatchBody && !bytes.Equal(x.body, body) { continue }
+		if !x.used {
+			x.used = replay
+			return x
+		}
+		last = x
+	}
+	return last
+}
+
+func (this *harReplay) match(req *RequestMock) (bool) {
+	return this.find(req, false) != nil
+}
+
+func (this *harReplay) respond(req *RequestMock) (*http.Response, error) {
+	x := this.find(req, true)
+	if x == nil { return nil, ErrNoHandler }
+
+	r := x.entry.Response
+	resp := &http.Response{
+		StatusCode: r.Status,
+		Status: strings.TrimSpace(strconv.Itoa(r.Status) + " " + r.StatusText),
+		Header: http.Header{},
+		Body: ioutil.NopCloser(bytes.NewReader(r.Content.bytes())),
+	}
+	for _, h := range r.Headers {
+		resp.Header.Add(h.Name, h.Value)
+	}
+	// HAR content is recorded decoded, so these describe a body we don't have
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
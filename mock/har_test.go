@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/test"
+
+	"testing"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestReplayHAR(t *testing.T) {
+	server := test.NewServer()
+	defer server.Close()
+	har := reqtify.NewHARRecorder()
+	real := reqtify.New(server.URL, nil, nil, nil, "test", reqtify.WithHARRecorder(har))
+
+	// bodies are only recorded as they're read
+	var recorded test.EchoResponse
+	for _, n := range []string{"1", "2"} {
+		if _, err := real.New("/items").Arg("page", n).Arg("size", 10).JSONInto(&recorded).Do(); err != nil { t.Fatalf("Record Failure: %s", err.Error()) }
+	}
+	real.New("/items").Arg("page", "3").JSONInto(&recorded).Do()
+	real.New("/search").Method(reqtify.POST).Arg("q", "a").JSONInto(&recorded).Do()
+	real.New("/search").Method(reqtify.POST).Arg("q", "b").JSONInto(&recorded).Do()
+	var archive bytes.Buffer
+	har.WriteTo(&archive)
+
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://somewhere.else"}}
+	if err := m.ReplayHARFrom(bytes.NewReader(archive.Bytes()), HAROptions{MatchBody: true}); err != nil { t.Fatalf("Load Failure: %s", err.Error()) }
+
+	var echo test.EchoResponse
+	// parameters in a different order still match
+	if _, err := m.New("/items").URLArg("size", 10).URLArg("page", 2).JSONInto(&echo).Do(); err != nil || echo.Query != "page=2&size=10" { t.Errorf("Replay Mismatch: got %+v, %v", echo, err) }
+	if _, err := m.New("/search").Method(reqtify.POST).Arg("q", "b").JSONInto(&echo).Do(); err != nil || echo.Body != "q=b" { t.Errorf("Body Mismatch: got %+v, %v", echo, err) }
+	if _, err := m.New("/search").Method(reqtify.POST).Arg("q", "c").Do(); !errors.Is(err, ErrNoHandler) { t.Errorf("Unmatched Mismatch: got %v", err) }
+	if _, err := m.New("/items").Method(reqtify.DELETE).Arg("page", 3).Do(); !errors.Is(err, ErrNoHandler) { t.Errorf("Method Mismatch: got %v", err) }
+
+	// routes registered later answer what the archive doesn't
+	m.HandleMatching(MatchRequest("GET", "/extra"), func(req *RequestMock) (*http.Response, error) { return &http.Response{StatusCode: 204, Body: http.NoBody}, nil })
+	if resp, err := m.New("/extra").Do(); err != nil || resp.StatusCode != 204 { t.Errorf("Route Mismatch: got %v", err) }
+}
+
+func TestReplayHARCompressed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"a":1}`))
+		gz.Close()
+	}))
+	defer server.Close()
+	har := reqtify.NewHARRecorder()
+	real := reqtify.New(server.URL, nil, nil, nil, "test", reqtify.WithHARRecorder(har), reqtify.WithAcceptEncoding("gzip"))
+	var recorded map[string]int
+	if _, err := real.New("/thing").JSONInto(&recorded).Do(); err != nil || recorded["a"] != 1 { t.Fatalf("Record Failure: got %v, %v", recorded, err) }
+	var archive bytes.Buffer
+	har.WriteTo(&archive)
+
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://somewhere.else"}}
+	if err := m.ReplayHARFrom(bytes.NewReader(archive.Bytes()), HAROptions{}); err != nil { t.Fatalf("Load Failure: %s", err.Error()) }
+	var replayed map[string]int
+	resp, err := m.New("/thing").JSONInto(&replayed).Do()
+	if err != nil || replayed["a"] != 1 { t.Fatalf("Replay Mismatch: got %v, %v", replayed, err) }
+	if resp.Header.Get("Content-Encoding") != "" || resp.Header.Get("Content-Length") != "" { t.Errorf("Header Mismatch: got %v", resp.Header) }
+}
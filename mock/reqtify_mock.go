@@ -2,22 +2,41 @@ package mock
 
 import (
 	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/internal/exchange"
 
+	"bytes"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"os"
+	"sync"
+	"time"
+
 )
 
 var ErrNoHandler error = errors.New("ReqtifierMock received a request it was not expecting")
 
 type ReqtifyAnalyzer func(req *RequestMock) (*http.Response, error)
 
+// ReqtifierMock is safe for concurrent use: requests may be made from many
+// goroutines while routes, expectations and faults are being registered.
 type ReqtifierMock struct {
 	FakeReqtifier *reqtify.ReqtifierImpl
 
+	lock        sync.Mutex // guards analyzeFunc, routes and expectations.
 	analyzeFunc ReqtifyAnalyzer
+	routes     *routes
+	expectations []*Expectation
+}
+
+// returns the statistics of FakeReqtifier. Requests answered by the mock
+// aren't counted.
+func (this *ReqtifierMock) Stats() (map[string]reqtify.EndpointStats) {
+	return this.FakeReqtifier.Stats()
 }
 
 func (this *ReqtifierMock) New(endpoint string) (reqtify.Request) {
@@ -37,7 +56,29 @@ func (this *ReqtifierMock) New(endpoint string) (reqtify.Request) {
 }
 
 func (this *ReqtifierMock) AnalyzeWith(f ReqtifyAnalyzer) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
 	this.analyzeFunc = f
+	this.routes = nil
+	this.expectations = nil
+}
+
+func (this *ReqtifierMock) analyzer() (ReqtifyAnalyzer) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.analyzeFunc
+}
+
+// returns the mock's routes, installing them in place of any analyzer if
+// there are none yet.
+func (this *ReqtifierMock) routeTable() (*routes) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.routes == nil {
+		this.routes = &routes{}
+		this.analyzeFunc = this.routes.dispatch
+	}
+	return this.routes
 }
 
 type ResponseAndError struct {
@@ -50,13 +91,20 @@ type MockReqtifyRequestExaminer struct {
 	Responses chan<- ResponseAndError
 }
 
+// how many requests and responses an examiner's channels hold before
+// blocking.
+const ExaminerBuffer = exchange.Buffer
+
+// Examine routes requests to the returned examiner. Responses are matched to
+// requests in the order the requests were read from Requests, so concurrent
+// requests each get their own response even though they share the channels.
 func (this *ReqtifierMock) Examine() MockReqtifyRequestExaminer {
-	requestReader := make(chan *RequestMock)
-	responseWriter := make(chan ResponseAndError)
+	requestReader := make(chan *RequestMock, ExaminerBuffer)
+	responseWriter := make(chan ResponseAndError, ExaminerBuffer)
+	var waiting exchange.Queue[ResponseAndError]
 
 	this.AnalyzeWith(func(req *RequestMock) (*http.Response, error) {
-		requestReader <- req
-		response := <- responseWriter
+		response := <- waiting.Push(func() { requestReader <- req }, responseWriter)
 		return response.Response, response.Error
 	})
 
@@ -73,11 +121,46 @@ type RequestMock struct {
 }
 
 func (this *RequestMock) Do() (*http.Response, error) {
-	if this.Mock.analyzeFunc != nil {
-		resp, errrrrrrr := this.Mock.analyzeFunc(this)
+	return this.RequestImpl.Complete(this.do())
+}
+
+func (this *RequestMock) do() (*http.Response, error) {
+	defer this.RequestImpl.CloseFiles()
+
+	if this.BuildError != nil {
+		return nil, this.BuildError
+	}
+
+	if analyze := this.Mock.analyzer(); analyze != nil {
+		// retried as FakeReqtifier would retry a request it sent
+		reqt := this.Mock.FakeReqtifier
+		if reqt == nil { reqt = &reqtify.ReqtifierImpl{} }
+		resp, errrrrrrr := reqt.Retrying(&this.RequestImpl, func(int) (*http.Response, error) { return analyze(this) })
+
+		if resp != nil {
+			this.RequestImpl.CheckChecksums(resp)
+			this.RequestImpl.CaptureHeaders(resp)
+			if err := this.RequestImpl.CheckStatus(resp); err != nil && errrrrrrr == nil {
+				return resp, err
+			}
+		}
+
+		if this.DownloadWriter != nil && resp != nil {
+			_, err := io.Copy(this.DownloadWriter, resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(nil))
+			if err != nil {
+				return resp, err
+			}
+			return resp, errrrrrrr
+		}
 
 		// Packing into response, if we have one
-		if len(this.RequestImpl.Response)!= 0 {
+		unmarshallers, errorBody := this.RequestImpl.Response, interface{}(nil)
+		if resp != nil {
+			unmarshallers, errorBody = this.RequestImpl.UnmarshallersFor(resp.StatusCode)
+		}
+		if len(unmarshallers)!= 0 {
 			var body []byte
 			var err error
 			if resp != nil {
@@ -87,12 +170,26 @@ func (this *RequestMock) Do() (*http.Response, error) {
 			if err != nil {
 				return nil, err
 			}
-			for _, response := range this.RequestImpl.Response {
-				e := response.Unmarshal(body)
-				if err != nil {
+			for _, response := range unmarshallers {
+				text, e := this.RequestImpl.CheckUTF8(response, body)
+				if e == nil {
+					e = response.Unmarshal(text)
+				}
+				if err == nil {
 					err = e
 				}
 			}
+			if errrrrrrr == nil {
+				errrrrrrr = err
+			}
+		}
+
+		if errrrrrrr == nil {
+			errrrrrrr = this.RequestImpl.ValidateResponse(unmarshallers)
+		}
+
+		if errrrrrrr == nil && errorBody != nil {
+			errrrrrrr = &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status, Body: errorBody}
 		}
 
 		return resp, errrrrrrr
@@ -111,6 +208,21 @@ func (this *RequestMock) Path(path string) (reqtify.Request) {
 	return this
 }
 
+func (this *RequestMock) PathTemplate(template string) (reqtify.Request) {
+	this.RequestImpl.PathTemplate(template)
+	return this
+}
+
+func (this *RequestMock) IdempotencyKey(key string) (reqtify.Request) {
+	this.RequestImpl.IdempotencyKey(key)
+	return this
+}
+
+func (this *RequestMock) IdempotencyKeyFrom(v interface{}) (reqtify.Request) {
+	this.RequestImpl.IdempotencyKeyFrom(v)
+	return this
+}
+
 func (this *RequestMock) Header(key, value string) (reqtify.Request) {
 	this.RequestImpl.Header(key, value)
 	return this
@@ -151,6 +263,191 @@ func (this *RequestMock) FileArg(key, filename string, data io.Reader) (reqtify.
 	return this
 }
 
+func (this *RequestMock) FileArgTyped(key, filename, contentType string, data io.Reader) (reqtify.Request) {
+	this.RequestImpl.FileArgTyped(key, filename, contentType, data)
+	return this
+}
+
+func (this *RequestMock) FileArgPath(key, path string) (reqtify.Request) {
+	this.RequestImpl.FileArgPath(key, path)
+	return this
+}
+
+func (this *RequestMock) MappedFileArg(key, filename string, f *os.File) (reqtify.Request) {
+	this.RequestImpl.MappedFileArg(key, filename, f)
+	return this
+}
+
+func (this *RequestMock) Part(part reqtify.FormPart) (reqtify.Request) {
+	this.RequestImpl.Part(part)
+	return this
+}
+
+func (this *RequestMock) PartOrder(names ...string) (reqtify.Request) {
+	this.RequestImpl.PartOrder(names...)
+	return this
+}
+
+func (this *RequestMock) MultipartType(subtype string) (reqtify.Request) {
+	this.RequestImpl.MultipartType(subtype)
+	return this
+}
+
+func (this *RequestMock) Base64Encode(names ...string) (reqtify.Request) {
+	this.RequestImpl.Base64Encode(names...)
+	return this
+}
+
+func (this *RequestMock) Trailer(key string, value func() string) (reqtify.Request) {
+	this.RequestImpl.Trailer(key, value)
+	return this
+}
+
+func (this *RequestMock) TrailerInto(key string, into *string) (reqtify.Request) {
+	this.RequestImpl.TrailerInto(key, into)
+	return this
+}
+
+func (this *RequestMock) TrailersInto(into *http.Header) (reqtify.Request) {
+	this.RequestImpl.TrailersInto(into)
+	return this
+}
+
+func (this *RequestMock) Body(data io.Reader, contentType string) (reqtify.Request) {
+	this.RequestImpl.Body(data, contentType)
+	return this
+}
+
+func (this *RequestMock) JSONBody(v interface{}) (reqtify.Request) {
+	this.RequestImpl.JSONBody(v)
+	return this
+}
+
+func (this *RequestMock) CanonicalJSONBody(v interface{}) (reqtify.Request) {
+	this.RequestImpl.CanonicalJSONBody(v)
+	return this
+}
+
+func (this *RequestMock) JSONAPIBody(v interface{}) (reqtify.Request) {
+	this.RequestImpl.JSONAPIBody(v)
+	return this
+}
+
+func (this *RequestMock) MsgpackBody(v interface{}) (reqtify.Request) {
+	this.RequestImpl.MsgpackBody(v)
+	return this
+}
+
+func (this *RequestMock) CBORBody(v interface{}) (reqtify.Request) {
+	this.RequestImpl.CBORBody(v)
+	return this
+}
+
+func (this *RequestMock) VerifyChecksum(algo, expected string) (reqtify.Request) {
+	this.RequestImpl.VerifyChecksum(algo, expected)
+	return this
+}
+
+func (this *RequestMock) UserAgentSuffix(suffix string) (reqtify.Request) {
+	this.RequestImpl.UserAgentSuffix(suffix)
+	return this
+}
+
+func (this *RequestMock) Close() (reqtify.Request) {
+	this.RequestImpl.Close()
+	return this
+}
+
+func (this *RequestMock) CacheTTL(ttl time.Duration) (reqtify.Request) {
+	this.RequestImpl.CacheTTL(ttl)
+	return this
+}
+
+func (this *RequestMock) Context(ctx context.Context) (reqtify.Request) {
+	this.RequestImpl.Context(ctx)
+	return this
+}
+
+func (this *RequestMock) Timeout(d time.Duration) (reqtify.Request) {
+	this.RequestImpl.Timeout(d)
+	return this
+}
+
+func (this *RequestMock) HeaderTimeout(d time.Duration) (reqtify.Request) {
+	this.RequestImpl.HeaderTimeout(d)
+	return this
+}
+
+func (this *RequestMock) IdleTimeout(d time.Duration) (reqtify.Request) {
+	this.RequestImpl.IdleTimeout(d)
+	return this
+}
+
+func (this *RequestMock) Retry(policy reqtify.RetryPolicy) (reqtify.Request) {
+	this.RequestImpl.Retry(policy)
+	return this
+}
+
+func (this *RequestMock) RetryNonIdempotent() (reqtify.Request) {
+	this.RequestImpl.RetryNonIdempotent()
+	return this
+}
+
+func (this *RequestMock) RateGroup(name string) (reqtify.Request) {
+	this.RequestImpl.RateGroup(name)
+	return this
+}
+
+func (this *RequestMock) Confirm() (reqtify.Request) {
+	this.RequestImpl.Confirm()
+	return this
+}
+
+func (this *RequestMock) Priority(n int) (reqtify.Request) {
+	this.RequestImpl.Priority(n)
+	return this
+}
+
+func (this *RequestMock) Durable(outbox *reqtify.Outbox) (reqtify.Request) {
+	this.RequestImpl.Durable(outbox)
+	return this
+}
+
+func (this *RequestMock) OData(q *reqtify.ODataQuery) (reqtify.Request) {
+	this.RequestImpl.OData(q)
+	return this
+}
+
+func (this *RequestMock) GraphQL(query string) (reqtify.Request) {
+	this.RequestImpl.GraphQL(query)
+	return this
+}
+
+func (this *RequestMock) Variable(name string, value interface{}) (reqtify.Request) {
+	this.RequestImpl.Variable(name, value)
+	return this
+}
+
+func (this *RequestMock) Essential() (reqtify.Request) {
+	this.RequestImpl.Essential()
+	return this
+}
+
+func (this *RequestMock) AcceptEncoding(encoding string) (reqtify.Request) {
+	this.RequestImpl.AcceptEncoding(encoding)
+	return this
+}
+
+func (this *RequestMock) RawEncoding() (reqtify.Request) {
+	this.RequestImpl.RawEncoding()
+	return this
+}
+
+func (this *RequestMock) CompressBody() (reqtify.Request) {
+	this.RequestImpl.CompressBody()
+	return this
+}
+
 func (this *RequestMock) ArgDefault(key string, value, def interface{}) (reqtify.Request) {
 	this.RequestImpl.ArgDefault(key, value, def)
 	return this
@@ -171,16 +468,139 @@ func (this *RequestMock) Into(into reqtify.ResponseUnmarshaller) (reqtify.Reques
 	return this
 }
 
+func (this *RequestMock) IntoForStatus(rangeSpec string, into reqtify.ResponseUnmarshaller) (reqtify.Request) {
+	this.RequestImpl.IntoForStatus(rangeSpec, into)
+	return this
+}
+
 func (this *RequestMock) JSONInto(into interface{}) (reqtify.Request) {
 	this.RequestImpl.JSONInto(into)
 	return this
 }
 
+func (this *RequestMock) ErrorJSONInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.ErrorJSONInto(into)
+	return this
+}
+
+func (this *RequestMock) ExtractInto(path string, into interface{}) (reqtify.Request) {
+	this.RequestImpl.ExtractInto(path, into)
+	return this
+}
+
 func (this *RequestMock) XMLInto(into interface{}) (reqtify.Request) {
 	this.RequestImpl.XMLInto(into)
 	return this
 }
 
+func (this *RequestMock) TextInto(into *string) (reqtify.Request) {
+	this.RequestImpl.TextInto(into)
+	return this
+}
+
+func (this *RequestMock) BytesInto(into *[]byte) (reqtify.Request) {
+	this.RequestImpl.BytesInto(into)
+	return this
+}
+
+func (this *RequestMock) YAMLInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.YAMLInto(into)
+	return this
+}
+
+func (this *RequestMock) HALInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.HALInto(into)
+	this.RequestImpl.BindHAL(this)
+	return this
+}
+
+func (this *RequestMock) JSONAPIInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.JSONAPIInto(into)
+	return this
+}
+
+func (this *RequestMock) MsgpackInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.MsgpackInto(into)
+	return this
+}
+
+func (this *RequestMock) CBORInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.CBORInto(into)
+	return this
+}
+
+func (this *RequestMock) CSVInto(into interface{}) (reqtify.Request) {
+	this.RequestImpl.CSVInto(into)
+	return this
+}
+
+func (this *RequestMock) DownloadTo(w io.Writer) (reqtify.Request) {
+	this.RequestImpl.DownloadTo(w)
+	return this
+}
+
+func (this *RequestMock) HeaderInto(key string, into *string) (reqtify.Request) {
+	this.RequestImpl.HeaderInto(key, into)
+	return this
+}
+
+func (this *RequestMock) HeadersInto(into *http.Header) (reqtify.Request) {
+	this.RequestImpl.HeadersInto(into)
+	return this
+}
+
+func (this *RequestMock) ExpectStatus(codes ...int) (reqtify.Request) {
+	this.RequestImpl.ExpectStatus(codes...)
+	return this
+}
+
+func (this *RequestMock) ExpectStatusRange(min, max int) (reqtify.Request) {
+	this.RequestImpl.ExpectStatusRange(min, max)
+	return this
+}
+
+func (this *RequestMock) DoAsync() (*reqtify.Future) {
+	return reqtify.NewFuture(this.Do)
+}
+
+func (this *RequestMock) Probe() (*reqtify.ProbeResult, error) {
+	return reqtify.ProbeRequest(this)
+}
+
+func (this *RequestMock) OnComplete(f func(*http.Response, error)) (reqtify.Request) {
+	this.RequestImpl.OnComplete(f)
+	return this
+}
+
+func (this *RequestMock) OnError(f func(error)) (reqtify.Request) {
+	this.RequestImpl.OnError(f)
+	return this
+}
+
+func (this *RequestMock) On1xx(f func(code int, header textproto.MIMEHeader)) (reqtify.Request) {
+	this.RequestImpl.On1xx(f)
+	return this
+}
+
+func (this *RequestMock) Clone() (reqtify.Request) {
+	c := this.RequestImpl.Clone().(*reqtify.RequestImpl)
+	m := &RequestMock{RequestImpl: *c, Mock: this.Mock}
+	m.BindHAL(m)
+	return m
+}
+
+func (this *RequestMock) Follow(link string) (reqtify.Request) {
+	c := this.RequestImpl.Follow(link).(*reqtify.RequestImpl)
+	m := &RequestMock{RequestImpl: *c, Mock: this.Mock}
+	m.BindHAL(m)
+	return m
+}
+
+func (this *RequestMock) Validate(check func() error) (reqtify.Request) {
+	this.RequestImpl.Validate(check)
+	return this
+}
+
 func (this *RequestMock) DebugPrint() (reqtify.Request) {
 	this.RequestImpl.DebugPrint()
 	return this
@@ -0,0 +1,44 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type closeCounter struct {
+	*strings.Reader
+	closed int
+}
+
+func (this *closeCounter) Close() (error) {
+	this.closed++
+	return nil
+}
+
+func TestResponseHandling(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test", UTF8Policy: reqtify.UTF8Reject}}
+	m.AnalyzeWith(func(req *RequestMock) (*http.Response, error) {
+		if req.GetPath() == "/invalid" { return JSONResponse(200, "\"caf\xe9\""), nil }
+		return JSONResponse(200, `"hello"`), nil
+	})
+
+	// the Reqtifier's UTF-8 policy applies
+	var text string
+	_, err := m.New("/invalid").TextInto(&text).Do()
+	var utf8Err *reqtify.InvalidUTF8Error
+	if !errors.As(err, &utf8Err) || utf8Err.Offset != 4 { t.Errorf("UTF-8 Mismatch: got %v", err) }
+
+	// so do checksums
+	_, err = m.New("/").VerifyChecksum("sha256", strings.Repeat("00", 32)).TextInto(&text).Do()
+	var sumErr *reqtify.ChecksumError
+	if !errors.As(err, &sumErr) { t.Errorf("Checksum Mismatch: got %v", err) }
+
+	// and files are closed once the request is done
+	file := &closeCounter{Reader: strings.NewReader("data")}
+	m.New("/").Method(reqtify.POST).FileArg("file", "data.txt", file).Do()
+	if file.closed != 1 { t.Errorf("Close Mismatch: got %d closes, expected 1", file.closed) }
+}
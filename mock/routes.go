@@ -0,0 +1,250 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/match"
+
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+   Routes let tests register canned responses for the requests they expect,
+   instead of answering every request from a single analyzer function, so one
+   mock can serve a whole fake API. Each request is answered by the first
+   route which matches it, and requests no route matches fail with
+   ErrNoHandler:
+
+	m.Handle("GET", "/users/{id}", func(req *mock.RequestMock, params mock.Params) (*http.Response, error) {
+		return mock.JSONResponse(200, `{"id": "` + params["id"] + `"}`), nil
+	})
+
+   Paths are relative to the mock's root. In patterns, {name} matches a
+   single path segment, and {name...} at the end matches the rest of the
+   path.
+
+   Registering a route replaces any function set with AnalyzeWith, and
+   calling AnalyzeWith afterwards replaces the routes.
+*/
+
+// reports whether a route applies to a request.
+type Matcher func(req *RequestMock) bool
+
+type route struct {
+	match   Matcher
+	respond ReqtifyAnalyzer
+	unused  func() []string // describes canned responses not yet given, if any.
+}
+
+type routes struct {
+	lock      sync.Mutex
+	list      []*route
+	unmatched []string
+	injections []injection
+}
+
+// the path parameters extracted from a request by a route's pattern.
+type Params map[string]string
+
+// answers requests a pattern-based route matches, given the path parameters.
+type RouteHandler func(req *RequestMock, params Params) (*http.Response, error)
+
+// adds a route answering requests for method and pattern (see MatchRequest)
+// with handler.
+func (this *ReqtifierMock) Handle(method, pattern string, handler RouteHandler) {
+	match := MatchRequest(method, pattern)
+	this.HandleMatching(match, func(req *RequestMock) (*http.Response, error) {
+		params, _ := PathParams(pattern, req)
+		return handler(req, params)
+	})
+}
+
+// adds a route, answering the requests match accepts with respond.
+func (this *ReqtifierMock) HandleMatching(match Matcher, respond ReqtifyAnalyzer) {
+	this.addRoute(&route{match: match, respond: respond})
+}
+
+func (this *ReqtifierMock) addRoute(r *route) {
+	routes := this.routeTable()
+	routes.lock.Lock()
+	routes.list = append(routes.list, r)
+	routes.lock.Unlock()
+}
+
+// describes the canned responses of every route which haven't been given.
+func (this *routes) unused() ([]string) {
+	this.lock.Lock()
+	list := this.list
+	this.lock.Unlock()
+
+	var unused []string
+	for _, r := range list {
+		if r.unused != nil { unused = append(unused, r.unused()...) }
+	}
+	return unused
+}
+
+func (this *routes) dispatch(req *RequestMock) (*http.Response, error) {
+	return this.inject(req, this.route)(req)
+}
+
+func (this *routes) route(req *RequestMock) (*http.Response, error) {
+	this.lock.Lock()
+	list := this.list
+	this.lock.Unlock()
+
+	for _, r := range list {
+		if r.match(req) { return r.respond(req) }
+	}
+
+	this.lock.Lock()
+	this.unmatched = append(this.unmatched, string(req.Verb) + " " + req.URL())
+	this.lock.Unlock()
+	return nil, ErrNoHandler
+}
+
+// matches requests by method, and a path pattern relative to the mock's
+// root, which may contain {name} and {name...} parameters. The pattern may
+// include a query, in which case the request's query must hold the same
+// parameters, in any order. An empty method matches any method.
+func MatchRequest(method, pattern string) (Matcher) {
+	want, err := url.Parse(pattern)
+	return func(req *RequestMock) bool {
+		if err != nil { return false }
+		if method != "" && !strings.EqualFold(method, string(req.Verb)) { return false }
+		got, err := url.Parse(req.URL())
+		if err != nil { return false }
+		if _, ok := matchPattern(want.Path, relativePath(req)); !ok { return false }
+		return sameQuery(got, want)
+	}
+}
+
+// returns the parameters pattern extracts from req's path, and whether it
+// matched at all.
+func PathParams(pattern string, req *RequestMock) (Params, bool) {
+	if u, err := url.Parse(pattern); err == nil { pattern = u.Path }
+	return matchPattern(pattern, relativePath(req))
+}
+
+// the request's path relative to the mock's root, escaped, without any query.
+func relativePath(req *RequestMock) (string) {
+	path := req.URLPath
+	if u, err := url.Parse(path); err == nil {
+		path = u.EscapedPath()
+		if u.IsAbs() && req.ReqClient != nil {
+			if root, err := url.Parse(req.ReqClient.Root); err == nil && root.Host == u.Host {
+				path = strings.TrimPrefix(path, strings.TrimSuffix(root.Path, "/"))
+			}
+		}
+	}
+	return path
+}
+
+func matchPattern(pattern, path string) (Params, bool) {
+	params := Params{}
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range patternParts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "...}") && i == len(patternParts) - 1 {
+			if i < len(pathParts) { params[p[1:len(p) - 4]] = strings.Join(pathParts[i:], "/") }
+			return params, true
+		}
+		if i >= len(pathParts) { return nil, false }
+		value, err := url.PathUnescape(pathParts[i])
+		if err != nil { value = pathParts[i] }
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if value == "" { return nil, false }
+			params[p[1:len(p) - 1]] = value
+		} else if p != value {
+			return nil, false
+		}
+	}
+	if len(pathParts) != len(patternParts) { return nil, false }
+	return params, true
+}
+
+// reports whether got has the query parameters of want, in any order. If
+// want has no query, any query matches.
+func sameQuery(got, want *url.URL) (bool) {
+	if want.RawQuery == "" { return true }
+	return reflect.DeepEqual(got.Query(), want.Query())
+}
+
+// adapts matchers from the match package, which look at the http.Request a
+// request would send, to route requests in the mock. Bodies built from
+// readers are read into memory first, so matching doesn't consume them.
+func Matching(matchers ...match.Matcher) (Matcher) {
+	return func(req *RequestMock) bool {
+		defer snapshotBody(req)()
+		r, err := req.HTTPRequest(requestContext(req))
+		if err != nil { return false }
+		return match.Request(r, matchers...) == nil
+	}
+}
+
+// reads req's raw body, form files and parts into memory, replacing them with
+// readers of the copies. Calling the returned function replaces them again,
+// once the copies have been read.
+func snapshotBody(req *RequestMock) (reset func()) {
+	var raw []byte
+	if req.RawBody != nil { raw, _ = ioutil.ReadAll(req.RawBody) }
+	files := map[string][][]byte{}
+	for key, list := range req.FormFiles {
+		for _, f := range list {
+			data, _ := ioutil.ReadAll(f.Data)
+			if closer, ok := f.Data.(io.Closer); ok { closer.Close() }
+			files[key] = append(files[key], data)
+		}
+	}
+	req.FormParts = copyParts(req.FormParts)
+	parts := snapshotParts(req.FormParts)
+
+	reset = func() {
+		if req.RawBody != nil { req.RawBody = bytes.NewReader(raw) }
+		for key, list := range req.FormFiles {
+			for i := range list {
+				list[i].Data = ioutil.NopCloser(bytes.NewReader(files[key][i]))
+			}
+		}
+		parts()
+	}
+	reset()
+	return reset
+}
+
+// copies parts, and the parts nested in them, so their data can be replaced
+// without touching the caller's.
+func copyParts(parts []reqtify.FormPart) ([]reqtify.FormPart) {
+	if parts == nil { return nil }
+	parts = append([]reqtify.FormPart(nil), parts...)
+	for i := range parts {
+		parts[i].Parts = copyParts(parts[i].Parts)
+	}
+	return parts
+}
+
+// reads the data of parts, and the parts nested in them, into memory. Calling
+// the returned function replaces it with readers of the copies.
+func snapshotParts(parts []reqtify.FormPart) (reset func()) {
+	var data [][]byte
+	var nested []func()
+	for _, part := range parts {
+		var d []byte
+		if part.Data != nil { d, _ = ioutil.ReadAll(part.Data) }
+		if closer, ok := part.Data.(io.Closer); ok { closer.Close() }
+		data = append(data, d)
+		nested = append(nested, snapshotParts(part.Parts))
+	}
+	return func() {
+		for i := range parts {
+			if parts[i].Data != nil { parts[i].Data = ioutil.NopCloser(bytes.NewReader(data[i])) }
+			nested[i]()
+		}
+	}
+}
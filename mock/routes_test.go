@@ -0,0 +1,59 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/match"
+
+	"testing"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+func TestHandle(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test/api"}}
+	users := map[string]string{"1": "alice", "a/b": "slashed"}
+	m.Handle("GET", "/users/{id}", func(req *RequestMock, params Params) (*http.Response, error) {
+		name, ok := users[params["id"]]
+		if !ok { return JSONResponse(404, `{}`), nil }
+		return JSONResponse(200, fmt.Sprintf(`{"id": %q, "name": %q}`, params["id"], name)), nil
+	})
+	m.Handle("DELETE", "/users/{id}/posts/{post}", func(req *RequestMock, params Params) (*http.Response, error) {
+		return JSONResponse(200, fmt.Sprintf(`{"id": %q, "name": %q}`, params["id"], params["post"])), nil
+	})
+	m.Handle("", "/files/{path...}", func(req *RequestMock, params Params) (*http.Response, error) {
+		return JSONResponse(200, fmt.Sprintf(`{"id": %q, "name": %q}`, req.Verb, params["path"])), nil
+	})
+
+	var user struct{ ID, Name string }
+	for path, expected := range map[string]string{
+		"/users/1": "{1 alice}",
+		"/users/a%2Fb": "{a/b slashed}",
+		"https://this.is.a.test/api/users/1": "{1 alice}",
+		"/files/a/b/c.txt": "{GET a/b/c.txt}",
+		"/files": "{GET }",
+	} {
+		user.ID, user.Name = "", ""
+		if _, err := m.New(path).JSONInto(&user).Do(); err != nil || fmt.Sprint(user) != expected { t.Errorf("Route Mismatch: %s gave %v, %v", path, user, err) }
+	}
+	if _, err := m.New("/users/1/posts/9").Method(reqtify.DELETE).JSONInto(&user).Do(); err != nil || user.ID != "1" || user.Name != "9" { t.Errorf("Params Mismatch: got %+v", user) }
+
+	for _, path := range []string{"/users", "/users/1/extra", "/users/", "/other"} {
+		if _, err := m.New(path).Do(); err != ErrNoHandler { t.Errorf("Unmatched Mismatch: %s gave %v", path, err) }
+	}
+	if resp, err := m.New("/users/2").Do(); err != nil || resp.StatusCode != 404 { t.Errorf("Handler Mismatch: got %v", err) }
+}
+
+func TestMatching(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.HandleMatching(Matching(match.Method(reqtify.POST), match.JSONBody(map[string]int{"n": 1})), func(req *RequestMock) (*http.Response, error) {
+		body, _ := req.GetBody()
+		data, _ := ioutil.ReadAll(body)
+		return JSONResponse(200, string(data)), nil
+	})
+
+	var got map[string]int
+	if _, err := m.New("/things").Method(reqtify.POST).Body(bytes.NewReader([]byte(`{"n":1}`)), "application/json").JSONInto(&got).Do(); err != nil || got["n"] != 1 { t.Errorf("Matching Mismatch: got %v, %v", got, err) }
+	if _, err := m.New("/things").Method(reqtify.POST).JSONBody(map[string]int{"n": 2}).Do(); err != ErrNoHandler { t.Errorf("Matching Mismatch: got %v", err) }
+}
@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// a response which can be returned any number of times, each with its own
+// copy of the body.
+type canned struct {
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+func newCanned(resp *http.Response, err error) (canned) {
+	c := canned{resp: resp, err: err}
+	if resp != nil && resp.Body != nil {
+		c.body, _ = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	return c
+}
+
+func (this canned) response() (*http.Response, error) {
+	if this.resp == nil {
+		if this.err != nil { return nil, this.err }
+		return response(200, "", nil), nil
+	}
+	resp := *this.resp
+	resp.Header = this.resp.Header.Clone()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(this.body))
+	return &resp, this.err
+}
+
+// answers requests for method and pattern (see MatchRequest) with each of
+// responses in turn, repeating the last once they run out. Requests with a
+// RetryPolicy are retried as a real Reqtifier would retry them, so this tests
+// retries deterministically:
+//
+//	m.Sequence("GET", "/flaky",
+//		mock.ResponseAndError{Response: mock.JSONResponse(503, `{}`)},
+//		mock.ResponseAndError{Error: io.ErrUnexpectedEOF},
+//		mock.ResponseAndError{Response: mock.JSONResponse(200, `{"ok": true}`)},
+//	)
+func (this *ReqtifierMock) Sequence(method, pattern string, responses ...ResponseAndError) {
+	var lock sync.Mutex
+	var list []canned
+	for _, r := range responses {
+		list = append(list, newCanned(r.Response, r.Error))
+	}
+
+	given := 0
+	this.addRoute(&route{
+		match: MatchRequest(method, pattern),
+		respond: func(req *RequestMock) (*http.Response, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			given++
+			if len(list) == 0 { return response(200, "", nil), nil }
+			next := list[0]
+			if len(list) > 1 { list = list[1:] }
+			return next.response()
+		},
+		unused: func() []string {
+			lock.Lock()
+			defer lock.Unlock()
+			if given >= len(responses) { return nil }
+			return []string{fmt.Sprintf("%s %s, %d of %d responses", method, pattern, len(responses) - given, len(responses))}
+		},
+	})
+}
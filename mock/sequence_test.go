@@ -0,0 +1,52 @@
+package mock
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"fmt"
+	"io"
+)
+
+func TestSequence(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Sequence("GET", "/flaky",
+		ResponseAndError{Response: JSONResponse(503, `{"title": "down"}`)},
+		ResponseAndError{Error: io.ErrUnexpectedEOF},
+		ResponseAndError{Response: JSONResponse(200, `{"title": "up"}`)},
+	)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		var p post
+		resp, err := m.New("/flaky").JSONInto(&p).Do()
+		if err != nil {
+			got = append(got, err.Error())
+		} else {
+			got = append(got, fmt.Sprint(resp.StatusCode, " ", p.Title))
+		}
+	}
+	if fmt.Sprint(got) != "[503 down unexpected EOF 200 up 200 up]" { t.Errorf("Sequence Mismatch: got %q", got) }
+}
+
+func TestSequenceRetried(t *testing.T) {
+	m := &ReqtifierMock{FakeReqtifier: &reqtify.ReqtifierImpl{Root: "https://this.is.a.test"}}
+	m.Sequence("GET", "/flaky",
+		ResponseAndError{Response: JSONResponse(503, `{"title": "down"}`)},
+		ResponseAndError{Error: io.ErrUnexpectedEOF},
+		ResponseAndError{Response: JSONResponse(200, `{"title": "up"}`)},
+	)
+	m.Sequence("POST", "/flaky",
+		ResponseAndError{Response: JSONResponse(503, `{"title": "down"}`)},
+		ResponseAndError{Response: JSONResponse(200, `{"title": "up"}`)},
+	)
+
+	var p post
+	resp, err := m.New("/flaky").Retry(reqtify.RetryPolicy{MaxAttempts: 3}).JSONInto(&p).Do()
+	if err != nil || resp.StatusCode != 200 || p.Title != "up" { t.Errorf("Retry Mismatch: got %v, %+v", err, p) }
+
+	// POSTs aren't idempotent, so they aren't retried
+	resp, err = m.New("/flaky").Method(reqtify.POST).Retry(reqtify.RetryPolicy{MaxAttempts: 3}).Do()
+	if err != nil || resp.StatusCode != 503 { t.Errorf("POST Mismatch: got %v, %v", resp, err) }
+	if resp, err = m.New("/flaky").Method(reqtify.POST).Do(); err != nil || resp.StatusCode != 200 { t.Errorf("Second POST Mismatch: got %v, %v", resp, err) }
+}
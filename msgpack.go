@@ -0,0 +1,36 @@
+package reqtify
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const MsgpackContentType = "application/msgpack"
+
+type MsgpackUnmarshaller struct {
+	output_value interface{}
+}
+
+func (this MsgpackUnmarshaller) Unmarshal(body []byte) error {
+	return msgpack.Unmarshal(body, this.output_value)
+}
+
+func FromMsgpack(output_value interface{}) ResponseUnmarshaller {
+	return MsgpackUnmarshaller{output_value: output_value}
+}
+
+func (this *RequestImpl) MsgpackInto(into interface{}) (Request) {
+	this.Response = append(this.Response, FromMsgpack(into))
+	return this
+}
+
+// marshals v as MessagePack and uses it as the request body.
+func (this *RequestImpl) MsgpackBody(v interface{}) (Request) {
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	return this.Body(bytes.NewReader(data), MsgpackContentType)
+}
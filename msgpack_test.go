@@ -0,0 +1,36 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"net/http"
+	"io/ioutil"
+)
+
+func TestMsgpack(t *testing.T) {
+	type point struct {
+		X, Y int
+		Label string `msgpack:"label"`
+	}
+
+	var http_mock_client test.MockHttpClient
+	var contentType string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		contentType = req.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var out point
+	_, err := reqt.New("/echo").Method(POST).MsgpackBody(point{X: 1, Y: -2, Label: "origin-ish"}).MsgpackInto(&out).Do()
+	if err != nil { t.Fatalf("Msgpack Failure: %s", err.Error()) }
+	if contentType != MsgpackContentType { t.Errorf("Content Type Mismatch: got %q", contentType) }
+	if out != (point{X: 1, Y: -2, Label: "origin-ish"}) { t.Errorf("Msgpack Mismatch: got %+v", out) }
+
+	_, err = reqt.New("/echo").Method(POST).MsgpackBody(make(chan int)).Do()
+	if err == nil { t.Errorf("Build Error Mismatch: unencodable body accepted") }
+}
@@ -1,8 +1,14 @@
 package reqtify
 
 import (
+	"encoding/base64"
 	"io"
-	"math/rand"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"fmt"
 	"bytes"
@@ -22,12 +28,145 @@ import (
 	3. call close()
 	4. call toReader() to create an io.Reader which emits the form's body
 	5. call contentType() to fetch the correct content type for the form
+
+   Form arguments are written first, then files, both in order of their names,
+   then parts added with Part, in the order they were added. PartOrder moves
+   the named fields to the front, for APIs which expect, say, a metadata part
+   before the file it describes.
+
+   Bodies needn't be forms. MultipartType makes one multipart/mixed or
+   multipart/related instead, for upload APIs which take, say, a JSON part
+   describing a file followed by the file itself:
+
+	req.MultipartType("related").
+		Part(FormPart{Header: http.Header{"Content-Type": {"application/json"}}, Data: metadata}).
+		Part(FormPart{Header: http.Header{"Content-Type": {"image/png"}}, Data: image})
+
+   and a part can itself be a multipart body, made of more parts, by setting
+   its Multipart.
+
+   Files and parts can be sent base64 encoded, with a Content-Transfer-Encoding
+   header saying so, for gateways which mangle binary bodies. See Base64Encode.
 */
 
+// a part of a multipart form with headers of its own, for APIs which need
+// more control over a part than FormArg and FileArg give, like a Content-ID.
+type FormPart struct {
+	Name     string
+	Filename string      // optional.
+	Header   http.Header // sent as is, after Content-Disposition, which is made from Name and Filename in forms, unless it's set here.
+	Data     io.Reader
+	Base64   bool        // see Base64Encode.
+
+	// if set, the part is a multipart body of this type, like "mixed", made
+	// of Parts, and Data is ignored. Its Content-Type is set to match.
+	Multipart string
+	Parts     []FormPart
+}
+
+// makes the request body multipart of the given type, like "mixed", or
+// "related; type=\"application/json\"", instead of multipart/form-data. Parts
+// of bodies which aren't forms only have the headers they're given.
+func (this *RequestImpl) MultipartType(subtype string) (Request) {
+	this.ForceMultipart = true
+	this.MultipartSubtype = subtype
+	return this
+}
+
+// adds part to the multipart form, after form arguments and files. Its Data
+// is closed when the request is done, as FileArg's is.
+func (this *RequestImpl) Part(part FormPart) (Request) {
+	this.FormParts = append(this.FormParts, part)
+	return this
+}
+
+// sends the form fields with the given names first, in that order. Fields
+// with the same name keep their usual order, as do fields which aren't named.
+func (this *RequestImpl) PartOrder(names ...string) (Request) {
+	this.PartNames = names
+	return this
+}
+
+// sends the files and parts with the given names base64 encoded, with a
+// Content-Transfer-Encoding header saying so. Setting Base64 on a FormFile or
+// FormPart does the same for just that one.
+func (this *RequestImpl) Base64Encode(names ...string) (Request) {
+	this.Base64Names = append(this.Base64Names, names...)
+	return this
+}
+
+func (this *RequestImpl) base64Encoded(name string) (bool) {
+	for _, n := range this.Base64Names {
+		if n == name { return true }
+	}
+	return false
+}
+
+// one field of a multipart form, waiting to be added to the body.
+type formField struct {
+	name string
+	add  func()
+}
+
+// moves the fields named by order to the front, in that order.
+func orderFields(fields []formField, order []string) {
+	if len(order) == 0 { return }
+	rank := make(map[string]int, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		rank[order[i]] = i
+	}
+	position := func(name string) (int) {
+		if i, ok := rank[name]; ok { return i }
+		return len(order)
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return position(fields[i].name) < position(fields[j].name) })
+}
+
+// a BoundarySource returns the boundary to use for each multipart request
+// body. It must return valid boundaries: 1 to 70 letters, digits, or any of
+// '()+_,-./:=? , not ending in a space. Invalid ones are replaced with random
+// ones.
+type BoundarySource func() string
+
+// sets where the boundaries of multipart request bodies come from. They're
+// random by default.
+func WithBoundarySource(source BoundarySource) Option {
+	return func(r *ReqtifierImpl) {
+		r.Boundaries = source
+	}
+}
+
+// uses boundary for every multipart request body, so that bodies can be
+// compared byte for byte in tests. Form fields and files are always written
+// in order of their names. It panics if boundary isn't valid.
+func WithFixedBoundary(boundary string) Option {
+	if !validBoundary(boundary) { panic("reqtify: invalid multipart boundary " + strconv.Quote(boundary)) }
+	return WithBoundarySource(func() string { return boundary })
+}
+
+// reports whether b is a boundary RFC 2046 allows.
+func validBoundary(b string) (bool) {
+	if len(b) < 1 || len(b) > 70 || b[len(b) - 1] == ' ' { return false }
+	for _, c := range b {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.ContainsRune("'()+_,-./:=? ", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 type multipartRequestBody struct {
+	subtype     string // "form-data" if empty.
+	random      *Random
 	readerlist  []io.Reader
 	boundary    []byte
 	effBoundary []byte
+	size        int64 // the length of the body so far, if every part's is known.
+	unsized     bool  // some part's length isn't known.
+	children    int   // how many nested bodies have been made, to give each its own boundary.
 }
 
 var letters []byte = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._")
@@ -35,12 +174,20 @@ var letters []byte = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXY
 func (this *multipartRequestBody) randomBoundary() {
 	this.effBoundary = []byte("------multipart")
 	for i := 0; i < 32; i++ {
-		this.effBoundary = append(this.effBoundary, letters[rand.Intn(len(letters))])
+		this.effBoundary = append(this.effBoundary, letters[this.random.Intn(len(letters))])
 	}
 	this.effBoundary = append(this.effBoundary, '-', '-')
 	this.boundary = this.effBoundary[2:len(this.effBoundary)-2]
 }
 
+// uses boundary instead of a random one, if it's valid. It must be called
+// before anything is added.
+func (this *multipartRequestBody) setBoundary(boundary string) {
+	if !validBoundary(boundary) { return }
+	this.boundary = []byte(boundary)
+	this.effBoundary = append(append([]byte("--"), this.boundary...), '-', '-')
+}
+
 func (this *multipartRequestBody) boundaryReader() (io.Reader) {
 	if this.boundary == nil { this.randomBoundary() }
 	return &readOnlyReader{buffer: this.effBoundary[:len(this.effBoundary)-2]}
@@ -52,34 +199,253 @@ func (this *multipartRequestBody) endBoundaryReader() (io.Reader) {
 }
 
 func (this *multipartRequestBody) toReader() (io.Reader) {
-	return io.MultiReader(this.readerlist...)
+	if this.unsized { return io.MultiReader(this.readerlist...) }
+	return &sizedReader{Reader: io.MultiReader(this.readerlist...), size: this.size}
+}
+
+// a body whose length is known ahead of time, so it can be sent with a
+// Content-Length.
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+func (this *sizedReader) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, this.Reader)
 }
 
 func (this *multipartRequestBody) contentType() (string) {
 	if this.boundary == nil { this.randomBoundary() }
+	if !this.form() { return fmt.Sprintf("multipart/%s; boundary=\"%s\"", this.subtype, this.boundary) }
 	return fmt.Sprintf("multipart/form-data; charset=utf-8; boundary=\"%s\"", this.boundary)
 }
 
 func (this *multipartRequestBody) addParam(key, value string) {
+	header := fmt.Sprintf("\r\nContent-Disposition: form-data; name=\"%s\"\r\n\r\n", escapeQuotes(key))
 	this.readerlist = append(this.readerlist,
 		this.boundaryReader(),
-		bytes.NewBuffer([]byte(fmt.Sprintf("\r\nContent-Disposition: form-data; name=\"%s\"\r\n\r\n", escapeQuotes(key)))),
+		bytes.NewBuffer([]byte(header)),
 		bytes.NewBuffer([]byte(value)),
 		bytes.NewBuffer([]byte("\r\n")),
 	)
+	this.size += int64(len(this.effBoundary) - 2 + len(header) + len(value) + 2)
 }
 
 func (this *multipartRequestBody) addFileParam(key string, file FormFile) {
 	this.readerlist = append(this.readerlist,
 		this.boundaryReader(),
-		bytes.NewBuffer([]byte(fmt.Sprintf("\r\nContent-Disposition: form-data; name=\"%s\"; filename=\"%s\"\r\nContent-Type: application/octet-stream\r\n\r\n", escapeQuotes(key), escapeQuotes(file.Name)))),
-		file.Data,
+		&filePartReader{key: key, file: file},
+		bytes.NewBuffer([]byte("\r\n")),
+	)
+	if file.sized && file.ContentType != "" {
+		size := file.size
+		if file.Base64 { size = base64Length(size) }
+		this.size += int64(len(this.effBoundary) - 2 + len(filePartHeader(key, file.Name, file.ContentType, file.Base64))) + size + 2
+	} else {
+		this.unsized = true
+	}
+}
+
+func (this *multipartRequestBody) form() (bool) {
+	return this.subtype == "" || this.subtype == "form-data"
+}
+
+func (this *multipartRequestBody) addPart(part FormPart) {
+	data := part.Data
+	if part.Multipart != "" { data, part.Header = this.nested(part) }
+	if data == nil { data = bytes.NewReader(nil) }
+	if part.Base64 {
+		part.Header = part.Header.Clone()
+		if part.Header == nil { part.Header = make(http.Header) }
+		part.Header.Set("Content-Transfer-Encoding", "base64")
+		data = newBase64Reader(data)
+	}
+	header := formPartHeader(part, this.form())
+	this.readerlist = append(this.readerlist,
+		this.boundaryReader(),
+		strings.NewReader(header),
+		data,
 		bytes.NewBuffer([]byte("\r\n")),
 	)
+	if size, ok := readerLength(data); ok {
+		this.size += int64(len(this.effBoundary) - 2 + len(header)) + size + 2
+	} else {
+		this.unsized = true
+	}
+}
+
+// builds the multipart body a part is made of, returning it and the part's
+// headers with its Content-Type. Its boundary is this one with a prefix, so
+// neither can appear inside the other.
+func (this *multipartRequestBody) nested(part FormPart) (io.Reader, http.Header) {
+	if this.boundary == nil { this.randomBoundary() }
+	child := multipartRequestBody{subtype: part.Multipart, random: this.random}
+	boundary := fmt.Sprintf("%d_%s", this.children, this.boundary)
+	if len(boundary) > 70 { boundary = boundary[:70] }
+	this.children++
+	child.setBoundary(strings.TrimRight(boundary, " "))
+	for _, p := range part.Parts {
+		child.addPart(p)
+	}
+	child.close()
+
+	header := part.Header.Clone()
+	if header == nil { header = make(http.Header) }
+	header.Set("Content-Type", child.contentType())
+	return child.toReader(), header
+}
+
+func formPartHeader(part FormPart, form bool) (string) {
+	var b strings.Builder
+	b.WriteString("\r\n")
+	if disposition := part.Header.Get("Content-Disposition"); disposition != "" {
+		b.WriteString("Content-Disposition: " + disposition + "\r\n")
+	} else if form {
+		fmt.Fprintf(&b, "Content-Disposition: form-data; name=\"%s\"", escapeQuotes(part.Name))
+		if part.Filename != "" { fmt.Fprintf(&b, "; filename=\"%s\"", escapeQuotes(part.Filename)) }
+		b.WriteString("\r\n")
+	}
+	for _, k := range sortedKeys(part.Header) {
+		if http.CanonicalHeaderKey(k) == "Content-Disposition" { continue }
+		for _, v := range part.Header[k] {
+			b.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// returns how much is left to read from data, if that's known.
+func readerLength(data io.Reader) (int64, bool) {
+	switch d := data.(type) {
+	case interface{ Len() int }:
+		return int64(d.Len()), true
+	case *os.File:
+		return remainingLength(d)
+	case *sizedReader:
+		return d.size, true
+	case *base64Reader:
+		if size, ok := readerLength(d.reader); ok { return base64Length(size), true }
+	}
+	return 0, false
+}
+
+func filePartHeader(key, filename, contentType string, base64 bool) (string) {
+	encoding := ""
+	if base64 { encoding = "Content-Transfer-Encoding: base64\r\n" }
+	return fmt.Sprintf("\r\nContent-Disposition: form-data; name=\"%s\"; filename=\"%s\"\r\nContent-Type: %s\r\n%s\r\n", escapeQuotes(key), escapeQuotes(filename), contentType, encoding)
+}
+
+// emits a file part's headers and data. If the file's content type isn't
+// known, it's worked out from the start of the data, but only once the part
+// is reached, so nothing is read ahead of time.
+type filePartReader struct {
+	key    string
+	file   FormFile
+	reader io.Reader
+}
+
+func (this *filePartReader) open() {
+	if this.reader == nil {
+		contentType, data := this.file.ContentType, this.file.Data
+		if contentType == "" { contentType, data = sniffContentType(this.file.Name, data) }
+		if this.file.Base64 { data = newBase64Reader(data) }
+		this.reader = io.MultiReader(strings.NewReader(filePartHeader(this.key, this.file.Name, contentType, this.file.Base64)), data)
+	}
+}
+
+func (this *filePartReader) Read(p []byte) (int, error) {
+	this.open()
+	return this.reader.Read(p)
+}
+
+// passes the data's own WriteTo through, so a memory mapped file is written
+// in one go.
+func (this *filePartReader) WriteTo(w io.Writer) (int64, error) {
+	this.open()
+	return io.Copy(w, this.reader)
+}
+
+// guesses the content type of data with http.DetectContentType, or if that
+// only finds it's binary, the extension of filename. It returns a reader
+// which reads all of data, including what it looked at.
+func sniffContentType(filename string, data io.Reader) (string, io.Reader) {
+	start := make([]byte, 512)
+	n, err := io.ReadFull(data, start)
+	start = start[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		data = bytes.NewReader(start)
+	} else if err != nil {
+		data = io.MultiReader(bytes.NewReader(start), &failedReader{err: err})
+	} else {
+		data = io.MultiReader(bytes.NewReader(start), data)
+	}
+
+	return detectContentType(filename, start), data
+}
+
+// guesses the content type of data starting with start, as sniffContentType
+// does.
+func detectContentType(filename string, start []byte) (string) {
+	contentType := http.DetectContentType(start)
+	if contentType == "application/octet-stream" {
+		if byExtension := mime.TypeByExtension(filepath.Ext(filename)); byExtension != "" { contentType = byExtension }
+	}
+	return contentType
+}
+
+// the most bytes base64Reader encodes on each line, making lines of 76
+// characters, the most MIME allows.
+const base64LineBytes = 57
+
+// base64 encodes what it reads, in lines separated by CRLFs.
+type base64Reader struct {
+	reader  io.Reader
+	pending []byte // encoded, but not read yet.
+	lines   int
+	err     error
+}
+
+func newBase64Reader(r io.Reader) (*base64Reader) {
+	return &base64Reader{reader: r}
+}
+
+func (this *base64Reader) Read(p []byte) (int, error) {
+	for len(this.pending) == 0 {
+		if this.err != nil { return 0, this.err }
+		var line [base64LineBytes]byte
+		n, err := io.ReadFull(this.reader, line[:])
+		if err == io.ErrUnexpectedEOF { err = io.EOF }
+		this.err = err
+		if n == 0 { continue }
+		if this.lines != 0 { this.pending = append(this.pending, '\r', '\n') }
+		this.lines++
+		this.pending = append(this.pending, base64.StdEncoding.EncodeToString(line[:n])...)
+	}
+	n := copy(p, this.pending)
+	this.pending = this.pending[n:]
+	return n, nil
+}
+
+// returns how long size bytes are once base64Reader has encoded them.
+func base64Length(size int64) (int64) {
+	if size == 0 { return 0 }
+	lines := (size + base64LineBytes - 1) / base64LineBytes
+	return (size + 2) / 3 * 4 + (lines - 1) * 2
+}
+
+// an io.Reader which fails with err.
+type failedReader struct {
+	err error
+}
+
+func (this *failedReader) Read(p []byte) (int, error) {
+	return 0, this.err
 }
 
 func (this *multipartRequestBody) close() {
 	this.readerlist = append(this.readerlist, this.endBoundaryReader())
+	this.size += int64(len(this.effBoundary))
 }
 
 // an io.Reader which reads from a read only buffer.
@@ -0,0 +1,192 @@
+package reqtify
+
+import (
+	"testing"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+func TestFixedBoundary(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithFixedBoundary("fixed-boundary"))
+	body := func(req Request) (string, string) {
+		reader, contentType := req.GetBody()
+		data, _ := ioutil.ReadAll(reader)
+		return string(data), contentType
+	}
+
+	first, contentType := body(reqt.New("/upload").Method(POST).FormArg("b", "2").FormArg("a", "1").FileArg("z", "z.txt", strings.NewReader("zz")).FileArg("y", "y.txt", strings.NewReader("yy")))
+	second, _ := body(reqt.New("/upload").Method(POST).FileArg("y", "y.txt", strings.NewReader("yy")).FileArg("z", "z.txt", strings.NewReader("zz")).FormArg("a", "1").FormArg("b", "2"))
+	if first != second { t.Errorf("Body Mismatch: got\n%q\nand\n%q", first, second) }
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] != "fixed-boundary" { t.Fatalf("Content-Type Mismatch: got %s", contentType) }
+	var names []string
+	r := multipart.NewReader(bytes.NewReader([]byte(first)), params["boundary"])
+	for part, err := r.NextPart(); err == nil; part, err = r.NextPart() {
+		names = append(names, part.FormName())
+	}
+	if strings.Join(names, ",") != "a,b,y,z" { t.Errorf("Order Mismatch: got %v", names) }
+
+	for _, b := range []string{"", "ends in space ", "no\"quotes", strings.Repeat("x", 71)} {
+		func() {
+			defer func() {
+				if recover() == nil { t.Errorf("Panic Mismatch: %q was accepted", b) }
+			}()
+			WithFixedBoundary(b)
+		}()
+	}
+}
+
+func TestFileContentTypes(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithFixedBoundary("fixed-boundary"))
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 1000)...)
+	read := false
+	lazy := readerFunc(func(p []byte) (int, error) {
+		read = true
+		return 0, io.EOF
+	})
+
+	req := reqt.New("/upload").Method(POST).
+		FileArgTyped("a", "a.dat", "image/webp", strings.NewReader("RIFF")).
+		FileArg("b", "photo", bytes.NewReader(png)).
+		FileArg("c", "doc.pdf", bytes.NewReader([]byte{0, 1, 2})).
+		FileArg("d", "blob", bytes.NewReader([]byte{0, 1, 2})).
+		FileArg("e", "e.txt", lazy)
+	reader, _ := req.GetBody()
+	if read { t.Errorf("Lazy Mismatch: file read before the body was") }
+	data, _ := ioutil.ReadAll(reader)
+
+	expected := map[string]string{"a": "image/webp", "b": "image/png", "c": "application/pdf", "d": "application/octet-stream", "e": "text/plain; charset=utf-8"}
+	r := multipart.NewReader(bytes.NewReader(data), "fixed-boundary")
+	parts := 0
+	for part, err := r.NextPart(); err == nil; part, err = r.NextPart() {
+		parts++
+		if got := part.Header.Get("Content-Type"); got != expected[part.FormName()] { t.Errorf("Content-Type Mismatch: %s: got %q, expected %q", part.FormName(), got, expected[part.FormName()]) }
+		if part.FormName() == "b" {
+			if b, _ := ioutil.ReadAll(part); !bytes.Equal(b, png) { t.Errorf("Data Mismatch: got %d bytes, expected %d", len(b), len(png)) }
+		}
+	}
+	if parts != 5 { t.Errorf("Parts Mismatch: got %d, expected 5", parts) }
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (this readerFunc) Read(p []byte) (int, error) { return this(p) }
+
+func TestFormParts(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithFixedBoundary("fixed-boundary"))
+	req := reqt.New("/upload").Method(POST).
+		FormArg("z", "1").
+		FileArg("file", "a.txt", strings.NewReader("hello")).
+		Part(FormPart{Name: "meta", Header: http.Header{"Content-Type": {"application/json"}, "Content-Id": {"<meta@x>"}}, Data: strings.NewReader("{}")}).
+		Part(FormPart{Name: "raw", Header: http.Header{"Content-Disposition": {`attachment; filename="raw.bin"`}}, Data: bytes.NewReader([]byte{1, 2})}).
+		PartOrder("meta", "file")
+	reader, _ := req.GetBody()
+	if sized, ok := reader.(*sizedReader); ok { t.Errorf("Size Mismatch: got %d, expected none, a sniffed file's length isn't known", sized.size) }
+	data, _ := ioutil.ReadAll(reader)
+
+	r := multipart.NewReader(bytes.NewReader(data), "fixed-boundary")
+	var names []string
+	for part, err := r.NextPart(); err == nil; part, err = r.NextPart() {
+		body, _ := ioutil.ReadAll(part)
+		names = append(names, part.Header.Get("Content-Disposition"))
+		if strings.Contains(part.Header.Get("Content-Disposition"), `"meta"`) {
+			if got := part.Header.Get("Content-ID"); got != "<meta@x>" { t.Errorf("Content-ID Mismatch: got %q", got) }
+			if got := part.Header.Get("Content-Type"); got != "application/json" { t.Errorf("Content-Type Mismatch: got %q", got) }
+			if string(body) != "{}" { t.Errorf("Body Mismatch: got %q", body) }
+		}
+	}
+	expected := []string{`form-data; name="meta"`, `form-data; name="file"; filename="a.txt"`, `form-data; name="z"`, `attachment; filename="raw.bin"`}
+	if strings.Join(names, "|") != strings.Join(expected, "|") { t.Errorf("Order Mismatch: got %q, expected %q", names, expected) }
+
+	// bodies made only of parts of known length are sized
+	req = reqt.New("/upload").Method(POST).Part(FormPart{Name: "a", Data: strings.NewReader("abc")})
+	reader, _ = req.GetBody()
+	data, _ = ioutil.ReadAll(reader)
+	if sized, ok := reader.(*sizedReader); !ok || sized.size != int64(len(data)) { t.Errorf("Size Mismatch: got %v, expected %d", reader, len(data)) }
+}
+
+func TestMultipartRelated(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithFixedBoundary("fixed-boundary"))
+	req := reqt.New("/upload").Method(POST).MultipartType(`related; type="application/json"`).
+		Part(FormPart{Header: http.Header{"Content-Type": {"application/json"}}, Data: strings.NewReader(`{"name":"a.png"}`)}).
+		Part(FormPart{Multipart: "mixed", Parts: []FormPart{
+			{Header: http.Header{"Content-Type": {"image/png"}}, Data: bytes.NewReader([]byte{1, 2, 3})},
+			{Header: http.Header{"Content-Type": {"text/plain"}}, Data: strings.NewReader("caption")},
+		}})
+	reader, contentType := req.GetBody()
+	data, _ := ioutil.ReadAll(reader)
+	if sized, ok := reader.(*sizedReader); !ok || sized.size != int64(len(data)) { t.Errorf("Size Mismatch: got %v, expected %d", reader, len(data)) }
+
+	mediatype, params, _ := mime.ParseMediaType(contentType)
+	if mediatype != "multipart/related" || params["type"] != "application/json" || params["boundary"] != "fixed-boundary" { t.Errorf("Content-Type Mismatch: got %q", contentType) }
+
+	r := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+	part, err := r.NextPart()
+	if err != nil { t.Fatalf("Part Failure: %s", err.Error()) }
+	if part.Header.Get("Content-Disposition") != "" { t.Errorf("Disposition Mismatch: got %q, expected none", part.Header.Get("Content-Disposition")) }
+	if b, _ := ioutil.ReadAll(part); string(b) != `{"name":"a.png"}` { t.Errorf("Body Mismatch: got %q", b) }
+
+	part, err = r.NextPart()
+	if err != nil { t.Fatalf("Part Failure: %s", err.Error()) }
+	mediatype, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if mediatype != "multipart/mixed" || params["boundary"] == "fixed-boundary" { t.Errorf("Nested Content-Type Mismatch: got %q", part.Header.Get("Content-Type")) }
+	nested := multipart.NewReader(part, params["boundary"])
+	var bodies []string
+	for p, err := nested.NextPart(); err == nil; p, err = nested.NextPart() {
+		b, _ := ioutil.ReadAll(p)
+		bodies = append(bodies, p.Header.Get("Content-Type") + ":" + string(b))
+	}
+	if strings.Join(bodies, "|") != "image/png:\x01\x02\x03|text/plain:caption" { t.Errorf("Nested Mismatch: got %q", bodies) }
+	if _, err := r.NextPart(); err != io.EOF { t.Errorf("End Mismatch: got %v, expected EOF", err) }
+}
+
+func TestBase64Parts(t *testing.T) {
+	// an extension in Go's own mime table, so the part's size is known everywhere
+	path := filepath.Join(t.TempDir(), "blob.png")
+	blob := make([]byte, 200)
+	for i := range blob { blob[i] = byte(i) }
+	if err := ioutil.WriteFile(path, blob, 0600); err != nil { t.Fatal(err) }
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithFixedBoundary("fixed-boundary"))
+	req := reqt.New("/upload").Method(POST).
+		FileArgPath("blob", path).
+		FileArgTyped("plain", "plain.bin", "application/octet-stream", bytes.NewReader([]byte{0, 1})).
+		Part(FormPart{Name: "part", Data: strings.NewReader("ab"), Base64: true}).
+		Base64Encode("blob")
+	reader, _ := req.GetBody()
+	data, _ := ioutil.ReadAll(reader)
+	req.(*RequestImpl).closeFiles()
+
+	r := multipart.NewReader(bytes.NewReader(data), "fixed-boundary")
+	for part, err := r.NextPart(); err == nil; part, err = r.NextPart() {
+		raw, _ := ioutil.ReadAll(part)
+		encoding := part.Header.Get("Content-Transfer-Encoding")
+		switch part.FormName() {
+		case "blob":
+			for _, line := range strings.Split(string(raw), "\r\n") {
+				if len(line) > 76 { t.Errorf("Line Mismatch: got %d characters, expected at most 76", len(line)) }
+			}
+			decoded, err := base64.StdEncoding.DecodeString(strings.Replace(string(raw), "\r\n", "", -1))
+			if encoding != "base64" || err != nil || !bytes.Equal(decoded, blob) { t.Errorf("Blob Mismatch: got %q, %q", encoding, raw) }
+		case "plain":
+			if encoding != "" || !bytes.Equal(raw, []byte{0, 1}) { t.Errorf("Plain Mismatch: got %q, %q", encoding, raw) }
+		case "part":
+			if encoding != "base64" || string(raw) != "YWI=" { t.Errorf("Part Mismatch: got %q, %q", encoding, raw) }
+		}
+	}
+
+	// encoding doesn't lose track of the body's length
+	req = reqt.New("/upload").Method(POST).FileArgPath("blob", path).Base64Encode("blob")
+	reader, _ = req.GetBody()
+	data, _ = ioutil.ReadAll(reader)
+	req.(*RequestImpl).closeFiles()
+	if sized, ok := reader.(*sizedReader); !ok || sized.size != int64(len(data)) { t.Errorf("Size Mismatch: got %v, expected %d", reader, len(data)) }
+}
@@ -0,0 +1,193 @@
+package reqtify
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// an ODataQuery builds the system query options of an OData request, like
+// $filter and $select, escaping the values in filters properly:
+//
+//	q := reqtify.OData().Filter("Name eq %v and Age gt %v", "O'Brien", 30).Select("Name", "Age").Top(10)
+//	api.New("/People").OData(q).JSONInto(&people).Do()
+//
+// sends ?$filter=Name eq 'O''Brien' and Age gt 30&$select=Name,Age&$top=10.
+type ODataQuery struct {
+	filters []string
+	selects []string
+	expands []string
+	orderBy []string
+	search  string
+	top     *int
+	skip    *int
+	count   bool
+	err     error
+}
+
+func OData() (*ODataQuery) {
+	return &ODataQuery{}
+}
+
+// adds a filter, which must hold as well as any others. expr is a format
+// string for fmt.Sprintf, whose args are formatted as OData literals first:
+// strings are quoted, times are written in ISO 8601, nil is null, and slices
+// become lists, for the in operator.
+func (this *ODataQuery) Filter(expr string, args ...interface{}) (*ODataQuery) {
+	literals := make([]interface{}, len(args))
+	for i, arg := range args {
+		literal, err := ODataLiteral(arg)
+		if err != nil && this.err == nil { this.err = err }
+		literals[i] = literal
+	}
+	this.filters = append(this.filters, fmt.Sprintf(expr, literals...))
+	return this
+}
+
+// limits the properties returned.
+func (this *ODataQuery) Select(fields ...string) (*ODataQuery) {
+	this.selects = append(this.selects, fields...)
+	return this
+}
+
+// includes related entities, like "Orders" or "Orders($select=Total)".
+func (this *ODataQuery) Expand(navigation ...string) (*ODataQuery) {
+	this.expands = append(this.expands, navigation...)
+	return this
+}
+
+// sorts the results, by properties optionally followed by " desc".
+func (this *ODataQuery) OrderBy(fields ...string) (*ODataQuery) {
+	this.orderBy = append(this.orderBy, fields...)
+	return this
+}
+
+// returns at most n results.
+func (this *ODataQuery) Top(n int) (*ODataQuery) {
+	this.top = &n
+	return this
+}
+
+// skips the first n results.
+func (this *ODataQuery) Skip(n int) (*ODataQuery) {
+	this.skip = &n
+	return this
+}
+
+// asks for the total number of matching results to be included.
+func (this *ODataQuery) Count() (*ODataQuery) {
+	this.count = true
+	return this
+}
+
+// searches for free text.
+func (this *ODataQuery) Search(terms string) (*ODataQuery) {
+	this.search = terms
+	return this
+}
+
+// returns the query options, or an error if a filter argument couldn't be
+// formatted.
+func (this *ODataQuery) Values() (url.Values, error) {
+	if this.err != nil { return nil, this.err }
+
+	v := url.Values{}
+	if len(this.filters) == 1 {
+		v.Set("$filter", this.filters[0])
+	} else if len(this.filters) > 1 {
+		v.Set("$filter", "(" + strings.Join(this.filters, ") and (") + ")")
+	}
+	if len(this.selects) != 0 { v.Set("$select", strings.Join(this.selects, ",")) }
+	if len(this.expands) != 0 { v.Set("$expand", strings.Join(this.expands, ",")) }
+	if len(this.orderBy) != 0 { v.Set("$orderby", strings.Join(this.orderBy, ",")) }
+	if this.top != nil { v.Set("$top", strconv.Itoa(*this.top)) }
+	if this.skip != nil { v.Set("$skip", strconv.Itoa(*this.skip)) }
+	if this.count { v.Set("$count", "true") }
+	if this.search != "" { v.Set("$search", this.search) }
+	return v, nil
+}
+
+// adds the query options of q to the URL.
+func (this *RequestImpl) OData(q *ODataQuery) (Request) {
+	values, err := q.Values()
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	for k, list := range values {
+		for _, value := range list {
+			this.QueryParams.Add(k, value)
+		}
+	}
+	return this
+}
+
+// formats v as an OData literal, for use in a filter.
+func ODataLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return "duration'" + odataDuration(v) + "'", nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.String:
+		return ODataLiteral(rv.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, rv.Len())
+		for i := range items {
+			item, err := ODataLiteral(rv.Index(i).Interface())
+			if err != nil { return "", err }
+			items[i] = item
+		}
+		return "(" + strings.Join(items, ",") + ")", nil
+	case reflect.Ptr:
+		if rv.IsNil() { return "null", nil }
+		return ODataLiteral(rv.Elem().Interface())
+	}
+	return "", fmt.Errorf("odata: can't format %T as a literal", v)
+}
+
+// formats a duration in ISO 8601, like P1DT2H3M4.5S.
+func odataDuration(d time.Duration) (string) {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+	if days := d / (24 * time.Hour); days != 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+		d -= days * 24 * time.Hour
+	}
+	b.WriteByte('T')
+	if hours := d / time.Hour; hours != 0 {
+		b.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes != 0 {
+		b.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+		d -= minutes * time.Minute
+	}
+	b.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+	return b.String()
+}
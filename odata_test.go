@@ -0,0 +1,37 @@
+package reqtify
+
+import (
+	"testing"
+	"net/url"
+	"time"
+)
+
+func TestOData(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	q := OData().Filter("Name eq %v and Age gt %v", "O'Brien", 30).Filter("Created ge %v or Manager eq %v", when, nil).
+		Filter("Status in %v", []string{"a", "b"}).Select("Name", "Age").Expand("Orders($top=2)").OrderBy("Age desc", "Name").Top(10).Skip(20).Count()
+
+	req := reqt.New("/People").OData(q)
+	u, err := url.Parse(req.URL())
+	if err != nil { t.Fatalf("Parse Failure: %s", err.Error()) }
+	query := u.Query()
+	expected := map[string]string{
+		"$filter": "(Name eq 'O''Brien' and Age gt 30) and (Created ge 2024-03-01T12:00:00Z or Manager eq null) and (Status in ('a','b'))",
+		"$select": "Name,Age",
+		"$expand": "Orders($top=2)",
+		"$orderby": "Age desc,Name",
+		"$top": "10",
+		"$skip": "20",
+		"$count": "true",
+	}
+	for k, v := range expected {
+		if query.Get(k) != v { t.Errorf("Option Mismatch: %s is %q, expected %q", k, query.Get(k), v) }
+	}
+	if len(query) != len(expected) { t.Errorf("Count Mismatch: got %v", query) }
+
+	if _, err := reqt.New("/People").OData(OData().Filter("X eq %v", struct{}{})).Do(); err == nil { t.Errorf("Literal Mismatch: accepted a struct") }
+	for v, expected := range map[interface{}]string{90 * time.Minute + 1500 * time.Millisecond: "duration'PT1H30M1.5S'", 1.5: "1.5", uint8(7): "7", true: "true"} {
+		if got, _ := ODataLiteral(v); got != expected { t.Errorf("Literal Mismatch: got %s, expected %s", got, expected) }
+	}
+}
@@ -0,0 +1,299 @@
+package reqtify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+   An Outbox keeps requests which couldn't be delivered in a directory, and
+   retries them on a schedule until they are, even across restarts. It's for
+   requests which mustn't be lost, like outbound notifications:
+
+	outbox := &reqtify.Outbox{Dir: "/var/lib/bot/outbox", Reqtifier: api, Retry: reqtify.RetryPolicy{Backoff: time.Minute, MaxBackoff: time.Hour}}
+	go outbox.Run(ctx)
+
+	_, err := api.New("/notify").Method(reqtify.PUT).JSONBody(n).Durable(outbox).Do()
+	var queued *reqtify.QueuedError
+	if errors.As(err, &queued) {
+		// it'll be delivered later
+	}
+
+   Only idempotent requests may be queued, since a request which timed out
+   may have been received anyway, and will be sent again: GET, HEAD, PUT,
+   DELETE, and other safe requests, and others with an Idempotency-Key header.
+
+   Each request is stored as a JSON file, including its headers and any basic
+   authentication credentials, in plaintext: API tokens in Authorization
+   headers, passwords, and anything else the request carries are readable by
+   whoever can read the directory. It should be private, and on a disk those
+   secrets may be kept on. Cookies aren't stored.
+
+   Files which can't be read, like ones truncated by a crash, are renamed
+   with a .corrupt extension and reported to Failed, so one bad file doesn't
+   hold up the rest.
+*/
+
+// a request waiting in an Outbox.
+type OutboxEntry struct {
+	ID       string
+	Method   HttpVerb
+	URL      string
+	Headers  map[string]string `json:",omitempty"`
+	User     string            `json:",omitempty"`
+	Password string            `json:",omitempty"`
+	Body     []byte            `json:",omitempty"`
+	BodyType string            `json:",omitempty"`
+
+	Created  time.Time
+	Attempts int       // the number of times it has been sent, including the first.
+	Next     time.Time // when it's due to be sent again.
+	LastError string   `json:",omitempty"`
+}
+
+type Outbox struct {
+	Dir       string
+	Reqtifier Reqtifier // used to send queued requests.
+
+	// when to retry queued requests. MaxAttempts counts the original attempt,
+	// and if it's 0, requests are retried forever. Backoff defaults to a minute.
+	Retry     RetryPolicy
+
+	// called with requests which are given up on, which are left in Dir
+	// with a .failed extension instead of .json, and with unreadable files,
+	// which are left with a .corrupt extension. For those, only the entry's
+	// ID is known.
+	Failed    func(entry OutboxEntry, err error)
+
+	lock      sync.Mutex
+}
+
+// returned by Do when a request failed and was queued in an Outbox.
+type QueuedError struct {
+	ID  string
+	Err error
+}
+
+func (this *QueuedError) Error() (string) {
+	return fmt.Sprintf("queued as %s: %s", this.ID, this.Err.Error())
+}
+
+func (this *QueuedError) Unwrap() (error) {
+	return this.Err
+}
+
+var ErrNotIdempotent error = errors.New("only idempotent requests can be queued in an outbox")
+
+// queues this request in outbox if it fails in a way that's worth retrying:
+// a transport error, or a response DefaultRetryOn would retry. Do then
+// returns a *QueuedError. Like DebugPrint, this resolves the request body,
+// so it should be called after the body is set.
+func (this *RequestImpl) Durable(outbox *Outbox) (Request) {
+	this.DurableOutbox = outbox
+	if !this.replayable() && this.BuildError == nil {
+		this.BuildError = this.cacheBody()
+	}
+	return this
+}
+
+func idempotent(verb HttpVerb, headers map[string]string) (bool) {
+	switch verb {
+	case GET, HEAD, PUT, DELETE, OPTIONS, TRACE, PROPFIND, REPORT:
+		return true
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, IdempotencyKeyHeader) && v != "" { return true }
+	}
+	return false
+}
+
+// queues req if it failed, returning the error Do should.
+func (this *Outbox) queue(req *RequestImpl, resp *http.Response, err error) (error) {
+	if req.BuildError != nil && err == req.BuildError { return err }
+	if resp != nil {
+		if !DefaultRetryOn(resp, nil) { return err }
+		if err == nil { err = &ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status} }
+	} else if err == nil {
+		return nil
+	}
+	if !idempotent(req.Verb, req.Headers) { return fmt.Errorf("%w: %s", ErrNotIdempotent, err.Error()) }
+
+	now := this.clock().Now()
+	entry := OutboxEntry{
+		Method: req.Verb,
+		URL: req.URL(),
+		Headers: req.Headers,
+		User: req.BasicUser,
+		Password: req.BasicPassword,
+		Created: now,
+		Attempts: 1,
+		Next: now.Add(this.delay(1, resp)),
+		LastError: err.Error(),
+	}
+	if req.ReqClient != nil {
+		entry.ID = req.ReqClient.NewID()
+	} else {
+		entry.ID = UUIDv4{}.NewID()
+	}
+	if reader, mimetype := req.GetBody(); reader != nil {
+		body, readErr := ioutil.ReadAll(reader)
+		if readErr != nil { return readErr }
+		entry.Body, entry.BodyType = body, mimetype
+	}
+
+	if saveErr := this.save(entry); saveErr != nil {
+		return fmt.Errorf("%s, and couldn't be queued: %w", err.Error(), saveErr)
+	}
+	return &QueuedError{ID: entry.ID, Err: err}
+}
+
+func (this *Outbox) delay(attempt int, resp *http.Response) (time.Duration) {
+	policy := this.Retry
+	if policy.Backoff == 0 { policy.Backoff = time.Minute }
+	var random *Random
+	if r, ok := this.Reqtifier.(*ReqtifierImpl); ok { random = r.Random }
+	return policy.delay(attempt, resp, this.clock().Now(), random)
+}
+
+// the clock of the outbox's Reqtifier, if it has one.
+func (this *Outbox) clock() (Clock) {
+	if r, ok := this.Reqtifier.(*ReqtifierImpl); ok { return r.clock() }
+	return SystemClock{}
+}
+
+func (this *Outbox) path(id, ext string) (string) {
+	return filepath.Join(this.Dir, url.PathEscape(id) + ext)
+}
+
+// writes an entry, replacing any earlier version of it.
+func (this *Outbox) save(entry OutboxEntry) (error) {
+	data, err := json.Marshal(entry)
+	if err != nil { return err }
+	if err := os.MkdirAll(this.Dir, 0700); err != nil { return err }
+
+	// written beside it and renamed into place, so it's never seen half written
+	tmp, err := ioutil.TempFile(this.Dir, ".tmp-")
+	if err != nil { return err }
+	_, err = tmp.Write(data)
+	if err == nil { err = tmp.Sync() }
+	if closeErr := tmp.Close(); err == nil { err = closeErr }
+	if err == nil { err = os.Rename(tmp.Name(), this.path(entry.ID, ".json")) }
+	if err != nil { os.Remove(tmp.Name()) }
+	return err
+}
+
+// returns the requests waiting to be sent, earliest due first.
+func (this *Outbox) Pending() ([]OutboxEntry, error) {
+	names, err := filepath.Glob(filepath.Join(this.Dir, "*.json"))
+	if err != nil { return nil, err }
+
+	var entries []OutboxEntry
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) { continue }
+		var entry OutboxEntry
+		if err == nil { err = json.Unmarshal(data, &entry) }
+		if err != nil {
+			if err := this.quarantine(name, err); err != nil { return nil, err }
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Next.Before(entries[j].Next) })
+	return entries, nil
+}
+
+// moves aside a file which couldn't be read because of err, and reports it.
+func (this *Outbox) quarantine(name string, err error) (error) {
+	id, unescapeErr := url.PathUnescape(strings.TrimSuffix(filepath.Base(name), ".json"))
+	if unescapeErr != nil { id = strings.TrimSuffix(filepath.Base(name), ".json") }
+	if renameErr := os.Rename(name, strings.TrimSuffix(name, ".json") + ".corrupt"); renameErr != nil {
+		return ignoreNotExist(renameErr)
+	}
+	if this.Failed != nil { this.Failed(OutboxEntry{ID: id}, fmt.Errorf("%s: %w", name, err)) }
+	return nil
+}
+
+// sends every queued request which is due. Those which succeed are removed,
+// and those which fail again are rescheduled, or given up on.
+func (this *Outbox) Flush(ctx context.Context) (error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	entries, err := this.Pending()
+	if err != nil { return err }
+	now := this.clock().Now()
+	for _, entry := range entries {
+		if entry.Next.After(now) { break }
+		if err := ctx.Err(); err != nil { return err }
+		if err := this.send(ctx, entry); err != nil { return err }
+	}
+	return nil
+}
+
+// sends an entry, and records the outcome.
+func (this *Outbox) send(ctx context.Context, entry OutboxEntry) (error) {
+	req := this.Reqtifier.New(entry.URL).Method(entry.Method).Context(ctx)
+	for k, v := range entry.Headers {
+		req = req.Header(k, v)
+	}
+	if entry.User != "" || entry.Password != "" { req = req.BasicAuthentication(entry.User, entry.Password) }
+	if entry.Body != nil || entry.BodyType != "" { req = req.Body(bytes.NewReader(entry.Body), entry.BodyType) }
+
+	resp, err := req.Do()
+	if resp != nil && resp.Body != nil { resp.Body.Close() }
+	if err == nil && resp != nil && resp.StatusCode >= 400 {
+		err = &ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+	if err == nil {
+		return ignoreNotExist(os.Remove(this.path(entry.ID, ".json")))
+	}
+	if ctx.Err() != nil { return ctx.Err() }
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	// only failures which might be temporary are worth trying again
+	retryable := resp == nil || DefaultRetryOn(resp, nil)
+	if retryable && (this.Retry.MaxAttempts == 0 || entry.Attempts < this.Retry.MaxAttempts) {
+		entry.Next = this.clock().Now().Add(this.delay(entry.Attempts, resp))
+		return this.save(entry)
+	}
+
+	if err := this.save(entry); err != nil { return err }
+	if err := os.Rename(this.path(entry.ID, ".json"), this.path(entry.ID, ".failed")); err != nil { return err }
+	if this.Failed != nil { this.Failed(entry, err) }
+	return nil
+}
+
+func ignoreNotExist(err error) (error) {
+	if os.IsNotExist(err) { return nil }
+	return err
+}
+
+// flushes the outbox whenever a request is due, and at least once a minute
+// to notice requests queued since, until ctx is canceled. Errors reading
+// or writing the outbox are retried the same way.
+func (this *Outbox) Run(ctx context.Context) (error) {
+	for {
+		this.Flush(ctx)
+
+		wait := time.Minute
+		if entries, err := this.Pending(); err == nil && len(entries) != 0 {
+			if until := entries[0].Next.Sub(this.clock().Now()); until < wait { wait = until }
+		}
+		if wait < time.Second { wait = time.Second }
+		if err := clockSleep(ctx, this.clock(), wait); err != nil { return err }
+	}
+}
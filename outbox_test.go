@@ -0,0 +1,77 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	server := test.NewServer(test.FailFirst(2, http.StatusServiceUnavailable))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+	outbox := &Outbox{Dir: t.TempDir(), Reqtifier: reqt, Retry: RetryPolicy{Backoff: time.Millisecond}}
+
+	_, err := reqt.New("/notify").Method(PUT).Header("X-Token", "abc").JSONBody(map[string]int{"n": 1}).Durable(outbox).Do()
+	var queued *QueuedError
+	if !errors.As(err, &queued) { t.Fatalf("Queue Mismatch: got %v", err) }
+	pending, err := outbox.Pending()
+	if err != nil || len(pending) != 1 || pending[0].ID != queued.ID || string(pending[0].Body) != `{"n":1}` || pending[0].Attempts != 1 { t.Fatalf("Pending Mismatch: got %+v, %v", pending, err) }
+
+	// the second attempt fails too, and the third is delivered
+	for i := 0; i < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if err := outbox.Flush(context.Background()); err != nil { t.Fatalf("Flush Failure: %s", err.Error()) }
+	}
+	if pending, _ = outbox.Pending(); len(pending) != 0 { t.Errorf("Delivery Mismatch: still pending %+v", pending) }
+	requests := server.Requests()
+	if len(requests) != 3 { t.Fatalf("Count Mismatch: %d requests", len(requests)) }
+	if last := requests[2]; last.Method != "PUT" || last.URL != "/notify" || string(last.Body) != `{"n":1}` || last.Header.Get("X-Token") != "abc" || last.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Replay Mismatch: got %+v", last)
+	}
+
+	// requests which succeed or fail for good aren't queued, and only idempotent ones can be
+	if _, err = reqt.New("/ok").Durable(outbox).Do(); err != nil { t.Errorf("Success Mismatch: got %v", err) }
+	broken := New(server.URL + "/missing", nil, nil, nil, "test")
+	if _, err = broken.New("").Method(POST).Durable(outbox).Do(); err != nil { t.Errorf("Status Mismatch: got %v", err) }
+	down := New("http://127.0.0.1:1", nil, nil, nil, "test")
+	if _, err = down.New("/").Method(POST).Durable(outbox).Do(); !errors.Is(err, ErrNotIdempotent) { t.Errorf("Idempotent Mismatch: got %v", err) }
+
+	// given up on after MaxAttempts
+	var failed []string
+	outbox = &Outbox{Dir: t.TempDir(), Reqtifier: down, Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}, Failed: func(e OutboxEntry, err error) { failed = append(failed, e.ID) }}
+	_, err = down.New("/").Method(POST).Header("Idempotency-Key", "k1").Durable(outbox).Do()
+	if !errors.As(err, &queued) { t.Fatalf("Queue Mismatch: got %v", err) }
+	time.Sleep(5 * time.Millisecond)
+	outbox.Flush(context.Background())
+	if pending, _ = outbox.Pending(); len(pending) != 0 || len(failed) != 1 || failed[0] != queued.ID { t.Errorf("Failed Mismatch: got %v, pending %+v", failed, pending) }
+	if _, err := os.Stat(filepath.Join(outbox.Dir, queued.ID + ".failed")); err != nil { t.Errorf("Failed File Mismatch: %v", err) }
+}
+
+func TestOutboxCorruptEntries(t *testing.T) {
+	server := test.NewServer(test.FailFirst(1, http.StatusServiceUnavailable))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+	var failed []string
+	var failure error
+	outbox := &Outbox{Dir: t.TempDir(), Reqtifier: reqt, Retry: RetryPolicy{Backoff: time.Millisecond}, Failed: func(e OutboxEntry, err error) { failed, failure = append(failed, e.ID), err }}
+
+	_, err := reqt.New("/notify").Method(PUT).Durable(outbox).Do()
+	var queued *QueuedError
+	if !errors.As(err, &queued) { t.Fatalf("Queue Mismatch: got %v", err) }
+	if err := ioutil.WriteFile(filepath.Join(outbox.Dir, "broken.json"), []byte(`{"ID": "bro`), 0600); err != nil { t.Fatal(err) }
+
+	// the corrupt entry is moved aside, and the good one still delivered
+	time.Sleep(5 * time.Millisecond)
+	if err := outbox.Flush(context.Background()); err != nil { t.Fatalf("Flush Failure: %s", err.Error()) }
+	if len(failed) != 1 || failed[0] != "broken" || failure == nil { t.Errorf("Failed Mismatch: got %v, %v", failed, failure) }
+	if _, err := os.Stat(filepath.Join(outbox.Dir, "broken.corrupt")); err != nil { t.Errorf("Corrupt File Mismatch: %v", err) }
+	if pending, err := outbox.Pending(); err != nil || len(pending) != 0 { t.Errorf("Pending Mismatch: got %+v, %v", pending, err) }
+	if len(server.Requests()) != 2 { t.Errorf("Delivery Mismatch: got %d requests, expected 2", len(server.Requests())) }
+}
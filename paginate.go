@@ -0,0 +1,387 @@
+package reqtify
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+/*
+   Many APIs split long lists across pages, and say where the next one is in
+   a Link header (RFC 8288), as GitHub does:
+
+	Link: <https://api.github.com/repositories/1/issues?page=2>; rel="next", <...?page=9>; rel="last"
+
+   Paginate follows those links, making each request through the same
+   Reqtifier, so the rate limiter, retries and so on apply to every page:
+
+	for issues, err := range reqtify.PaginateJSON[[]Issue](api.New("/repos/x/y/issues").Arg("per_page", 100), 0) {
+		if err != nil { return err }
+		...
+	}
+*/
+
+// a link from a Link header.
+type Link struct {
+	URL    string
+	Rel    []string          // the link relation types, lowercased.
+	Params map[string]string // the other parameters, by lowercased name.
+}
+
+// reports whether the link has the given relation type.
+func (this Link) Is(rel string) (bool) {
+	for _, r := range this.Rel {
+		if r == strings.ToLower(rel) { return true }
+	}
+	return false
+}
+
+// parses the Link headers of a response. Malformed links are skipped.
+func ParseLinks(header http.Header) ([]Link) {
+	var links []Link
+	for _, value := range header.Values("Link") {
+		for value != "" {
+			value = strings.TrimLeft(value, " \t,")
+			if !strings.HasPrefix(value, "<") { break }
+			end := strings.IndexByte(value, '>')
+			if end < 0 { break }
+			link := Link{URL: value[1:end], Params: make(map[string]string)}
+			value = value[end + 1:]
+
+			// parameters, up to the comma which starts the next link
+			for {
+				value = strings.TrimLeft(value, " \t")
+				if !strings.HasPrefix(value, ";") { break }
+				value = strings.TrimLeft(value[1:], " \t")
+
+				i := strings.IndexAny(value, "=;,")
+				if i < 0 { i = len(value) }
+				name := strings.ToLower(strings.TrimSpace(value[:i]))
+				value = value[i:]
+				var param string
+				if strings.HasPrefix(value, "=") {
+					param, value = linkParamValue(strings.TrimLeft(value[1:], " \t"))
+				}
+
+				if name == "rel" {
+					link.Rel = strings.Fields(strings.ToLower(param))
+				} else if name != "" {
+					link.Params[name] = param
+				}
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// reads a token or quoted string, returning it and the rest of the input.
+func linkParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, ";,")
+		if i < 0 { i = len(s) }
+		return strings.TrimSpace(s[:i]), s[i:]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i + 1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i + 1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+// returns the URL of the link with rel="next", or "" if there isn't one.
+func NextLink(resp *http.Response) (string) {
+	for _, link := range ParseLinks(resp.Header) {
+		if link.Is("next") { return link.URL }
+	}
+	return ""
+}
+
+// returns a copy of this request, which may be changed and sent without
+// affecting the original. The two share unmarshallers and their targets.
+func (this *RequestImpl) Clone() (Request) {
+	return this.clone()
+}
+
+func (this *RequestImpl) clone() (*RequestImpl) {
+	// a body read from a stream can only be sent once, unless it's cached
+	if !this.replayable() && this.BuildError == nil {
+		this.BuildError = this.cacheBody()
+	}
+
+	c := *this
+	c.QueryParams = cloneValues(this.QueryParams)
+	c.FormParams = cloneValues(this.FormParams)
+	c.AutoParams = cloneValues(this.AutoParams)
+	c.Headers = make(map[string]string, len(this.Headers))
+	for k, v := range this.Headers {
+		c.Headers[k] = v
+	}
+	if this.GraphQLVariables != nil {
+		c.GraphQLVariables = make(map[string]interface{}, len(this.GraphQLVariables))
+		for k, v := range this.GraphQLVariables {
+			c.GraphQLVariables[k] = v
+		}
+	}
+	c.FormFiles = make(map[string][]FormFile, len(this.FormFiles))
+	for k, v := range this.FormFiles {
+		c.FormFiles[k] = append([]FormFile(nil), v...)
+	}
+
+	// so appending to one doesn't write into the other's backing array
+	if this.Trailers != nil {
+		c.Trailers = make(map[string]func() string, len(this.Trailers))
+		for k, v := range this.Trailers {
+			c.Trailers[k] = v
+		}
+	}
+	c.FormParts = append([]FormPart(nil), this.FormParts...)
+	c.PartNames = append([]string(nil), this.PartNames...)
+	c.Base64Names = append([]string(nil), this.Base64Names...)
+	c.Cookies = append([]*http.Cookie(nil), this.Cookies...)
+	c.Checksums = append([]Checksum(nil), this.Checksums...)
+	c.AgentSuffix = append([]string(nil), this.AgentSuffix...)
+	c.Response = append([]ResponseUnmarshaller(nil), this.Response...)
+	if c.hasHAL() { c.BindHAL(&c) }
+	c.StatusResponse = append([]StatusUnmarshaller(nil), this.StatusResponse...)
+	c.Validators = append([]func() error(nil), this.Validators...)
+	c.HeaderCaptures = append([]HeaderCapture(nil), this.HeaderCaptures...)
+	c.ExpectedStatus = append([]StatusRange(nil), this.ExpectedStatus...)
+	c.CompletionHooks = append([]func(*http.Response, error){}, this.CompletionHooks...)
+	c.InformationalHooks = append([]func(int, textproto.MIMEHeader){}, this.InformationalHooks...)
+	return &c
+}
+
+func cloneValues(v url.Values) (url.Values) {
+	c := make(url.Values, len(v))
+	for k, list := range v {
+		c[k] = append([]string(nil), list...)
+	}
+	return c
+}
+
+// returns a copy of this request, sent to link instead. link may be
+// relative to this request's URL, like links in responses often are. Its
+// query replaces any query parameters set on this request.
+func (this *RequestImpl) Follow(link string) (Request) {
+	c := this.clone()
+	base, err := url.Parse(this.URL())
+	if err != nil {
+		c.setBuildError(err)
+		return c
+	}
+	target, err := base.Parse(link)
+	if err != nil {
+		c.setBuildError(err)
+		return c
+	}
+
+	c.QueryParams = target.Query()
+	if c.Verb.bodiless() || c.RawBody != nil {
+		// these were sent in the query, which the link replaces
+		c.AutoParams = url.Values{}
+	}
+	target.RawQuery = ""
+	c.URLPath = target.String()
+	if root := this.ReqClient.Root; root != "" && strings.HasPrefix(c.URLPath, root) {
+		c.URLPath = strings.TrimPrefix(c.URLPath, root)
+	}
+	return c
+}
+
+// yields the response to first, and then to each page linked from the
+// previous one with rel="next", until there are no more, limit pages have
+// been fetched (unless limit is 0), or a request fails. Each response's
+// body is closed once the loop moves on from it.
+//
+// first's unmarshallers are used for every page, so each decodes into the
+// same targets, overwriting the last page.
+func Paginate(first Request, limit int) (iter.Seq2[*http.Response, error]) {
+	return func(yield func(*http.Response, error) bool) {
+		req := first
+		for page := 0; limit == 0 || page < limit; page++ {
+			resp, err := req.Do()
+			more := yield(resp, err)
+			if resp != nil && resp.Body != nil { resp.Body.Close() }
+			if !more || err != nil { return }
+
+			next := NextLink(resp)
+			if next == "" { return }
+			req = req.Follow(next)
+		}
+	}
+}
+
+// like Paginate, but yields each page decoded as JSON into a new T.
+func PaginateJSON[T any](first Request, limit int) (iter.Seq2[T, error]) {
+	return func(yield func(T, error) bool) {
+		req := first
+		for page := 0; limit == 0 || page < limit; page++ {
+			var v T
+			resp, err := req.Clone().JSONInto(&v).Do()
+			if resp != nil && resp.Body != nil { resp.Body.Close() }
+			if !yield(v, err) || err != nil { return }
+
+			next := NextLink(resp)
+			if next == "" { return }
+			req = req.Follow(next)
+		}
+	}
+}
+
+// returns a function for PaginateCursor which sends the cursor as the
+// argument name.
+func CursorArg(name string) (func(req Request, cursor string) Request) {
+	return func(req Request, cursor string) Request {
+		return req.Arg(name, cursor)
+	}
+}
+
+// yields each page of a cursor paginated API, decoded as JSON into a new T.
+// cursor extracts the cursor for the next page from each page, and apply
+// adds it to a copy of first, typically with CursorArg. Pagination stops
+// when the cursor is empty or repeats itself, after limit pages (unless
+// limit is 0), or when a request fails.
+//
+//	type Page struct {
+//		Items []Item
+//		Meta  struct{ Next string }
+//	}
+//	pages := reqtify.PaginateCursor(api.New("/items"), 0, func(p Page) string { return p.Meta.Next }, reqtify.CursorArg("after"))
+func PaginateCursor[T any](first Request, limit int, cursor func(page T) string, apply func(req Request, cursor string) Request) (iter.Seq2[T, error]) {
+	return func(yield func(T, error) bool) {
+		req := first
+		last := ""
+		for page := 0; limit == 0 || page < limit; page++ {
+			var v T
+			resp, err := req.Clone().JSONInto(&v).Do()
+			if resp != nil && resp.Body != nil { resp.Body.Close() }
+			if !yield(v, err) || err != nil { return }
+
+			next := cursor(v)
+			if next == "" || next == last { return }
+			last = next
+			req = apply(first.Clone(), next)
+		}
+	}
+}
+
+// describes an API paginated by page number or offset. See PaginateOffset.
+type OffsetPaging struct {
+	Param      string // the argument holding the page number or offset, like "page" or "offset".
+	Start      int    // the value of Param for the first page.
+	Step       int    // how much Param grows each page: 1 for page numbers, Limit for offsets. Defaults to 1.
+
+	LimitParam string // the argument holding the page size, like "limit" or "per_page", if any.
+	Limit      int    // the page size.
+	StopShort  bool   // stop after a page with fewer than Limit items, rather than fetching an empty one.
+
+	MaxPages   int    // stop after this many pages, unless 0.
+
+	// how many pages to fetch ahead of the one being yielded, in parallel.
+	// Pages fetched ahead of the end of the list are wasted requests.
+	Prefetch   int
+}
+
+// returns the number of items in a page, for pages which are slices, arrays
+// or maps, and 1 for anything else.
+func pageLength(page interface{}) (int) {
+	v := reflect.ValueOf(page)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len()
+	}
+	return 1
+}
+
+// yields each page of an API paginated by page number or offset, decoded as
+// JSON into a new T. count returns the number of items in a page; if nil,
+// T should be a slice. Pagination stops at the first empty page (or short
+// one, with StopShort), after MaxPages pages, or when a request fails.
+//
+// Pages are requested with copies of first, without its unmarshallers and
+// validators, since they'd run concurrently. Requests still in flight when
+// the loop ends are canceled.
+//
+//	// ?offset=0&limit=50, ?offset=50&limit=50, ...
+//	paging := reqtify.OffsetPaging{Param: "offset", Step: 50, LimitParam: "limit", Limit: 50, StopShort: true, Prefetch: 2}
+//	for users, err := range reqtify.PaginateOffset[[]User](api.New("/users"), paging, nil) {
+func PaginateOffset[T any](first Request, paging OffsetPaging, count func(page T) int) (iter.Seq2[T, error]) {
+	if paging.Step == 0 { paging.Step = 1 }
+	if count == nil { count = func(page T) int { return pageLength(page) } }
+
+	type result struct {
+		page T
+		err  error
+	}
+
+	return func(yield func(T, error) bool) {
+		parent := context.Background()
+		if c, ok := first.(interface{ context() context.Context }); ok { parent = c.context() }
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		var pending []chan result
+		launched := 0
+		launch := func() {
+			req := pageClone(first, ctx).Arg(paging.Param, paging.Start + launched * paging.Step)
+			if paging.LimitParam != "" { req = req.Arg(paging.LimitParam, paging.Limit) }
+			launched++
+
+			ch := make(chan result, 1)
+			pending = append(pending, ch)
+			go func() {
+				var r result
+				resp, err := req.JSONInto(&r.page).Do()
+				if resp != nil && resp.Body != nil { resp.Body.Close() }
+				r.err = err
+				ch <- r
+			}()
+		}
+
+		for {
+			for len(pending) <= paging.Prefetch && (paging.MaxPages == 0 || launched < paging.MaxPages) {
+				launch()
+			}
+			if len(pending) == 0 { return }
+
+			r := <-pending[0]
+			pending = pending[1:]
+			if !yield(r.page, r.err) || r.err != nil { return }
+
+			n := count(r.page)
+			if n == 0 || (paging.StopShort && n < paging.Limit) { return }
+		}
+	}
+}
+
+// returns a copy of first for fetching a page alongside others, if it's built
+// on RequestImpl: it doesn't share first's unmarshallers or validators, and
+// is sent with ctx. Other implementations are cloned as they are.
+func pageClone(first Request, ctx context.Context) (Request) {
+	c := first.Clone()
+	if p, ok := c.(interface{ detachPage(context.Context) }); ok { p.detachPage(ctx) }
+	return c
+}
+
+func (this *RequestImpl) detachPage(ctx context.Context) {
+	this.Response = nil
+	this.StatusResponse = nil
+	this.Validators = nil
+	this.RequestContext = ctx
+}
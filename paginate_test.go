@@ -0,0 +1,182 @@
+package reqtify
+
+import (
+	"testing"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"encoding/json"
+	"time"
+)
+
+func TestParseLinks(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://api.example/items?page=2&a=b,c>; rel="next prefetch"; title="Page \"2\"", <https://api.example/items?page=9>; REL=last`)
+	header.Add("Link", `</items?page=1>;rel=first;type=text/html, garbage`)
+
+	links := ParseLinks(header)
+	if len(links) != 3 { t.Fatalf("Count Mismatch: got %+v", links) }
+	if l := links[0]; l.URL != "https://api.example/items?page=2&a=b,c" || !l.Is("next") || !l.Is("Prefetch") || l.Params["title"] != `Page "2"` {
+		t.Errorf("Link Mismatch: got %+v", l)
+	}
+	if l := links[1]; !l.Is("last") || l.Is("next") { t.Errorf("Link Mismatch: got %+v", l) }
+	if l := links[2]; l.URL != "/items?page=1" || !l.Is("first") || l.Params["type"] != "text/html" { t.Errorf("Link Mismatch: got %+v", l) }
+	if NextLink(&http.Response{Header: header}) != "https://api.example/items?page=2&a=b,c" { t.Errorf("Next Mismatch") }
+}
+
+func TestPaginate(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 { page = 1 }
+		switch page {
+		case 1:
+			w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=2&per_page=%s>; rel="next"`, server.URL, r.URL.Query().Get("per_page")))
+		case 2:
+			w.Header().Set("Link", `</items?page=3&per_page=2>; rel="next"`)
+		}
+		fmt.Fprintf(w, `[%d, %d]`, page * 2 - 1, page * 2)
+	}))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test")
+	var items []int
+	var pages []string
+	for resp, err := range Paginate(reqt.New("/items").Arg("per_page", 2).JSONInto(&items), 0) {
+		if err != nil { t.Fatalf("Page Failure: %s", err.Error()) }
+		pages = append(pages, resp.Request.URL.RequestURI() + fmt.Sprint(items))
+	}
+	expected := []string{"/items?per_page=2[1 2]", "/items?page=2&per_page=2[3 4]", "/items?page=3&per_page=2[5 6]"}
+	if fmt.Sprint(pages) != fmt.Sprint(expected) { t.Errorf("Paginate Mismatch: got %q", pages) }
+
+	var all []int
+	for page, err := range PaginateJSON[[]int](reqt.New("/items").Arg("per_page", 2), 2) {
+		if err != nil { t.Fatalf("Page Failure: %s", err.Error()) }
+		all = append(all, page...)
+	}
+	if fmt.Sprint(all) != "[1 2 3 4]" { t.Errorf("PaginateJSON Mismatch: got %v", all) }
+
+	first := reqt.New("/items").Arg("per_page", 2).(*RequestImpl)
+	next := first.Follow(server.URL + "/items?page=2").(*RequestImpl)
+	if next.URLPath != "/items" || next.URL() != server.URL + "/items?page=2" || first.URL() != server.URL + "/items?per_page=2" {
+		t.Errorf("Follow Mismatch: got %s from %s", next.URL(), first.URL())
+	}
+	if elsewhere := first.Follow("https://cdn.example/x?y=z").(*RequestImpl); elsewhere.URL() != "https://cdn.example/x?y=z" { t.Errorf("Follow Mismatch: got %s", elsewhere.URL()) }
+}
+
+func TestPaginateCursor(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Query().Get("after") {
+		case "":
+			fmt.Fprint(w, `{"items": [1, 2], "next": "c1"}`)
+		case "c1":
+			fmt.Fprint(w, `{"items": [3], "next": "c2"}`)
+		default:
+			fmt.Fprint(w, `{"items": [], "next": "c2"}`)
+		}
+	}))
+	defer server.Close()
+
+	type page struct {
+		Items []int  `json:"items"`
+		Next  string `json:"next"`
+	}
+	reqt := New(server.URL, nil, nil, nil, "test")
+	first := reqt.New("/items").Arg("limit", 2)
+
+	var all []int
+	for p, err := range PaginateCursor(first, 0, func(p page) string { return p.Next }, CursorArg("after")) {
+		if err != nil { t.Fatalf("Page Failure: %s", err.Error()) }
+		all = append(all, p.Items...)
+	}
+	// the third page repeats its cursor, which ends pagination
+	if fmt.Sprint(all) != "[1 2 3]" || requests != 3 { t.Errorf("Cursor Mismatch: got %v after %d requests", all, requests) }
+	if first.URL() != server.URL + "/items?limit=2" { t.Errorf("First Mismatch: modified to %s", first.URL()) }
+
+	requests = 0
+	for range PaginateCursor(first, 1, func(p page) string { return p.Next }, CursorArg("after")) {}
+	if requests != 1 { t.Errorf("Limit Mismatch: %d requests", requests) }
+}
+
+func TestPaginateOffset(t *testing.T) {
+	var lock sync.Mutex
+	requested := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requested[r.URL.RequestURI()] = true
+		lock.Unlock()
+
+		// 7 items in total
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var items []int
+		for i := offset; i < offset + limit && i < 7; i++ {
+			items = append(items, i)
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test")
+	paging := OffsetPaging{Param: "offset", Step: 3, LimitParam: "limit", Limit: 3, StopShort: true, Prefetch: 2}
+	var all, shared []int
+	for page, err := range PaginateOffset[[]int](reqt.New("/items").JSONInto(&shared), paging, nil) {
+		if err != nil { t.Fatalf("Page Failure: %s", err.Error()) }
+		all = append(all, page...)
+	}
+	if fmt.Sprint(all) != "[0 1 2 3 4 5 6]" { t.Errorf("Offset Mismatch: got %v", all) }
+	// pages aren't decoded into the first request's targets as well
+	if shared != nil { t.Errorf("Unmarshaller Mismatch: got %v", shared) }
+	lock.Lock()
+	for _, query := range []string{"/items?limit=3&offset=0", "/items?limit=3&offset=3", "/items?limit=3&offset=6"} {
+		if !requested[query] { t.Errorf("Prefetch Mismatch: %s not requested in %v", query, requested) }
+	}
+	requested = make(map[string]bool)
+	lock.Unlock()
+
+	// without StopShort, an empty page ends it
+	paging = OffsetPaging{Param: "offset", Start: 1, LimitParam: "limit", Limit: 4, MaxPages: 10}
+	var pages int
+	for range PaginateOffset(reqt.New("/more"), paging, func(page []int) int { return len(page) }) {
+		pages++
+	}
+	lock.Lock()
+	more := 0
+	for uri := range requested {
+		if strings.HasPrefix(uri, "/more?") { more++ }
+	}
+	lock.Unlock()
+	if pages != 7 || more != 7 { t.Errorf("Empty Mismatch: got %d pages, requested %v", pages, requested) }
+}
+
+func TestPaginateOffsetCancel(t *testing.T) {
+	canceled := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "0" {
+			// prefetched pages hang until they're abandoned
+			<-r.Context().Done()
+			canceled <- r.URL.RawQuery
+			return
+		}
+		w.Write([]byte("[1, 2]"))
+	}))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test")
+	for page, err := range PaginateOffset[[]int](reqt.New("/items"), OffsetPaging{Param: "page", Prefetch: 2}, nil) {
+		if err != nil || len(page) != 2 { t.Fatalf("Page Mismatch: got %v, %v", page, err) }
+		break
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-canceled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Cancel Mismatch: prefetched page %d still in flight", i + 1)
+		}
+	}
+}
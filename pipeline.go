@@ -0,0 +1,119 @@
+package reqtify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+/*
+   A Pipeline runs requests which depend on each other in order, feeding what
+   each one decoded to the function which builds the next, like the common
+   "authenticate, then fetch, then act" sequence:
+
+	p := reqtify.NewPipeline()
+	reqtify.Step(p, "login", func(_ struct{}, tok *Token) reqtify.Request {
+		return api.New("/login").Method(reqtify.POST).Arg("key", key).JSONInto(tok)
+	})
+	reqtify.Step(p, "profile", func(tok Token, me *User) reqtify.Request {
+		return api.New("/me").Header("Authorization", "Bearer " + tok.Value).JSONInto(me)
+	})
+	me, err := reqtify.RunPipeline[User](ctx, p)
+
+   Every request shares the context passed to Run, and the first to fail
+   stops the pipeline.
+*/
+type Pipeline struct {
+	steps   []pipelineStep
+	out     reflect.Type
+	err     error
+}
+
+type pipelineStep struct {
+	name string
+	run  func(ctx context.Context, prev interface{}) (interface{}, *http.Response, error)
+}
+
+// returned by Run when one of a pipeline's steps fails.
+type PipelineError struct {
+	Step  string
+	Index int
+	Err   error
+}
+
+func (this *PipelineError) Error() (string) {
+	return fmt.Sprintf("pipeline step %d (%s) failed: %s", this.Index, this.Step, this.Err.Error())
+}
+
+func (this *PipelineError) Unwrap() (error) {
+	return this.Err
+}
+
+// the outcome of running a pipeline.
+type PipelineResult struct {
+	Outputs   []interface{}    // what each step decoded, as an Out.
+	Responses []*http.Response // each step's response, with its body closed.
+}
+
+// returns the output of the last step, or nil if the pipeline is empty.
+func (this *PipelineResult) Last() (interface{}) {
+	if len(this.Outputs) == 0 { return nil }
+	return this.Outputs[len(this.Outputs) - 1]
+}
+
+func NewPipeline() (*Pipeline) {
+	return &Pipeline{out: reflect.TypeOf(struct{}{})}
+}
+
+// adds a step to a pipeline. build is given the previous step's output,
+// or an empty struct{} for the first step, and returns the request to send,
+// which should decode its response into out. In must be the previous step's
+// Out.
+func Step[In, Out any](p *Pipeline, name string, build func(prev In, out *Out) Request) (*Pipeline) {
+	if in := reflect.TypeOf((*In)(nil)).Elem(); p.err == nil && in != p.out && !(in.Kind() == reflect.Interface && p.out.Implements(in)) {
+		p.err = &PipelineError{Step: name, Index: len(p.steps), Err: fmt.Errorf("takes %s, but the previous step produces %s", in, p.out)}
+	}
+	p.out = reflect.TypeOf((*Out)(nil)).Elem()
+
+	p.steps = append(p.steps, pipelineStep{
+		name: name,
+		run: func(ctx context.Context, prev interface{}) (interface{}, *http.Response, error) {
+			var out Out
+			resp, err := build(prev.(In), &out).Context(ctx).Do()
+			if resp != nil && resp.Body != nil { resp.Body.Close() }
+			return out, resp, err
+		},
+	})
+	return p
+}
+
+// runs each step in turn, stopping at the first which fails, or when ctx
+// is canceled. The error is then a *PipelineError, and the result holds the
+// steps which finished, including the one that failed.
+func (this *Pipeline) Run(ctx context.Context) (*PipelineResult, error) {
+	if this.err != nil { return nil, this.err }
+
+	result := &PipelineResult{}
+	var prev interface{} = struct{}{}
+	for i, step := range this.steps {
+		if err := ctx.Err(); err != nil { return result, &PipelineError{Step: step.name, Index: i, Err: err} }
+
+		out, resp, err := step.run(ctx, prev)
+		result.Outputs = append(result.Outputs, out)
+		result.Responses = append(result.Responses, resp)
+		if err != nil { return result, &PipelineError{Step: step.name, Index: i, Err: err} }
+		prev = out
+	}
+	return result, nil
+}
+
+// runs p, and returns the last step's output, which must be a T.
+func RunPipeline[T any](ctx context.Context, p *Pipeline) (T, error) {
+	var zero T
+	result, err := p.Run(ctx)
+	if err != nil { return zero, err }
+	last, ok := result.Last().(T)
+	if !ok { return zero, fmt.Errorf("pipeline produces %T, not %T", result.Last(), zero) }
+	return last, nil
+}
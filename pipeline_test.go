@@ -0,0 +1,58 @@
+package reqtify
+
+import (
+	"testing"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestPipeline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			fmt.Fprintf(w, `{"token": "t-%s"}`, r.URL.Query().Get("key"))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t-k" { w.WriteHeader(401); return }
+			fmt.Fprint(w, `{"name": "alice"}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	type token struct { Token string `json:"token"` }
+	type user struct { Name string `json:"name"` }
+	login := func(key string) *Pipeline {
+		return Step(NewPipeline(), "login", func(_ struct{}, tok *token) Request {
+			return reqt.New("/login").Arg("key", key).JSONInto(tok)
+		})
+	}
+	profile := func(tok token, me *user) Request {
+		return reqt.New("/me").Header("Authorization", "Bearer " + tok.Token).ExpectStatus(200).JSONInto(me)
+	}
+
+	me, err := RunPipeline[user](context.Background(), Step(login("k"), "profile", profile))
+	if err != nil || me.Name != "alice" { t.Errorf("Pipeline Mismatch: got %+v, %v", me, err) }
+
+	var calls int
+	p := Step(Step(login("wrong"), "profile", profile), "act", func(me user, _ *struct{}) Request {
+		calls++
+		return reqt.New("/act")
+	})
+	result, err := p.Run(context.Background())
+	var pipeErr *PipelineError
+	var unexpected *UnexpectedStatusError
+	if !errors.As(err, &pipeErr) || pipeErr.Step != "profile" || pipeErr.Index != 1 || !errors.As(err, &unexpected) || calls != 0 { t.Errorf("Failure Mismatch: got %v", err) }
+	if len(result.Outputs) != 2 || result.Outputs[0].(token).Token != "t-wrong" || result.Responses[1].StatusCode != 401 { t.Errorf("Result Mismatch: got %+v", result) }
+
+	mismatched := Step(login("k"), "act", func(me user, _ *struct{}) Request { return reqt.New("/act") })
+	if _, err = mismatched.Run(context.Background()); err == nil || calls != 0 { t.Errorf("Type Mismatch: got %v", err) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = login("k").Run(ctx); !errors.Is(err, context.Canceled) { t.Errorf("Cancel Mismatch: got %v", err) }
+}
@@ -0,0 +1,203 @@
+package reqtify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// a RetryPolicy controls whether and how a failed request is retried.
+type RetryPolicy struct {
+	MaxAttempts int           // the total number of attempts, including the first.
+	Backoff     time.Duration // the delay before the first retry. It doubles after each subsequent one.
+	MaxBackoff  time.Duration // the maximum delay between attempts, unlimited if zero.
+	Jitter      float64       // randomly lengthens or shortens each delay by up to this fraction of it, so clients don't retry in lockstep.
+	IdempotencyKeys bool      // gives requests which aren't idempotent, like POSTs, a generated Idempotency-Key, so they can be retried safely.
+
+	// decides whether an attempt should be retried. If nil, DefaultRetryOn is used.
+	RetryOn     func(resp *http.Response, err error) bool
+}
+
+// retries transport errors, and responses indicating the server is
+// overloaded or temporarily unavailable.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil { return true }
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (this *RetryPolicy) attempts() (int) {
+	if this == nil || this.MaxAttempts < 1 { return 1 }
+	return this.MaxAttempts
+}
+
+func (this *RetryPolicy) shouldRetry(resp *http.Response, err error) (bool) {
+	if this.RetryOn != nil { return this.RetryOn(resp, err) }
+	return DefaultRetryOn(resp, err)
+}
+
+// returns how long to wait after the given attempt before the next one, if
+// it's now, with jitter drawn from random. A Retry-After header on the
+// response is honored, up to MaxBackoff.
+func (this *RetryPolicy) delay(attempt int, resp *http.Response, now time.Time, random *Random) (time.Duration) {
+	d := this.Backoff
+	for i := 1; i < attempt && (this.MaxBackoff == 0 || d < this.MaxBackoff); i++ {
+		d *= 2
+	}
+	if this.Jitter > 0 {
+		d += time.Duration(float64(d) * this.Jitter * (2 * random.Float64() - 1))
+	}
+
+	if resp != nil {
+		if after := retryAfter(resp.Header.Get("Retry-After"), now); after > d {
+			d = after
+		}
+	}
+
+	if this.MaxBackoff != 0 && d > this.MaxBackoff {
+		d = this.MaxBackoff
+	}
+	return d
+}
+
+// parses a Retry-After header, which is either a number of seconds or a date,
+// into how long to wait from now.
+func retryAfter(value string, now time.Time) (time.Duration) {
+	if value == "" { return 0 }
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) (error) {
+	if d <= 0 { return ctx.Err() }
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <- t.C:
+		return nil
+	case <- ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// settings applied to requests which don't override them. See WithVerbDefaults.
+type RequestDefaults struct {
+	Timeout   time.Duration // the time limit for each attempt, including reading the response body.
+	HeaderTimeout time.Duration // see RequestImpl.HeaderTimeout.
+	IdleTimeout   time.Duration // see RequestImpl.IdleTimeout.
+	Retry     *RetryPolicy
+	RateGroup string
+}
+
+// applies defaults to every request made with the given verb which doesn't
+// set its own timeout, retry policy, or rate group. For example, a client
+// might allow generous timeouts for POST uploads but retry GETs quickly:
+//
+//	WithVerbDefaults(POST, RequestDefaults{Timeout: 10 * time.Minute}),
+//	WithVerbDefaults(GET, RequestDefaults{Timeout: 10 * time.Second, Retry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Second}}),
+func WithVerbDefaults(verb HttpVerb, defaults RequestDefaults) Option {
+	return func(r *ReqtifierImpl) {
+		if r.VerbDefaults == nil {
+			r.VerbDefaults = make(map[HttpVerb]RequestDefaults)
+		}
+		r.VerbDefaults[verb] = defaults
+	}
+}
+
+// registers a named rate limiter. Requests in the group wait on it instead of
+// on the Reqtifier's main RateLimiter.
+func WithRateGroup(name string, limiter *time.Ticker) Option {
+	return func(r *ReqtifierImpl) {
+		if r.RateGroups == nil {
+			r.RateGroups = make(map[string]*time.Ticker)
+		}
+		r.RateGroups[name] = limiter
+	}
+}
+
+// resolves the settings for a request from its own overrides and the
+// defaults for its verb.
+func (this *ReqtifierImpl) settingsFor(req *RequestImpl) (RequestDefaults) {
+	settings := this.VerbDefaults[req.Verb]
+	if settings.HeaderTimeout == 0 { settings.HeaderTimeout = this.HeaderTimeout }
+	if settings.IdleTimeout == 0 { settings.IdleTimeout = this.IdleTimeout }
+	if req.HeaderTimeoutOverride != nil { settings.HeaderTimeout = *req.HeaderTimeoutOverride }
+	if req.IdleTimeoutOverride != nil { settings.IdleTimeout = *req.IdleTimeoutOverride }
+	if req.AttemptTimeout != nil { settings.Timeout = *req.AttemptTimeout }
+	if req.RetryPolicy != nil { settings.Retry = req.RetryPolicy }
+	if req.Group != "" { settings.RateGroup = req.Group }
+	return settings
+}
+
+func (this *ReqtifierImpl) rateLimiter(group string) (*time.Ticker, error) {
+	if group == "" { return this.RateLimiter, nil }
+	limiter, ok := this.RateGroups[group]
+	if !ok { return nil, fmt.Errorf("unknown rate group %q", group) }
+	return limiter, nil
+}
+
+// sets the context for this request. If it's canceled, the request is
+// aborted, including any pending retries.
+func (this *RequestImpl) Context(ctx context.Context) (Request) {
+	this.RequestContext = ctx
+	return this
+}
+
+func (this *RequestImpl) context() (context.Context) {
+	if this.RequestContext == nil { return context.Background() }
+	return this.RequestContext
+}
+
+// limits how long each attempt at this request may take, including reading
+// the response body. Zero means no limit, even if the verb has a default.
+func (this *RequestImpl) Timeout(d time.Duration) (Request) {
+	this.AttemptTimeout = &d
+	return this
+}
+
+// sets the retry policy for this request. Use RetryPolicy{MaxAttempts: 1}
+// to disable retries when the verb has a default policy. Only idempotent
+// requests are retried: GET, HEAD, PUT and DELETE requests, other safe
+// ones like OPTIONS and PROPFIND, and those with an idempotency key. See
+// RetryNonIdempotent.
+func (this *RequestImpl) Retry(policy RetryPolicy) (Request) {
+	this.RetryPolicy = &policy
+	return this
+}
+
+// allows this request to be retried even though it isn't idempotent, like a
+// POST without an idempotency key, for APIs where sending it twice is
+// harmless.
+func (this *RequestImpl) RetryNonIdempotent() (Request) {
+	this.RetryAnyMethod = true
+	return this
+}
+
+// makes this request wait on the named rate limiter. See WithRateGroup.
+func (this *RequestImpl) RateGroup(name string) (Request) {
+	this.Group = name
+	return this
+}
+
+// releases a context when the body it governs is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (this *cancelOnClose) Close() (error) {
+	err := this.ReadCloser.Close()
+	this.cancel()
+	return err
+}
@@ -0,0 +1,121 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"time"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"io/ioutil"
+	"strings"
+)
+
+func TestRetryAndVerbDefaults(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var bodies []string
+	failures := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			b, _ := ioutil.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+		}
+		if failures > 0 {
+			failures--
+			return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader("busy"))}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test",
+		WithVerbDefaults(POST, RequestDefaults{Retry: &RetryPolicy{MaxAttempts: 3}}),
+		WithRateGroup("slow", time.NewTicker(time.Millisecond)),
+	)
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// file bodies must be replayed intact on each attempt
+	failures = 2
+	resp, err := reqt.New("/").Method(POST).RetryNonIdempotent().FileArg("f", "f.txt", ioutil.NopCloser(strings.NewReader("contents"))).Do()
+	if err != nil || resp.StatusCode != 200 { t.Errorf("Retry Failure: got %v, %v", resp, err) }
+	if len(bodies) != 3 || bodies[0] != bodies[2] || !strings.Contains(bodies[2], "contents") {
+		t.Errorf("Body Mismatch: got %q", bodies)
+	}
+
+	// retries are exhausted
+	failures = 5
+	resp, err = reqt.New("/").Method(POST).RetryNonIdempotent().Do()
+	if err != nil || resp.StatusCode != 503 || failures != 2 { t.Errorf("Retry Mismatch: got %v, %v, expected 503", resp, err) }
+
+	// POSTs aren't retried unless they're idempotent
+	failures = 1
+	resp, _ = reqt.New("/").Method(POST).Do()
+	if resp.StatusCode != 503 { t.Errorf("Idempotent Mismatch: got %d, expected 503", resp.StatusCode) }
+	failures = 1
+	resp, _ = reqt.New("/").Method(POST).IdempotencyKey("k").Do()
+	if resp.StatusCode != 200 { t.Errorf("Idempotency Key Mismatch: got %d, expected 200", resp.StatusCode) }
+
+	// request overrides verb default
+	failures = 1
+	resp, _ = reqt.New("/").Method(POST).Retry(RetryPolicy{MaxAttempts: 1}).Do()
+	if resp.StatusCode != 503 { t.Errorf("Override Mismatch: got %d, expected 503", resp.StatusCode) }
+
+	// GET has no default
+	failures = 1
+	resp, _ = reqt.New("/").RateGroup("slow").Do()
+	if resp.StatusCode != 503 { t.Errorf("Default Mismatch: got %d, expected 503", resp.StatusCode) }
+
+	_, err = reqt.New("/").RateGroup("missing").Do()
+	if err == nil { t.Errorf("Group Mismatch: unknown rate group accepted") }
+
+	// a canceled context stops retries
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10 * time.Millisecond, cancel)
+	failures = 1
+	_, err = reqt.New("/").Context(ctx).Retry(RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}).Do()
+	if err != context.Canceled { t.Errorf("Context Mismatch: got %v, expected context.Canceled", err) }
+}
+
+func TestTimeout(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		<- req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithVerbDefaults(GET, RequestDefaults{Timeout: 10 * time.Millisecond}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	_, err := reqt.New("/").Do()
+	if err != context.DeadlineExceeded { t.Errorf("Timeout Mismatch: got %v, expected context.DeadlineExceeded", err) }
+}
+
+func TestStallTimeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/headers" {
+			<- r.Context().Done()
+			return
+		}
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<- r.Context().Done()
+	}))
+	defer server.Close()
+
+	reqt := New(server.URL, nil, nil, nil, "test", WithStallTimeouts(50 * time.Millisecond, 50 * time.Millisecond))
+
+	_, err := reqt.New("/headers").Do()
+	if stall, ok := err.(*StallError); !ok || stall.Phase != StallHeaders { t.Errorf("Header Stall Mismatch: got %v", err) }
+
+	resp, err := reqt.New("/body").Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if stall, ok := err.(*StallError); !ok || stall.Phase != StallBody || string(body) != "partial" { t.Errorf("Body Stall Mismatch: got %q, %v", body, err) }
+
+	// a request can lift the limit
+	resp, err = reqt.New("/body").IdleTimeout(0).Timeout(200 * time.Millisecond).Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	_, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if _, ok := err.(*StallError); ok || err == nil { t.Errorf("Override Mismatch: got %v", err) }
+}
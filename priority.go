@@ -0,0 +1,159 @@
+package reqtify
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// a PriorityQueue decides which waiting request gets each tick of a rate
+// limiter, so that when the limiter is the bottleneck, interactive requests
+// can jump ahead of background traffic. Higher priorities go first, and
+// requests of equal priority go in the order they started waiting.
+//
+//	api := reqtify.New(root, time.NewTicker(time.Second), nil, nil, "bot", reqtify.WithPriorityQueue())
+//	api.New("/refresh").Do()                   // priority 0
+//	api.New("/user/me").Priority(10).Do()      // sent at the next tick
+type PriorityQueue struct {
+	lock   sync.Mutex
+	queues map[*time.Ticker]*limiterQueue
+}
+
+// makes requests wait for the rate limiter in priority order, rather than
+// in whatever order the runtime happens to wake them.
+func WithPriorityQueue() Option {
+	return func(r *ReqtifierImpl) {
+		r.Queue = &PriorityQueue{}
+	}
+}
+
+// sets this request's priority in the Reqtifier's PriorityQueue, if it has
+// one. The default is 0, and negative priorities are allowed.
+func (this *RequestImpl) Priority(n int) (Request) {
+	this.QueuePriority = n
+	return this
+}
+
+func (this *PriorityQueue) queue(limiter *time.Ticker) (*limiterQueue) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.queues == nil { this.queues = make(map[*time.Ticker]*limiterQueue) }
+	q, ok := this.queues[limiter]
+	if !ok {
+		q = &limiterQueue{limiter: limiter}
+		this.queues[limiter] = q
+	}
+	return q
+}
+
+// waits for the next tick of limiter which is due to a request of this
+// priority.
+func (this *PriorityQueue) wait(ctx context.Context, limiter *time.Ticker, priority int) (error) {
+	return this.queue(limiter).wait(ctx, priority)
+}
+
+// the waiters on a single rate limiter. While there are any, a goroutine
+// receives its ticks and hands each to the first of them.
+type limiterQueue struct {
+	limiter *time.Ticker
+
+	lock    sync.Mutex
+	waiters waiterHeap
+	seq     uint64
+	running bool
+	banked  bool // a tick was received with no one waiting for it.
+}
+
+type queueWaiter struct {
+	priority int
+	seq      uint64
+	index    int // in the heap, or -1 once the waiter has been given a tick.
+	ready    chan struct{}
+}
+
+func (this *limiterQueue) wait(ctx context.Context, priority int) (error) {
+	this.lock.Lock()
+	if this.banked {
+		this.banked = false
+		this.lock.Unlock()
+		return nil
+	}
+	this.seq++
+	w := &queueWaiter{priority: priority, seq: this.seq, ready: make(chan struct{})}
+	heap.Push(&this.waiters, w)
+	if !this.running {
+		this.running = true
+		go this.dispatch()
+	}
+	this.lock.Unlock()
+
+	select {
+	case <- w.ready:
+		return nil
+	case <- ctx.Done():
+		this.lock.Lock()
+		defer this.lock.Unlock()
+		if w.index >= 0 {
+			heap.Remove(&this.waiters, w.index)
+		} else {
+			// it was given a tick as it gave up, so pass it on
+			this.grant()
+		}
+		return ctx.Err()
+	}
+}
+
+// gives a tick to the first waiter, or keeps it for the next one.
+func (this *limiterQueue) grant() {
+	if len(this.waiters) == 0 {
+		this.banked = true
+		return
+	}
+	w := heap.Pop(&this.waiters).(*queueWaiter)
+	close(w.ready)
+}
+
+func (this *limiterQueue) dispatch() {
+	for {
+		<- this.limiter.C
+		this.lock.Lock()
+		this.grant()
+		if len(this.waiters) == 0 {
+			this.running = false
+			this.lock.Unlock()
+			return
+		}
+		this.lock.Unlock()
+	}
+}
+
+type waiterHeap []*queueWaiter
+
+func (this waiterHeap) Len() (int) { return len(this) }
+
+func (this waiterHeap) Less(i, j int) (bool) {
+	if this[i].priority != this[j].priority { return this[i].priority > this[j].priority }
+	return this[i].seq < this[j].seq
+}
+
+func (this waiterHeap) Swap(i, j int) {
+	this[i], this[j] = this[j], this[i]
+	this[i].index = i
+	this[j].index = j
+}
+
+func (this *waiterHeap) Push(x interface{}) {
+	w := x.(*queueWaiter)
+	w.index = len(*this)
+	*this = append(*this, w)
+}
+
+func (this *waiterHeap) Pop() (interface{}) {
+	old := *this
+	w := old[len(old) - 1]
+	old[len(old) - 1] = nil
+	w.index = -1
+	*this = old[:len(old) - 1]
+	return w
+}
@@ -0,0 +1,54 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	var lock sync.Mutex
+	var order []string
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		lock.Lock()
+		order = append(order, req.URL.Path)
+		lock.Unlock()
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	ticker := time.NewTicker(40 * time.Millisecond)
+	defer ticker.Stop()
+	reqt := New("https://this.is.a.test", ticker, &http.Client{}, nil, "test", WithPriorityQueue())
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	start := func(path string, priority int, ctx context.Context) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := reqt.New(path).Priority(priority).Context(ctx).Do()
+			if err != nil && !errors.Is(err, context.Canceled) { t.Errorf("Request Failure: %s", err.Error()) }
+		}()
+		time.Sleep(2 * time.Millisecond)
+	}
+	start("/low1", 0, context.Background())
+	start("/low2", 0, context.Background())
+	start("/gone", 10, ctx)
+	start("/background", -1, context.Background())
+	start("/high", 5, context.Background())
+	start("/low3", 0, context.Background())
+	// the most urgent request gives up before the first tick
+	cancel()
+	wg.Wait()
+
+	if fmt.Sprint(order) != "[/high /low1 /low2 /low3 /background]" { t.Errorf("Order Mismatch: got %v", order) }
+}
@@ -0,0 +1,87 @@
+package reqtify
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// what Probe learned about a remote resource without downloading it.
+type ProbeResult struct {
+	StatusCode   int
+	Size         int64     // -1 if the server didn't say.
+	ContentType  string
+	LastModified time.Time // zero if the server didn't say.
+	ETag         string
+	AcceptRanges bool      // the server said it serves byte ranges, so downloads can be resumed.
+}
+
+// sends the request as a HEAD to find out the size, type, and validators of
+// what it points at. Servers which reject HEAD with 403, 405 or 501 are sent
+// a GET for its first byte instead, which is all that's downloaded. The
+// request's unmarshallers and expected statuses are ignored, and a status
+// of 400 or above is returned as a *ResponseError.
+func (this *RequestImpl) Probe() (*ProbeResult, error) {
+	return ProbeRequest(this)
+}
+
+// probes req as its Probe method does, leaving req itself as it was. This is
+// how Probe is implemented, and is exported for Request implementations
+// outside this package. Those which don't embed RequestImpl are sent with
+// their unmarshallers and expected statuses intact.
+func ProbeRequest(req Request) (*ProbeResult, error) {
+	resp, err := probeClone(req).Method(HEAD).Do()
+	if err == nil && headRejected(resp.StatusCode) {
+		if resp.Body != nil { resp.Body.Close() }
+		resp, err = probeClone(req).Method(GET).Header("Range", "bytes=0-0").Do()
+		if err == nil && resp.Body != nil { resp.Body.Close() }
+	}
+	if err != nil { return nil, err }
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		return nil, &ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+
+	result := &ProbeResult{
+		StatusCode: resp.StatusCode,
+		Size: -1,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag: resp.Header.Get("ETag"),
+		AcceptRanges: strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes"),
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil { result.LastModified = t }
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		// the whole size follows the slash: "bytes 0-0/1234", or "bytes */0" for an empty file
+		result.AcceptRanges = true
+		if i := strings.LastIndexByte(resp.Header.Get("Content-Range"), '/'); i != -1 {
+			if size, err := strconv.ParseInt(resp.Header.Get("Content-Range")[i + 1:], 10, 64); err == nil { result.Size = size }
+		}
+	default:
+		if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil { result.Size = size }
+	}
+	return result, nil
+}
+
+// returns a copy of req which doesn't decode or check its response, if it's
+// built on RequestImpl. Other implementations are probed as they are, except
+// that no request has its response decompressed, which would discard the
+// Content-Length and Content-Range describing the encoded content.
+func probeClone(req Request) (Request) {
+	c := req.Clone()
+	if s, ok := c.(interface{ stripResponseHandling() }); ok { s.stripResponseHandling() }
+	return c.RawEncoding()
+}
+
+func (this *RequestImpl) stripResponseHandling() {
+	this.Response = nil
+	this.StatusResponse = nil
+	this.Validators = nil
+	this.ExpectedStatus = nil
+	this.DownloadWriter = nil
+}
+
+func headRejected(status int) (bool) {
+	return status == http.StatusForbidden || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented
+}
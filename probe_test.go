@@ -0,0 +1,108 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method + " " + r.Header.Get("Range"))
+		if r.URL.Path == "/missing" { http.NotFound(w, r); return }
+		if r.URL.Path == "/nohead" && r.Method == "HEAD" { w.WriteHeader(http.StatusMethodNotAllowed); return }
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "video/mp4")
+		http.ServeContent(w, r, "", modified, strings.NewReader(strings.Repeat("x", 1234)))
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	for _, path := range []string{"/file", "/nohead"} {
+		methods = nil
+		var ignored map[string]interface{}
+		result, err := reqt.New(path).Into(FromJSON(&ignored)).ExpectStatus(204).Probe()
+		if err != nil { t.Fatalf("%s: Probe Failure: %s", path, err.Error()) }
+		if result.Size != 1234 { t.Errorf("%s: Size Mismatch: got %d, expected 1234", path, result.Size) }
+		if result.ContentType != "video/mp4" { t.Errorf("%s: Content-Type Mismatch: got %q", path, result.ContentType) }
+		if result.ETag != `"v1"` { t.Errorf("%s: ETag Mismatch: got %q", path, result.ETag) }
+		if !result.LastModified.Equal(modified) { t.Errorf("%s: Last-Modified Mismatch: got %s", path, result.LastModified) }
+		if !result.AcceptRanges { t.Errorf("%s: Accept-Ranges Mismatch: got false", path) }
+		if path == "/nohead" && (len(methods) != 2 || methods[1] != "GET bytes=0-0") { t.Errorf("Fallback Mismatch: got %q", methods) }
+		if path == "/file" && len(methods) != 1 { t.Errorf("HEAD Mismatch: got %q", methods) }
+	}
+
+	_, err := reqt.New("/missing").Probe()
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 404 { t.Errorf("Error Mismatch: got %v, expected a 404", err) }
+}
+
+func TestProbeCompressed(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method + " " + r.Header.Get("Range"))
+		w.Header().Set("Content-Encoding", "gzip")
+		if r.URL.Path == "/nohead" && r.Method == "HEAD" { w.WriteHeader(http.StatusMethodNotAllowed); return }
+		if r.Method == "HEAD" {
+			w.Header().Set("Content-Length", "1234")
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-0/1234")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte{0x1f})
+	}))
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test", WithAcceptEncoding("gzip"))
+
+	for _, path := range []string{"/file", "/nohead"} {
+		methods = nil
+		result, err := reqt.New(path).Probe()
+		if err != nil { t.Fatalf("%s: Probe Failure: %s", path, err.Error()) }
+		if result.Size != 1234 { t.Errorf("%s: Size Mismatch: got %d, expected 1234", path, result.Size) }
+		if path == "/nohead" && (len(methods) != 2 || methods[1] != "GET bytes=0-0") { t.Errorf("Fallback Mismatch: got %q", methods) }
+	}
+}
+
+// a Request implementation which doesn't embed RequestImpl.
+type wrappedRequest struct {
+	Request
+}
+
+func (this wrappedRequest) Clone() (Request) {
+	return wrappedRequest{this.Request.Clone()}
+}
+
+func TestProbeRequest(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var closed []string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		method := req.Method
+		resp := &http.Response{StatusCode: 206, Header: http.Header{"Content-Range": {"bytes 0-0/10"}}}
+		if method == "HEAD" { resp.StatusCode = 405 }
+		resp.Body = &closeRecorder{Reader: strings.NewReader(""), close: func() { closed = append(closed, method) }}
+		return resp, nil
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	result, err := ProbeRequest(wrappedRequest{reqt.New("/file")})
+	if err != nil { t.Fatalf("Probe Failure: %s", err.Error()) }
+	if result.Size != 10 { t.Errorf("Size Mismatch: got %d, expected 10", result.Size) }
+	if len(closed) != 2 || closed[0] != "HEAD" || closed[1] != "GET" { t.Errorf("Close Mismatch: got %q, expected both responses closed", closed) }
+}
+
+type closeRecorder struct {
+	*strings.Reader
+	close func()
+}
+
+func (this *closeRecorder) Close() (error) {
+	this.close()
+	return nil
+}
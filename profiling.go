@@ -0,0 +1,61 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+/*
+   In a large bot talking to many APIs, CPU profiles and execution traces
+   usually just show time spent somewhere in net/http. With profiling enabled,
+   the goroutine performing each request is tagged with pprof labels:
+
+	reqtify   the Reqtifier's name (see WithName)
+	endpoint  the request's path template (see PathTemplate), or its path
+	method    the request's verb
+
+   so profiles can be broken down per integration, for example with
+   `go tool pprof -tagfocus reqtify=mastodon`. Optionally, each request is also
+   recorded as a task in execution traces.
+*/
+
+// names the Reqtifier, for profiling and anything else which needs to tell
+// several Reqtifiers apart.
+func WithName(name string) Option {
+	return func(r *ReqtifierImpl) {
+		r.Name = name
+	}
+}
+
+// labels goroutines performing requests for CPU and goroutine profiles, and
+// if traceRegions is set, records each request as a task in execution traces.
+func WithProfiling(traceRegions bool) Option {
+	return func(r *ReqtifierImpl) {
+		r.ProfileLabels = true
+		r.TraceRegions = traceRegions
+	}
+}
+
+func (this *ReqtifierImpl) profiled(req *RequestImpl, do func(*RequestImpl) (*http.Response, error)) (resp *http.Response, err error) {
+	ctx := req.context()
+	if this.TraceRegions && trace.IsEnabled() {
+		var task *trace.Task
+		ctx, task = trace.NewTask(ctx, "reqtify " + string(req.Verb) + " " + req.pathTemplate())
+		defer task.End()
+		if this.Name != "" {
+			trace.Log(ctx, "reqtify", this.Name)
+		}
+	}
+
+	labels := pprof.Labels("reqtify", this.Name, "endpoint", req.pathTemplate(), "method", string(req.Verb))
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		// so middleware, and goroutines the transport starts, see the labels too
+		saved := req.RequestContext
+		req.RequestContext = ctx
+		resp, err = do(req)
+		req.RequestContext = saved
+	})
+	return resp, err
+}
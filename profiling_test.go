@@ -0,0 +1,42 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"runtime/pprof"
+	"strings"
+)
+
+func TestProfilingLabels(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	labels := map[string]string{}
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		for _, key := range []string{"reqtify", "endpoint", "method"} {
+			labels[key], _ = pprof.Label(req.Context(), key)
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithName("example"), WithProfiling(true))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/posts").Method(POST).Do()
+	if labels["reqtify"] != "example" || labels["endpoint"] != "/posts" || labels["method"] != "POST" { t.Errorf("Label Mismatch: got %v", labels) }
+}
+
+func TestProfilingLabelsTemplate(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var endpoint string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		endpoint, _ = pprof.Label(req.Context(), "endpoint")
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithProfiling(false))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/posts/123").PathTemplate("/posts/{id}").Do()
+	if endpoint != "/posts/{id}" { t.Errorf("Endpoint Mismatch: got %q, expected \"/posts/{id}\"", endpoint) }
+}
@@ -0,0 +1,62 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+)
+
+/*
+   So that downstream services can correlate a bot's traffic with whatever
+   caused it, headers like traceparent and X-Request-ID can be attached to
+   the context once, for example copied from the request an HTTP handler is
+   serving:
+
+	ctx = reqtify.ContextWithIncomingHeaders(ctx, r.Header)
+
+   and every request made with that context (see RequestImpl.Context) sends
+   them. Headers the request already sets itself aren't overridden, and a
+   Propagator (see WithTracing) replaces trace context with its own span's.
+*/
+
+// the headers ContextWithIncomingHeaders copies if it isn't told which.
+var DefaultPropagatedHeaders = []string{"traceparent", "tracestate", "baggage", "X-Request-ID", "X-Correlation-ID"}
+
+type propagatedHeadersKey struct{}
+
+// returns a copy of ctx carrying headers to send with every request made
+// with it, in addition to any it already carries.
+func ContextWithHeaders(ctx context.Context, header http.Header) (context.Context) {
+	merged := HeadersFromContext(ctx)
+	for k, values := range header {
+		merged[http.CanonicalHeaderKey(k)] = append([]string(nil), values...)
+	}
+	return context.WithValue(ctx, propagatedHeadersKey{}, merged)
+}
+
+// returns a copy of ctx carrying the headers named by names, or
+// DefaultPropagatedHeaders if there are none, which incoming has.
+func ContextWithIncomingHeaders(ctx context.Context, incoming http.Header, names ...string) (context.Context) {
+	if len(names) == 0 { names = DefaultPropagatedHeaders }
+	header := make(http.Header)
+	for _, name := range names {
+		if values := incoming.Values(name); len(values) != 0 {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return ContextWithHeaders(ctx, header)
+}
+
+// returns a copy of the headers attached to ctx. It's empty if there are none.
+func HeadersFromContext(ctx context.Context) (http.Header) {
+	header, _ := ctx.Value(propagatedHeadersKey{}).(http.Header)
+	if header == nil { return make(http.Header) }
+	return header.Clone()
+}
+
+func applyPropagatedHeaders(ctx context.Context, r *http.Request) {
+	header, _ := ctx.Value(propagatedHeadersKey{}).(http.Header)
+	for k, values := range header {
+		if len(r.Header.Values(k)) != 0 { continue }
+		r.Header[k] = append([]string(nil), values...)
+	}
+}
@@ -0,0 +1,52 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestHeaderPropagation(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var last *http.Request
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		last = req
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	incoming := make(http.Header)
+	incoming.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	incoming.Set("X-Request-Id", "abc")
+	incoming.Set("Cookie", "session=1")
+	ctx := ContextWithIncomingHeaders(context.Background(), incoming)
+	ctx = ContextWithHeaders(ctx, http.Header{"x-tenant": {"blue"}})
+
+	reqt.New("/a").Context(ctx).Do()
+	if last.Header.Get("traceparent") != incoming.Get("traceparent") { t.Errorf("Traceparent Mismatch: got %q", last.Header.Get("traceparent")) }
+	if last.Header.Get("X-Request-ID") != "abc" { t.Errorf("Request ID Mismatch: got %q", last.Header.Get("X-Request-ID")) }
+	if last.Header.Get("X-Tenant") != "blue" { t.Errorf("Tenant Mismatch: got %q", last.Header.Get("X-Tenant")) }
+	if last.Header.Get("Cookie") != "" { t.Errorf("Cookie Mismatch: got %q", last.Header.Get("Cookie")) }
+
+	// explicit values win
+	reqt.New("/a").Header("X-Request-ID", "mine").Context(ctx).Do()
+	if last.Header.Get("X-Request-ID") != "mine" { t.Errorf("Override Mismatch: got %q", last.Header.Get("X-Request-ID")) }
+
+	// and copies are independent
+	h := HeadersFromContext(ctx)
+	h.Set("X-Request-ID", "changed")
+	if HeadersFromContext(ctx).Get("X-Request-ID") != "abc" { t.Errorf("Copy Mismatch: got %q", HeadersFromContext(ctx).Get("X-Request-ID")) }
+
+	// a tracing propagator replaces trace context with its own span's
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithTracing(&testTracer{}, PropagatorFunc(func(ctx context.Context, header http.Header) {
+		header.Set("traceparent", "child")
+	})))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	reqt.New("/a").Context(ctx).Do()
+	if last.Header.Get("traceparent") != "child" || last.Header.Get("X-Request-ID") != "abc" { t.Errorf("Tracing Mismatch: got %v", last.Header) }
+}
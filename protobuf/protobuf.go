@@ -0,0 +1,46 @@
+// Package protobuf decodes responses from, and encodes request bodies in, the
+// protocol buffers wire format. It's kept apart from reqtify so that programs
+// which don't use protocol buffers don't build google.golang.org/protobuf:
+//
+//	var reply pb.EchoReply
+//	protobuf.Body(api.New("/twirp/Echo").Method(reqtify.POST), &pb.EchoRequest{Text: "hi"}).
+//		Into(protobuf.Into(&reply)).Do()
+package protobuf
+
+import (
+	"bytes"
+
+	"github.com/thewug/reqtify"
+	"google.golang.org/protobuf/proto"
+)
+
+const ContentType = "application/x-protobuf"
+
+// decodes a response in the protobuf wire format.
+type Unmarshaller struct {
+	into proto.Message
+}
+
+func (this Unmarshaller) Unmarshal(body []byte) (error) {
+	return proto.Unmarshal(body, this.into)
+}
+
+func (this Unmarshaller) MediaTypes() ([]string) {
+	return []string{ContentType, "application/protobuf"}
+}
+
+func (this Unmarshaller) Targets() ([]interface{}) {
+	return []interface{}{this.into}
+}
+
+// returns an unmarshaller which decodes a protobuf response into into.
+func Into(into proto.Message) (reqtify.ResponseUnmarshaller) {
+	return Unmarshaller{into: into}
+}
+
+// marshals m in the protobuf wire format and uses it as req's body.
+func Body(req reqtify.Request, m proto.Message) (reqtify.Request) {
+	data, err := proto.Marshal(m)
+	if err != nil { return reqtify.FailBuild(req, err) }
+	return req.Body(bytes.NewReader(data), ContentType)
+}
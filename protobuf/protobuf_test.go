@@ -0,0 +1,44 @@
+package protobuf
+
+import (
+	"github.com/thewug/reqtify"
+	"github.com/thewug/reqtify/test"
+
+	"testing"
+	"bytes"
+	"net/http"
+	"io/ioutil"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type validatedString struct {
+	*wrapperspb.StringValue
+	checked *bool
+}
+
+func (this validatedString) Validate() (error) {
+	*this.checked = true
+	return nil
+}
+
+func TestProtobuf(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var contentType, accept string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		contentType, accept = req.Header.Get("Content-Type"), req.Header.Get("Accept")
+		body, _ := ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+	})
+
+	reqt := reqtify.New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*reqtify.ReqtifierImpl).HttpClient = &http_mock_client
+
+	var checked bool
+	out := validatedString{&wrapperspb.StringValue{}, &checked}
+	_, err := Body(reqt.New("/twirp/Echo").Method(reqtify.POST), wrapperspb.String("hello")).Into(Into(out)).Do()
+	if err != nil { t.Fatalf("Proto Failure: %s", err.Error()) }
+	if contentType != ContentType || accept != "application/x-protobuf, application/protobuf" { t.Errorf("Content Type Mismatch: got %q, accepting %q", contentType, accept) }
+	if out.GetValue() != "hello" { t.Errorf("Proto Mismatch: got %q", out.GetValue()) }
+	if !checked { t.Errorf("Validate Mismatch: decoded value wasn't validated") }
+}
@@ -0,0 +1,174 @@
+package reqtify
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// a QuietHours window pauses or slows requests during certain hours of
+// certain days, for APIs with maintenance windows or scraping agreements
+// which only allow heavy use off-peak. Requests marked Essential ignore it.
+//
+//	// nothing but essential requests during weekday business hours
+//	WithQuietHours(MustParseQuietHours("Mon-Fri 09:00-17:00"))
+//
+//	// background refreshes at most once a minute overnight
+//	q := MustParseQuietHours("22:00-06:00")
+//	q.Groups, q.Interval = []string{"refresh"}, time.Minute
+//	WithQuietHours(q)
+type QuietHours struct {
+	Days       []time.Weekday // the days on which the window starts, or every day if empty.
+	Start, End time.Duration  // since midnight. If End is before Start, the window ends the next day.
+	Location  *time.Location  // the time zone the window is in. Defaults to time.Local.
+
+	Groups     []string       // the rate groups the window applies to, or all requests if empty.
+	PathPrefix string         // the paths the window applies to, relative to the root.
+
+	// if zero, requests wait for the window to end. Otherwise they are sent,
+	// but no more often than this.
+	Interval   time.Duration
+
+	lock       sync.Mutex
+	next       time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parses a window like "Mon-Fri 22:00-06:00", "Sat,Sun 00:00-24:00" or
+// "01:30-04:00". Day ranges may wrap around the end of the week.
+func ParseQuietHours(spec string) (*QuietHours, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 { return nil, fmt.Errorf("bad quiet hours %q", spec) }
+
+	q := &QuietHours{}
+	if len(fields) == 2 {
+		for _, part := range strings.Split(fields[0], ",") {
+			first, last, ranged := strings.Cut(strings.ToLower(part), "-")
+			from, ok := weekdays[first]
+			if !ok { return nil, fmt.Errorf("bad day %q in quiet hours %q", first, spec) }
+			to := from
+			if ranged {
+				if to, ok = weekdays[last]; !ok { return nil, fmt.Errorf("bad day %q in quiet hours %q", last, spec) }
+			}
+			for d := from; ; d = (d + 1) % 7 {
+				q.Days = append(q.Days, d)
+				if d == to { break }
+			}
+		}
+	}
+
+	start, end, ok := strings.Cut(fields[len(fields) - 1], "-")
+	if !ok { return nil, fmt.Errorf("bad quiet hours %q", spec) }
+	var err error
+	if q.Start, err = parseClock(start); err != nil { return nil, fmt.Errorf("bad quiet hours %q: %w", spec, err) }
+	if q.End, err = parseClock(end); err != nil { return nil, fmt.Errorf("bad quiet hours %q: %w", spec, err) }
+	return q, nil
+}
+
+// like ParseQuietHours, but panics if spec is invalid.
+func MustParseQuietHours(spec string) (*QuietHours) {
+	q, err := ParseQuietHours(spec)
+	if err != nil { panic(err) }
+	return q
+}
+
+// parses a time of day like "09:30", or "24:00" for the end of the day.
+func parseClock(s string) (time.Duration, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours * 60 + minutes > 24 * 60 {
+		return 0, fmt.Errorf("bad time of day %q", s)
+	}
+	return time.Duration(hours) * time.Hour + time.Duration(minutes) * time.Minute, nil
+}
+
+// pauses or slows requests during the given windows. See QuietHours.
+func WithQuietHours(windows ...*QuietHours) Option {
+	return func(r *ReqtifierImpl) {
+		r.QuietHours = append(r.QuietHours, windows...)
+	}
+}
+
+// exempts this request from quiet hours.
+func (this *RequestImpl) Essential() (Request) {
+	this.IsEssential = true
+	return this
+}
+
+func (this *QuietHours) matches(req *RequestImpl, group string) (bool) {
+	if !strings.HasPrefix(req.URLPath, this.PathPrefix) { return false }
+	if len(this.Groups) == 0 { return true }
+	for _, g := range this.Groups {
+		if g == group { return true }
+	}
+	return false
+}
+
+func (this *QuietHours) startsOn(d time.Weekday) (bool) {
+	if len(this.Days) == 0 { return true }
+	for _, day := range this.Days {
+		if day == d { return true }
+	}
+	return false
+}
+
+// reports whether the window is in effect at t, and if so, when it ends.
+func (this *QuietHours) active(t time.Time) (bool, time.Time) {
+	loc := this.Location
+	if loc == nil { loc = time.Local }
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	since := t.Sub(midnight)
+
+	if this.Start <= this.End {
+		if this.startsOn(t.Weekday()) && since >= this.Start && since < this.End {
+			return true, midnight.Add(this.End)
+		}
+		return false, time.Time{}
+	}
+
+	// the window wraps past midnight, so it may have started today or yesterday
+	if this.startsOn(t.Weekday()) && since >= this.Start {
+		return true, midnight.AddDate(0, 0, 1).Add(this.End)
+	}
+	if this.startsOn((t.Weekday() + 6) % 7) && since < this.End {
+		return true, midnight.Add(this.End)
+	}
+	return false, time.Time{}
+}
+
+// waits until the quiet hours applying to a request allow it to be sent.
+func (this *ReqtifierImpl) waitQuietHours(ctx context.Context, req *RequestImpl, group string) (error) {
+	if req.IsEssential { return nil }
+	for _, q := range this.QuietHours {
+		if !q.matches(req, group) { continue }
+		for {
+			active, end := q.active(this.clock().Now())
+			if !active { break }
+
+			if q.Interval == 0 {
+				if err := clockSleep(ctx, this.clock(), end.Sub(this.clock().Now())); err != nil { return err }
+				continue
+			}
+
+			// reserve the next slot, so concurrent requests are spaced out too
+			q.lock.Lock()
+			now := this.clock().Now()
+			if q.next.Before(now) { q.next = now }
+			at := q.next
+			q.next = q.next.Add(q.Interval)
+			q.lock.Unlock()
+			if err := clockSleep(ctx, this.clock(), at.Sub(now)); err != nil { return err }
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"net/http"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("Fri-Mon 22:00-06:30")
+	if err != nil { t.Fatalf("Parse Failure: %s", err.Error()) }
+	if len(q.Days) != 4 || q.Days[0] != time.Friday || q.Days[3] != time.Monday || q.Start != 22 * time.Hour || q.End != 6 * time.Hour + 30 * time.Minute {
+		t.Errorf("Parse Mismatch: got %+v", q)
+	}
+	if q, err = ParseQuietHours("00:00-24:00"); err != nil || q.Days != nil || q.End != 24 * time.Hour { t.Errorf("Parse Mismatch: got %+v, %v", q, err) }
+	for _, spec := range []string{"", "Mon", "Moo 01:00-02:00", "25:00-26:00", "01:00", "Mon 01:60-02:00", "a b c"} {
+		if _, err := ParseQuietHours(spec); err == nil { t.Errorf("Parse Mismatch: %q accepted", spec) }
+	}
+
+	q = MustParseQuietHours("Fri 22:00-06:00")
+	q.Location = time.UTC
+	at := func(day, hour int) (time.Time) { return time.Date(2024, 1, day, hour, 0, 0, 0, time.UTC) } // 2024-01-05 is a friday
+	cases := []struct {
+		t      time.Time
+		active bool
+		end    time.Time
+	}{
+		{at(5, 21), false, time.Time{}},
+		{at(5, 23), true, at(6, 6)},
+		{at(6, 5), true, at(6, 6)},
+		{at(6, 7), false, time.Time{}},
+		{at(6, 23), false, time.Time{}},
+		{at(4, 23), false, time.Time{}},
+	}
+	for _, c := range cases {
+		if active, end := q.active(c.t); active != c.active || !end.Equal(c.end) { t.Errorf("Window Mismatch: at %s got %t until %s", c.t, active, end) }
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	var sent []time.Time
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, time.Now())
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	pause := MustParseQuietHours("00:00-24:00")
+	pause.PathPrefix = "/scrape"
+	slow := MustParseQuietHours("00:00-24:00")
+	slow.Groups, slow.Interval = []string{"background"}, 50 * time.Millisecond
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithQuietHours(pause, slow), WithRateGroup("background", nil))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+	_, err := reqt.New("/scrape/page").Context(ctx).Do()
+	if err != context.DeadlineExceeded || len(sent) != 0 { t.Errorf("Pause Mismatch: got %v after %d requests", err, len(sent)) }
+
+	if _, err = reqt.New("/scrape/page").Essential().Do(); err != nil || len(sent) != 1 { t.Errorf("Essential Mismatch: got %v after %d requests", err, len(sent)) }
+	if _, err = reqt.New("/api").Do(); err != nil || len(sent) != 2 { t.Errorf("Unmatched Mismatch: got %v after %d requests", err, len(sent)) }
+
+	sent = nil
+	for i := 0; i < 3; i++ {
+		if _, err = reqt.New("/api").RateGroup("background").Do(); err != nil { t.Errorf("Slow Failure: %s", err.Error()) }
+	}
+	if len(sent) != 3 || sent[2].Sub(sent[0]) < 95 * time.Millisecond { t.Errorf("Slow Mismatch: got %d requests in %s", len(sent), sent[len(sent) - 1].Sub(sent[0])) }
+}
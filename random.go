@@ -0,0 +1,57 @@
+package reqtify
+
+import (
+	"crypto/rand"
+	mathrand "math/rand"
+	"sync"
+)
+
+// a Random is a source of randomness safe for concurrent use. Everything
+// random a Reqtifier does, like choosing multipart boundaries, jittering
+// retry delays, and generating UUIDs, can be made to draw from one, so that
+// its behavior is reproducible under a fixed seed for debugging and fuzzing:
+//
+//	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithRandom(reqtify.NewRandom(42)))
+//
+// A nil *Random uses unpredictable randomness, from crypto/rand where that
+// matters, like UUIDs, and math/rand elsewhere.
+type Random struct {
+	lock sync.Mutex
+	rand *mathrand.Rand
+}
+
+// returns a Random which produces the same values each time for seed.
+func NewRandom(seed int64) (*Random) {
+	return &Random{rand: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// returns a number in [0, n).
+func (this *Random) Intn(n int) (int) {
+	if this == nil { return mathrand.Intn(n) }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.rand.Intn(n)
+}
+
+// returns a number in [0.0, 1.0).
+func (this *Random) Float64() (float64) {
+	if this == nil { return mathrand.Float64() }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.rand.Float64()
+}
+
+// fills b with random bytes.
+func (this *Random) Read(b []byte) (int, error) {
+	if this == nil { return rand.Read(b) }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.rand.Read(b)
+}
+
+// sets the source of randomness for the Reqtifier.
+func WithRandom(random *Random) Option {
+	return func(r *ReqtifierImpl) {
+		r.Random = random
+	}
+}
@@ -0,0 +1,31 @@
+package reqtify
+
+import (
+	"testing"
+	"io/ioutil"
+	"time"
+)
+
+func TestRandom(t *testing.T) {
+	run := func(seed int64) (string, string) {
+		reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithRandom(NewRandom(seed)))
+		body, _ := reqt.New("/upload").Method(POST).FormArg("a", "1").Multipart().GetBody()
+		data, _ := ioutil.ReadAll(body)
+		return string(data), reqt.(*ReqtifierImpl).NewID()
+	}
+	body1, id1 := run(42)
+	body2, id2 := run(42)
+	body3, id3 := run(43)
+	if body1 != body2 || id1 != id2 { t.Errorf("Seed Mismatch: got %q, %s and %q, %s", body1, id1, body2, id2) }
+	if body1 == body3 || id1 == id3 { t.Errorf("Seed Mismatch: different seeds gave %q, %s", body3, id3) }
+
+	policy := RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+	a, b := NewRandom(1), NewRandom(1)
+	for i := 1; i < 20; i++ {
+		d := policy.delay(1, nil, time.Now(), a)
+		if d != policy.delay(1, nil, time.Now(), b) { t.Errorf("Jitter Mismatch: got different delays under the same seed") }
+		if d < 500 * time.Millisecond || d > 1500 * time.Millisecond { t.Errorf("Jitter Mismatch: got %s, expected within 50%% of 1s", d) }
+	}
+	var unseeded *Random
+	if d := policy.delay(1, nil, time.Now(), unseeded); d < 500 * time.Millisecond || d > 1500 * time.Millisecond { t.Errorf("Jitter Mismatch: got %s", d) }
+}
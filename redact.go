@@ -0,0 +1,168 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// a Redactor masks sensitive values in a Reqtifier's diagnostic output, like
+// DebugPrint, logging and dumps, before anything is written:
+//
+//	api := reqtify.New(root, nil, nil, nil, "bot", reqtify.WithRedactor(&reqtify.Redactor{Params: []string{"api_key", "password"}}))
+//
+// Headers in DefaultRedactedHeaders are always masked. Parameters are masked
+// wherever they appear: in URL queries, url encoded form bodies, and JSON
+// bodies, as object keys at any depth. Names are matched without regard to
+// case. Passwords in URLs are masked too.
+type Redactor struct {
+	Headers []string // headers to mask, in addition to DefaultRedactedHeaders.
+	Params  []string // parameter names to mask, like "api_key".
+	Mask    string   // what values are replaced with. Defaults to "[redacted]".
+}
+
+// sets how the Reqtifier's diagnostic output is redacted. Without one, only
+// the DefaultRedactedHeaders and passwords in URLs are masked.
+func WithRedactor(redactor *Redactor) Option {
+	return func(r *ReqtifierImpl) {
+		r.Redactor = redactor
+	}
+}
+
+func (this *ReqtifierImpl) redactor() (*Redactor) {
+	if this == nil || this.Redactor == nil { return &Redactor{} }
+	return this.Redactor
+}
+
+// the Redactor of the Reqtifier sending r, for middleware.
+func requestRedactor(r *http.Request) (*Redactor) {
+	if req, ok := RequestFromContext(r.Context()); ok { return req.ReqClient.redactor() }
+	return &Redactor{}
+}
+
+func (this *Redactor) mask() (string) {
+	if this.Mask == "" { return "[redacted]" }
+	return this.Mask
+}
+
+func (this *Redactor) sensitiveHeader(name string) (bool) {
+	for _, h := range DefaultRedactedHeaders {
+		if strings.EqualFold(h, name) { return true }
+	}
+	for _, h := range this.Headers {
+		if strings.EqualFold(h, name) { return true }
+	}
+	return false
+}
+
+func (this *Redactor) sensitiveParam(name string) (bool) {
+	for _, p := range this.Params {
+		if strings.EqualFold(p, name) { return true }
+	}
+	return false
+}
+
+// returns value, or the mask if the header name is sensitive.
+func (this *Redactor) Header(name, value string) (string) {
+	if this.sensitiveHeader(name) { return this.mask() }
+	return value
+}
+
+// returns a copy of headers with sensitive ones masked.
+func (this *Redactor) HeaderMap(headers map[string]string) (map[string]string) {
+	redacted := make(map[string]string, len(headers))
+	for k, v := range headers {
+		redacted[k] = this.Header(k, v)
+	}
+	return redacted
+}
+
+// returns a copy of h with sensitive headers masked.
+func (this *Redactor) HTTPHeader(h http.Header) (http.Header) {
+	redacted := make(http.Header, len(h))
+	for k, values := range h {
+		for _, v := range values {
+			redacted[k] = append(redacted[k], this.Header(k, v))
+		}
+	}
+	return redacted
+}
+
+// returns a copy of values with sensitive parameters masked.
+func (this *Redactor) Values(values url.Values) (url.Values) {
+	redacted := make(url.Values, len(values))
+	for k, list := range values {
+		for _, v := range list {
+			if this.sensitiveParam(k) { v = this.mask() }
+			redacted[k] = append(redacted[k], v)
+		}
+	}
+	return redacted
+}
+
+// returns rawURL with its password and sensitive query parameters masked.
+// The query is only re-encoded if something in it is masked.
+func (this *Redactor) URL(rawURL string) (string) {
+	u, err := url.Parse(rawURL)
+	if err != nil { return rawURL }
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), this.mask())
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for k := range query {
+			if this.sensitiveParam(k) {
+				u.RawQuery = this.Values(query).Encode()
+				break
+			}
+		}
+	}
+	return u.String()
+}
+
+// returns body, of type contentType, with sensitive parameters masked, if
+// it's a url encoded form or JSON. Other bodies are returned as they are.
+func (this *Redactor) Body(contentType string, body []byte) ([]byte) {
+	if len(this.Params) == 0 { return body }
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediatype == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil { return body }
+		for k := range values {
+			if this.sensitiveParam(k) { return []byte(this.Values(values).Encode()) }
+		}
+	case mediatype == "application/json" || strings.HasSuffix(mediatype, "+json"):
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if d.Decode(&v) != nil { return body }
+		if !this.redactJSON(v) { return body }
+		if redacted, err := json.Marshal(v); err == nil { return redacted }
+	}
+	return body
+}
+
+// masks sensitive keys in a decoded JSON value, reporting whether any were.
+func (this *Redactor) redactJSON(v interface{}) (bool) {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if this.sensitiveParam(k) {
+				v[k] = this.mask()
+				changed = true
+			} else if this.redactJSON(child) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if this.redactJSON(child) { changed = true }
+		}
+	}
+	return changed
+}
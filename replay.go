@@ -0,0 +1,172 @@
+package reqtify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+/*
+   net/http sends a request body again when it follows a 307 or 308
+   redirect, and when it retries a request on a connection which turned out
+   to be dead, but only if it knows how: from http.Request.GetBody. It can
+   only work that out for itself for bodies held in memory. reqtify fills it
+   in for every body it can:
+
+	- bodies already held in memory, like forms, and bodies cached for
+	  retries, are simply read again.
+	- regular files are read again from the same offset, with ReadAt. The
+	  transport would close the file once it's sent it, so instead it's
+	  closed when the request is done.
+	- other streams aren't, unless the Reqtifier has a replay limit (see
+	  WithReplayLimit). Then they're recorded as they're sent, up to the
+	  limit, and the rest read on demand, if the stream hasn't been closed.
+	  Past the limit, or without one, GetBody returns a
+	  *BodyNotReplayableError, so that a redirect which needs the body
+	  fails rather than being returned as the response.
+
+   Bodies of requests which may be retried are cached before they're sent in
+   any case, so they don't need recording.
+*/
+
+// a reasonable replay limit, for WithReplayLimit.
+const DefaultReplayLimit = 1 << 20
+
+// keeps up to limit bytes of each streamed request body, so that it can be
+// sent again if it's redirected. Streams aren't kept by default, since that
+// costs memory and copying for every upload.
+func WithReplayLimit(limit int64) Option {
+	return func(r *ReqtifierImpl) {
+		r.ReplayLimit = limit
+	}
+}
+
+func (this *ReqtifierImpl) replayLimit() (int64) {
+	if this == nil || this.ReplayLimit < 0 { return 0 }
+	return this.ReplayLimit
+}
+
+// returned by http.Request.GetBody when a request body can't be sent again,
+// because it was too large to keep a copy of, couldn't be read again, or was
+// streamed without a replay limit.
+type BodyNotReplayableError struct {
+	Limit int64 // the replay limit the body exceeded, if it did, or 0 if there isn't one.
+	Err   error // why it couldn't be read again, if that's the reason.
+}
+
+func (this *BodyNotReplayableError) Error() (string) {
+	if this.Err != nil { return "request body can't be sent again: " + this.Err.Error() }
+	if this.Limit == 0 { return "request body can't be sent again: it was streamed, and there's no replay limit" }
+	return fmt.Sprintf("request body can't be sent again: it's larger than the replay limit of %d bytes", this.Limit)
+}
+
+func (this *BodyNotReplayableError) Unwrap() (error) {
+	return this.Err
+}
+
+// fills in r.GetBody, if net/http couldn't, for body, which r was built from.
+func (this *RequestImpl) setGetBody(r *http.Request, body io.Reader) {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody != nil { return }
+
+	if this.body != nil {
+		r.GetBody = func() (io.ReadCloser, error) {
+			body, _ := this.GetBody()
+			if this.CompressRequest { return gzipReader(body), nil }
+			return ioutil.NopCloser(body), nil
+		}
+		return
+	}
+
+	if f, ok := body.(*os.File); ok {
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if length, ok := remainingLength(f); ok && err == nil {
+			this.heldFiles = append(this.heldFiles, f)
+			r.Body = heldFile{f}
+			r.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(io.NewSectionReader(f, offset, length)), nil
+			}
+			return
+		}
+	}
+
+	limit := this.ReqClient.replayLimit()
+	if limit == 0 {
+		r.GetBody = func() (io.ReadCloser, error) { return nil, &BodyNotReplayableError{} }
+		return
+	}
+	recorder := &replayRecorder{reader: r.Body, limit: limit}
+	r.Body = recorder
+	r.GetBody = recorder.replay
+}
+
+// a file sent as a request body, which the transport mustn't close, since it
+// may be read again. It's closed by closeFiles instead. Everything else, like
+// the WriteTo which lets the transport use sendfile, is the file's own.
+type heldFile struct {
+	*os.File
+}
+
+func (this heldFile) Close() (error) {
+	return nil
+}
+
+// keeps a copy of the first limit bytes read from a body.
+type replayRecorder struct {
+	lock     sync.Mutex
+	reader   io.ReadCloser
+	limit    int64
+	buffer   bytes.Buffer
+	overflow bool
+	done     bool // the whole body has been read.
+	closed   bool
+}
+
+func (this *replayRecorder) Read(p []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.read(p)
+}
+
+func (this *replayRecorder) read(p []byte) (int, error) {
+	n, err := this.reader.Read(p)
+	if !this.overflow {
+		if int64(this.buffer.Len() + n) > this.limit {
+			this.overflow = true
+			this.buffer = bytes.Buffer{}
+		} else {
+			this.buffer.Write(p[:n])
+		}
+	}
+	if err == io.EOF { this.done = true }
+	return n, err
+}
+
+func (this *replayRecorder) Close() (error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed { return nil }
+	this.closed = true
+	return this.reader.Close()
+}
+
+// returns a fresh copy of the body, reading whatever hasn't been sent yet.
+func (this *replayRecorder) replay() (io.ReadCloser, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if !this.done && !this.overflow {
+		if this.closed { return nil, &BodyNotReplayableError{Err: os.ErrClosed} }
+		p := make([]byte, 32 * 1024)
+		for !this.done && !this.overflow {
+			if _, err := this.read(p); err != nil && err != io.EOF {
+				return nil, &BodyNotReplayableError{Err: err}
+			}
+		}
+	}
+	if this.overflow { return nil, &BodyNotReplayableError{Limit: this.limit} }
+	return ioutil.NopCloser(bytes.NewReader(this.buffer.Bytes())), nil
+}
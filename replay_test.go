@@ -0,0 +1,101 @@
+package reqtify
+
+import (
+	"testing"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func TestReplayOnRedirect(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" { body, _ = gzip.NewReader(r.Body) }
+		b, _ := ioutil.ReadAll(body)
+		bodies = append(bodies, string(b))
+		if r.URL.Path == "/old" {
+			w.Header().Set("Location", "/new")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+
+	// hides the type of a reader, so net/http can't replay it itself
+	stream := func(s string) io.Reader { return io.MultiReader(strings.NewReader(s)) }
+	reqt := New(server.URL, nil, nil, nil, "test", WithReplayLimit(DefaultReplayLimit))
+
+	bodies = nil
+	resp, err := reqt.New("/old").Method(POST).Body(stream("streamed"), "text/plain").Do()
+	if err != nil || resp.StatusCode != 200 { t.Fatalf("Stream Failure: %v, %v", resp, err) }
+	if len(bodies) != 2 || bodies[0] != "streamed" || bodies[1] != "streamed" { t.Errorf("Stream Mismatch: got %q", bodies) }
+
+	bodies = nil
+	resp, err = reqt.New("/old").Method(POST).Body(stream("compressed"), "text/plain").CompressBody().Do()
+	if err != nil || resp.StatusCode != 200 { t.Fatalf("Compressed Failure: %v, %v", resp, err) }
+	if len(bodies) != 2 || bodies[1] != "compressed" { t.Errorf("Compressed Mismatch: got %q", bodies) }
+
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := ioutil.WriteFile(path, []byte("skip:file contents"), 0600); err != nil { t.Fatal(err) }
+	f, err := os.Open(path)
+	if err != nil { t.Fatal(err) }
+	f.Seek(5, io.SeekStart)
+	bodies = nil
+	resp, err = reqt.New("/old").Method(PUT).Body(f, "text/plain").Do()
+	if err != nil || resp.StatusCode != 200 { t.Fatalf("File Failure: %v, %v", resp, err) }
+	if len(bodies) != 2 || bodies[0] != "file contents" || bodies[1] != "file contents" { t.Errorf("File Mismatch: got %q", bodies) }
+
+	// even once the file's been renamed
+	f, err = os.Open(path)
+	if err != nil { t.Fatal(err) }
+	f.Seek(5, io.SeekStart)
+	r, _ := reqt.New("/old").Method(PUT).Body(f, "text/plain").(*RequestImpl).HTTPRequest(context.Background())
+	if err := os.Rename(path, path + ".moved"); err != nil { t.Fatal(err) }
+	body, err := r.GetBody()
+	if err != nil { t.Fatalf("Renamed Failure: %s", err.Error()) }
+	if b, _ := ioutil.ReadAll(body); string(b) != "file contents" { t.Errorf("Renamed Mismatch: got %q", b) }
+	f.Close()
+
+	// streams aren't recorded unless asked for, so redirects which need them fail
+	unlimited := New(server.URL, nil, nil, nil, "test")
+	r, _ = unlimited.New("/old").Method(POST).Body(stream("streamed"), "text/plain").(*RequestImpl).HTTPRequest(context.Background())
+	if _, ok := r.Body.(*replayRecorder); ok { t.Errorf("Opt In Mismatch: stream recorded by default") }
+	bodies = nil
+	_, err = unlimited.New("/old").Method(POST).Body(stream("streamed"), "text/plain").Do()
+	var unlimitedErr *BodyNotReplayableError
+	if !errors.As(err, &unlimitedErr) || unlimitedErr.Limit != 0 || unlimitedErr.Err != nil { t.Errorf("Unlimited Mismatch: got %v", err) }
+	if len(bodies) != 1 { t.Errorf("Unlimited Mismatch: got %q", bodies) }
+
+	// bodies over the limit can't be replayed
+	small := New(server.URL, nil, nil, nil, "test", WithReplayLimit(4))
+	bodies = nil
+	_, err = small.New("/old").Method(POST).Body(stream("too long"), "text/plain").Do()
+	var replayErr *BodyNotReplayableError
+	if !errors.As(err, &replayErr) || replayErr.Limit != 4 { t.Errorf("Limit Mismatch: got %v", err) }
+	if len(bodies) != 1 { t.Errorf("Limit Mismatch: got %q", bodies) }
+}
+
+func TestReplayRecorder(t *testing.T) {
+	// bodies not yet read in full are read on demand
+	recorder := &replayRecorder{reader: ioutil.NopCloser(strings.NewReader("hello world")), limit: 100}
+	p := make([]byte, 5)
+	recorder.Read(p)
+	body, err := recorder.replay()
+	if err != nil { t.Fatalf("Replay Failure: %s", err.Error()) }
+	if b, _ := ioutil.ReadAll(body); string(b) != "hello world" { t.Errorf("Replay Mismatch: got %q", b) }
+	body, _ = recorder.replay()
+	if b, _ := ioutil.ReadAll(body); string(b) != "hello world" { t.Errorf("Second Replay Mismatch: got %q", b) }
+
+	// unless they've been closed
+	recorder = &replayRecorder{reader: ioutil.NopCloser(strings.NewReader("hello world")), limit: 100}
+	recorder.Read(p)
+	recorder.Close()
+	if _, err := recorder.replay(); !errors.Is(err, os.ErrClosed) { t.Errorf("Closed Mismatch: got %v", err) }
+}
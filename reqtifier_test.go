@@ -213,3 +213,243 @@ func TestReqtifierImplDo(t *testing.T) {
 		t.Logf("\nreq 1: %+v\nreq 2: %+v\n", req, req2)
 	}
 }
+
+func TestUserAgent(t *testing.T) {
+	ua := NewUserAgent("MyBot", "1.2").Contact("https://example.com/bot").Comment("a (test)").Product("reqtify", "")
+	expected := `MyBot/1.2 (+https://example.com/bot; a \(test\)) reqtify`
+	if ua.String() != expected { t.Errorf("Agent Mismatch: got %s, expected %s", ua.String(), expected) }
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "ignored", WithUserAgent(ua))
+	req := reqt.New("/").UserAgentSuffix("cleanup/2").(*RequestImpl)
+	if req.userAgent() != expected + " cleanup/2" { t.Errorf("Agent Mismatch: got %s, expected %s", req.userAgent(), expected + " cleanup/2") }
+
+	reqt = New("https://this.is.a.test", nil, nil, nil, "plain")
+	if agent := reqt.New("/").(*RequestImpl).userAgent(); agent != "plain" { t.Errorf("Agent Mismatch: got %s, expected plain", agent) }
+}
+
+func TestYAMLInto(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("apiVersion: v1\nitems:\n  - name: a\n  - name: b\n"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var out struct {
+		APIVersion string `yaml:"apiVersion"`
+		Items      []struct{ Name string `yaml:"name"` } `yaml:"items"`
+	}
+	_, err := reqt.New("/config").YAMLInto(&out).Do()
+	if err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+	if out.APIVersion != "v1" || len(out.Items) != 2 || out.Items[1].Name != "b" { t.Errorf("YAML Mismatch: got %+v", out) }
+}
+
+func TestTextAndBytesInto(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("pong"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	var raw []byte
+	resp, err := reqt.New("/ping").TextInto(&text).BytesInto(&raw).Do()
+	if err != nil { t.Fatalf("Capture Failure: %s", err.Error()) }
+	if text != "pong" || string(raw) != "pong" { t.Errorf("Capture Mismatch: got %q, %q", text, raw) }
+
+	// the captured bytes don't alias the body still readable from the response
+	body, _ := ioutil.ReadAll(resp.Body)
+	raw[0] = 'x'
+	if string(body) != "pong" { t.Errorf("Alias Mismatch: got %q", body) }
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("<html>not json</html>"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// a body which can't be decoded fails Do, but the response is still returned
+	var out map[string]interface{}
+	var text string
+	resp, err := reqt.New("/").JSONInto(&out).TextInto(&text).Do()
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) { t.Errorf("Error Mismatch: got %v, expected a json.SyntaxError", err) }
+	if resp == nil || resp.StatusCode != 200 { t.Errorf("Response Mismatch: got %v", resp) }
+
+	// the others still see the body
+	if text != "<html>not json</html>" { t.Errorf("Text Mismatch: got %q", text) }
+}
+
+func TestFromFunc(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("version=1.2.3"))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var version string
+	_, err := reqt.New("/version").Into(FromFunc(func(body []byte) error {
+		version = strings.TrimPrefix(string(body), "version=")
+		return nil
+	})).Do()
+	if err != nil || version != "1.2.3" { t.Errorf("Func Mismatch: got %q, %v", version, err) }
+
+	failure := errors.New("unparseable")
+	_, err = reqt.New("/version").Into(FromFunc(func([]byte) error { return failure })).Do()
+	if err != failure { t.Errorf("Error Mismatch: got %v", err) }
+}
+
+func TestHeaderInto(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("X-Next-Cursor", "abc")
+		header.Set("X-Ratelimit-Remaining", "41")
+		return &http.Response{StatusCode: 429, Header: header, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	cursor, missing := "", "stale"
+	var all http.Header
+	_, err := reqt.New("/items").HeaderInto("x-next-cursor", &cursor).HeaderInto("X-Request-Id", &missing).HeadersInto(&all).Do()
+	if err != nil || cursor != "abc" || missing != "" { t.Errorf("Header Mismatch: got %q, %q, %v", cursor, missing, err) }
+	if all.Get("X-Ratelimit-Remaining") != "41" { t.Errorf("Headers Mismatch: got %v", all) }
+}
+
+func TestCompletionHooks(t *testing.T) {
+	status := 200
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var calls []string
+	var failure error
+	req := reqt.New("/").ExpectStatus(200).
+		OnComplete(func(resp *http.Response, err error) { calls = append(calls, fmt.Sprint("first ", resp.StatusCode, " ", err != nil)) }).
+		OnError(func(err error) { failure = err }).
+		OnComplete(func(resp *http.Response, err error) { calls = append(calls, "second") })
+	resp, err := req.Do()
+	if err != nil || resp.StatusCode != 200 || failure != nil || fmt.Sprint(calls) != "[first 200 false second]" { t.Errorf("Success Mismatch: got %q, %v", calls, failure) }
+
+	status = 500
+	calls = nil
+	_, err = req.Do()
+	if err == nil || failure != err || fmt.Sprint(calls) != "[first 500 true second]" { t.Errorf("Failure Mismatch: got %q, %v", calls, failure) }
+
+	failure = nil
+	_, err = reqt.New("/").IntoForStatus("bad", FromText(new(string))).OnError(func(err error) { failure = err }).Do()
+	if err == nil || failure != err { t.Errorf("Build Mismatch: got %v", failure) }
+}
+
+func TestMockHttpClientRecording(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/fail" { return nil, errors.New("offline") }
+		var body []byte
+		if req.Body != nil { body, _ = ioutil.ReadAll(req.Body) }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("echo " + string(body)))}, nil
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/ignored").Do()
+	if http_mock_client.LastRequest() != nil { t.Errorf("Record Mismatch: got a request recorded before Record") }
+
+	http_mock_client.Record()
+	var text string
+	if _, err := reqt.New("/a").Method(POST).FormArg("x", "1").TextInto(&text).Do(); err != nil || text != "echo x=1" { t.Errorf("Response Mismatch: got %q, %v", text, err) }
+	reqt.New("/fail").Do()
+
+	recorded := http_mock_client.Recorded()
+	if len(recorded) != 2 { t.Fatalf("Recorded Mismatch: got %d, expected 2", len(recorded)) }
+	body, _ := ioutil.ReadAll(recorded[0].Request.Body)
+	respBody, _ := ioutil.ReadAll(recorded[0].Response.Body)
+	if recorded[0].Request.URL.Path != "/a" || string(body) != "x=1" || string(respBody) != "echo x=1" { t.Errorf("Exchange Mismatch: got %s, %q, %q", recorded[0].Request.URL, body, respBody) }
+	if recorded[1].Response != nil || recorded[1].Error == nil || recorded[1].Error.Error() != "offline" { t.Errorf("Error Mismatch: got %v", recorded[1].Error) }
+	if last := http_mock_client.LastRequest(); last == nil || last.URL.Path != "/fail" { t.Errorf("LastRequest Mismatch: got %v", last) }
+
+	// bodies can be read again from each call
+	body, _ = ioutil.ReadAll(http_mock_client.Recorded()[0].Request.Body)
+	if string(body) != "x=1" { t.Errorf("Body Mismatch: got %q", body) }
+}
+
+type closeCounter struct {
+	*strings.Reader
+	closes int
+}
+
+func (this *closeCounter) Close() (error) {
+	this.closes++
+	return nil
+}
+
+func TestFormFileClosing(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// readers which can't be closed are fine
+	if _, err := reqt.New("/").Method(POST).FileArg("f", "f.txt", strings.NewReader("data")).Do(); err != nil { t.Errorf("Reader Failure: %s", err.Error()) }
+
+	// closers are closed once, unless told not to be
+	closed, kept := &closeCounter{Reader: strings.NewReader("data")}, &closeCounter{Reader: strings.NewReader("data")}
+	req := reqt.New("/").Method(POST).FileArg("a", "a.txt", closed).FileArg("b", "b.txt", NoClose(kept))
+	req.Do()
+	req.Do()
+	if closed.closes != 1 { t.Errorf("Close Mismatch: got %d closes, expected 1", closed.closes) }
+	if kept.closes != 0 { t.Errorf("NoClose Mismatch: got %d closes, expected 0", kept.closes) }
+
+	// even if the request is never sent
+	failing := New("https://this.is.a.test", nil, nil, func(Request) error { return errors.New("no") }, "test")
+	closed = &closeCounter{Reader: strings.NewReader("data")}
+	if _, err := failing.New("/").Method(POST).FileArg("a", "a.txt", closed).Do(); err == nil { t.Errorf("LastChance Mismatch: request wasn't stopped") }
+	if closed.closes != 1 { t.Errorf("Failure Close Mismatch: got %d closes, expected 1", closed.closes) }
+}
+
+func TestCustomMethods(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var method, query, body string
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		method, query, body = req.Method, req.URL.RawQuery, ""
+		if req.Body != nil {
+			b, _ := ioutil.ReadAll(req.Body)
+			body = string(b)
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	for _, verb := range []HttpVerb{OPTIONS, PROPFIND, REPORT, HttpVerb("QUERY")} {
+		if _, err := reqt.New("/").Method(verb).Body(strings.NewReader("<x/>"), "text/xml").Do(); err != nil { t.Errorf("%s: Request Failure: %s", verb, err.Error()) }
+		if method != string(verb) || body != "<x/>" { t.Errorf("%s: Mismatch: got %s with body %q", verb, method, body) }
+	}
+
+	// bodiless methods send their arguments in the URL
+	for _, verb := range []HttpVerb{HEAD, TRACE} {
+		if _, err := reqt.New("/").Method(verb).Arg("a", "1").Do(); err != nil { t.Errorf("%s: Request Failure: %s", verb, err.Error()) }
+		if query != "a=1" || body != "" { t.Errorf("%s: Argument Mismatch: got query %q, body %q", verb, query, body) }
+	}
+
+	if _, err := reqt.New("/").Method(HttpVerb("BAD VERB")).Do(); err == nil { t.Errorf("Invalid Method Mismatch: got no error") }
+	if idempotent(POST, nil) || !idempotent(PROPFIND, nil) { t.Errorf("Idempotency Mismatch") }
+}
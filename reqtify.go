@@ -2,17 +2,22 @@ package reqtify
 
 import (
 	"bytes"
+	"context"
 	"time"
 	"io"
 	"net/http"
 	"net/url"
+	"net/textproto"
+	"os"
 	"io/ioutil"
+	"sort"
 	"encoding/json"
 	"encoding/xml"
 	"strings"
 	"strconv"
 	"fmt"
-	"log"
+
+	"gopkg.in/yaml.v3"
 )
 
 type HttpVerb string
@@ -23,15 +28,68 @@ const PUT HttpVerb = "PUT"
 const PATCH HttpVerb = "PATCH"
 const DELETE HttpVerb = "DELETE"
 const HEAD HttpVerb = "HEAD"
+const OPTIONS HttpVerb = "OPTIONS"
+const TRACE HttpVerb = "TRACE"
+
+// WebDAV (RFC 4918) and its versioning extensions (RFC 3253). Method accepts
+// other methods too, like HttpVerb("QUERY").
+const PROPFIND HttpVerb = "PROPFIND"
+const PROPPATCH HttpVerb = "PROPPATCH"
+const MKCOL HttpVerb = "MKCOL"
+const COPY HttpVerb = "COPY"
+const MOVE HttpVerb = "MOVE"
+const LOCK HttpVerb = "LOCK"
+const UNLOCK HttpVerb = "UNLOCK"
+const REPORT HttpVerb = "REPORT"
+
+// reports whether requests with this verb are only meant to retrieve
+// information, and don't change anything on the server.
+func (this HttpVerb) safe() (bool) {
+	switch this {
+	case GET, HEAD, OPTIONS, TRACE, PROPFIND, REPORT:
+		return true
+	}
+	return false
+}
+
+// reports whether requests with this verb are sent without a body, so their
+// Arg values go in the URL.
+func (this HttpVerb) bodiless() (bool) {
+	return this == GET || this == HEAD || this == TRACE
+}
+
+// reports whether v can be sent as a method: a non-empty RFC 9110 token.
+func validVerb(v HttpVerb) (bool) {
+	if v == "" { return false }
+	for _, c := range v {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
 
 type FormFile struct {
 	Name string
 	Data io.Reader
+	ContentType string // sniffed from Data if empty.
+	Path string // the file to read Data from, when the request is sent. See FileArgPath.
+	Base64 bool // see Base64Encode.
+
+	size  int64 // the length of Data, if sized.
+	sized bool
 }
 
 type ResponseError struct {
 	StatusCode int
 	StatusText string
+
+	// the decoded error body, if the request asked for one with ErrorJSONInto
+	// or IntoForStatus.
+	Body       interface{}
 }
 
 func (r *ResponseError) Error() string {
@@ -40,13 +98,22 @@ func (r *ResponseError) Error() string {
 
 type Reqtifier interface {
 	New(string) (Request)
+	Stats() (map[string]EndpointStats)
 }
 
 type Request interface {
 	Do() (*http.Response, error)
+	DoAsync() (*Future)
+	Probe() (*ProbeResult, error)
+	OnComplete(f func(*http.Response, error)) (Request)
+	OnError(f func(error)) (Request)
+	On1xx(f func(code int, header textproto.MIMEHeader)) (Request)
 
 	Method(v HttpVerb) (Request)
 	Path(path string) (Request)
+	IdempotencyKey(key string) (Request)
+	IdempotencyKeyFrom(v interface{}) (Request)
+	PathTemplate(template string) (Request)
 	Header(key, value string) (Request)
 	Cookie(c *http.Cookie) (Request)
 	BasicAuthentication(user, password string) (Request)
@@ -56,21 +123,84 @@ type Request interface {
 	URLArg(key string, value interface{}) (Request)
 	FormArg(key string, value interface{}) (Request)
 	FileArg(key, filename string, data io.Reader) (Request)
+	FileArgTyped(key, filename, contentType string, data io.Reader) (Request)
+	FileArgPath(key, path string) (Request)
+	MappedFileArg(key, filename string, f *os.File) (Request)
+	Part(part FormPart) (Request)
+	PartOrder(names ...string) (Request)
+	MultipartType(subtype string) (Request)
+	Base64Encode(names ...string) (Request)
+	Trailer(key string, value func() string) (Request)
+	Body(data io.Reader, contentType string) (Request)
+	JSONBody(v interface{}) (Request)
+	CanonicalJSONBody(v interface{}) (Request)
+	MsgpackBody(v interface{}) (Request)
+	JSONAPIBody(v interface{}) (Request)
+	CBORBody(v interface{}) (Request)
+	VerifyChecksum(algo, expected string) (Request)
+	UserAgentSuffix(suffix string) (Request)
+	Close() (Request)
+	CacheTTL(ttl time.Duration) (Request)
+	Context(ctx context.Context) (Request)
+	Timeout(d time.Duration) (Request)
+	HeaderTimeout(d time.Duration) (Request)
+	IdleTimeout(d time.Duration) (Request)
+	Retry(policy RetryPolicy) (Request)
+	RetryNonIdempotent() (Request)
+	RateGroup(name string) (Request)
+	Confirm() (Request)
+	Essential() (Request)
+	Priority(n int) (Request)
+	Durable(outbox *Outbox) (Request)
+	GraphQL(query string) (Request)
+	OData(q *ODataQuery) (Request)
+	Variable(name string, value interface{}) (Request)
+	AcceptEncoding(encoding string) (Request)
+	RawEncoding() (Request)
+	CompressBody() (Request)
 
 	ArgDefault(key string, value, def interface{}) (Request)
 	URLArgDefault(key string, value, def interface{}) (Request)
 	FormArgDefault(key string, value, def interface{}) (Request)
 
 	Into(into ResponseUnmarshaller) (Request)
+	IntoForStatus(rangeSpec string, into ResponseUnmarshaller) (Request)
 	JSONInto(into interface{}) (Request)
+	ErrorJSONInto(into interface{}) (Request)
+	ExtractInto(path string, into interface{}) (Request)
 	XMLInto(into interface{}) (Request)
+	TextInto(into *string) (Request)
+	BytesInto(into *[]byte) (Request)
+	YAMLInto(into interface{}) (Request)
+	MsgpackInto(into interface{}) (Request)
+	JSONAPIInto(into interface{}) (Request)
+	HALInto(into interface{}) (Request)
+	CBORInto(into interface{}) (Request)
+	CSVInto(into interface{}) (Request)
+	DownloadTo(w io.Writer) (Request)
+	HeaderInto(key string, into *string) (Request)
+	HeadersInto(into *http.Header) (Request)
+	TrailerInto(key string, into *string) (Request)
+	TrailersInto(into *http.Header) (Request)
+	Validate(check func() error) (Request)
+	ExpectStatus(codes ...int) (Request)
+	ExpectStatusRange(min, max int) (Request)
 
 	DebugPrint() (Request)
+	Snapshot() ([]byte, error)
 	GetBody() (io.Reader, string)
+	Clone() (Request)
+	Follow(link string) (Request)
 
 	Target() (string)
 	URL() (string)
 	GetPath() (string)
+	GetVerb() (HttpVerb)
+	GetHeader(key string) (string)
+	GetArg(key string) (string)
+	GetURLArg(key string) (string)
+	GetFormArg(key string) (string)
+	GetCookies() ([]*http.Cookie)
 }
 
 type HttpRequester interface {
@@ -81,12 +211,70 @@ type HttpRequester interface {
 	PostForm(url string, data url.Values) (resp *http.Response, err error)
 }
 
+// a RoundTripFunc sends a fully formed http.Request and returns its response.
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+// a Middleware wraps the round trip of every request made through a Reqtifier.
+// it may inspect or modify the outgoing http.Request before calling next, and
+// inspect or replace the http.Response that comes back.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// an Option configures optional behavior of a Reqtifier created with New.
+type Option func(*ReqtifierImpl)
+
+// adds middleware to the Reqtifier. See ReqtifierImpl.Use.
+func WithMiddleware(m ...Middleware) Option {
+	return func(r *ReqtifierImpl) {
+		r.Use(m...)
+	}
+}
+
 type ReqtifierImpl struct {
 	Root         string
 	RateLimiter *time.Ticker
 	HttpClient   HttpRequester
+	ownTransport *http.Transport // HttpClient's transport, once it's been copied so it can be configured. See transport.
 	LastChance   func(Request) error
-	AgentName    string
+	AgentName    string // deprecated: UserAgent takes precedence if set.
+	UserAgent   *UserAgent
+	Middleware []Middleware
+	VerifyDigests bool
+	DisableKeepAlives bool
+	AcceptEncoding string
+	VerbDefaults map[HttpVerb]RequestDefaults
+	RateGroups   map[string]*time.Ticker
+	SafeMode   []SafeModeRule
+	DisableDecompression bool
+	IDGenerator  IDGenerator
+	LocaleMapping *LocaleMapping
+	CopyBufferSize int
+	Name         string
+	ProfileLabels bool
+	TraceRegions bool
+	HeaderTimeout time.Duration
+	IdleTimeout  time.Duration
+	UTF8Policy   UTF8Policy
+	JSONCodec    JSONCodec
+	XMLOptions  *XMLOptions
+	QuietHours []*QuietHours
+	Queue       *PriorityQueue
+	Boundaries  BoundarySource
+	Clock       Clock
+	Random     *Random
+	Logger      Logger
+	Redactor   *Redactor
+	Dumper     *Dumper
+	Tracer      Tracer
+	Propagator  Propagator
+	Metrics    *Metrics
+	SlowThreshold time.Duration
+	OnSlowRequest func(Request, time.Duration)
+	RequestIDHeader string
+	RequestIDGenerator IDGenerator
+	RetryBudget *RetryBudget
+	ReplayLimit  int64
+
+	stats       statsTable
 }
 
 type ResponseUnmarshaller interface {
@@ -95,9 +283,13 @@ type ResponseUnmarshaller interface {
 
 type JSONUnmarshaller struct {
 	output_value interface{}
+	codec        JSONCodec
 }
 
 func (this JSONUnmarshaller) Unmarshal(body []byte) error {
+	if this.codec != nil {
+		return this.codec.Unmarshal(body, this.output_value)
+	}
 	return json.Unmarshal(body, this.output_value)
 }
 
@@ -107,9 +299,13 @@ func FromJSON(output_value interface{}) ResponseUnmarshaller {
 
 type XMLUnmarshaller struct {
 	output_value interface{}
+	options     *XMLOptions
 }
 
 func (this XMLUnmarshaller) Unmarshal(body []byte) error {
+	if this.options != nil {
+		return this.options.decode(body, this.output_value)
+	}
 	return xml.Unmarshal(body, this.output_value)
 }
 
@@ -117,6 +313,56 @@ func FromXML(output_value interface{}) ResponseUnmarshaller {
 	return XMLUnmarshaller{output_value: output_value}
 }
 
+// adapts an ordinary function to a ResponseUnmarshaller.
+type UnmarshalFunc func([]byte) error
+
+func (this UnmarshalFunc) Unmarshal(body []byte) error {
+	return this(body)
+}
+
+// wraps a function as a ResponseUnmarshaller, for one-off parsers.
+func FromFunc(f func([]byte) error) ResponseUnmarshaller {
+	return UnmarshalFunc(f)
+}
+
+type TextUnmarshaller struct {
+	output_value *string
+}
+
+func (this TextUnmarshaller) Unmarshal(body []byte) error {
+	*this.output_value = string(body)
+	return nil
+}
+
+func FromText(output_value *string) ResponseUnmarshaller {
+	return TextUnmarshaller{output_value: output_value}
+}
+
+type BytesUnmarshaller struct {
+	output_value *[]byte
+}
+
+func (this BytesUnmarshaller) Unmarshal(body []byte) error {
+	*this.output_value = append([]byte(nil), body...)
+	return nil
+}
+
+func FromBytes(output_value *[]byte) ResponseUnmarshaller {
+	return BytesUnmarshaller{output_value: output_value}
+}
+
+type YAMLUnmarshaller struct {
+	output_value interface{}
+}
+
+func (this YAMLUnmarshaller) Unmarshal(body []byte) error {
+	return yaml.Unmarshal(body, this.output_value)
+}
+
+func FromYAML(output_value interface{}) ResponseUnmarshaller {
+	return YAMLUnmarshaller{output_value: output_value}
+}
+
 type cachedBody struct {
 	body   []byte
 	mimetype string
@@ -124,25 +370,91 @@ type cachedBody struct {
 
 type RequestImpl struct {
 	URLPath        string
+	URLTemplate    string
 	Verb           HttpVerb
 	QueryParams    url.Values
 	FormParams     url.Values
 	AutoParams     url.Values
 	FormFiles      map[string][]FormFile
+	FormParts      []FormPart
+	PartNames      []string // see PartOrder.
+	Base64Names    []string // see Base64Encode.
 	Headers        map[string]string
 	BasicUser      string
 	BasicPassword  string
 	Cookies     []*http.Cookie
 	ForceMultipart bool
+	MultipartSubtype string // see MultipartType.
+	RawBody        io.Reader
+	RawBodyType    string
+	Checksums    []Checksum
+	AgentSuffix  []string
+	CloseConnection bool
+	CacheLifetime  *time.Duration
+	RequestContext context.Context
+	AttemptTimeout *time.Duration
+	RetryPolicy    *RetryPolicy
+	RetryAnyMethod bool
+	Group          string
+	Confirmed      bool
+	IsEssential    bool
+	QueuePriority  int
+	DurableOutbox  *Outbox
+	GraphQLQuery   string
+	GraphQLVariables map[string]interface{}
+	NoDecompression bool
+	CompressRequest bool
+	DownloadWriter io.Writer
+	HeaderTimeoutOverride *time.Duration
+	IdleTimeoutOverride *time.Duration
+
+	// an error encountered while building the request, returned by Do.
+	BuildError     error
 
 	Response     []ResponseUnmarshaller
+	StatusResponse []StatusUnmarshaller
+	Validators   []func() error
+	HeaderCaptures []HeaderCapture
+	Trailers       map[string]func() string // see Trailer.
+	ExpectedStatus []StatusRange
+	CompletionHooks []func(*http.Response, error)
+	InformationalHooks []func(int, textproto.MIMEHeader) // see On1xx.
 
 	ReqClient     *ReqtifierImpl
+	RequestID     string // set when it's sent, if the Reqtifier labels requests with IDs.
 
 	body          *cachedBody
+	attempts      int // how many times it's been sent, so far.
+	pathFiles     []*lazyFile // files opened for FileArgPath, to close when it's done.
+	heldFiles     []*os.File  // body files kept open so they can be sent again, to close when it's done.
+	filesClosed   bool
 }
 
-func New(root string, rl *time.Ticker, client *http.Client, lc func(Request) (error), agent string) (Reqtifier) {
+// records an error found while building the request, unless it already has one.
+func (this *RequestImpl) setBuildError(err error) {
+	if this.BuildError == nil { this.BuildError = err }
+}
+
+// records err as req's build error, so that Do returns it, unless it already
+// has one. For helpers outside this package which add to requests. Requests
+// which don't embed RequestImpl are replaced by one which fails with err.
+func FailBuild(req Request, err error) (Request) {
+	if s, ok := req.(interface{ setBuildError(error) }); ok {
+		s.setBuildError(err)
+		return req
+	}
+	return failedRequest(err)
+}
+
+// returns a request which fails with err, for when there's no request to
+// build one from.
+func failedRequest(err error) (Request) {
+	req := (&ReqtifierImpl{}).New("").(*RequestImpl)
+	req.BuildError = err
+	return req
+}
+
+func New(root string, rl *time.Ticker, client *http.Client, lc func(Request) (error), agent string, opts ...Option) (Reqtifier) {
 	r := ReqtifierImpl{
 		Root: root,
 		RateLimiter: rl,
@@ -155,82 +467,361 @@ func New(root string, rl *time.Ticker, client *http.Client, lc func(Request) (er
 		r.HttpClient = &http.Client{Transport: &http.Transport{} }
 	}
 
+	for _, opt := range opts {
+		opt(&r)
+	}
+
 	return &r
 }
 
-func (this *ReqtifierImpl) Do(req *RequestImpl) (*http.Response, error) {
-	// wait for rate limiter to be ready
-	if this.RateLimiter != nil { <- this.RateLimiter.C }
+type requestContextKey struct{}
 
-	// figure out request URL from query params and other stuff
-	callURL := req.URL()
+func withRequest(ctx context.Context, req *RequestImpl) (context.Context) {
+	return context.WithValue(ctx, requestContextKey{}, req)
+}
 
-	// calculate request body
-	var body io.Reader
-	var bodytype string
-	if req.Verb != GET {
-		body, bodytype = req.GetBody()
-	}
+// returns the reqtify request which produced an http.Request, from its
+// context, so that middleware can consult per-request settings.
+func RequestFromContext(ctx context.Context) (*RequestImpl, bool) {
+	req, ok := ctx.Value(requestContextKey{}).(*RequestImpl)
+	return req, ok
+}
 
-	r, err := http.NewRequest(string(req.Verb), callURL, body)
-	if err != nil { return nil, err }
+type attemptContextKey struct{}
 
-	// set headers
-	for key, value := range req.Headers {
-		r.Header.Add(key, value)
-	}
+func withAttempt(ctx context.Context, attempt int) (context.Context) {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
 
-	// override content-type header, if one was explicitly specified
-	if bodytype != "" {
-		r.Header.Add("Content-Type", bodytype)
+// returns which attempt at sending its request an http.Request is, counting
+// from 1, from its context. It returns 0 for requests reqtify didn't send.
+func AttemptFromContext(ctx context.Context) (int) {
+	attempt, _ := ctx.Value(attemptContextKey{}).(int)
+	return attempt
+}
+
+// appends middleware to the Reqtifier's chain. Middleware added first is
+// outermost: it sees the request first and the response last.
+func (this *ReqtifierImpl) Use(m ...Middleware) {
+	this.Middleware = append(this.Middleware, m...)
+}
+
+// builds the round trip function for a request, with all middleware applied.
+func (this *ReqtifierImpl) roundTripper() (RoundTripFunc) {
+	send := RoundTripFunc(this.HttpClient.Do)
+	for i := len(this.Middleware) - 1; i >= 0; i-- {
+		send = this.Middleware[i](send)
 	}
+	return send
+}
 
-	// override authentication with HTTP basic auth, if specified
-	if (req.BasicUser != "" || req.BasicPassword != "") {
-		r.SetBasicAuth(req.BasicUser, req.BasicPassword)
+func (this *ReqtifierImpl) Do(req *RequestImpl) (*http.Response, error) {
+	defer req.closeFiles()
+	this.assignRequestID(req)
+	start := this.clock().Now()
+	resp, err := this.traceAndProfile(req)
+	elapsed := this.clock().Now().Sub(start)
+	this.stats.record(string(req.Verb) + " " + req.pathTemplate(), elapsed, req.attempts, resp, err)
+	this.checkSlow(req, elapsed)
+	if err != nil && req.RequestID != "" {
+		err = &RequestIDError{RequestID: req.RequestID, Err: err}
 	}
+	return resp, err
+}
 
-	// Add cookies
-	for _, cookie := range req.Cookies {
-		r.AddCookie(cookie)
+func (this *ReqtifierImpl) traceAndProfile(req *RequestImpl) (*http.Response, error) {
+	do := this.do
+	if this.Tracer != nil {
+		do = func(req *RequestImpl) (*http.Response, error) { return this.traced(req, this.do) }
+	}
+	if this.ProfileLabels {
+		return this.profiled(req, do)
 	}
+	return do(req)
+}
+
+func (this *ReqtifierImpl) do(req *RequestImpl) (*http.Response, error) {
+	if req.BuildError != nil { return nil, req.BuildError }
+	if err := this.applySafeMode(req); err != nil { return nil, err }
+
+	settings := this.settingsFor(req)
+	limiter, err := this.rateLimiter(settings.RateGroup)
+	if err != nil { return nil, err }
 
-	resp, err := this.HttpClient.Do(r)
+	resp, err := this.retrying(req, settings.Retry, func(attempt int) (*http.Response, error) {
+		return this.send(req, limiter, settings, attempt)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// try to close any closable formfiles passed to us
-	for _, list := range req.FormFiles {
-		for _, file := range list {
-			closer := file.Data.(io.ReadCloser)
-			if closer != nil {
-				closer.Close()
-			}
-		}
+	verifyResponseChecksums(req, resp, this.VerifyDigests)
+	req.CaptureHeaders(resp)
+	if resp.Body != nil { resp.Body = req.captureTrailers(resp) }
+
+	if err := req.CheckStatus(resp); err != nil {
+		return resp, err
+	}
+
+	if req.DownloadWriter != nil {
+		return resp, this.download(req, resp)
 	}
 
 	// Packing into response, if we have one
-	if len(req.Response)!= 0 {
-		body, err := ioutil.ReadAll(resp.Body)
+	unmarshallers, errorBody := req.UnmarshallersFor(resp.StatusCode)
+	if len(unmarshallers)!= 0 {
+		var body []byte
+		body, err = ioutil.ReadAll(resp.Body)
 		if err != nil {
 			return nil, err
 		}
 		resp.Body.Close()
 
 		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
-		for _, response := range req.Response {
-			e := response.Unmarshal(body)
-			if err != nil {
+		for _, response := range unmarshallers {
+			text, e := this.checkUTF8(response, body)
+			if e == nil {
+				e = response.Unmarshal(text)
+			}
+			if err == nil {
 				err = e
 			}
 		}
 	}
 
+	if err == nil {
+		err = req.ValidateResponse(unmarshallers)
+	}
+
+	if err == nil && errorBody != nil {
+		err = &ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status, Body: errorBody}
+	}
+
 	// OK, though err might not be nil if there is a marshalling error
 	return resp, err
 }
 
+// runs send, retrying it as the request's retry policy and this Reqtifier's
+// defaults say, the way Do does. This is exported for Request implementations
+// outside this package, like mocks, which answer requests themselves but
+// should be retried as if they had been sent.
+func (this *ReqtifierImpl) Retrying(req *RequestImpl, send func(attempt int) (*http.Response, error)) (*http.Response, error) {
+	return this.retrying(req, this.settingsFor(req).Retry, send)
+}
+
+func (this *ReqtifierImpl) retrying(req *RequestImpl, policy *RetryPolicy, send func(attempt int) (*http.Response, error)) (*http.Response, error) {
+	// bodies built from readers can only be read once, so keep a copy if we might retry
+	this.autoIdempotencyKey(req, policy)
+
+	attempts := policy.attempts()
+	if !req.RetryAnyMethod && !idempotent(req.Verb, req.Headers) {
+		attempts = 1
+	}
+	if attempts > 1 && !req.replayable() {
+		if err := req.cacheBody(); err != nil { return nil, err }
+	}
+
+	this.RetryBudget.deposit(this.clock().Now())
+	var resp *http.Response
+	var err error
+	for attempt := 1; ; attempt++ {
+		resp, err = send(attempt)
+		if attempt >= attempts || req.context().Err() != nil || !policy.shouldRetry(resp, err) {
+			break
+		}
+		if !this.RetryBudget.withdraw(this.clock().Now()) {
+			this.logf(LogWarn, "reqtify: %s: attempt %d of %d failed (%s), not retrying, retry budget spent", this.describe(req), attempt, attempts, attemptOutcome(resp, err))
+			break
+		}
+
+		delay := policy.delay(attempt, resp, this.clock().Now(), this.Random)
+		this.logf(LogDebug, "reqtify: %s: attempt %d of %d failed (%s), retrying in %s", this.describe(req), attempt, attempts, attemptOutcome(resp, err), delay)
+		if span := spanFromContext(req.context()); span != nil {
+			span.AddEvent("retry", map[string]interface{}{"attempt": attempt, "reason": attemptOutcome(resp, err), "delay": delay.String()})
+		}
+		if resp != nil && resp.Body != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := clockSleep(req.context(), this.clock(), delay); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, err
+}
+
+// performs a single attempt at sending a request, waiting for the rate limiter first.
+func (this *ReqtifierImpl) send(req *RequestImpl, limiter *time.Ticker, settings RequestDefaults, attempt int) (*http.Response, error) {
+	ctx := req.context()
+
+	if err := this.waitQuietHours(ctx, req, settings.RateGroup); err != nil {
+		return nil, err
+	}
+
+	// wait for rate limiter to be ready
+	waitStart := this.clock().Now()
+	if limiter != nil && this.Queue != nil {
+		if err := this.Queue.wait(ctx, limiter, req.QueuePriority); err != nil {
+			return nil, err
+		}
+	} else if limiter != nil {
+		select {
+		case <- limiter.C:
+		case <- ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if limiter != nil {
+		this.Metrics.observeWait(settings.RateGroup, this.clock().Now().Sub(waitStart))
+	}
+
+	cancel := context.CancelFunc(func(){})
+	if settings.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+	}
+
+	var stall *stallWatch
+	if settings.HeaderTimeout > 0 || settings.IdleTimeout > 0 {
+		ctx, stall = newStallWatch(ctx, this.clock(), settings.HeaderTimeout, settings.IdleTimeout)
+		timeoutCancel := cancel
+		cancel = func() {
+			stall.release()
+			timeoutCancel()
+		}
+	}
+
+	r, err := req.HTTPRequest(withAttempt(withRequest(ctx, req), attempt))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if this.AcceptEncoding != "" && r.Header.Get("Accept-Encoding") == "" {
+		r.Header.Set("Accept-Encoding", this.AcceptEncoding)
+	}
+
+	req.attempts = attempt
+	if req.RequestID != "" { r.Header.Set(this.RequestIDHeader, req.RequestID) }
+	applyPropagatedHeaders(ctx, r)
+	if this.Propagator != nil && spanFromContext(ctx) != nil {
+		this.Propagator.Inject(ctx, r.Header)
+	}
+
+	r.Close = this.DisableKeepAlives || req.CloseConnection
+	this.prepareEncoding(req, r)
+	this.applyLocale(ctx, r)
+
+	stall.start()
+	resp, err := this.roundTripper()(r)
+	stall.headers()
+	if err != nil {
+		err = stall.translate(err)
+		cancel()
+		return nil, err
+	}
+
+	if err := this.decodeResponse(req, resp); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the timeout covers reading the body too, so it can't be released until the body is closed
+	if resp.Body != nil {
+		resp.Body = &cancelOnClose{ReadCloser: stall.body(resp.Body), cancel: cancel}
+	} else {
+		cancel()
+	}
+	return resp, nil
+}
+
+// builds the http.Request this request describes, as it would be sent before
+// any Reqtifier-wide settings or middleware are applied.
+func (this *RequestImpl) HTTPRequest(ctx context.Context) (*http.Request, error) {
+	// figure out request URL from query params and other stuff
+	callURL := this.URL()
+
+	// calculate request body
+	var body io.Reader
+	var bodytype string
+	if !this.Verb.bodiless() {
+		body, bodytype = this.GetBody()
+	}
+	if body != nil && this.CompressRequest {
+		body = gzipReader(body)
+	}
+
+	r, err := http.NewRequestWithContext(this.traceInformational(ctx), string(this.Verb), callURL, body)
+	if err != nil {
+		if closer, ok := body.(io.Closer); ok && this.CompressRequest {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	// net/http only knows the length of in-memory bodies. A plain file can be
+	// handed to the transport as it is, if we tell it how long it is, which
+	// lets it use sendfile rather than copying the file through userspace.
+	if f, ok := body.(*os.File); ok {
+		if length, ok := remainingLength(f); ok {
+			r.ContentLength = length
+			if length == 0 {
+				r.Body = http.NoBody
+			}
+		}
+	}
+
+	// so the body can be sent again on redirects
+	this.setGetBody(r, body)
+
+	if sized, ok := body.(*sizedReader); ok {
+		r.ContentLength = sized.size
+	}
+	this.declareTrailers(r)
+
+	// set headers
+	for key, value := range this.Headers {
+		r.Header.Add(key, value)
+	}
+
+	// ask for something we know how to decode, unless told otherwise
+	if r.Header.Get("Accept") == "" {
+		if accept := this.accept(); accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+	}
+
+	// override content-type header, if one was explicitly specified
+	if bodytype != "" {
+		r.Header.Set("Content-Type", bodytype)
+	}
+
+	if body != nil && this.CompressRequest {
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+
+	// override authentication with HTTP basic auth, if specified
+	if (this.BasicUser != "" || this.BasicPassword != "") {
+		r.SetBasicAuth(this.BasicUser, this.BasicPassword)
+	}
+
+	// Add cookies
+	for _, cookie := range this.Cookies {
+		r.AddCookie(cookie)
+	}
+
+	return r, nil
+}
+
+// returns the number of bytes left to read in f, if it's a regular file.
+func remainingLength(f *os.File) (int64, bool) {
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() { return 0, false }
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || offset > info.Size() { return 0, false }
+	return info.Size() - offset, true
+}
+
 func (this *ReqtifierImpl) New(endpoint string) (Request) {
 	return &RequestImpl{
 		URLPath: endpoint,
@@ -252,30 +843,53 @@ func (this *RequestImpl) GetBody() (io.Reader, string) {
 		}
 
 		return &readOnlyReader{buffer: this.body.body}, this.body.mimetype
-	} else if this.ForceMultipart || len(this.FormFiles) != 0 {
-		var m multipartRequestBody
-		for k, va := range this.FormParams {
-			for _, v := range va {
-				m.addParam(k, v)
+	} else if this.RawBody != nil {
+		return this.RawBody, this.RawBodyType
+	} else if this.ForceMultipart || len(this.FormFiles) != 0 || len(this.FormParts) != 0 {
+		m := multipartRequestBody{subtype: this.MultipartSubtype}
+		if this.ReqClient != nil {
+			m.random = this.ReqClient.Random
+			if this.ReqClient.Boundaries != nil { m.setBoundary(this.ReqClient.Boundaries()) }
+		}
+		var fields []formField
+		for _, k := range sortedKeys(this.FormParams) {
+			for _, v := range this.FormParams[k] {
+				k, v := k, v
+				fields = append(fields, formField{k, func() { m.addParam(k, v) }})
 			}
 		}
-		if this.Verb != GET {
-			for k, va := range this.AutoParams {
-				for _, v := range va {
-					m.addParam(k, v)
+		if !this.Verb.bodiless() {
+			for _, k := range sortedKeys(this.AutoParams) {
+				for _, v := range this.AutoParams[k] {
+					k, v := k, v
+					fields = append(fields, formField{k, func() { m.addParam(k, v) }})
 				}
 			}
 		}
-		for k, va := range this.FormFiles {
-			for _, v := range va {
-				m.addFileParam(k, v)
+		for _, k := range sortedKeys(this.FormFiles) {
+			for _, v := range this.FormFiles[k] {
+				k, v := k, v
+				fields = append(fields, formField{k, func() {
+					if v.Path != "" { v = this.openLazily(v) }
+					v.Base64 = v.Base64 || this.base64Encoded(k)
+					m.addFileParam(k, v)
+				}})
 			}
 		}
+		for _, part := range this.FormParts {
+			part := part
+			part.Base64 = part.Base64 || this.base64Encoded(part.Name)
+			fields = append(fields, formField{part.Name, func() { m.addPart(part) }})
+		}
+		orderFields(fields, this.PartNames)
+		for _, field := range fields {
+			field.add()
+		}
 		m.close()
 		return m.toReader(), m.contentType()
 	} else {
 		params := this.FormParams.Encode()
-		if len(this.AutoParams) != 0 && this.Verb != GET {
+		if len(this.AutoParams) != 0 && !this.Verb.bodiless() {
 			if len(params) != 0 {
 				params += "&"
 			}
@@ -285,28 +899,65 @@ func (this *RequestImpl) GetBody() (io.Reader, string) {
 	}
 }
 
+// returns the keys of m in order, so that bodies built from maps are
+// reproducible.
+func sortedKeys[V any](m map[string]V) ([]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (this *RequestImpl) Path(path string) (Request) {
 	this.URLPath = path
 	return this
 }
 
+// sets the request method. Besides the constants, any method the server
+// understands can be used, as HttpVerb("QUERY"). Methods are case sensitive,
+// and if v isn't a valid one, Do returns an error.
 func (this *RequestImpl) Method(v HttpVerb) (Request) {
+	if !validVerb(v) {
+		this.setBuildError(fmt.Errorf("reqtify: invalid method %q", v))
+		return this
+	}
 	this.Verb = v
 	return this
 }
 
+// decodes the response body with into. If decoding fails, Do returns the
+// first unmarshaller's error along with the response.
 func (this *RequestImpl) Into(into ResponseUnmarshaller) (Request) {
 	this.Response = append(this.Response, into)
 	return this
 }
 
 func (this *RequestImpl) JSONInto(into interface{}) (Request) {
-	this.Response = append(this.Response, FromJSON(into))
+	this.Response = append(this.Response, JSONUnmarshaller{output_value: into, codec: this.jsonCodec()})
 	return this
 }
 
 func (this *RequestImpl) XMLInto(into interface{}) (Request) {
-	this.Response = append(this.Response, FromXML(into))
+	var options *XMLOptions
+	if this.ReqClient != nil { options = this.ReqClient.XMLOptions }
+	this.Response = append(this.Response, XMLUnmarshaller{output_value: into, options: options})
+	return this
+}
+
+func (this *RequestImpl) TextInto(into *string) (Request) {
+	this.Response = append(this.Response, FromText(into))
+	return this
+}
+
+func (this *RequestImpl) BytesInto(into *[]byte) (Request) {
+	this.Response = append(this.Response, FromBytes(into))
+	return this
+}
+
+func (this *RequestImpl) YAMLInto(into interface{}) (Request) {
+	this.Response = append(this.Response, FromYAML(into))
 	return this
 }
 
@@ -379,11 +1030,86 @@ func (this *RequestImpl) FormArg(key string, value interface{}) (Request) {
 	return this.argDefaultHelper(key, value, nil, this.FormParams)
 }
 
+// adds a file to the multipart form. Its content type is sniffed from the
+// start of data, or failing that, guessed from the extension of filename.
 func (this *RequestImpl) FileArg(key, filename string, data io.Reader) (Request) {
-	this.FormFiles[key] = append(this.FormFiles[key], FormFile{Name: filename, Data: data})
+	return this.FileArgTyped(key, filename, "", data)
+}
+
+// adds a file of the given content type to the multipart form. If
+// contentType is empty, it's worked out as FileArg does.
+func (this *RequestImpl) FileArgTyped(key, filename, contentType string, data io.Reader) (Request) {
+	this.FormFiles[key] = append(this.FormFiles[key], FormFile{Name: filename, Data: data, ContentType: contentType})
+	return this
+}
+
+// wraps a file's data so that it isn't closed once the request is done, for
+// readers the caller wants to keep using. Files are closed by default if
+// they're io.Closers, whether the request succeeds or not.
+func NoClose(data io.Reader) (io.Reader) {
+	return noCloser{data}
+}
+
+type noCloser struct {
+	io.Reader
+}
+
+// closes the request's files, once, however the request ended. Do calls
+// this; it's exported for Request implementations which embed RequestImpl.
+func (this *RequestImpl) CloseFiles() {
+	this.closeFiles()
+}
+
+func (this *RequestImpl) closeFiles() {
+	if this.filesClosed { return }
+	this.filesClosed = true
+	for _, list := range this.FormFiles {
+		for _, file := range list {
+			if closer, ok := file.Data.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+	closeParts(this.FormParts)
+	for _, f := range this.pathFiles {
+		f.Close()
+	}
+	this.pathFiles = nil
+	for _, f := range this.heldFiles {
+		f.Close()
+	}
+	this.heldFiles = nil
+}
+
+func closeParts(parts []FormPart) {
+	for _, part := range parts {
+		if closer, ok := part.Data.(io.Closer); ok {
+			closer.Close()
+		}
+		closeParts(part.Parts)
+	}
+}
+
+// sets the request body verbatim. Form arguments are ignored when a body is
+// set this way, and Arg values are sent in the URL instead of the body.
+// If data is a regular *os.File, it's sent from its current offset with a
+// Content-Length, which lets the transport use sendfile where available.
+func (this *RequestImpl) Body(data io.Reader, contentType string) (Request) {
+	this.RawBody = data
+	this.RawBodyType = contentType
 	return this
 }
 
+// marshals v as JSON and uses it as the request body.
+func (this *RequestImpl) JSONBody(v interface{}) (Request) {
+	data, err := this.jsonCodec().Marshal(v)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	return this.Body(bytes.NewReader(data), "application/json")
+}
+
 // for ArgDefault, URLArgDefault, and FormArgDefault, in addition to omitting the argument
 // if nil is passed (see above), it is also omitted if it matches a provided default value,
 // or if the converted string matches that value (so 3 will match a default of either 3, or "3")
@@ -430,7 +1156,12 @@ func (this *RequestImpl) Multipart() (Request) {
 	return this
 }
 
+// returns the URL this request is sent to, without its query. Paths which
+// are absolute URLs, like those from Follow, are used as they are.
 func (this *RequestImpl) Target() (string) {
+	if strings.HasPrefix(this.URLPath, "http://") || strings.HasPrefix(this.URLPath, "https://") {
+		return this.URLPath
+	}
 	return this.ReqClient.Root + this.URLPath
 }
 
@@ -438,10 +1169,43 @@ func (this *RequestImpl) GetPath() (string) {
 	return this.URLPath
 }
 
+func (this *RequestImpl) GetVerb() (HttpVerb) {
+	return this.Verb
+}
+
+// returns the value of a header set with Header. Keys are matched without
+// regard to case, as in HTTP.
+func (this *RequestImpl) GetHeader(key string) (string) {
+	if value, ok := this.Headers[key]; ok { return value }
+	for k, value := range this.Headers {
+		if strings.EqualFold(k, key) { return value }
+	}
+	return ""
+}
+
+// these return the first value of an argument set with Arg, URLArg, or
+// FormArg respectively, or "" if it isn't set.
+
+func (this *RequestImpl) GetArg(key string) (string) {
+	return this.AutoParams.Get(key)
+}
+
+func (this *RequestImpl) GetURLArg(key string) (string) {
+	return this.QueryParams.Get(key)
+}
+
+func (this *RequestImpl) GetFormArg(key string) (string) {
+	return this.FormParams.Get(key)
+}
+
+func (this *RequestImpl) GetCookies() ([]*http.Cookie) {
+	return this.Cookies
+}
+
 func (this *RequestImpl) URL() (string) {
 	callURL := this.Target()
 	params := this.QueryParams.Encode()
-	if len(this.AutoParams) != 0 && this.Verb == GET {
+	if len(this.AutoParams) != 0 && (this.Verb.bodiless() || this.RawBody != nil) {
 		if len(params) != 0 {
 			params += "&"
 		}
@@ -460,25 +1224,50 @@ func (this *RequestImpl) URL() (string) {
 // array and store it, and GetBody() will return the stored one later, rather
 // than re-resolving it. This is done because the body may be constructed from
 // external io.Readers which can't be seeked or re-read, only read once.
+// The request is logged at LogInfo, to the Reqtifier's Logger, with secrets
+// masked by its Redactor.
 func (this *RequestImpl) DebugPrint() (Request) {
+	err := this.cacheBody()
+	if err != nil { panic("Error reading request body: " + err.Error()) }
+
+	redactor := this.ReqClient.redactor()
+	this.ReqClient.logf(LogInfo, "Request URL: %s\nUser agent: %s\nOther request headers: %+v\nRequest body:\n%s\n\n", redactor.URL(this.URL()), this.userAgent(), redactor.HeaderMap(this.Headers), string(redactor.Body(this.body.mimetype, this.body.body)))
+	return this
+}
+
+// resolves the request body to a byte array and stores it, so that GetBody
+// can be called repeatedly. See DebugPrint.
+func (this *RequestImpl) cacheBody() (error) {
+	if this.body != nil { return nil }
+
 	reader, mimetype := this.GetBody()
 	body, err := ioutil.ReadAll(reader)
-	if err != nil { panic("Error reading request body: " + err.Error()) }
+	if err != nil { return err }
 
 	this.body = &cachedBody{
 		body: body,
 		mimetype: mimetype,
 	}
+	return nil
+}
 
-	log.Printf("Request URL: %s\nUser agent: %s\nOther request headers: %+v\nRequest body:\n%s\n\n", this.URL(), this.ReqClient.AgentName, this.Headers, string(body))
-	return this
+// reports whether GetBody can be called more than once and produce the
+// same body each time.
+func (this *RequestImpl) replayable() (bool) {
+	return this.Verb.bodiless() || this.body != nil || (len(this.FormFiles) == 0 && len(this.FormParts) == 0 && this.RawBody == nil)
 }
 
 // Call this function to execute the call.
 // it can return a nil response if an error occurs.
 func (this *RequestImpl) Do() (*http.Response, error) {
-	if len(this.ReqClient.AgentName) != 0 {
-	        this.Header("User-Agent", this.ReqClient.AgentName)
+	return this.Complete(this.do())
+}
+
+func (this *RequestImpl) do() (*http.Response, error) {
+	defer this.closeFiles()
+
+	if agent := this.userAgent(); len(agent) != 0 {
+	        this.Header("User-Agent", agent)
 	}
 
 	if this.ReqClient.LastChance != nil {
@@ -0,0 +1,59 @@
+package reqtify
+
+import (
+	"net/http"
+)
+
+/*
+   With a request ID header, every request is labelled with a unique ID,
+   which stays the same across retries, so that its attempts can be found in
+   the logs of the API it was sent to. The ID also appears in reqtify's own
+   log lines, and in errors, as a RequestIDError.
+
+   If a request sets the header itself, or its context carries one (see
+   ContextWithHeaders), that ID is used instead of a new one.
+*/
+
+// labels every request with a unique ID from gen in the header named, like
+// "X-Request-ID". If gen is nil, the Reqtifier's IDGenerator is used.
+func WithRequestIDHeader(header string, gen IDGenerator) Option {
+	return func(r *ReqtifierImpl) {
+		r.RequestIDHeader = http.CanonicalHeaderKey(header)
+		r.RequestIDGenerator = gen
+	}
+}
+
+// an error from a request which had an ID, to correlate it with logs.
+type RequestIDError struct {
+	RequestID string
+	Err       error
+}
+
+func (this *RequestIDError) Error() (string) {
+	return this.Err.Error() + " (request id " + this.RequestID + ")"
+}
+
+func (this *RequestIDError) Unwrap() (error) {
+	return this.Err
+}
+
+// picks the request's ID, if the Reqtifier labels requests with them.
+func (this *ReqtifierImpl) assignRequestID(req *RequestImpl) {
+	if this.RequestIDHeader == "" || req.RequestID != "" { return }
+	if id := req.GetHeader(this.RequestIDHeader); id != "" {
+		req.RequestID = id
+	} else if id := HeadersFromContext(req.context()).Get(this.RequestIDHeader); id != "" {
+		req.RequestID = id
+	} else if this.RequestIDGenerator != nil {
+		req.RequestID = this.RequestIDGenerator.NewID()
+	} else {
+		req.RequestID = this.NewID()
+	}
+}
+
+// describes a request for log lines.
+func (this *ReqtifierImpl) describe(req *RequestImpl) (string) {
+	description := string(req.Verb) + " " + this.redactor().URL(req.URL())
+	if req.RequestID != "" { description += " (request id " + req.RequestID + ")" }
+	return description
+}
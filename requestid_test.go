@@ -0,0 +1,56 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var ids []string
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		ids = append(ids, req.Header.Get("X-Request-ID"))
+		if calls == 1 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	n := 0
+	var logged []string
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithRequestIDHeader("x-request-id", IDGeneratorFunc(func() string {
+		n++
+		return strings.Repeat("a", n)
+	})), WithLogger(LoggerFunc(func(level LogLevel, format string, args ...interface{}) {
+		logged = append(logged, strings.TrimSpace(strings.SplitN(format, ":", 2)[0] + " " + args[0].(string)))
+	})))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// one id for every attempt, which errors carry
+	_, err := reqt.New("/a").Retry(RetryPolicy{MaxAttempts: 2}).ExpectStatus(200).Do()
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "a" { t.Errorf("ID Mismatch: got %v, expected [a a]", ids) }
+	var idErr *RequestIDError
+	if !errors.As(err, &idErr) || idErr.RequestID != "a" { t.Errorf("Error Mismatch: got %v", err) }
+	var statusErr *UnexpectedStatusError
+	if !errors.As(err, &statusErr) { t.Errorf("Unwrap Mismatch: got %T", errors.Unwrap(err)) }
+	if len(logged) != 1 || logged[0] != "reqtify GET https://this.is.a.test/a (request id a)" { t.Errorf("Log Mismatch: got %q", logged) }
+
+	// explicit and propagated ids are kept
+	ids = nil
+	reqt.New("/a").Header("X-Request-ID", "mine").Do()
+	reqt.New("/a").Context(ContextWithHeaders(context.Background(), http.Header{"X-Request-Id": {"upstream"}})).Do()
+	reqt.New("/a").Do()
+	if len(ids) != 3 || ids[0] != "mine" || ids[1] != "upstream" || ids[2] != "aa" { t.Errorf("Kept Mismatch: got %v", ids) }
+
+	// without a generator, the Reqtifier's is used
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithRequestIDHeader("X-Request-ID", nil), WithIDGenerator(IDGeneratorFunc(func() string { return "default" })))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	ids = nil
+	reqt.New("/a").Do()
+	if len(ids) != 1 || ids[0] != "default" { t.Errorf("Default Mismatch: got %v", ids) }
+}
@@ -0,0 +1,107 @@
+package reqtify
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// a SafeModeRule describes a class of dangerous requests, and what to do with
+// them unless they are explicitly confirmed with Confirm. Typically this means
+// asking the API for a dry run, using whatever header or query parameter it
+// supports for that, or refusing to send the request at all.
+type SafeModeRule struct {
+	Verbs       []HttpVerb // the verbs the rule applies to, or all verbs if empty.
+	PathPrefix  string     // the paths the rule applies to, relative to the root. Whole segments are matched, so "/posts" covers "/posts/1" but not "/postscript".
+
+	Header      string     // a header to add to unconfirmed requests, if not empty.
+	HeaderValue string
+	Query       string     // a query parameter to add to unconfirmed requests, if not empty.
+	QueryValue  string
+	Block       bool       // refuse to send unconfirmed requests.
+}
+
+// returned by Do for requests blocked by a SafeModeRule.
+type UnconfirmedError struct {
+	Verb HttpVerb
+	Path string
+}
+
+func (this *UnconfirmedError) Error() string {
+	return fmt.Sprintf("safe mode: refusing to send unconfirmed %s %s (call Confirm() to send it)", this.Verb, this.Path)
+}
+
+// guards requests matching any of the rules. When more than one rule matches
+// a request, all of them are applied.
+//
+//	// ask the API for a dry run of any unconfirmed DELETE
+//	WithSafeMode(SafeModeRule{Verbs: []HttpVerb{DELETE}, Query: "dry_run", QueryValue: "true"})
+func WithSafeMode(rules ...SafeModeRule) Option {
+	return func(r *ReqtifierImpl) {
+		r.SafeMode = append(r.SafeMode, rules...)
+	}
+}
+
+func (this *SafeModeRule) matches(req *RequestImpl) (bool) {
+	if !underPath(safeModePath(req), this.PathPrefix) { return false }
+	if len(this.Verbs) == 0 { return true }
+	for _, v := range this.Verbs {
+		if v == req.Verb { return true }
+	}
+	return false
+}
+
+// returns the path req is sent to, relative to the root if it's under it.
+// Paths which are absolute URLs, like those from Follow, are resolved first,
+// as are dot-segments, which servers resolve, so that neither can slip past
+// a rule.
+func safeModePath(req *RequestImpl) (string) {
+	target, err := url.Parse(req.Target())
+	if err != nil { return req.URLPath }
+	p := cleanPath(target.Path)
+	root, err := url.Parse(req.ReqClient.Root)
+	if err != nil || root.Host != target.Host { return p }
+	prefix := strings.TrimSuffix(cleanPath(root.Path), "/")
+	if !underPath(p, prefix) { return p }
+	return p[len(prefix):]
+}
+
+// resolves the dot-segments in an absolute path, keeping a trailing slash.
+func cleanPath(p string) (string) {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" { cleaned += "/" }
+	return cleaned
+}
+
+// reports whether path is prefix, or is inside it, without splitting a
+// path segment.
+func underPath(path, prefix string) (bool) {
+	if !strings.HasPrefix(path, prefix) { return false }
+	return len(path) == len(prefix) || prefix == "" || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// applies safe mode rules to an unconfirmed request.
+func (this *ReqtifierImpl) applySafeMode(req *RequestImpl) (error) {
+	if req.Confirmed { return nil }
+	for _, rule := range this.SafeMode {
+		if !rule.matches(req) { continue }
+		if rule.Block {
+			return &UnconfirmedError{Verb: req.Verb, Path: req.URLPath}
+		}
+		if rule.Header != "" {
+			req.Header(rule.Header, rule.HeaderValue)
+		}
+		if rule.Query != "" {
+			req.QueryParams.Set(rule.Query, rule.QueryValue)
+		}
+	}
+	return nil
+}
+
+// confirms that this request should really be sent, exempting it from any
+// safe mode rules.
+func (this *RequestImpl) Confirm() (Request) {
+	this.Confirmed = true
+	return this
+}
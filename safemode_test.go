@@ -0,0 +1,57 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestSafeMode(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var last *http.Request
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		last = req
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithSafeMode(
+		SafeModeRule{Verbs: []HttpVerb{DELETE}, Query: "dry_run", QueryValue: "1", Header: "X-Dry-Run", HeaderValue: "yes"},
+		SafeModeRule{Verbs: []HttpVerb{POST}, PathPrefix: "/admin/", Block: true},
+	))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/posts/1").Method(DELETE).Do()
+	if last.URL.Query().Get("dry_run") != "1" || last.Header.Get("X-Dry-Run") != "yes" { t.Errorf("Dry Run Mismatch: got %s %v", last.URL, last.Header) }
+
+	reqt.New("/posts/1").Method(DELETE).Confirm().Do()
+	if last.URL.Query().Get("dry_run") != "" || last.Header.Get("X-Dry-Run") != "" { t.Errorf("Confirm Mismatch: got %s %v", last.URL, last.Header) }
+
+	_, err := reqt.New("/admin/purge").Method(POST).Do()
+	if _, ok := err.(*UnconfirmedError); !ok { t.Errorf("Block Mismatch: got %v, expected UnconfirmedError", err) }
+
+	_, err = reqt.New("/admin/purge").Method(POST).Confirm().Do()
+	if err != nil { t.Errorf("Confirm Failure: %s", err.Error()) }
+
+	// rules match whole path segments
+	_, err = reqt.New("/adminx").Method(POST).Do()
+	if err != nil { t.Errorf("Segment Mismatch: got %v, expected /adminx not to be blocked", err) }
+
+	// and apply to absolute URLs, like those from Follow
+	_, err = reqt.New("/posts").Follow("https://this.is.a.test/admin/purge").Method(POST).Do()
+	if _, ok := err.(*UnconfirmedError); !ok { t.Errorf("Follow Mismatch: got %v, expected UnconfirmedError", err) }
+
+	// and to paths with dot-segments, which the server resolves
+	_, err = reqt.New("/public/../admin/users/1").Method(POST).Do()
+	if _, ok := err.(*UnconfirmedError); !ok { t.Errorf("Dot-Segment Mismatch: got %v, expected UnconfirmedError", err) }
+	_, err = reqt.New("/admin/../public/1").Method(POST).Do()
+	if err != nil { t.Errorf("Dot-Segment Mismatch: got %v, expected /public/1 not to be blocked", err) }
+
+	sub := New("https://this.is.a.test/api/", nil, nil, nil, "test", WithSafeMode(SafeModeRule{PathPrefix: "/posts", Block: true}))
+	sub.(*ReqtifierImpl).HttpClient = &http_mock_client
+	_, err = sub.New("/").Follow("/api/posts/1").Do()
+	if _, ok := err.(*UnconfirmedError); !ok { t.Errorf("Root Mismatch: got %v, expected UnconfirmedError", err) }
+	_, err = sub.New("/").Follow("/postscript").Do()
+	if err != nil { t.Errorf("Root Mismatch: got %v, expected /postscript not to be blocked", err) }
+}
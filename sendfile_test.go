@@ -0,0 +1,69 @@
+package reqtify
+
+import (
+	"testing"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+)
+
+func writeTempFile(t testing.TB, size int) (string) {
+	path := filepath.Join(t.TempDir(), "body.bin")
+	if err := ioutil.WriteFile(path, bytes.Repeat([]byte("x"), size), 0600); err != nil { t.Fatal(err) }
+	return path
+}
+
+func fileBodyServer(lengths chan<- int64) (*httptest.Server) {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		if lengths != nil { lengths <- r.ContentLength; lengths <- n }
+	}))
+}
+
+func TestFileBody(t *testing.T) {
+	path := writeTempFile(t, 100000)
+	lengths := make(chan int64, 2)
+	server := fileBodyServer(lengths)
+	defer server.Close()
+
+	f, _ := os.Open(path)
+	defer f.Close()
+	f.Seek(1000, io.SeekStart)
+	_, err := New(server.URL, nil, nil, nil, "test").New("/").Method(PUT).Body(f, "application/octet-stream").Do()
+	if err != nil { t.Fatalf("Upload Failure: %s", err.Error()) }
+	if length, read := <- lengths, <- lengths; length != 99000 || read != 99000 {
+		t.Errorf("Length Mismatch: got Content-Length %d, body %d, expected 99000", length, read)
+	}
+}
+
+func benchmarkBody(b *testing.B, wrap func(*os.File) io.Reader) {
+	const size = 64 << 20
+	path := writeTempFile(b, size)
+	server := fileBodyServer(nil)
+	defer server.Close()
+	reqt := New(server.URL, nil, nil, nil, "test")
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f, _ := os.Open(path)
+		resp, err := reqt.New("/").Method(PUT).Body(wrap(f), "application/octet-stream").Do()
+		if err != nil { b.Fatal(err) }
+		resp.Body.Close()
+		f.Close()
+	}
+}
+
+// the file is passed to the transport directly, and can be sent with sendfile.
+func BenchmarkFileBody(b *testing.B) {
+	benchmarkBody(b, func(f *os.File) io.Reader { return f })
+}
+
+// the file is hidden behind another reader, and must be copied through userspace.
+func BenchmarkWrappedFileBody(b *testing.B) {
+	benchmarkBody(b, func(f *os.File) io.Reader { return struct{ io.Reader }{f} })
+}
@@ -0,0 +1,127 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"strings"
+)
+
+// the parts of a robots.txt file (RFC 9309) which matter for discovery: the
+// sitemaps it lists, and the paths each robot may fetch.
+type Robots struct {
+	Sitemaps []string
+	groups   []robotsGroup
+}
+
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parses a robots.txt file. Lines it doesn't understand are ignored.
+func ParseRobots(data []byte) (*Robots) {
+	var r Robots
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 { line = line[:i] }
+		key, value, ok := strings.Cut(line, ":")
+		if !ok { continue }
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "sitemap":
+			if value != "" { r.Sitemaps = append(r.Sitemaps, value) }
+		case "user-agent":
+			// consecutive user-agent lines share the group that follows them
+			if !inAgents {
+				r.groups = append(r.groups, robotsGroup{})
+				current = &r.groups[len(r.groups) - 1]
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			// an empty disallow allows everything, which is also the default
+			if current == nil || value == "" { continue }
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		default:
+			inAgents = false
+		}
+	}
+	return &r
+}
+
+// returns the rules which apply to agent: those of every group naming it, or
+// if there are none, those of the groups for all robots.
+func (this *Robots) rulesFor(agent string) ([]robotsRule) {
+	agent = strings.ToLower(agent)
+	var specific, general []robotsRule
+	for _, g := range this.groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				general = append(general, g.rules...)
+			} else if agent != "*" && a == agent {
+				specific = append(specific, g.rules...)
+			} else {
+				continue
+			}
+			break
+		}
+	}
+	if specific != nil { return specific }
+	return general
+}
+
+// reports whether agent may fetch path, which should include any query. The
+// most specific matching rule wins, and allow wins ties.
+func (this *Robots) Allowed(agent, path string) (bool) {
+	if path == "/robots.txt" { return true }
+
+	best, allowed := -1, true
+	for _, rule := range this.rulesFor(agent) {
+		if !robotsMatch(rule.pattern, path) { continue }
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allowed = len(rule.pattern), rule.allow
+		}
+	}
+	return allowed
+}
+
+// reports whether agent may fetch an absolute URL.
+func (this *Robots) AllowedURL(agent, location string) (bool) {
+	u, err := url.Parse(location)
+	if err != nil { return false }
+	return this.Allowed(agent, u.RequestURI())
+}
+
+// matches a robots.txt path pattern, in which * matches any sequence of
+// characters and a trailing $ anchors the end of the path.
+func robotsMatch(pattern, path string) (bool) {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored { pattern = pattern[:len(pattern) - 1] }
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) { return false }
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		last := i == len(parts) - 2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		j := strings.Index(rest, part)
+		if j < 0 { return false }
+		rest = rest[j + len(part):]
+	}
+	return !anchored || rest == ""
+}
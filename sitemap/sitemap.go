@@ -0,0 +1,263 @@
+// Package sitemap discovers the pages of a site through reqtify: from the
+// Sitemap lines in its robots.txt, through any sitemap indexes, down to the
+// URLs in each sitemap, skipping those robots.txt disallows.
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+// the largest sitemap the protocol allows, after decompression.
+const MaxSize = 50 * 1024 * 1024
+
+// a page listed in a sitemap, or a sitemap listed in a sitemap index.
+type URL struct {
+	Loc        string
+	LastMod    time.Time // zero if the sitemap doesn't say.
+	ChangeFreq string
+	Priority   float64   // zero if the sitemap doesn't say.
+	Sitemap    string    // the sitemap which listed it.
+}
+
+// the contents of one sitemap file. Sitemap indexes list other sitemaps
+// rather than pages.
+type Sitemap struct {
+	URLs     []URL
+	Sitemaps []URL
+}
+
+type xmlSitemap struct {
+	XMLName  xml.Name
+	URLs     []xmlURL `xml:"url"`
+	Sitemaps []xmlURL `xml:"sitemap"`
+}
+
+type xmlURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+func (this xmlURL) url(sitemap string) (URL) {
+	u := URL{
+		Loc: strings.TrimSpace(this.Loc),
+		LastMod: parseLastMod(this.LastMod),
+		ChangeFreq: strings.TrimSpace(this.ChangeFreq),
+		Sitemap: sitemap,
+	}
+	u.Priority, _ = strconv.ParseFloat(strings.TrimSpace(this.Priority), 64)
+	return u
+}
+
+// lastmod is a W3C datetime, which may be as coarse as a year.
+var lastModFormats = []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"}
+
+func parseLastMod(s string) (time.Time) {
+	s = strings.TrimSpace(s)
+	for _, format := range lastModFormats {
+		if t, err := time.Parse(format, s); err == nil { return t }
+	}
+	return time.Time{}
+}
+
+// decodes a sitemap or sitemap index, in XML or plain text, and gzipped or
+// not. location is recorded as the Sitemap of each URL.
+func Parse(data []byte, location string) (*Sitemap, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		// .xml.gz files are usually served as the gzip file itself, rather
+		// than with a Content-Encoding which reqtify would have decoded
+		z, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil { return nil, err }
+		data, err = io.ReadAll(io.LimitReader(z, MaxSize + 1))
+		if err != nil { return nil, err }
+	}
+	if len(data) > MaxSize { return nil, fmt.Errorf("sitemap: %s is larger than %d bytes", location, MaxSize) }
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) != 0 && trimmed[0] != '<' {
+		return parseText(trimmed, location), nil
+	}
+
+	var doc xmlSitemap
+	err := reqtify.FromXMLWith(&doc, reqtify.XMLOptions{Lenient: true, IgnoreNamespaces: true}).Unmarshal(data)
+	if err != nil { return nil, err }
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("sitemap: %s is not a sitemap", location)
+	}
+
+	var s Sitemap
+	for _, u := range doc.URLs {
+		if u.Loc != "" { s.URLs = append(s.URLs, u.url(location)) }
+	}
+	for _, u := range doc.Sitemaps {
+		if u.Loc != "" { s.Sitemaps = append(s.Sitemaps, u.url(location)) }
+	}
+	return &s, nil
+}
+
+// text sitemaps list one URL per line.
+func parseText(data []byte, location string) (*Sitemap) {
+	var s Sitemap
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, MaxSize)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			s.URLs = append(s.URLs, URL{Loc: line, Sitemap: location})
+		}
+	}
+	return &s
+}
+
+// a Discoverer walks sitemaps.
+type Discoverer struct {
+	Reqtifier reqtify.Reqtifier
+
+	// the product token matched against robots.txt user-agent lines.
+	// Defaults to "*", which only matches rules for all robots.
+	UserAgent string
+
+	// skips sitemaps and pages last modified before this time. Pages
+	// without a lastmod are never skipped.
+	Since     time.Time
+
+	// how deeply sitemap indexes may nest. Defaults to 3.
+	MaxDepth  int
+}
+
+func NewDiscoverer(r reqtify.Reqtifier, agent string) (*Discoverer) {
+	return &Discoverer{Reqtifier: r, UserAgent: agent}
+}
+
+func (this *Discoverer) fetch(ctx context.Context, location string) ([]byte, error) {
+	var body []byte
+	resp, err := this.Reqtifier.New(location).Method(reqtify.GET).Context(ctx).BytesInto(&body).Do()
+	if err != nil { return nil, err }
+	if resp.StatusCode != http.StatusOK {
+		return nil, &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status}
+	}
+	return body, nil
+}
+
+// fetches and parses a single sitemap.
+func (this *Discoverer) Fetch(ctx context.Context, location string) (*Sitemap, error) {
+	body, err := this.fetch(ctx, location)
+	if err != nil { return nil, fmt.Errorf("sitemap: fetching %s: %w", location, err) }
+	return Parse(body, location)
+}
+
+// fetches the robots.txt for the site which serves base. A missing
+// robots.txt allows everything.
+func (this *Discoverer) Robots(ctx context.Context, base string) (*Robots, error) {
+	u, err := url.Parse(base)
+	if err != nil { return nil, err }
+	location := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}).String()
+
+	var body []byte
+	resp, err := this.Reqtifier.New(location).Method(reqtify.GET).Context(ctx).BytesInto(&body).Do()
+	if err != nil { return nil, fmt.Errorf("sitemap: fetching %s: %w", location, err) }
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return &Robots{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap: fetching %s: %w", location, &reqtify.ResponseError{StatusCode: resp.StatusCode, StatusText: resp.Status})
+	}
+	return ParseRobots(body), nil
+}
+
+// yields the pages listed in the given sitemaps, following sitemap indexes.
+// Failures to fetch or parse a sitemap are yielded as errors; iteration
+// continues with the next sitemap unless the caller stops it.
+func (this *Discoverer) URLs(ctx context.Context, sitemaps ...string) (iter.Seq2[URL, error]) {
+	return func(yield func(URL, error) bool) {
+		this.walk(ctx, sitemaps, nil, yield)
+	}
+}
+
+// yields the pages of the site which serves base, from the sitemaps in its
+// robots.txt, or /sitemap.xml if it doesn't list any, leaving out the ones
+// robots.txt disallows.
+func (this *Discoverer) Discover(ctx context.Context, base string) (iter.Seq2[URL, error]) {
+	return func(yield func(URL, error) bool) {
+		robots, err := this.Robots(ctx, base)
+		if err != nil {
+			yield(URL{}, err)
+			return
+		}
+
+		sitemaps := robots.Sitemaps
+		if len(sitemaps) == 0 {
+			u, err := url.Parse(base)
+			if err != nil {
+				yield(URL{}, err)
+				return
+			}
+			sitemaps = []string{(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/sitemap.xml"}).String()}
+		}
+		this.walk(ctx, sitemaps, robots, yield)
+	}
+}
+
+type pending struct {
+	location string
+	depth    int
+}
+
+func (this *Discoverer) walk(ctx context.Context, sitemaps []string, robots *Robots, yield func(URL, error) bool) {
+	maxDepth := this.MaxDepth
+	if maxDepth <= 0 { maxDepth = 3 }
+	agent := this.UserAgent
+	if agent == "" { agent = "*" }
+
+	var queue []pending
+	for _, s := range sitemaps {
+		queue = append(queue, pending{location: s})
+	}
+
+	// indexes sometimes list each other, or themselves
+	visited := make(map[string]bool)
+	for len(queue) != 0 {
+		if ctx.Err() != nil {
+			yield(URL{}, ctx.Err())
+			return
+		}
+
+		next := queue[0]
+		queue = queue[1:]
+		if visited[next.location] { continue }
+		visited[next.location] = true
+
+		s, err := this.Fetch(ctx, next.location)
+		if err != nil {
+			if !yield(URL{}, err) { return }
+			continue
+		}
+
+		for _, child := range s.Sitemaps {
+			if next.depth >= maxDepth || this.stale(child) { continue }
+			queue = append(queue, pending{location: child.Loc, depth: next.depth + 1})
+		}
+		for _, u := range s.URLs {
+			if this.stale(u) || (robots != nil && !robots.AllowedURL(agent, u.Loc)) { continue }
+			if !yield(u, nil) { return }
+		}
+	}
+}
+
+func (this *Discoverer) stale(u URL) (bool) {
+	return !this.Since.IsZero() && !u.LastMod.IsZero() && u.LastMod.Before(this.Since)
+}
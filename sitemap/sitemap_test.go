@@ -0,0 +1,111 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/thewug/reqtify"
+)
+
+func TestRobots(t *testing.T) {
+	robots := ParseRobots([]byte(`
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: ExampleBot
+User-agent: otherbot
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`))
+
+	if len(robots.Sitemaps) != 1 || robots.Sitemaps[0] != "https://example.com/sitemap.xml" { t.Errorf("Sitemap Mismatch: got %q", robots.Sitemaps) }
+	cases := []struct {
+		agent, path string
+		allowed     bool
+	}{
+		{"mybot", "/", true},
+		{"mybot", "/private/x", false},
+		{"mybot", "/private/public/x", true},
+		{"mybot", "/files/a.pdf", false},
+		{"mybot", "/files/a.pdf?download", true},
+		{"examplebot", "/anything", false},
+		{"OtherBot", "/anything", false},
+		{"examplebot", "/robots.txt", true},
+	}
+	for _, c := range cases {
+		if got := robots.Allowed(c.agent, c.path); got != c.allowed { t.Errorf("Allowed Mismatch: %s %s got %t", c.agent, c.path, got) }
+	}
+	if (&Robots{}).Allowed("*", "/x") != true { t.Errorf("Default Mismatch: empty robots.txt disallows") }
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(`<?xml version="1.0"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/a</loc><lastmod>2024-01-02</lastmod><changefreq>daily</changefreq><priority>0.8</priority></url>
+	<url><loc> https://example.com/b </loc></url>
+</urlset>`), "x")
+	if err != nil || len(s.URLs) != 2 || s.URLs[0].LastMod.Day() != 2 || s.URLs[0].Priority != 0.8 || s.URLs[1].Loc != "https://example.com/b" || s.URLs[1].Sitemap != "x" {
+		t.Errorf("Urlset Mismatch: got %+v, %v", s, err)
+	}
+
+	s, err = Parse([]byte("https://example.com/a\n\nhttps://example.com/b\n"), "x")
+	if err != nil || len(s.URLs) != 2 { t.Errorf("Text Mismatch: got %+v, %v", s, err) }
+
+	if _, err = Parse([]byte(`<html/>`), "x"); err == nil { t.Errorf("Format Mismatch: html accepted") }
+}
+
+func TestDiscover(t *testing.T) {
+	var zipped bytes.Buffer
+	z := gzip.NewWriter(&zipped)
+	z.Write([]byte(`<urlset><url><loc>https://example.com/new</loc><lastmod>2024-06-01</lastmod></url><url><loc>https://example.com/private/secret</loc></url><url><loc>https://example.com/old</loc><lastmod>2020-01-01</lastmod></url></urlset>`))
+	z.Close()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\nSitemap: " + server.URL + "/index.xml\n"))
+		case "/index.xml":
+			w.Write([]byte(`<sitemapindex>
+				<sitemap><loc>` + server.URL + `/pages.xml.gz</loc></sitemap>
+				<sitemap><loc>` + server.URL + `/index.xml</loc></sitemap>
+				<sitemap><loc>` + server.URL + `/missing.xml</loc></sitemap>
+				<sitemap><loc>` + server.URL + `/ancient.xml</loc><lastmod>2019-01-01</lastmod></sitemap>
+			</sitemapindex>`))
+		case "/pages.xml.gz":
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(zipped.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	d := NewDiscoverer(reqtify.New("", nil, nil, nil, "test"), "testbot")
+	d.Since = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var found []string
+	var errs int
+	for u, err := range d.Discover(context.Background(), server.URL + "/some/page") {
+		if err != nil {
+			errs++
+			continue
+		}
+		found = append(found, u.Loc)
+	}
+	if len(found) != 1 || found[0] != "https://example.com/new" || errs != 1 { t.Errorf("Discover Mismatch: got %q with %d errors", found, errs) }
+
+	// stopping early
+	for range d.URLs(context.Background(), server.URL + "/pages.xml.gz", server.URL + "/missing.xml") {
+		break
+	}
+}
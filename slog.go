@@ -0,0 +1,110 @@
+package reqtify
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+   Structured logging with log/slog. Each attempt at a request is logged as
+   one record, once its response body has been read or closed, with these
+   attributes:
+
+	method    the HTTP method
+	url       the URL, masked by the Reqtifier's Redactor
+	status    the response's status code, if there is a response
+	duration  the time from sending the request to finishing the body
+	bytes     the number of response body bytes read
+	attempt   which attempt this was, counting from 1
+	error     why the attempt failed, if it did
+
+   Attempts which fail or get a 5xx status are logged at LevelWarn, and
+   others at LevelInfo.
+*/
+
+// logs each attempt at a request to handler. See SlogMiddleware.
+func WithSlog(handler slog.Handler) Option {
+	return WithMiddleware(SlogMiddleware(slog.New(handler)))
+}
+
+// returns middleware which logs each attempt at a request to logger.
+func SlogMiddleware(logger *slog.Logger) (Middleware) {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			entry := &slogEntry{
+				logger: logger,
+				ctx: r.Context(),
+				start: time.Now(),
+				attrs: []slog.Attr{
+					slog.String("method", r.Method),
+					slog.String("url", requestRedactor(r).URL(r.URL.String())),
+				},
+			}
+
+			resp, err := next(r)
+			if err != nil {
+				entry.attrs = append(entry.attrs, slog.String("error", err.Error()))
+				entry.level = slog.LevelWarn
+				entry.log()
+				return resp, err
+			}
+
+			entry.attrs = append(entry.attrs, slog.Int("status", resp.StatusCode))
+			if resp.StatusCode >= 500 { entry.level = slog.LevelWarn }
+			if resp.Body == nil {
+				entry.log()
+				return resp, err
+			}
+			resp.Body = &slogBody{ReadCloser: resp.Body, entry: entry}
+			return resp, err
+		}
+	}
+}
+
+type slogEntry struct {
+	logger *slog.Logger
+	ctx    context.Context
+	start  time.Time
+	level  slog.Level
+	attrs  []slog.Attr
+	bytes  int64
+	once   sync.Once
+}
+
+func (this *slogEntry) log() {
+	this.once.Do(func() {
+		attrs := append(this.attrs,
+			slog.Duration("duration", time.Since(this.start)),
+			slog.Int64("bytes", this.bytes),
+			slog.Int("attempt", AttemptFromContext(this.ctx)),
+		)
+		this.logger.LogAttrs(this.ctx, this.level, "reqtify request", attrs...)
+	})
+}
+
+// counts a response body as it's read, and logs its entry once it's done.
+type slogBody struct {
+	io.ReadCloser
+	entry *slogEntry
+}
+
+func (this *slogBody) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+	this.entry.bytes += int64(n)
+	if err != nil && err != io.EOF {
+		this.entry.attrs = append(this.entry.attrs, slog.String("error", err.Error()))
+		this.entry.level = slog.LevelWarn
+	}
+	if err != nil { this.entry.log() }
+	return n, err
+}
+
+func (this *slogBody) Close() (error) {
+	err := this.ReadCloser.Close()
+	this.entry.log()
+	return err
+}
@@ -0,0 +1,43 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+func TestSlog(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader("busy"))}, nil }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("hello world"))}, nil
+	})
+
+	var b bytes.Buffer
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithSlog(slog.NewJSONHandler(&b, nil)))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	if _, err := reqt.New("/a").URLArg("q", 1).BasicAuthentication("u", "p").Retry(RetryPolicy{MaxAttempts: 2}).TextInto(&text).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+
+	var records []map[string]interface{}
+	d := json.NewDecoder(&b)
+	for d.More() {
+		var record map[string]interface{}
+		if err := d.Decode(&record); err != nil { t.Fatalf("Decode Failure: %s", err.Error()) }
+		records = append(records, record)
+	}
+	if len(records) != 2 { t.Fatalf("Records Mismatch: got %d, expected 2", len(records)) }
+	first, second := records[0], records[1]
+	if first["level"] != "WARN" || first["status"] != 503.0 || first["attempt"] != 1.0 || first["bytes"] != 4.0 { t.Errorf("First Mismatch: got %v", first) }
+	if second["level"] != "INFO" || second["status"] != 200.0 || second["attempt"] != 2.0 || second["bytes"] != 11.0 { t.Errorf("Second Mismatch: got %v", second) }
+	if second["method"] != "GET" || second["url"] != "https://this.is.a.test/a?q=1" || second["msg"] != "reqtify request" { t.Errorf("Request Mismatch: got %v", second) }
+	if _, ok := second["duration"]; !ok { t.Errorf("Duration Mismatch: got %v", second) }
+}
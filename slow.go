@@ -0,0 +1,24 @@
+package reqtify
+
+import (
+	"time"
+)
+
+// calls onSlow with any request which takes longer than threshold, including
+// retries and reading the response, to help diagnose APIs which degrade
+// gradually. If onSlow is nil, slow requests are logged at LogWarn instead.
+func WithSlowRequestThreshold(threshold time.Duration, onSlow func(Request, time.Duration)) Option {
+	return func(r *ReqtifierImpl) {
+		r.SlowThreshold = threshold
+		r.OnSlowRequest = onSlow
+	}
+}
+
+func (this *ReqtifierImpl) checkSlow(req *RequestImpl, elapsed time.Duration) {
+	if this.SlowThreshold <= 0 || elapsed <= this.SlowThreshold { return }
+	if this.OnSlowRequest == nil {
+		this.logf(LogWarn, "reqtify: %s: slow request, took %s", this.describe(req), elapsed)
+		return
+	}
+	this.OnSlowRequest(req, elapsed)
+}
@@ -0,0 +1,41 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/slow" { clock.Advance(3 * time.Second) }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	var slow []string
+	var took time.Duration
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithSlowRequestThreshold(2 * time.Second, func(req Request, d time.Duration) {
+		slow = append(slow, req.GetPath())
+		took = d
+	}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	reqt.New("/fast").Do()
+	reqt.New("/slow").Do()
+	if len(slow) != 1 || slow[0] != "/slow" { t.Errorf("Slow Mismatch: got %v, expected [/slow]", slow) }
+	if took != 3 * time.Second { t.Errorf("Duration Mismatch: got %s, expected 3s", took) }
+
+	// without a callback, slow requests are logged
+	var logged []string
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithSlowRequestThreshold(2 * time.Second, nil), WithLogger(LoggerFunc(func(level LogLevel, format string, args ...interface{}) {
+		if level == LogWarn { logged = append(logged, format) }
+	})))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	reqt.New("/slow").Do()
+	if len(logged) != 1 || !strings.Contains(logged[0], "slow request") { t.Errorf("Log Mismatch: got %v", logged) }
+}
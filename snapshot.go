@@ -0,0 +1,153 @@
+package reqtify
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+   Snapshots render a request as stable text, for comparing against a golden
+   file in tests (see test.Golden), so that wrapper libraries notice when a
+   change alters the requests they make:
+
+	POST https://api.example.com/posts?draft=1
+	Accept: application/json
+	Content-Type: application/json
+	User-Agent: MyBot/1.2
+
+	{
+	  "tags": [
+	    "cat"
+	  ],
+	  "title": "hello"
+	}
+
+   Headers are sorted, and bodies are canonicalized according to their type:
+   JSON is re-indented with its keys sorted, url encoded forms are written
+   one sorted parameter per line, multipart boundaries are replaced with a
+   fixed one and each part is rendered in the order it's sent, and gzipped
+   bodies are decompressed. Bodies which aren't text are written in base64.
+
+   The snapshot describes the request as HTTPRequest builds it, so headers
+   added by middleware or Reqtifier-wide settings don't appear in it.
+*/
+
+const snapshotBoundary = "BOUNDARY"
+
+// renders the request as a stable text snapshot. The body is resolved and
+// stored first, as with DebugPrint, so the request can still be sent.
+func (this *RequestImpl) Snapshot() ([]byte, error) {
+	if err := this.cacheBody(); err != nil { return nil, err }
+	r, err := this.HTTPRequest(context.Background())
+	if err != nil { return nil, err }
+	r.Header.Set("User-Agent", this.userAgent())
+
+	var body []byte
+	if r.Body != nil {
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil { return nil, err }
+	}
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		if z, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			if plain, err := ioutil.ReadAll(z); err == nil { body = plain }
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(r.Method + " " + r.URL.String() + "\n")
+	contentType := r.Header.Get("Content-Type")
+	if mediatype, params, err := mime.ParseMediaType(contentType); err == nil && params["boundary"] != "" {
+		// the boundary is random, so use a fixed one in its place
+		params["boundary"] = snapshotBoundary
+		r.Header.Set("Content-Type", mime.FormatMediaType(mediatype, params))
+	}
+	writeSnapshotHeaders(&b, r.Header)
+	if len(body) != 0 {
+		b.WriteString("\n")
+		writeSnapshotBody(&b, contentType, body)
+	}
+	return b.Bytes(), nil
+}
+
+func writeSnapshotHeaders(b *bytes.Buffer, header map[string][]string) {
+	for _, key := range sortedKeys(header) {
+		for _, value := range header[key] {
+			b.WriteString(key + ": " + value + "\n")
+		}
+	}
+}
+
+func writeSnapshotBody(b *bytes.Buffer, contentType string, body []byte) {
+	mediatype, params, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediatype == "application/json" || strings.HasSuffix(mediatype, "+json"):
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if d.Decode(&v) == nil {
+			if indented, err := json.MarshalIndent(v, "", "  "); err == nil {
+				b.Write(indented)
+				b.WriteString("\n")
+				return
+			}
+		}
+	case mediatype == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for _, key := range sortedKeys(values) {
+				for _, value := range values[key] {
+					b.WriteString(key + "=" + value + "\n")
+				}
+			}
+			return
+		}
+	case strings.HasPrefix(mediatype, "multipart/") && params["boundary"] != "":
+		// parts are rendered in the order they were sent, which is
+		// deterministic (see PartOrder), so golden files catch changes to it
+		var parts []string
+		r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		ok := true
+		for {
+			part, err := r.NextPart()
+			if err != nil {
+				ok = err == io.EOF
+				break
+			}
+			data, err := ioutil.ReadAll(part)
+			if err != nil {
+				ok = false
+				break
+			}
+			var text bytes.Buffer
+			text.WriteString("--" + snapshotBoundary + "\n")
+			writeSnapshotHeaders(&text, part.Header)
+			text.WriteString("\n")
+			writeSnapshotBody(&text, part.Header.Get("Content-Type"), data)
+			parts = append(parts, text.String())
+		}
+		if ok {
+			for _, part := range parts {
+				b.WriteString(part)
+			}
+			b.WriteString("--" + snapshotBoundary + "--\n")
+			return
+		}
+	}
+
+	if utf8.Valid(body) {
+		b.Write(body)
+		if !bytes.HasSuffix(body, []byte("\n")) { b.WriteString("\n") }
+		return
+	}
+	b.WriteString("base64:" + base64.StdEncoding.EncodeToString(body) + "\n")
+}
@@ -0,0 +1,41 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+type goldenReporter struct {
+	errors []string
+}
+
+func (this *goldenReporter) Helper() {}
+func (this *goldenReporter) Errorf(format string, args ...interface{}) { this.errors = append(this.errors, format) }
+
+func TestSnapshot(t *testing.T) {
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+
+	for name, req := range map[string]Request{
+		"json": reqt.New("/posts").Method(POST).URLArg("draft", 1).Header("X-Trace", "abc").JSONBody(map[string]interface{}{"title": "hello", "tags": []string{"cat"}, "n": 1.50}).CompressBody(),
+		"form": reqt.New("/posts").Method(PUT).FormArg("b", "2").FormArg("a", "1 & 2").Arg("a", "3"),
+		"multipart": reqt.New("/upload").Method(POST).FormArg("title", "photo").FileArg("file", "cat.bin", bytes.NewReader([]byte{0xff, 0x00, 0x01})).FileArg("notes", "notes.txt", strings.NewReader("meow")),
+		"partorder": reqt.New("/upload").Method(POST).FormArg("title", "photo").FileArg("file", "cat.txt", strings.NewReader("purr")).Part(FormPart{Name: "metadata", Header: http.Header{"Content-Type": {"application/json"}}, Data: strings.NewReader(`{"a":1}`)}).PartOrder("metadata"),
+		"get": reqt.New("/posts").URLArg("q", "cats").BasicAuthentication("user", "pass"),
+	} {
+		snapshot, err := req.Snapshot()
+		if err != nil { t.Errorf("Snapshot Failure: %s: %s", name, err.Error()); continue }
+		test.Golden(t, "testdata/snapshot_" + name + ".golden", snapshot)
+
+		// the body is kept, so it can be snapshotted again, or sent
+		again, err := req.Snapshot()
+		if err != nil || !bytes.Equal(snapshot, again) { t.Errorf("Repeat Mismatch: %s: got %s, %v", name, again, err) }
+	}
+
+	t.Setenv(test.UpdateGoldenEnv, "")
+	var r goldenReporter
+	snapshot, _ := reqt.New("/posts").Method(PUT).FormArg("a", "changed").Snapshot()
+	if test.Golden(&r, "testdata/snapshot_form.golden", snapshot) || len(r.errors) != 1 { t.Errorf("Golden Mismatch: got a match for a changed request") }
+}
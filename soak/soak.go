@@ -0,0 +1,158 @@
+/*
+   Package soak runs a request workload over and over while watching the
+   process for resource leaks: goroutines, open file descriptors, and heap.
+   Leaks in HTTP clients (unclosed bodies, forgotten tickers, connections
+   which are never reused) are invisible in a single request, but add up
+   quickly under sustained load:
+
+	report := soak.Run(func(i int) error {
+		resp, err := api.New("/status").Do()
+		if err != nil { return err }
+		return resp.Body.Close()
+	}, soak.Options{Iterations: 5000})
+	if !report.OK() {
+		log.Fatal(report)
+	}
+*/
+package soak
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	Iterations  int           // how many times to run the workload. Defaults to 1000.
+	Duration    time.Duration // if set, keep running until this much time has passed instead.
+	Warmup      int           // iterations to run before measuring the baseline. Defaults to 50.
+	SampleEvery int           // how often to sample resource usage. Defaults to every 100 iterations.
+
+	// how far above the baseline each resource may end up before it's
+	// considered a leak. They default to 10 goroutines, 10 file descriptors,
+	// and 16MB of heap.
+	GoroutineSlack int
+	FDSlack        int
+	HeapSlack      uint64
+
+	// how long to wait at the end for goroutines and connections to wind
+	// down before the final measurement. Defaults to 2 seconds.
+	Settle      time.Duration
+}
+
+// the resource usage of the process at some point during a run.
+type Sample struct {
+	Iteration  int
+	Goroutines int
+	FDs        int    // -1 if open file descriptors can't be counted on this platform.
+	HeapBytes  uint64 // live heap, measured after a garbage collection.
+}
+
+type Report struct {
+	Iterations int
+	Elapsed    time.Duration
+	Errors     int
+	FirstError error
+
+	Baseline   Sample
+	Samples    []Sample
+	Final      Sample
+
+	// a description of each resource which leaked.
+	Leaks      []string
+}
+
+// reports whether the run finished without leaks. Workload errors don't count.
+func (this Report) OK() (bool) {
+	return len(this.Leaks) == 0
+}
+
+func (this Report) String() (string) {
+	s := fmt.Sprintf("%d iterations in %s, %d errors", this.Iterations, this.Elapsed, this.Errors)
+	if len(this.Leaks) != 0 {
+		s += "; leaked " + strings.Join(this.Leaks, ", ")
+	}
+	return s
+}
+
+func countFDs() (int) {
+	entries, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil { return -1 }
+	return len(entries)
+}
+
+func sample(iteration int) (Sample) {
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return Sample{
+		Iteration: iteration,
+		Goroutines: runtime.NumGoroutine(),
+		FDs: countFDs(),
+		HeapBytes: mem.HeapAlloc,
+	}
+}
+
+func (this *Options) defaults() {
+	if this.Iterations <= 0 { this.Iterations = 1000 }
+	if this.Warmup <= 0 { this.Warmup = 50 }
+	if this.SampleEvery <= 0 { this.SampleEvery = 100 }
+	if this.GoroutineSlack <= 0 { this.GoroutineSlack = 10 }
+	if this.FDSlack <= 0 { this.FDSlack = 10 }
+	if this.HeapSlack == 0 { this.HeapSlack = 16 << 20 }
+	if this.Settle <= 0 { this.Settle = 2 * time.Second }
+}
+
+// runs workload repeatedly, and reports on the resources it leaked. The
+// workload is passed the iteration number, starting from 0 after warmup.
+func Run(workload func(i int) error, opts Options) (Report) {
+	opts.defaults()
+	var report Report
+
+	for i := 0; i < opts.Warmup; i++ {
+		workload(-1)
+	}
+	report.Baseline = sample(0)
+
+	start := time.Now()
+	for i := 0; ; i++ {
+		if opts.Duration > 0 {
+			if time.Since(start) >= opts.Duration { break }
+		} else if i >= opts.Iterations {
+			break
+		}
+
+		if err := workload(i); err != nil {
+			if report.Errors == 0 { report.FirstError = err }
+			report.Errors++
+		}
+		report.Iterations++
+		if (i + 1) % opts.SampleEvery == 0 {
+			report.Samples = append(report.Samples, sample(i + 1))
+		}
+	}
+	report.Elapsed = time.Since(start)
+
+	// give goroutines a chance to exit before deciding they've leaked
+	deadline := time.Now().Add(opts.Settle)
+	for {
+		report.Final = sample(report.Iterations)
+		if report.Final.Goroutines <= report.Baseline.Goroutines + opts.GoroutineSlack || time.Now().After(deadline) { break }
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if d := report.Final.Goroutines - report.Baseline.Goroutines; d > opts.GoroutineSlack {
+		report.Leaks = append(report.Leaks, fmt.Sprintf("%d goroutines", d))
+	}
+	if report.Baseline.FDs >= 0 {
+		if d := report.Final.FDs - report.Baseline.FDs; d > opts.FDSlack {
+			report.Leaks = append(report.Leaks, fmt.Sprintf("%d file descriptors", d))
+		}
+	}
+	if report.Final.HeapBytes > report.Baseline.HeapBytes + opts.HeapSlack {
+		report.Leaks = append(report.Leaks, fmt.Sprintf("%d bytes of heap", report.Final.HeapBytes - report.Baseline.HeapBytes))
+	}
+	return report
+}
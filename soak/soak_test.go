@@ -0,0 +1,47 @@
+package soak
+
+import (
+	"github.com/thewug/reqtify"
+
+	"testing"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+)
+
+func TestCleanWorkload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	api := reqtify.New(server.URL, nil, nil, nil, "soak")
+
+	report := Run(func(i int) error {
+		resp, err := api.New("/").Do()
+		if err != nil { return err }
+		io.Copy(ioutil.Discard, resp.Body)
+		return resp.Body.Close()
+	}, Options{Iterations: 300, Settle: 500 * time.Millisecond})
+	if !report.OK() || report.Errors != 0 { t.Errorf("Clean Mismatch: %s", report) }
+}
+
+func TestLeakyWorkload(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	var files []*os.File
+	defer func() {
+		for _, f := range files { f.Close() }
+	}()
+
+	report := Run(func(i int) error {
+		go func() { <- block }()
+		if f, err := os.Open(os.DevNull); err == nil { files = append(files, f) }
+		return nil
+	}, Options{Iterations: 50, Warmup: 1, SampleEvery: 10, Settle: 100 * time.Millisecond})
+
+	if report.OK() || len(report.Samples) != 5 { t.Fatalf("Leak Mismatch: got %s with %d samples", report, len(report.Samples)) }
+	if report.Baseline.FDs >= 0 && len(report.Leaks) != 2 { t.Errorf("Leak Mismatch: expected goroutine and fd leaks, got %q", report.Leaks) }
+}
@@ -0,0 +1,128 @@
+package reqtify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+/*
+   An overall timeout is a poor fit for streaming responses, which may
+   legitimately take hours, but it's the only thing that stops a server which
+   sends headers and then never sends the body. Stall timeouts limit how long
+   reqtify waits for any progress at all: for the response headers to arrive,
+   and for each read of the response body to return something.
+*/
+
+const (
+	StallHeaders = "headers"
+	StallBody    = "body"
+)
+
+// returned by Do, or by reads of a response body, when a server stops
+// responding for longer than a stall timeout.
+type StallError struct {
+	Phase string // StallHeaders or StallBody.
+	Limit time.Duration
+}
+
+func (this *StallError) Error() string {
+	if this.Phase == StallHeaders {
+		return fmt.Sprintf("reqtify: no response headers within %s", this.Limit)
+	}
+	return fmt.Sprintf("reqtify: response body stalled for more than %s", this.Limit)
+}
+
+// reports that this is a timeout, like net.Error.
+func (this *StallError) Timeout() bool {
+	return true
+}
+
+// sets stall timeouts for every request which doesn't override them: how long
+// to wait for response headers, and how long any single read of the response
+// body may wait for data. Zero means no limit.
+func WithStallTimeouts(header, idle time.Duration) Option {
+	return func(r *ReqtifierImpl) {
+		r.HeaderTimeout = header
+		r.IdleTimeout = idle
+	}
+}
+
+// limits how long to wait for response headers. Zero means no limit.
+func (this *RequestImpl) HeaderTimeout(d time.Duration) (Request) {
+	this.HeaderTimeoutOverride = &d
+	return this
+}
+
+// limits how long any single read of the response body may wait for data.
+// Zero means no limit.
+func (this *RequestImpl) IdleTimeout(d time.Duration) (Request) {
+	this.IdleTimeoutOverride = &d
+	return this
+}
+
+// cancels an attempt's context when the server stalls.
+type stallWatch struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	header  time.Duration
+	idle    time.Duration
+	clock   Clock
+	stop    func()
+}
+
+func newStallWatch(ctx context.Context, clock Clock, header, idle time.Duration) (context.Context, *stallWatch) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return ctx, &stallWatch{ctx: ctx, cancel: cancel, header: header, idle: idle, clock: clock}
+}
+
+func (this *stallWatch) expire(phase string, d time.Duration) (func()) {
+	return func() { this.cancel(&StallError{Phase: phase, Limit: d}) }
+}
+
+// starts waiting for response headers.
+func (this *stallWatch) start() {
+	if this == nil || this.header <= 0 { return }
+	this.stop = clockAfterFunc(this.clock, this.header, this.expire(StallHeaders, this.header))
+}
+
+// stops waiting for response headers.
+func (this *stallWatch) headers() {
+	if this == nil || this.stop == nil { return }
+	this.stop()
+	this.stop = nil
+}
+
+// replaces an error caused by a stall with a StallError.
+func (this *stallWatch) translate(err error) (error) {
+	if this == nil || err == nil { return err }
+	if stall, ok := context.Cause(this.ctx).(*StallError); ok { return stall }
+	return err
+}
+
+func (this *stallWatch) release() {
+	if this == nil { return }
+	this.headers()
+	this.cancel(nil)
+}
+
+func (this *stallWatch) body(body io.ReadCloser) (io.ReadCloser) {
+	if this == nil || this.idle <= 0 { return body }
+	return &stallReader{ReadCloser: body, watch: this}
+}
+
+type stallReader struct {
+	io.ReadCloser
+	watch *stallWatch
+}
+
+func (this *stallReader) Read(p []byte) (int, error) {
+	stop := clockAfterFunc(this.watch.clock, this.watch.idle, this.watch.expire(StallBody, this.watch.idle))
+	n, err := this.ReadCloser.Read(p)
+	stop()
+	if err != nil && err != io.EOF {
+		err = this.watch.translate(err)
+	}
+	return n, err
+}
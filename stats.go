@@ -0,0 +1,121 @@
+package reqtify
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+   Every Reqtifier keeps a few statistics about each of its endpoints, for
+   visibility without setting up metrics (see WithMetrics):
+
+	for endpoint, s := range api.Stats() {
+		log.Printf("%s: %d requests, %d errors, p99 %s", endpoint, s.Requests, s.Errors, s.P99)
+	}
+
+   Endpoints are named for their method and path template (see PathTemplate),
+   like "GET /posts/{id}". Latencies cover whole requests, including retries
+   and reading the response, over the last StatsWindow requests.
+
+   Requests without a path template are named for their path, so a client
+   which fetches many distinct paths would track each one separately. Once
+   StatsMaxEndpoints are tracked, requests to any other endpoint are counted
+   together as StatsOtherEndpoint instead.
+*/
+
+// how many of the most recent requests to each endpoint latency percentiles
+// are taken over.
+const StatsWindow = 256
+
+// how many endpoints are tracked separately.
+const StatsMaxEndpoints = 256
+
+// the name requests to endpoints beyond StatsMaxEndpoints are counted under.
+const StatsOtherEndpoint = "other"
+
+// statistics about requests to one endpoint.
+type EndpointStats struct {
+	Requests uint64         // requests made, however many attempts each took.
+	Errors   uint64         // requests which returned an error, including unexpected statuses.
+	Retries  uint64         // attempts after the first.
+	Statuses map[int]uint64 // requests, by the status of their final response.
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+type endpointStats struct {
+	EndpointStats
+	latencies []time.Duration // a ring of the most recent latencies, up to StatsWindow of them.
+	next      int
+}
+
+type statsTable struct {
+	lock      sync.Mutex
+	endpoints map[string]*endpointStats
+}
+
+func (this *statsTable) record(endpoint string, latency time.Duration, attempts int, resp *http.Response, err error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.endpoints == nil { this.endpoints = make(map[string]*endpointStats) }
+	s := this.endpoints[endpoint]
+	if s == nil && len(this.endpoints) >= StatsMaxEndpoints {
+		endpoint = StatsOtherEndpoint
+		s = this.endpoints[endpoint]
+	}
+	if s == nil {
+		s = &endpointStats{EndpointStats: EndpointStats{Statuses: make(map[int]uint64)}}
+		this.endpoints[endpoint] = s
+	}
+
+	s.Requests++
+	if err != nil { s.Errors++ }
+	if attempts > 1 { s.Retries += uint64(attempts - 1) }
+	if resp != nil { s.Statuses[resp.StatusCode]++ }
+	if len(s.latencies) < StatsWindow {
+		s.latencies = append(s.latencies, latency)
+	} else {
+		s.latencies[s.next % StatsWindow] = latency
+	}
+	s.next++
+}
+
+// returns a snapshot of the statistics for each endpoint the Reqtifier has
+// sent requests to.
+func (this *ReqtifierImpl) Stats() (map[string]EndpointStats) {
+	stats := make(map[string]EndpointStats)
+	if this == nil { return stats }
+	this.stats.lock.Lock()
+	defer this.stats.lock.Unlock()
+
+	for endpoint, s := range this.stats.endpoints {
+		snapshot := s.EndpointStats
+		snapshot.Statuses = make(map[int]uint64, len(s.Statuses))
+		for status, count := range s.Statuses {
+			snapshot.Statuses[status] = count
+		}
+
+		n := len(s.latencies)
+		latencies := append([]time.Duration(nil), s.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		snapshot.P50 = percentile(latencies, 50)
+		snapshot.P90 = percentile(latencies, 90)
+		snapshot.P99 = percentile(latencies, 99)
+		if n != 0 { snapshot.Max = latencies[n - 1] }
+		stats[endpoint] = snapshot
+	}
+	return stats
+}
+
+// returns the nearest-rank percentile p of sorted.
+func percentile(sorted []time.Duration, p int) (time.Duration) {
+	if len(sorted) == 0 { return 0 }
+	rank := (p * len(sorted) + 99) / 100
+	if rank < 1 { rank = 1 }
+	return sorted[rank - 1]
+}
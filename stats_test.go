@@ -0,0 +1,66 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		if strings.HasSuffix(req.URL.Path, "/404") { return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(strings.NewReader(""))}, nil }
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	if _, err := reqt.New("/posts/1").PathTemplate("/posts/{id}").Retry(RetryPolicy{MaxAttempts: 2}).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	reqt.New("/posts/2").PathTemplate("/posts/{id}").Do()
+	reqt.New("/posts/404").PathTemplate("/posts/{id}").ExpectStatus(200).Do()
+	reqt.New("/users").Method(POST).Do()
+
+	stats := reqt.Stats()
+	if len(stats) != 2 { t.Fatalf("Endpoints Mismatch: got %v", stats) }
+	posts := stats["GET /posts/{id}"]
+	if posts.Requests != 3 || posts.Errors != 1 || posts.Retries != 1 { t.Errorf("Counters Mismatch: got %+v", posts) }
+	if posts.Statuses[200] != 2 || posts.Statuses[404] != 1 || len(posts.Statuses) != 2 { t.Errorf("Statuses Mismatch: got %v", posts.Statuses) }
+	if posts.P50 > posts.P90 || posts.P90 > posts.P99 || posts.P99 != posts.Max { t.Errorf("Percentiles Mismatch: got %+v", posts) }
+	if users := stats["POST /users"]; users.Requests != 1 || users.Errors != 0 { t.Errorf("Users Mismatch: got %+v", users) }
+
+	// snapshots are independent
+	posts.Statuses[200] = 100
+	if reqt.Stats()["GET /posts/{id}"].Statuses[200] != 2 { t.Errorf("Snapshot Mismatch: got %v", reqt.Stats()["GET /posts/{id}"].Statuses) }
+}
+
+func TestStatsEndpointLimit(t *testing.T) {
+	reqt := &ReqtifierImpl{}
+	for i := 0; i < StatsMaxEndpoints + 10; i++ {
+		reqt.stats.record("GET /posts/" + strconv.Itoa(i), time.Millisecond, 1, nil, nil)
+	}
+	reqt.stats.record("GET /posts/0", time.Millisecond, 1, nil, nil)
+
+	stats := reqt.Stats()
+	if len(stats) != StatsMaxEndpoints + 1 { t.Errorf("Endpoints Mismatch: got %d, expected %d", len(stats), StatsMaxEndpoints + 1) }
+	if other := stats[StatsOtherEndpoint]; other.Requests != 10 || other.Max != time.Millisecond { t.Errorf("Other Mismatch: got %+v", other) }
+	if first := stats["GET /posts/0"]; first.Requests != 2 { t.Errorf("Tracked Mismatch: got %+v", first) }
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i) * time.Millisecond)
+	}
+	if p := percentile(latencies, 50); p != 100 * time.Millisecond { t.Errorf("P50 Mismatch: got %s", p) }
+	if p := percentile(latencies, 99); p != 198 * time.Millisecond { t.Errorf("P99 Mismatch: got %s", p) }
+	if p := percentile(latencies[:1], 99); p != time.Millisecond { t.Errorf("Single Mismatch: got %s", p) }
+	if p := percentile(nil, 50); p != 0 { t.Errorf("Empty Mismatch: got %s", p) }
+}
@@ -0,0 +1,88 @@
+package reqtify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// an unmarshaller used instead of the usual ones when the response status
+// falls within [Low, High]. See RequestImpl.IntoForStatus.
+type StatusUnmarshaller struct {
+	Low, High    int
+	Unmarshaller ResponseUnmarshaller
+
+	// attached to the ResponseError returned for 4xx and 5xx responses.
+	Value        interface{}
+}
+
+// parses a status range, which is a single status ("404"), a class ("4xx"),
+// or an inclusive range of either ("400-499", "4xx-5xx").
+func parseStatusRange(spec string) (int, int, error) {
+	spec = strings.TrimSpace(spec)
+	first, last := spec, spec
+	if i := strings.IndexByte(spec, '-'); i >= 0 {
+		first, last = strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+	}
+
+	low, _, err := parseStatus(first)
+	if err != nil { return 0, 0, fmt.Errorf("bad status range %q", spec) }
+	_, high, err := parseStatus(last)
+	if err != nil || high < low { return 0, 0, fmt.Errorf("bad status range %q", spec) }
+	return low, high, nil
+}
+
+func parseStatus(s string) (int, int, error) {
+	if len(s) == 3 && strings.ToLower(s[1:]) == "xx" {
+		class, err := strconv.Atoi(s[:1])
+		if err != nil || class < 1 { return 0, 0, strconv.ErrSyntax }
+		return class * 100, class * 100 + 99, nil
+	}
+	status, err := strconv.Atoi(s)
+	if err != nil || status < 100 || status > 999 { return 0, 0, strconv.ErrSyntax }
+	return status, status, nil
+}
+
+// unmarshals the response with into, instead of the usual unmarshallers,
+// when its status falls within rangeSpec, such as "404", "4xx" or "400-599".
+// If the status is 400 or above, Do also returns a *ResponseError whose Body
+// is into.
+func (this *RequestImpl) IntoForStatus(rangeSpec string, into ResponseUnmarshaller) (Request) {
+	return this.intoForStatus(rangeSpec, into, into)
+}
+
+// decodes 4xx and 5xx responses as JSON into the given value, which is then
+// attached to the *ResponseError returned by Do. APIs commonly send an error
+// envelope with a different shape than a successful response.
+func (this *RequestImpl) ErrorJSONInto(into interface{}) (Request) {
+	return this.intoForStatus("4xx-5xx", JSONUnmarshaller{output_value: into, codec: this.jsonCodec()}, into)
+}
+
+func (this *RequestImpl) intoForStatus(rangeSpec string, into ResponseUnmarshaller, value interface{}) (Request) {
+	low, high, err := parseStatusRange(rangeSpec)
+	if err != nil {
+		this.setBuildError(err)
+		return this
+	}
+	this.StatusResponse = append(this.StatusResponse, StatusUnmarshaller{Low: low, High: high, Unmarshaller: into, Value: value})
+	return this
+}
+
+// returns the unmarshallers to use for a response with the given status, and
+// the value to attach to the error for it, if any. Unmarshallers registered
+// for the status replace the ones added with Into and friends.
+func (this *RequestImpl) UnmarshallersFor(status int) ([]ResponseUnmarshaller, interface{}) {
+	var matched []ResponseUnmarshaller
+	var value interface{}
+	for _, s := range this.StatusResponse {
+		if status < s.Low || status > s.High { continue }
+		matched = append(matched, s.Unmarshaller)
+		if value == nil { value = s.Value }
+	}
+	if matched == nil && this.GraphQLQuery != "" {
+		return []ResponseUnmarshaller{GraphQLUnmarshaller{Unmarshallers: this.Response, StatusCode: status, codec: this.jsonCodec()}}, nil
+	}
+	if matched == nil { return this.Response, nil }
+	if status < 400 { value = nil }
+	return matched, value
+}
@@ -0,0 +1,79 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"errors"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestStatusUnmarshalling(t *testing.T) {
+	status := 200
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		body := `{"name":"alice"}`
+		if status >= 400 { body = `{"code":"not_found","message":"no such user"}` }
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var user struct{ Name string `json:"name"` }
+	var apiErr apiError
+	_, err := reqt.New("/user").JSONInto(&user).ErrorJSONInto(&apiErr).Do()
+	if err != nil || user.Name != "alice" || apiErr.Code != "" { t.Errorf("Success Mismatch: got %+v, %+v, %v", user, apiErr, err) }
+
+	status = 404
+	user.Name = ""
+	resp, err := reqt.New("/user").JSONInto(&user).ErrorJSONInto(&apiErr).Do()
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 404 || respErr.Body != &apiErr { t.Fatalf("Error Mismatch: got %#v", err) }
+	if resp == nil || user.Name != "" || apiErr.Code != "not_found" { t.Errorf("Body Mismatch: got %+v, %+v", user, apiErr) }
+
+	var text string
+	_, err = reqt.New("/user").IntoForStatus("404", FromText(&text)).Do()
+	if !errors.As(err, &respErr) || text != `{"code":"not_found","message":"no such user"}` { t.Errorf("Range Mismatch: got %q, %v", text, err) }
+
+	_, err = reqt.New("/user").IntoForStatus("5xx", FromText(&text)).Do()
+	if err != nil { t.Errorf("Unmatched Mismatch: got %v", err) }
+
+	for _, spec := range []string{"404", "4xx", "400-499", "4xx-5xx", " 404 "} {
+		if _, _, err := parseStatusRange(spec); err != nil { t.Errorf("Parse Mismatch: %q rejected: %v", spec, err) }
+	}
+	for _, spec := range []string{"", "4x", "xxx", "0xx", "500-400", "abc", "1000"} {
+		if _, _, err := parseStatusRange(spec); err == nil { t.Errorf("Parse Mismatch: %q accepted", spec) }
+	}
+	if _, err = reqt.New("/user").IntoForStatus("4", FromText(&text)).Do(); err == nil { t.Errorf("Build Mismatch: bad range accepted") }
+}
+
+func TestExpectStatus(t *testing.T) {
+	status := 200
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Status: "404 Not Found", Body: ioutil.NopCloser(strings.NewReader(`{"error": "` + strings.Repeat("x", 600) + `"}`))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var v map[string]string
+	if _, err := reqt.New("/").ExpectStatus(200, 201).JSONInto(&v).Do(); err != nil || len(v["error"]) != 600 { t.Errorf("Expected Mismatch: got %v", err) }
+
+	status = 404
+	v = nil
+	resp, err := reqt.New("/").ExpectStatus(200, 201).ExpectStatusRange(300, 399).JSONInto(&v).Do()
+	var unexpected *UnexpectedStatusError
+	if !errors.As(err, &unexpected) || unexpected.StatusCode != 404 || len(unexpected.Excerpt) != 512 || v != nil { t.Fatalf("Unexpected Mismatch: got %v", err) }
+	if !strings.HasPrefix(err.Error(), `unexpected status 404 Not Found (expected 200, 201, 300-399): "{\"error\"`) { t.Errorf("Message Mismatch: got %s", err.Error()) }
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != 404 { t.Errorf("Unwrap Mismatch: got %v", respErr) }
+	if body, _ := ioutil.ReadAll(resp.Body); len(body) != 613 { t.Errorf("Body Mismatch: %d bytes left", len(body)) }
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+   FakeClock is a reqtify.Clock whose time only moves when told to, so that
+   retries, rate limits and cache lifetimes can be tested without sleeping:
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	api := reqtify.New(root, nil, nil, nil, "test", reqtify.WithClock(clock))
+	go api.New("/flaky").Retry(reqtify.RetryPolicy{MaxAttempts: 3, Backoff: time.Minute}).Do()
+	clock.BlockUntil(1)        // wait for the first attempt to fail and back off
+	clock.Advance(time.Minute) // and let the retry go ahead
+
+   Tickers it creates can't be stopped, since time.Ticker's Stop can't be
+   intercepted, but they never fire unless the clock is advanced.
+*/
+type FakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{} // closed and replaced whenever waiters changes.
+}
+
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration // zero for one-shot waits.
+	c      chan time.Time
+}
+
+// returns a FakeClock showing start.
+func NewFakeClock(start time.Time) (*FakeClock) {
+	return &FakeClock{now: start, changed: make(chan struct{})}
+}
+
+func (this *FakeClock) Now() (time.Time) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.now
+}
+
+// returns a channel which receives the time once the clock is advanced by
+// d. If d isn't positive, it receives the time right away.
+func (this *FakeClock) After(d time.Duration) (<-chan time.Time) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	c := make(chan time.Time, 1)
+	if d <= 0 {
+		c <- this.now
+		return c
+	}
+	this.add(&fakeWaiter{at: this.now.Add(d), c: c})
+	return c
+}
+
+// returns a ticker which ticks every d the clock is advanced by. Like a
+// real ticker, it drops ticks if nobody is reading them.
+func (this *FakeClock) NewTicker(d time.Duration) (*time.Ticker) {
+	if d <= 0 { panic("non-positive interval for FakeClock.NewTicker") }
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	c := make(chan time.Time, 1)
+	this.add(&fakeWaiter{at: this.now.Add(d), period: d, c: c})
+	return &time.Ticker{C: c}
+}
+
+// moves the clock forward by d, firing everything due on the way in order.
+func (this *FakeClock) Advance(d time.Duration) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	end := this.now.Add(d)
+	for len(this.waiters) != 0 && !this.waiters[0].at.After(end) {
+		w := this.waiters[0]
+		this.waiters = this.waiters[1:]
+		this.now = w.at
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period != 0 {
+			w.at = w.at.Add(w.period)
+			this.waiters = append(this.waiters, w)
+			this.sort()
+		}
+	}
+	this.now = end
+	this.notify()
+}
+
+// sets the clock to t, which may be earlier than its current time. Nothing
+// fires when it moves backwards.
+func (this *FakeClock) Set(t time.Time) {
+	this.lock.Lock()
+	now := this.now
+	this.lock.Unlock()
+	if t.After(now) {
+		this.Advance(t.Sub(now))
+		return
+	}
+	this.lock.Lock()
+	this.now = t
+	this.lock.Unlock()
+}
+
+// returns how many one-shot waits are pending, which is how many goroutines
+// are sleeping on the clock. Waits abandoned because a context was canceled
+// still count until the clock passes them.
+func (this *FakeClock) Waiters() (int) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.sleepers()
+}
+
+// waits until at least n goroutines are sleeping on the clock, so a test can
+// be sure something is waiting before advancing past it. Waiting on a
+// ticker doesn't count, since tickers are always waited on.
+func (this *FakeClock) BlockUntil(n int) {
+	for {
+		this.lock.Lock()
+		if this.sleepers() >= n {
+			this.lock.Unlock()
+			return
+		}
+		if this.changed == nil { this.changed = make(chan struct{}) }
+		changed := this.changed
+		this.lock.Unlock()
+		<- changed
+	}
+}
+
+func (this *FakeClock) sleepers() (int) {
+	n := 0
+	for _, w := range this.waiters {
+		if w.period == 0 { n++ }
+	}
+	return n
+}
+
+func (this *FakeClock) add(w *fakeWaiter) {
+	this.waiters = append(this.waiters, w)
+	this.sort()
+	this.notify()
+}
+
+func (this *FakeClock) sort() {
+	sort.SliceStable(this.waiters, func(i, j int) bool { return this.waiters[i].at.Before(this.waiters[j].at) })
+}
+
+func (this *FakeClock) notify() {
+	if this.changed != nil { close(this.changed) }
+	this.changed = make(chan struct{})
+}
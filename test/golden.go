@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// set this environment variable to rewrite golden files with what the tests
+// produce, instead of comparing against them:
+//
+//	REQTIFY_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "REQTIFY_UPDATE_GOLDEN"
+
+// the parts of *testing.T which Golden uses.
+type TestReporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// compares got with the contents of the golden file at path, failing t with
+// a line diff if they differ, and reports whether they matched. If
+// UpdateGoldenEnv is set, the file is written with got instead. Together
+// with Request.Snapshot, this catches accidental changes to the requests a
+// library makes:
+//
+//	snapshot, err := api.CreatePost("hello").Snapshot()
+//	test.Golden(t, "testdata/create_post.golden", snapshot)
+func Golden(t TestReporter, path string, got []byte) (bool) {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("updating golden file: %s", err.Error())
+			return false
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Errorf("updating golden file: %s", err.Error())
+			return false
+		}
+		return true
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("reading golden file (set %s=1 to create it): %s", UpdateGoldenEnv, err.Error())
+		return false
+	}
+	if bytes.Equal(got, want) { return true }
+	t.Errorf("%s differs (set %s=1 to update it):\n%s", path, UpdateGoldenEnv, diffLines(string(want), string(got)))
+	return false
+}
+
+// a minimal line diff of want and got, with removed lines prefixed by "-",
+// added ones by "+", and unchanged ones by " ".
+func diffLines(want, got string) (string) {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// lengths of the longest common subsequences of the suffixes of a and b
+	lcs := make([][]int, len(a) + 1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b) + 1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i + 1][j + 1] + 1
+			} else if lcs[i + 1][j] >= lcs[i][j + 1] {
+				lcs[i][j] = lcs[i + 1][j]
+			} else {
+				lcs[i][j] = lcs[i][j + 1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&out, " %s\n", a[i])
+			i, j = i + 1, j + 1
+		case i < len(a) && (j == len(b) || lcs[i + 1][j] >= lcs[i][j + 1]):
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
@@ -1,11 +1,16 @@
 package test
 
 import (
+	"bytes"
 	"net/http"
 	"io"
+	"io/ioutil"
 	"strings"
 	"errors"
 	"net/url"
+	"sync"
+
+	"github.com/thewug/reqtify/internal/exchange"
 )
 
 var ErrNoHandler error = errors.New("HttpClientMock received a request it was not expecting")
@@ -17,14 +22,31 @@ type requestResponseNode struct {
 	Response *http.Response
 	Error    error
 
+	requestBody  []byte
+	responseBody []byte
 	next *requestResponseNode
 }
 
+// a request a MockHttpClient recorded, and what it returned.
+type Exchange struct {
+	Request  *http.Request
+	Response *http.Response
+	Error    error
+}
+
+// MockHttpClient is safe for concurrent use, including swapping the
+// analyzer while requests are in flight.
 type MockHttpClient struct {
+	lock        sync.RWMutex
 	analyzeFunc HttpReqAnalyzer
+
+	recording   bool
+	first, last *requestResponseNode
 }
 
 func (this *MockHttpClient) AnalyzeWith(f HttpReqAnalyzer) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
 	this.analyzeFunc = f
 }
 
@@ -38,13 +60,20 @@ type MockHttpReqExaminer struct {
 	Responses chan<- ResponseAndError
 }
 
+// how many requests and responses an examiner's channels hold before
+// blocking.
+const ExaminerBuffer = exchange.Buffer
+
+// Examine routes requests to the returned examiner. Responses are matched to
+// requests in the order the requests were read from Requests, so concurrent
+// requests each get their own response even though they share the channels.
 func (this *MockHttpClient) Examine() MockHttpReqExaminer {
-	requestReader := make(chan *http.Request)
-	responseWriter := make(chan ResponseAndError)
+	requestReader := make(chan *http.Request, ExaminerBuffer)
+	responseWriter := make(chan ResponseAndError, ExaminerBuffer)
+	var waiting exchange.Queue[ResponseAndError]
 
 	this.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
-		requestReader <- req
-		response := <- responseWriter
+		response := <- waiting.Push(func() { requestReader <- req }, responseWriter)
 		return response.Response, response.Error
 	})
 
@@ -55,11 +84,100 @@ func (this *MockHttpClient) Examine() MockHttpReqExaminer {
 }
 
 func (this *MockHttpClient) Do(req *http.Request) (*http.Response, error) {
-	if this.analyzeFunc != nil {
-		return this.analyzeFunc(req)
+	this.lock.RLock()
+	analyze, recording := this.analyzeFunc, this.recording
+	this.lock.RUnlock()
+
+	var node *requestResponseNode
+	if recording {
+		node = &requestResponseNode{Request: req}
+		req.Body, node.requestBody = bufferBody(req.Body)
+	}
+
+	var resp *http.Response
+	err := ErrNoHandler
+	if analyze != nil {
+		resp, err = analyze(req)
+	}
+
+	if node != nil {
+		node.Response, node.Error = resp, err
+		if resp != nil {
+			resp.Body, node.responseBody = bufferBody(resp.Body)
+		}
+		this.lock.Lock()
+		if this.last == nil {
+			this.first = node
+		} else {
+			this.last.next = node
+		}
+		this.last = node
+		this.lock.Unlock()
+	}
+	return resp, err
+}
+
+// starts recording every request, and the response or error returned for it,
+// discarding anything recorded before. Bodies are read into memory as they
+// pass through, so they can be read again from what Recorded returns.
+func (this *MockHttpClient) Record() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.recording = true
+	this.first, this.last = nil, nil
+}
+
+// returns what's been recorded since Record was called, in the order the
+// requests completed. Each call returns fresh copies of the bodies.
+func (this *MockHttpClient) Recorded() ([]Exchange) {
+	this.lock.RLock()
+	first, last := this.first, this.last
+	this.lock.RUnlock()
+
+	var recorded []Exchange
+	for node := first; node != nil; node = node.next {
+		x := Exchange{Request: node.Request.Clone(node.Request.Context()), Error: node.Error}
+		if node.Request.Body != nil {
+			x.Request.Body = ioutil.NopCloser(bytes.NewReader(node.requestBody))
+		}
+		if node.Response != nil {
+			resp := *node.Response
+			if resp.Body != nil {
+				resp.Body = ioutil.NopCloser(bytes.NewReader(node.responseBody))
+			}
+			x.Response = &resp
+		}
+		recorded = append(recorded, x)
+		if node == last { break }
 	}
+	return recorded
+}
+
+// returns the most recently completed request which was recorded, or nil.
+func (this *MockHttpClient) LastRequest() (*http.Request) {
+	recorded := this.Recorded()
+	if len(recorded) == 0 { return nil }
+	return recorded[len(recorded) - 1].Request
+}
+
+// reads body into memory, returning a replacement for it and what it held.
+// An error reading it is returned again by the replacement, after the data.
+func bufferBody(body io.ReadCloser) (io.ReadCloser, []byte) {
+	if body == nil || body == http.NoBody { return body, nil }
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return ioutil.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err})), data
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), data
+}
+
+type errReader struct {
+	err error
+}
 
-	return nil, ErrNoHandler
+func (this errReader) Read(p []byte) (int, error) {
+	return 0, this.err
 }
 
 /*
@@ -0,0 +1,273 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+   Throwaway HTTP servers for integration tests, built from composable
+   behaviors. Each runs in-process on a loopback port, so retries, timeouts
+   and streaming can be tested against a real network connection without
+   any external services:
+
+	server := test.NewServer(test.FailFirst(2, http.StatusServiceUnavailable), test.Delay(10 * time.Millisecond))
+	defer server.Close()
+
+   Behaviors listed first see each request first. Requests which get past all
+   of them are answered by Echo, unless Respond is used.
+*/
+
+// a Behavior wraps the handling of a test server's requests.
+type Behavior func(next http.Handler) http.Handler
+
+// a request received by a test Server.
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+	At     time.Time
+}
+
+// the response body sent by Echo.
+type EchoResponse struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+type Server struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []RecordedRequest
+}
+
+// starts a server which applies behaviors to each request, and then echoes
+// it back.
+func NewServer(behaviors ...Behavior) (*Server) {
+	var handler http.Handler = http.HandlerFunc(echo)
+	for i := len(behaviors) - 1; i >= 0; i-- {
+		handler = behaviors[i](handler)
+	}
+
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s.lock.Lock()
+		s.requests = append(s.requests, RecordedRequest{Method: r.Method, URL: r.URL.String(), Header: r.Header.Clone(), Body: body, At: time.Now()})
+		s.lock.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// returns the requests the server has received so far.
+func (this *Server) Requests() ([]RecordedRequest) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return append([]RecordedRequest(nil), this.requests...)
+}
+
+// returns the number of requests the server has received so far.
+func (this *Server) Count() (int) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return len(this.requests)
+}
+
+func echo(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EchoResponse{
+		Method: r.Method,
+		Path: r.URL.Path,
+		Query: r.URL.RawQuery,
+		Header: r.Header,
+		Body: string(body),
+	})
+}
+
+// answers every request with the given status and body, instead of echoing it.
+// Behaviors listed after it are never reached.
+func Respond(status int, contentType string, body []byte) (Behavior) {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" { w.Header().Set("Content-Type", contentType) }
+			w.WriteHeader(status)
+			w.Write(body)
+		})
+	}
+}
+
+// adds a header to every response.
+func Header(key, value string) (Behavior) {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add(key, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// waits before handling each request, or until the client gives up.
+func Delay(d time.Duration) (Behavior) {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			select {
+			case <- t.C:
+				next.ServeHTTP(w, r)
+			case <- r.Context().Done():
+			}
+		})
+	}
+}
+
+// fails the first n requests with the given status.
+func FailFirst(n int, status int) (Behavior) {
+	var count int64
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt64(&count, 1) <= int64(n) {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// a source of randomness which can be shared by concurrent requests.
+type lockedRand struct {
+	lock sync.Mutex
+	rand *rand.Rand
+}
+
+func (this *lockedRand) chance(p float64) (bool) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.rand.Float64() < p
+}
+
+// fails a fraction p of requests with the given status. The failures are
+// chosen pseudo-randomly from seed, so a test sees the same ones every run.
+func FailRandomly(p float64, status int, seed int64) (Behavior) {
+	r := &lockedRand{rand: rand.New(rand.NewSource(seed))}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if r.chance(p) {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// closes the connection without responding to a fraction p of requests,
+// chosen pseudo-randomly from seed. Use 1 to drop every request.
+func Drop(p float64, seed int64) (Behavior) {
+	r := &lockedRand{rand: rand.New(rand.NewSource(seed))}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !r.chance(p) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
+
+// resets the connection without responding to a fraction p of requests,
+// chosen pseudo-randomly from seed, so the client sees "connection reset by
+// peer" rather than an orderly close, as with Drop.
+func Reset(p float64, seed int64) (Behavior) {
+	r := &lockedRand{rand: rand.New(rand.NewSource(seed))}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !r.chance(p) {
+				next.ServeHTTP(w, req)
+				return
+			}
+			if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+				if tcp, ok := conn.(*net.TCPConn); ok { tcp.SetLinger(0) }
+				conn.Close()
+			}
+		})
+	}
+}
+
+// sends only the first n bytes of each response body, though its
+// Content-Length promises all of it, and then closes the connection.
+func Truncate(n int) (Behavior) {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorded := httptest.NewRecorder()
+			next.ServeHTTP(recorded, r)
+
+			body := recorded.Body.Bytes()
+			for k, v := range recorded.Header() {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(recorded.Code)
+			if n < len(body) { body = body[:n] }
+			w.Write(body)
+			http.NewResponseController(w).Flush()
+			if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
+
+// sends response bodies chunk bytes at a time, waiting every between chunks,
+// to exercise streaming and idle timeouts.
+func Trickle(chunk int, every time.Duration) (Behavior) {
+	if chunk < 1 { chunk = 1 }
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorded := httptest.NewRecorder()
+			next.ServeHTTP(recorded, r)
+
+			for k, v := range recorded.Header() {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(recorded.Code)
+			rc := http.NewResponseController(w)
+			rc.Flush()
+
+			body := recorded.Body.Bytes()
+			for len(body) != 0 {
+				select {
+				case <- time.After(every):
+				case <- r.Context().Done():
+					return
+				}
+				n := chunk
+				if n > len(body) { n = len(body) }
+				w.Write(body[:n])
+				rc.Flush()
+				body = body[n:]
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package reqtify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limits the combined throughput of every reader sharing it to a fixed
+// number of bytes per second.
+type bandwidthLimiter struct {
+	lock  sync.Mutex
+	rate  int64
+	next  time.Time
+}
+
+func newBandwidthLimiter(rate int64) (*bandwidthLimiter) {
+	if rate <= 0 { return nil }
+	return &bandwidthLimiter{rate: rate}
+}
+
+// the largest read performed at once, so that the limit is applied smoothly
+// rather than in bursts of whatever size the caller's buffer happens to be.
+func (this *bandwidthLimiter) chunk() (int) {
+	c := this.rate / 10
+	if c < 1 { return 1 }
+	return int(c)
+}
+
+// accounts for n bytes having been transferred, and blocks until the limit
+// allows them to have been, by clock, or ctx is done.
+func (this *bandwidthLimiter) wait(ctx context.Context, clock Clock, n int) (error) {
+	if n <= 0 { return nil }
+
+	this.lock.Lock()
+	now := clock.Now()
+	if this.next.Before(now) {
+		this.next = now
+	}
+	this.next = this.next.Add(time.Duration(int64(n) * int64(time.Second) / this.rate))
+	until := this.next
+	this.lock.Unlock()
+
+	return clockSleep(ctx, clock, until.Sub(now))
+}
+
+// a body read no faster than its limiter allows. Reading fails with the
+// request context's error if it's done while waiting.
+type throttledReader struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+	ctx     context.Context
+	clock   Clock
+}
+
+func (this *throttledReader) Read(p []byte) (int, error) {
+	if c := this.limiter.chunk(); len(p) > c {
+		p = p[:c]
+	}
+	n, err := this.ReadCloser.Read(p)
+	if e := this.limiter.wait(this.ctx, this.clock, n); e != nil { return n, e }
+	return n, err
+}
+
+func (this *bandwidthLimiter) wrap(body io.ReadCloser, r *http.Request) (io.ReadCloser) {
+	if this == nil || body == nil || body == http.NoBody { return body }
+	return &throttledReader{ReadCloser: body, limiter: this, ctx: r.Context(), clock: requestClock(r)}
+}
+
+// limits the total upload and download bandwidth used by a Reqtifier, in bytes
+// per second, across all of its requests. A limit of zero means unlimited.
+// Only request and response bodies are counted.
+func WithMaxBytesPerSecond(up, down int64) Option {
+	upload := newBandwidthLimiter(up)
+	download := newBandwidthLimiter(down)
+
+	return WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			r.Body = upload.wrap(r.Body, r)
+			if getBody := r.GetBody; getBody != nil && upload != nil {
+				r.GetBody = func() (io.ReadCloser, error) {
+					body, err := getBody()
+					return upload.wrap(body, r), err
+				}
+			}
+
+			resp, err := next(r)
+			if resp != nil {
+				resp.Body = download.wrap(resp.Body, r)
+			}
+			return resp, err
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"errors"
+	"time"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestMaxBytesPerSecond(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(req.Body)
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(strings.Repeat("x", 200)))}, nil
+	})
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithMaxBytesPerSecond(1000, 2000))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	done := make(chan error)
+	go func() {
+		_, err := reqt.New("/").Method(POST).FormArg("data", strings.Repeat("y", 200)).Into(textCapture{into: &text}).Do()
+		done <- err
+	}()
+
+	// 205 bytes up at 1000/s, in reads of 100, then 200 bytes down at 2000/s
+	for _, wait := range []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 5 * time.Millisecond, 99 * time.Millisecond} {
+		clock.BlockUntil(1)
+		clock.Advance(wait)
+	}
+	select {
+	case <- done: t.Fatalf("Throttle Mismatch: finished after 304ms, expected 305ms")
+	case <- time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Millisecond)
+
+	if err := <- done; err != nil { t.Fatalf("Do Failure: %s", err.Error()) }
+	if len(text) != 200 { t.Errorf("Body Mismatch: got %d bytes, expected 200", len(text)) }
+}
+
+func TestMaxBytesPerSecondCancel(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{Body: ioutil.NopCloser(strings.NewReader(strings.Repeat("x", 200)))}, nil
+	})
+
+	clock := test.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithClock(clock), WithMaxBytesPerSecond(0, 1))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	// at a byte a second, the body would take minutes, but canceling stops it
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		var text string
+		_, err := reqt.New("/").Context(ctx).Into(textCapture{into: &text}).Do()
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	if err := <- done; !errors.Is(err, context.Canceled) { t.Errorf("Cancel Mismatch: got %v, expected context.Canceled", err) }
+}
+
+type textCapture struct {
+	into *string
+}
+
+func (this textCapture) Unmarshal(b []byte) error {
+	*this.into = string(b)
+	return nil
+}
@@ -0,0 +1,125 @@
+package reqtify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+/*
+   Distributed tracing. With a Tracer, each request gets one client span,
+   named for its method and path template (see PathTemplate), covering every
+   attempt at it. Attributes follow OpenTelemetry's semantic conventions:
+
+	http.request.method        the request's verb
+	url.full                   the URL, masked by the Reqtifier's Redactor
+	url.template               the path template
+	server.address             the host the request was sent to
+	http.response.status_code  the final response's status code
+	http.request.resend_count  how many times the request was retried
+
+   Each retry is also recorded as a "retry" event. The span is marked failed
+   if the request fails or gets a 4xx or 5xx status. If there is a
+   Propagator, it injects the span's context into each attempt's headers.
+
+   The interfaces are shaped after OpenTelemetry's, so that adapting its API
+   takes a few lines, without reqtify depending on it:
+
+	type otelTracer struct{ trace.Tracer }
+
+	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, reqtify.Span) {
+		ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+		return ctx, otelSpan{span}
+	}
+
+	propagator := reqtify.PropagatorFunc(func(ctx context.Context, h http.Header) {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+	})
+*/
+
+// a Tracer starts spans. The context it returns must carry the new span,
+// so that the Propagator can find it.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// a Span records one request. Attribute values are strings, ints, or bools.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	AddEvent(name string, attributes map[string]interface{})
+	RecordError(err error)
+	SetFailed(description string)
+	End()
+}
+
+// a Propagator writes the trace context carried by ctx into outgoing headers,
+// like the W3C traceparent header.
+type Propagator interface {
+	Inject(ctx context.Context, header http.Header)
+}
+
+// adapts an ordinary function to a Propagator.
+type PropagatorFunc func(ctx context.Context, header http.Header)
+
+func (this PropagatorFunc) Inject(ctx context.Context, header http.Header) {
+	this(ctx, header)
+}
+
+// traces each request with tracer, injecting trace context into outgoing
+// headers with propagator, if it isn't nil.
+func WithTracing(tracer Tracer, propagator Propagator) Option {
+	return func(r *ReqtifierImpl) {
+		r.Tracer = tracer
+		r.Propagator = propagator
+	}
+}
+
+// names the request for tracing, without the parts that change between calls,
+// like "/posts/{id}". The default is the path.
+func (this *RequestImpl) PathTemplate(template string) (Request) {
+	this.URLTemplate = template
+	return this
+}
+
+func (this *RequestImpl) pathTemplate() (string) {
+	if this.URLTemplate != "" { return this.URLTemplate }
+	return this.URLPath
+}
+
+type spanContextKey struct{}
+
+func spanFromContext(ctx context.Context) (Span) {
+	span, _ := ctx.Value(spanContextKey{}).(Span)
+	return span
+}
+
+func (this *ReqtifierImpl) traced(req *RequestImpl, do func(*RequestImpl) (*http.Response, error)) (*http.Response, error) {
+	ctx, span := this.Tracer.Start(req.context(), string(req.Verb) + " " + req.pathTemplate())
+	defer span.End()
+
+	// so send can find the span, and the propagator its context
+	saved := req.RequestContext
+	req.RequestContext = context.WithValue(ctx, spanContextKey{}, span)
+	resp, err := do(req)
+	req.RequestContext = saved
+
+	span.SetAttribute("http.request.method", string(req.Verb))
+	span.SetAttribute("url.full", this.redactor().URL(req.URL()))
+	span.SetAttribute("url.template", req.pathTemplate())
+	if target, e := url.Parse(req.Target()); e == nil {
+		span.SetAttribute("server.address", target.Hostname())
+	}
+	if req.attempts > 1 {
+		span.SetAttribute("http.request.resend_count", req.attempts - 1)
+	}
+	if resp != nil {
+		span.SetAttribute("http.response.status_code", resp.StatusCode)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetFailed(err.Error())
+	} else if resp != nil && resp.StatusCode >= 400 {
+		span.SetFailed(resp.Status)
+	}
+	return resp, err
+}
@@ -0,0 +1,86 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	events     []string
+	errors     []error
+	failed     string
+	ended      bool
+}
+
+func (this *testSpan) SetAttribute(key string, value interface{}) { this.attributes[key] = value }
+func (this *testSpan) AddEvent(name string, attributes map[string]interface{}) { this.events = append(this.events, name) }
+func (this *testSpan) RecordError(err error) { this.errors = append(this.errors, err) }
+func (this *testSpan) SetFailed(description string) { this.failed = description }
+func (this *testSpan) End() { this.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (this *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &testSpan{name: name, attributes: make(map[string]interface{})}
+	this.spans = append(this.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span.name), span
+}
+
+func TestTracing(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	var traceparents []string
+	calls := 0
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		calls++
+		traceparents = append(traceparents, req.Header.Get("traceparent"))
+		if calls == 1 { return &http.Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader("busy"))}, nil }
+		if calls == 2 { return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("hello"))}, nil }
+		return &http.Response{StatusCode: 404, Status: "404 Not Found", Body: ioutil.NopCloser(strings.NewReader("gone"))}, nil
+	})
+
+	tracer := &testTracer{}
+	propagator := PropagatorFunc(func(ctx context.Context, header http.Header) {
+		name, _ := ctx.Value(testSpanKey{}).(string)
+		header.Set("traceparent", name)
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test", WithTracing(tracer, propagator), WithRedactor(&Redactor{Params: []string{"token"}}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var text string
+	if _, err := reqt.New("/posts/7").PathTemplate("/posts/{id}").URLArg("token", "secret").Retry(RetryPolicy{MaxAttempts: 2}).TextInto(&text).Do(); err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+
+	if len(tracer.spans) != 1 { t.Fatalf("Spans Mismatch: got %d, expected 1", len(tracer.spans)) }
+	span := tracer.spans[0]
+	if span.name != "GET /posts/{id}" { t.Errorf("Name Mismatch: got %q, expected %q", span.name, "GET /posts/{id}") }
+	if !span.ended { t.Errorf("Ended Mismatch: span wasn't ended") }
+	if span.failed != "" || len(span.errors) != 0 { t.Errorf("Failed Mismatch: got %q, %v", span.failed, span.errors) }
+	if len(span.events) != 1 || span.events[0] != "retry" { t.Errorf("Events Mismatch: got %v", span.events) }
+	expected := map[string]interface{}{
+		"http.request.method": "GET",
+		"url.full": "https://this.is.a.test/posts/7?token=%5Bredacted%5D",
+		"url.template": "/posts/{id}",
+		"server.address": "this.is.a.test",
+		"http.request.resend_count": 1,
+		"http.response.status_code": 200,
+	}
+	for k, v := range expected {
+		if span.attributes[k] != v { t.Errorf("Attribute Mismatch: %s: got %v, expected %v", k, span.attributes[k], v) }
+	}
+	if len(traceparents) != 2 || traceparents[0] != span.name || traceparents[1] != span.name { t.Errorf("Traceparent Mismatch: got %v", traceparents) }
+
+	reqt.New("/posts/8").Do()
+	span = tracer.spans[1]
+	if span.name != "GET /posts/8" { t.Errorf("Name Mismatch: got %q, expected %q", span.name, "GET /posts/8") }
+	if span.failed != "404 Not Found" { t.Errorf("Failed Mismatch: got %q, expected %q", span.failed, "404 Not Found") }
+	if _, ok := span.attributes["http.request.resend_count"]; ok { t.Errorf("Resend Mismatch: got %v", span.attributes["http.request.resend_count"]) }
+}
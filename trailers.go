@@ -0,0 +1,112 @@
+package reqtify
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+/*
+   HTTP trailers are headers sent after a body rather than before it, so
+   they can carry things which aren't known until the whole body has been
+   sent, like a checksum computed while streaming it:
+
+	hash := sha256.New()
+	req.Body(io.TeeReader(file, hash), "application/octet-stream").
+		Trailer("X-Checksum-Sha256", func() string { return hex.EncodeToString(hash.Sum(nil)) })
+
+   A request with trailers is sent chunked, even if its length is known.
+   Trailers can't be sent without a body. Response trailers aren't known
+   until its body has been read, so TrailerInto fills them in once it has.
+*/
+
+// sends a trailer called key after the request body, whose value is whatever
+// value returns once the body has been sent. If the body is sent more than
+// once, as when the request is retried, value is called each time.
+func (this *RequestImpl) Trailer(key string, value func() string) (Request) {
+	if this.Trailers == nil { this.Trailers = make(map[string]func() string) }
+	this.Trailers[http.CanonicalHeaderKey(key)] = value
+	return this
+}
+
+// stores the first value of the response trailer key in into, or "" if the
+// response doesn't have it, once the response body has been read to the end.
+func (this *RequestImpl) TrailerInto(key string, into *string) (Request) {
+	this.HeaderCaptures = append(this.HeaderCaptures, HeaderCapture{Key: key, Into: into, Trailer: true})
+	return this
+}
+
+// stores a copy of the response trailers in into, once the response body has
+// been read to the end.
+func (this *RequestImpl) TrailersInto(into *http.Header) (Request) {
+	this.HeaderCaptures = append(this.HeaderCaptures, HeaderCapture{All: into, Trailer: true})
+	return this
+}
+
+// announces the request's trailers on r, and fills in their values whenever
+// r's body, or a copy of it from GetBody, has been read to the end.
+func (this *RequestImpl) declareTrailers(r *http.Request) {
+	if len(this.Trailers) == 0 || r.Body == nil || r.Body == http.NoBody { return }
+
+	r.Trailer = make(http.Header, len(this.Trailers))
+	for key := range this.Trailers {
+		r.Trailer[key] = nil
+	}
+	r.ContentLength = -1
+	r.Body = &trailerBody{ReadCloser: r.Body, trailer: r.Trailer, values: this.Trailers}
+	if getBody := r.GetBody; getBody != nil {
+		r.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil { return nil, err }
+			return &trailerBody{ReadCloser: body, trailer: r.Trailer, values: this.Trailers}, nil
+		}
+	}
+}
+
+// fills in trailer from values once the body it wraps has been read.
+type trailerBody struct {
+	io.ReadCloser
+	trailer http.Header
+	values  map[string]func() string
+}
+
+func (this *trailerBody) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+	if err == io.EOF {
+		for key, value := range this.values {
+			this.trailer.Set(key, value())
+		}
+	}
+	return n, err
+}
+
+// returns resp's body, wrapped so that the trailers this request asked for
+// are captured once it's been read, if it asked for any.
+func (this *RequestImpl) captureTrailers(resp *http.Response) (io.ReadCloser) {
+	for _, c := range this.HeaderCaptures {
+		if c.Trailer { return &trailerCapture{ReadCloser: resp.Body, req: this, resp: resp} }
+	}
+	return resp.Body
+}
+
+// captures trailers, once, when the response body reaches its end.
+type trailerCapture struct {
+	io.ReadCloser
+	req  *RequestImpl
+	resp *http.Response
+	once sync.Once
+}
+
+func (this *trailerCapture) Read(p []byte) (int, error) {
+	n, err := this.ReadCloser.Read(p)
+	if err == io.EOF {
+		this.once.Do(func() {
+			for _, c := range this.req.HeaderCaptures {
+				if !c.Trailer { continue }
+				if c.Into != nil { *c.Into = this.resp.Trailer.Get(c.Key) }
+				if c.All != nil { *c.All = this.resp.Trailer.Clone() }
+			}
+		})
+	}
+	return n, err
+}
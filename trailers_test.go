@@ -0,0 +1,72 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+func TestTrailers(t *testing.T) {
+	var got, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body, got = string(data), r.Trailer.Get("X-Checksum")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte("ok"))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer server.Close()
+
+	hash := sha256.New()
+	var status string
+	var trailers http.Header
+	var response []byte
+	_, err := New(server.URL, nil, nil, nil, "test").New("/").Method(PUT).
+		Body(io.TeeReader(bytes.NewReader([]byte("payload")), hash), "text/plain").
+		Trailer("X-Checksum", func() string { return hex.EncodeToString(hash.Sum(nil)) }).
+		TrailerInto("Grpc-Status", &status).
+		TrailersInto(&trailers).
+		Into(FromBytes(&response)).
+		Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+
+	sum := sha256.Sum256([]byte("payload"))
+	if body != "payload" { t.Errorf("Body Mismatch: got %q", body) }
+	if got != hex.EncodeToString(sum[:]) { t.Errorf("Request Trailer Mismatch: got %q, expected %q", got, hex.EncodeToString(sum[:])) }
+	if string(response) != "ok" { t.Errorf("Response Mismatch: got %q", response) }
+	if status != "0" { t.Errorf("Response Trailer Mismatch: got %q, expected \"0\"", status) }
+	if trailers.Get("Grpc-Status") != "0" { t.Errorf("Trailers Mismatch: got %v", trailers) }
+}
+
+func TestTrailersAfterRead(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: 200, Trailer: http.Header{}}
+		resp.Body = ioutil.NopCloser(readerFunc(func(p []byte) (int, error) {
+			resp.Trailer.Set("Checksum", "abc")
+			return 0, io.EOF
+		}))
+		return resp, nil
+	})
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var checksum string
+	resp, err := reqt.New("/").TrailerInto("Checksum", &checksum).Do()
+	if err != nil { t.Fatalf("Request Failure: %s", err.Error()) }
+	if checksum != "" { t.Errorf("Early Mismatch: got %q before the body was read", checksum) }
+	ioutil.ReadAll(resp.Body)
+	if checksum != "abc" { t.Errorf("Trailer Mismatch: got %q, expected \"abc\"", checksum) }
+
+	// requests without bodies don't announce trailers they can't send
+	r, _ := reqt.New("/").Method(POST).Body(strings.NewReader(""), "text/plain").Trailer("X", func() string { return "" }).(*RequestImpl).HTTPRequest(context.Background())
+	if r.Trailer != nil { t.Errorf("Empty Body Mismatch: got trailers %v", r.Trailer) }
+}
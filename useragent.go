@@ -0,0 +1,108 @@
+package reqtify
+
+import (
+	"strings"
+)
+
+// a product token in a User-Agent header, like "reqtify/1.0", with an
+// optional comment, like "(+https://example.com/bot)".
+type Product struct {
+	Name     string
+	Version  string
+	Comments []string
+}
+
+func (this Product) String() string {
+	s := this.Name
+	if this.Version != "" {
+		s += "/" + this.Version
+	}
+	if len(this.Comments) != 0 {
+		escaped := make([]string, len(this.Comments))
+		for i, c := range this.Comments {
+			escaped[i] = commentEscaper.Replace(c)
+		}
+		s += " (" + strings.Join(escaped, "; ") + ")"
+	}
+	return s
+}
+
+var commentEscaper = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+
+// a structured User-Agent, built from product tokens. Many APIs require (and
+// it's polite to send) a User-Agent naming your software, its version, and a
+// way to contact you:
+//
+//	NewUserAgent("MyBot", "1.2").Contact("https://example.com/bot").Product("reqtify", "")
+//
+// produces "MyBot/1.2 (+https://example.com/bot) reqtify".
+type UserAgent struct {
+	Products []Product
+}
+
+// creates a UserAgent whose first product token is name/version.
+func NewUserAgent(name, version string) (*UserAgent) {
+	return (&UserAgent{}).Product(name, version)
+}
+
+// appends a product token.
+func (this *UserAgent) Product(name, version string) (*UserAgent) {
+	this.Products = append(this.Products, Product{Name: name, Version: version})
+	return this
+}
+
+// adds a comment to the most recently added product token.
+func (this *UserAgent) Comment(comment string) (*UserAgent) {
+	if len(this.Products) == 0 { return this }
+	last := &this.Products[len(this.Products) - 1]
+	last.Comments = append(last.Comments, comment)
+	return this
+}
+
+// adds contact information (a URL or email address) as a comment on the
+// first product token, in the conventional "+url" form.
+func (this *UserAgent) Contact(contact string) (*UserAgent) {
+	if len(this.Products) == 0 { return this }
+	this.Products[0].Comments = append(this.Products[0].Comments, "+" + contact)
+	return this
+}
+
+func (this *UserAgent) String() string {
+	if this == nil { return "" }
+	tokens := make([]string, len(this.Products))
+	for i, p := range this.Products {
+		tokens[i] = p.String()
+	}
+	return strings.Join(tokens, " ")
+}
+
+// sets a structured User-Agent, which takes precedence over the agent name
+// passed to New.
+func WithUserAgent(ua *UserAgent) Option {
+	return func(r *ReqtifierImpl) {
+		r.UserAgent = ua
+	}
+}
+
+// returns the User-Agent to send for requests made through this Reqtifier,
+// not including any per-request suffix.
+func (this *ReqtifierImpl) userAgent() (string) {
+	if this.UserAgent != nil {
+		return this.UserAgent.String()
+	}
+	return this.AgentName
+}
+
+// appends tokens to the User-Agent sent with this request, for example to
+// identify the component of a larger bot which is making it.
+func (this *RequestImpl) UserAgentSuffix(suffix string) (Request) {
+	this.AgentSuffix = append(this.AgentSuffix, suffix)
+	return this
+}
+
+// returns the full User-Agent for this request.
+func (this *RequestImpl) userAgent() (string) {
+	tokens := []string{this.ReqClient.userAgent()}
+	tokens = append(tokens, this.AgentSuffix...)
+	return strings.TrimSpace(strings.Join(tokens, " "))
+}
@@ -0,0 +1,83 @@
+package reqtify
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+/*
+   Scraped APIs regularly emit invalid UTF-8, which encoding/json quietly
+   turns into U+FFFD, encoding/xml refuses to parse, and other decoders pass
+   through untouched into strings that downstream storage (like Postgres)
+   then rejects. A UTF8Policy makes the handling explicit and consistent for
+   all text based formats. Binary formats, like MessagePack and CBOR, are
+   never modified.
+*/
+
+type UTF8Policy int
+
+const (
+	UTF8Passthrough UTF8Policy = iota // leave response bodies alone, the default.
+	UTF8Replace                       // replace invalid sequences with U+FFFD before decoding.
+	UTF8Reject                        // fail decoding with an InvalidUTF8Error.
+)
+
+// returned from Do when a response contains invalid UTF-8 under UTF8Reject.
+type InvalidUTF8Error struct {
+	Offset int // the offset of the first invalid byte in the response body.
+}
+
+func (this *InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("reqtify: invalid UTF-8 in response body at offset %d", this.Offset)
+}
+
+// sets how invalid UTF-8 in text responses (text, JSON, XML, YAML, CSV, and
+// anything decoded by a TextDecoder, like HTML) is handled before they are
+// decoded.
+func WithUTF8Policy(policy UTF8Policy) Option {
+	return func(r *ReqtifierImpl) {
+		r.UTF8Policy = policy
+	}
+}
+
+// a ResponseUnmarshaller may implement TextDecoder to say that it decodes
+// text, so that the UTF8Policy applies to it.
+type TextDecoder interface {
+	DecodesText() bool
+}
+
+func isTextUnmarshaller(u ResponseUnmarshaller) (bool) {
+	switch u := u.(type) {
+	case TextUnmarshaller, JSONUnmarshaller, *JSONExtractor, XMLUnmarshaller, YAMLUnmarshaller, CSVUnmarshaller, GraphQLUnmarshaller, JSONAPIUnmarshaller, HALUnmarshaller:
+		return true
+	case TextDecoder:
+		return u.DecodesText()
+	}
+	return false
+}
+
+// applies the Reqtifier's UTF8Policy to a response body about to be decoded
+// by u, as Do does. Exported for Request implementations which embed
+// RequestImpl.
+func (this *RequestImpl) CheckUTF8(u ResponseUnmarshaller, body []byte) ([]byte, error) {
+	return this.ReqClient.checkUTF8(u, body)
+}
+
+// applies the UTF8Policy to a response body about to be decoded by u.
+func (this *ReqtifierImpl) checkUTF8(u ResponseUnmarshaller, body []byte) ([]byte, error) {
+	if this == nil || this.UTF8Policy == UTF8Passthrough || !isTextUnmarshaller(u) || utf8.Valid(body) {
+		return body, nil
+	}
+	if this.UTF8Policy == UTF8Replace {
+		return bytes.ToValidUTF8(body, []byte("\uFFFD")), nil
+	}
+
+	offset := 0
+	for offset < len(body) {
+		r, size := utf8.DecodeRune(body[offset:])
+		if r == utf8.RuneError && size == 1 { break }
+		offset += size
+	}
+	return nil, &InvalidUTF8Error{Offset: offset}
+}
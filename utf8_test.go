@@ -0,0 +1,39 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+func TestUTF8Policy(t *testing.T) {
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("<doc><name>caf\xe9</name></doc>"))}, nil
+	})
+
+	var out struct {
+		Name string `xml:"name"`
+	}
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	if _, err := reqt.New("/").XMLInto(&out).Do(); err == nil { t.Errorf("Passthrough Mismatch: invalid XML accepted") }
+
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithUTF8Policy(UTF8Replace))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	_, err := reqt.New("/").XMLInto(&out).Do()
+	if err != nil || out.Name != "caf�" { t.Errorf("Replace Mismatch: got %q, %v", out.Name, err) }
+
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithUTF8Policy(UTF8Reject))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	_, err = reqt.New("/").XMLInto(&out).Do()
+	if e, ok := err.(*InvalidUTF8Error); !ok || e.Offset != 14 { t.Errorf("Reject Mismatch: got %v", err) }
+
+	// binary formats aren't touched
+	var text string
+	_, err = reqt.New("/").Into(&textCapture{into: &text}).Do()
+	if err != nil || text != "<doc><name>caf\xe9</name></doc>" { t.Errorf("Binary Mismatch: got %q, %v", text, err) }
+}
@@ -0,0 +1,103 @@
+package reqtify
+
+/*
+   Validation turns a response which decoded fine but doesn't make sense into
+   an error from Do, so that callers don't each have to check for missing
+   fields or out of range values themselves.
+
+   Values which implement Validator are validated automatically when they're
+   decoded by JSONInto, XMLInto, YAMLInto, MsgpackInto, CBORInto, CSVInto or
+   ExtractInto, or by an unmarshaller which implements Targeter, like
+   protobuf.Into. Other checks can be added to individual requests:
+
+	api.New("/user").JSONInto(&user).Validate(func() error {
+		if user.ID == 0 { return errors.New("missing id") }
+		return nil
+	}).Do()
+
+   Validation only runs if every unmarshaller succeeded.
+*/
+
+// a Validator checks itself after being decoded from a response.
+type Validator interface {
+	Validate() error
+}
+
+// returned by Do when a decoded response fails validation.
+type ValidationError struct {
+	Err error
+}
+
+func (this *ValidationError) Error() (string) {
+	return "response failed validation: " + this.Err.Error()
+}
+
+func (this *ValidationError) Unwrap() (error) {
+	return this.Err
+}
+
+// runs check after the response has been successfully unmarshalled. If it
+// returns an error, Do returns it wrapped in a *ValidationError.
+func (this *RequestImpl) Validate(check func() error) (Request) {
+	this.Validators = append(this.Validators, check)
+	return this
+}
+
+// a ResponseUnmarshaller may implement Targeter to list the values it decodes
+// into, so that those which implement Validator are validated.
+type Targeter interface {
+	Targets() []interface{}
+}
+
+// returns the values an unmarshaller decodes into.
+func unmarshalTargets(u ResponseUnmarshaller) ([]interface{}) {
+	switch u := u.(type) {
+	case JSONUnmarshaller:
+		return []interface{}{u.output_value}
+	case XMLUnmarshaller:
+		return []interface{}{u.output_value}
+	case YAMLUnmarshaller:
+		return []interface{}{u.output_value}
+	case MsgpackUnmarshaller:
+		return []interface{}{u.output_value}
+	case CBORUnmarshaller:
+		return []interface{}{u.output_value}
+	case CSVUnmarshaller:
+		return []interface{}{u.output_value}
+	case JSONAPIUnmarshaller:
+		return []interface{}{u.output_value}
+	case HALUnmarshaller:
+		return []interface{}{u.output_value}
+	case GraphQLUnmarshaller:
+		var targets []interface{}
+		for _, inner := range u.Unmarshallers {
+			targets = append(targets, unmarshalTargets(inner)...)
+		}
+		return targets
+	case *JSONExtractor:
+		var targets []interface{}
+		for _, t := range u.targets {
+			targets = append(targets, t.into)
+		}
+		return targets
+	case Targeter:
+		return u.Targets()
+	}
+	return nil
+}
+
+// validates the values decoded by unmarshallers which implement Validator,
+// then runs the request's own checks.
+func (this *RequestImpl) ValidateResponse(unmarshallers []ResponseUnmarshaller) (error) {
+	for _, u := range unmarshallers {
+		for _, target := range unmarshalTargets(u) {
+			if v, ok := target.(Validator); ok {
+				if err := v.Validate(); err != nil { return &ValidationError{Err: err} }
+			}
+		}
+	}
+	for _, check := range this.Validators {
+		if err := check(); err != nil { return &ValidationError{Err: err} }
+	}
+	return nil
+}
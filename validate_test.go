@@ -0,0 +1,55 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"errors"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+type validatedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (this *validatedUser) Validate() (error) {
+	if this.ID == 0 { return errors.New("missing id") }
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	body := `{"id": 1, "name": "alice"}`
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+
+	var user validatedUser
+	checked := false
+	_, err := reqt.New("/user").JSONInto(&user).Validate(func() error { checked = true; return nil }).Do()
+	if err != nil || !checked { t.Errorf("Valid Mismatch: got %v, checked %t", err, checked) }
+
+	body = `{"name": "bob"}`
+	user = validatedUser{}
+	_, err = reqt.New("/user").JSONInto(&user).Do()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Err.Error() != "missing id" { t.Errorf("Interface Mismatch: got %v", err) }
+
+	var name string
+	_, err = reqt.New("/user").ExtractInto("name", &name).Validate(func() error {
+		if name != "alice" { return errors.New("wrong user") }
+		return nil
+	}).Do()
+	if !errors.As(err, &verr) || name != "bob" { t.Errorf("Check Mismatch: got %v", err) }
+
+	// validation doesn't run if decoding failed
+	body = `{"id": "one"}`
+	checked = false
+	_, err = reqt.New("/user").JSONInto(&user).Validate(func() error { checked = true; return nil }).Do()
+	if err == nil || errors.As(err, &verr) || checked { t.Errorf("Decode Mismatch: got %v, checked %t", err, checked) }
+}
@@ -0,0 +1,90 @@
+package reqtify
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+
+	"golang.org/x/net/html/charset"
+)
+
+/*
+   Real world XML, particularly RSS and Atom feeds from scraped sites, is
+   frequently not well formed: HTML entities like &nbsp; that the document
+   never declares, namespace prefixes that are never bound, unclosed <br>
+   tags, and encodings other than UTF-8. encoding/xml refuses all of those by
+   default. XMLOptions relaxes it.
+*/
+
+type XMLOptions struct {
+	// accept undeclared entities (HTML's are recognized), unclosed HTML
+	// void elements, and other common mistakes.
+	Lenient          bool
+
+	// drop namespaces from element and attribute names before matching them
+	// to struct fields, so that documents match regardless of how (or
+	// whether) they declare namespaces. Struct tags should then omit
+	// namespaces too.
+	IgnoreNamespaces bool
+
+	// converts documents declaring a non-UTF-8 encoding. Defaults to one
+	// which understands the encodings in the WHATWG Encoding Standard.
+	CharsetReader    func(charset string, input io.Reader) (io.Reader, error)
+}
+
+// sets the options used by XMLInto.
+func WithXMLOptions(options XMLOptions) Option {
+	return func(r *ReqtifierImpl) {
+		r.XMLOptions = &options
+	}
+}
+
+// decodes XML with options, rather than with encoding/xml's defaults.
+func FromXMLWith(output_value interface{}, options XMLOptions) ResponseUnmarshaller {
+	return XMLUnmarshaller{output_value: output_value, options: &options}
+}
+
+func (this *XMLOptions) decode(body []byte, v interface{}) error {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.CharsetReader = this.CharsetReader
+	if d.CharsetReader == nil {
+		d.CharsetReader = charset.NewReaderLabel
+	}
+	if this.Lenient {
+		d.Strict = false
+		d.AutoClose = xmlAutoClose
+		d.Entity = xml.HTMLEntity
+	}
+	if this.IgnoreNamespaces {
+		d = xml.NewTokenDecoder(namespaceStripper{d})
+	}
+	return d.Decode(v)
+}
+
+// HTML's void elements, except <link>, which RSS uses with content.
+var xmlAutoClose = []string{"basefont", "br", "area", "img", "param", "hr", "input", "col", "frame", "isindex", "base", "meta"}
+
+type namespaceStripper struct {
+	d *xml.Decoder
+}
+
+func (this namespaceStripper) Token() (xml.Token, error) {
+	t, err := this.d.Token()
+	switch e := t.(type) {
+	case xml.StartElement:
+		e.Name.Space = ""
+		attrs := make([]xml.Attr, 0, len(e.Attr))
+		for _, a := range e.Attr {
+			// namespace declarations would otherwise turn into attributes named "xmlns"
+			if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" { continue }
+			a.Name.Space = ""
+			attrs = append(attrs, a)
+		}
+		e.Attr = attrs
+		return e, err
+	case xml.EndElement:
+		e.Name.Space = ""
+		return e, err
+	}
+	return t, err
+}
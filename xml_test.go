@@ -0,0 +1,45 @@
+package reqtify
+
+import (
+	"github.com/thewug/reqtify/test"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"strings"
+)
+
+type testFeed struct {
+	Title string `xml:"channel>title"`
+	Items []struct {
+		Title string `xml:"title"`
+		Image struct {
+			URL string `xml:"url,attr"`
+		} `xml:"content"`
+	} `xml:"channel>item"`
+}
+
+func TestXMLOptions(t *testing.T) {
+	feed := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<rss><channel><title>Caf\xe9&nbsp;News</title>" +
+		"<item><title>One<br></title><media:content url=\"https://example.com/1.jpg\"/></item>" +
+		"</channel></rss>"
+
+	var http_mock_client test.MockHttpClient
+	http_mock_client.AnalyzeWith(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(feed))}, nil
+	})
+
+	reqt := New("https://this.is.a.test", nil, nil, nil, "test")
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	var out testFeed
+	if _, err := reqt.New("/feed").XMLInto(&out).Do(); err == nil { t.Errorf("Strict Mismatch: malformed feed accepted") }
+
+	reqt = New("https://this.is.a.test", nil, nil, nil, "test", WithXMLOptions(XMLOptions{Lenient: true, IgnoreNamespaces: true}))
+	reqt.(*ReqtifierImpl).HttpClient = &http_mock_client
+	out = testFeed{}
+	_, err := reqt.New("/feed").XMLInto(&out).Do()
+	if err != nil { t.Fatalf("Lenient Failure: %s", err.Error()) }
+	if out.Title != "Café News" || len(out.Items) != 1 || out.Items[0].Title != "One" || out.Items[0].Image.URL != "https://example.com/1.jpg" {
+		t.Errorf("Lenient Mismatch: got %+v", out)
+	}
+}